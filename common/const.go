@@ -8,6 +8,13 @@ const InviteTokenExpirySecond = 604800
 
 const CurrentUser = "user"
 
+// CurrentApiKey is set next to CurrentUser when a request authenticated with
+// an api key rather than a user token
+const CurrentApiKey = "api_key"
+
+const RequestId = "request_id"
+
 type Requester interface {
 	GetRole() string
+	IsMfaEnabled() bool
 }
@@ -0,0 +1,21 @@
+package common
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrTimeRangeInvalid = NewCustomError(
+	errors.New("time range invalid"),
+	"from must not be after to",
+	"ErrTimeRangeInvalid",
+)
+
+// ValidateTimeRange checks a filter's from and to, both of which are
+// included and either of which may be left open
+func ValidateTimeRange(from, to *time.Time) error {
+	if from != nil && to != nil && from.After(*to) {
+		return ErrTimeRangeInvalid
+	}
+	return nil
+}
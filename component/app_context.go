@@ -1,7 +1,11 @@
 package component
 
 import (
+	"app-invite-service/component/invitesigner"
+	"app-invite-service/component/mailer"
+	"app-invite-service/component/oidc"
 	"app-invite-service/component/tokenprovider"
+	"app-invite-service/config"
 
 	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
@@ -12,6 +16,12 @@ type AppContext interface {
 	GetDBConn() *gorm.DB
 	GetRedisConn() *redis.Client
 	GetTokenConfig() *tokenprovider.TokenConfig
+	GetMailer() mailer.Mailer
+	GetConfig() *config.Config
+	GetIDTokenSigner() *oidc.Signer
+	GetIdentityProviders() oidc.Registry
+	// GetInviteSigner is nil unless signed invitation tokens are enabled
+	GetInviteSigner() invitesigner.Signer
 }
 
 type appCtx struct {
@@ -19,6 +29,11 @@ type appCtx struct {
 	db          *gorm.DB
 	redis       *redis.Client
 	tokenConfig *tokenprovider.TokenConfig
+	mailer      mailer.Mailer
+	cfg         *config.Config
+	signer      *oidc.Signer
+	providers   oidc.Registry
+	invites     invitesigner.Signer
 }
 
 func NewAppContext(
@@ -26,8 +41,23 @@ func NewAppContext(
 	redis *redis.Client,
 	secretKey string,
 	tokenConfig *tokenprovider.TokenConfig,
+	mailer mailer.Mailer,
+	cfg *config.Config,
+	signer *oidc.Signer,
+	providers oidc.Registry,
+	invites invitesigner.Signer,
 ) AppContext {
-	return &appCtx{secretKey: secretKey, db: db, redis: redis, tokenConfig: tokenConfig}
+	return &appCtx{
+		secretKey:   secretKey,
+		db:          db,
+		redis:       redis,
+		tokenConfig: tokenConfig,
+		mailer:      mailer,
+		cfg:         cfg,
+		signer:      signer,
+		providers:   providers,
+		invites:     invites,
+	}
 }
 
 func (ctx *appCtx) GetDBConn() *gorm.DB {
@@ -45,3 +75,23 @@ func (ctx *appCtx) SecretKey() string {
 func (ctx *appCtx) GetTokenConfig() *tokenprovider.TokenConfig {
 	return ctx.tokenConfig
 }
+
+func (ctx *appCtx) GetMailer() mailer.Mailer {
+	return ctx.mailer
+}
+
+func (ctx *appCtx) GetConfig() *config.Config {
+	return ctx.cfg
+}
+
+func (ctx *appCtx) GetIDTokenSigner() *oidc.Signer {
+	return ctx.signer
+}
+
+func (ctx *appCtx) GetIdentityProviders() oidc.Registry {
+	return ctx.providers
+}
+
+func (ctx *appCtx) GetInviteSigner() invitesigner.Signer {
+	return ctx.invites
+}
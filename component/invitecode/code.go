@@ -0,0 +1,102 @@
+package invitecode
+
+import (
+	crand "crypto/rand"
+	"strings"
+)
+
+// Alphabet is Crockford's base32. It leaves out I, L, O and U, so codes
+// survive being read aloud, printed or typed in by hand.
+const Alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// A code is Groups groups of GroupSize symbols joined by "-", like
+// ABCD-EFGH-JKM7. The last symbol is the check symbol.
+const (
+	GroupSize = 4
+	Groups    = 3
+	Length    = GroupSize * Groups
+)
+
+const base = len(Alphabet)
+
+// decode maps every symbol a user may type to its value. Lower case is
+// accepted and the letters Crockford leaves out read as the digits they
+// look like.
+var decode = func() map[rune]int {
+	m := make(map[rune]int, 2*base+4)
+	for i, r := range Alphabet {
+		m[r] = i
+		m[r+'a'-'A'] = i
+	}
+	for _, r := range "oO" {
+		m[r] = 0
+	}
+	for _, r := range "iIlL" {
+		m[r] = 1
+	}
+	return m
+}()
+
+// Generate returns a random code with 55 bits of entropy
+func Generate() (string, error) {
+	b := make([]byte, Length-1)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+
+	values := make([]int, Length-1, Length)
+	for i := range b {
+		// 256 is a multiple of 32, so every symbol is equally likely
+		values[i] = int(b[i]) % base
+	}
+
+	return format(append(values, checkSymbol(values))), nil
+}
+
+// Parse normalises s and returns the code in its canonical form. It reports
+// false when s isn't a code or its check symbol doesn't match, so typos are
+// caught without a lookup.
+func Parse(s string) (string, bool) {
+	values := make([]int, 0, Length)
+	for _, r := range s {
+		if r == '-' || r == ' ' {
+			continue
+		}
+		v, ok := decode[r]
+		if !ok || len(values) == Length {
+			return "", false
+		}
+		values = append(values, v)
+	}
+
+	if len(values) != Length || checkSymbol(values[:Length-1]) != values[Length-1] {
+		return "", false
+	}
+
+	return format(values), true
+}
+
+// checkSymbol is the Luhn mod 32 check of values. It catches every single
+// mistyped symbol and every swap of two neighbouring ones but 0 and Z.
+func checkSymbol(values []int) int {
+	factor, sum := 2, 0
+	for i := len(values) - 1; i >= 0; i-- {
+		addend := factor * values[i]
+		addend = addend/base + addend%base
+		sum += addend
+		factor = 3 - factor
+	}
+
+	return (base - sum%base) % base
+}
+
+func format(values []int) string {
+	var sb strings.Builder
+	for i, v := range values {
+		if i > 0 && i%GroupSize == 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(Alphabet[v])
+	}
+	return sb.String()
+}
@@ -0,0 +1,96 @@
+package invitecode_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/component/invitecode"
+)
+
+func TestInviteCode_Generate(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{4}-[0-9A-HJKMNP-TV-Z]{4}-[0-9A-HJKMNP-TV-Z]{4}$`)
+
+	for i := 0; i < 100; i++ {
+		code, err := invitecode.Generate()
+		require.Nil(t, err)
+		assert.Regexp(t, pattern, code)
+
+		parsed, ok := invitecode.Parse(code)
+		assert.True(t, ok, code)
+		assert.Equal(t, code, parsed)
+	}
+}
+
+func TestInviteCode_Parse(t *testing.T) {
+	code, err := invitecode.Generate()
+	require.Nil(t, err)
+	compact := strings.ReplaceAll(code, "-", "")
+
+	tcs := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"canonical", code, code},
+		{"lower case", strings.ToLower(code), code},
+		{"without dashes", compact, code},
+		{"with spaces", compact[:4] + " " + compact[4:8] + " " + compact[8:], code},
+		{"empty", "", ""},
+		{"too short", code[:len(code)-1], ""},
+		{"too long", code + "0", ""},
+		{"outside alphabet", "U" + code[1:], ""},
+		{"legacy token", "aZ09bY18", ""},
+	}
+
+	for _, tc := range tcs {
+		parsed, ok := invitecode.Parse(tc.input)
+		assert.Equal(t, tc.expected != "", ok, tc.name)
+		assert.Equal(t, tc.expected, parsed, tc.name)
+	}
+}
+
+func TestInviteCode_ParseAmbiguousLetters(t *testing.T) {
+	// 0 and 1 are typed as the letters they look like
+	for _, code := range []string{"0000-0000-0000", "1111-1111-111F"} {
+		parsed, ok := invitecode.Parse(code)
+		require.True(t, ok, code)
+
+		typed := strings.NewReplacer("0", "o", "1", "l").Replace(code)
+		got, ok := invitecode.Parse(typed)
+		assert.True(t, ok, typed)
+		assert.Equal(t, parsed, got, typed)
+	}
+}
+
+func TestInviteCode_ParseCatchesTypos(t *testing.T) {
+	code, err := invitecode.Generate()
+	require.Nil(t, err)
+	symbols := []byte(strings.ReplaceAll(code, "-", ""))
+
+	for i := range symbols {
+		for _, r := range []byte(invitecode.Alphabet) {
+			if r == symbols[i] {
+				continue
+			}
+			typo := append([]byte{}, symbols...)
+			typo[i] = r
+			_, ok := invitecode.Parse(string(typo))
+			assert.False(t, ok, "typo %s", typo)
+		}
+	}
+
+	for i := 0; i+1 < len(symbols); i++ {
+		a, b := symbols[i], symbols[i+1]
+		if a == b || (a == '0' && b == 'Z') || (a == 'Z' && b == '0') {
+			continue
+		}
+		swapped := append([]byte{}, symbols...)
+		swapped[i], swapped[i+1] = b, a
+		_, ok := invitecode.Parse(string(swapped))
+		assert.False(t, ok, "swap %s", swapped)
+	}
+}
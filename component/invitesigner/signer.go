@@ -0,0 +1,259 @@
+package invitesigner
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Algorithms a Signer can use, see signed_invitation.algorithm in the config
+const (
+	AlgorithmHMAC    = "hmac"
+	AlgorithmEd25519 = "ed25519"
+)
+
+const (
+	version     = 1
+	algHMAC     = 1
+	algEd25519  = 2
+	idLength    = 8
+	payloadSize = 2 + idLength + 8 + 4 + 4
+)
+
+var (
+	ErrMalformed        = errors.New("signed invitation token is malformed")
+	ErrSignatureInvalid = errors.New("signed invitation token has an invalid signature")
+	ErrExpired          = errors.New("signed invitation token has expired")
+)
+
+// pattern matches the shape of a token: payload and signature, base64url encoded
+var pattern = regexp.MustCompile(`^[A-Za-z0-9_-]{35}\.[A-Za-z0-9_-]{43,86}$`)
+
+// Claims is what a token carries. Id names the token in the revocation list
+// and use counts; zero CampaignId and MaxUses mean none and no limit.
+type Claims struct {
+	Id         string
+	ExpiresAt  time.Time
+	CampaignId int
+	MaxUses    int
+}
+
+// Signer issues invitation tokens that can be checked without any storage:
+// a compact binary payload and its signature, each base64url encoded and
+// joined by ".".
+type Signer interface {
+	Algorithm() string
+	Sign(claims *Claims) (string, error)
+	// Verify checks the signature and expiry of token. Expired tokens come
+	// with their claims along with ErrExpired.
+	Verify(token string, now time.Time) (*Claims, error)
+	// PublicKey is what verifies tokens offline, nil for HMAC
+	PublicKey() ed25519.PublicKey
+}
+
+// IsSigned reports whether token has the shape of a signed token. It says
+// nothing about the signature.
+func IsSigned(token string) bool {
+	return pattern.MatchString(token)
+}
+
+// NewId returns a random token id
+func NewId() (string, error) {
+	b := make([]byte, idLength)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Load returns the signer the config asks for, or nil if signed tokens are off
+func Load(algorithm, secret, privateKeyFile string) (Signer, error) {
+	switch algorithm {
+	case "":
+		return nil, nil
+	case AlgorithmHMAC:
+		if len(secret) < 32 {
+			return nil, errors.New("signed invitation secret must have at least 32 bytes")
+		}
+		return NewHMACSigner([]byte(secret)), nil
+	case AlgorithmEd25519:
+		key, err := LoadEd25519Key(privateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return NewEd25519Signer(key), nil
+	}
+
+	return nil, errors.New("signed invitation algorithm must be hmac or ed25519")
+}
+
+// LoadEd25519Key reads a PEM encoded PKCS#8 Ed25519 private key
+func LoadEd25519Key(path string) (ed25519.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM block found in signed invitation key file")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("signed invitation key is not an Ed25519 key")
+	}
+
+	return key, nil
+}
+
+type hmacSigner struct {
+	secret []byte
+}
+
+func NewHMACSigner(secret []byte) Signer {
+	return &hmacSigner{secret: secret}
+}
+
+func (s *hmacSigner) Algorithm() string {
+	return AlgorithmHMAC
+}
+
+func (s *hmacSigner) mac(payload []byte) []byte {
+	m := hmac.New(sha256.New, s.secret)
+	m.Write(payload)
+	return m.Sum(nil)
+}
+
+func (s *hmacSigner) Sign(claims *Claims) (string, error) {
+	payload, err := marshal(algHMAC, claims)
+	if err != nil {
+		return "", err
+	}
+	return encode(payload, s.mac(payload)), nil
+}
+
+func (s *hmacSigner) Verify(token string, now time.Time) (*Claims, error) {
+	payload, sig, err := decode(token, algHMAC)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(sig, s.mac(payload)) {
+		return nil, ErrSignatureInvalid
+	}
+	return unmarshal(payload, now)
+}
+
+func (s *hmacSigner) PublicKey() ed25519.PublicKey {
+	return nil
+}
+
+type ed25519Signer struct {
+	key ed25519.PrivateKey
+}
+
+func NewEd25519Signer(key ed25519.PrivateKey) Signer {
+	return &ed25519Signer{key: key}
+}
+
+func (s *ed25519Signer) Algorithm() string {
+	return AlgorithmEd25519
+}
+
+func (s *ed25519Signer) Sign(claims *Claims) (string, error) {
+	payload, err := marshal(algEd25519, claims)
+	if err != nil {
+		return "", err
+	}
+	return encode(payload, ed25519.Sign(s.key, payload)), nil
+}
+
+func (s *ed25519Signer) Verify(token string, now time.Time) (*Claims, error) {
+	payload, sig, err := decode(token, algEd25519)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(s.PublicKey(), payload, sig) {
+		return nil, ErrSignatureInvalid
+	}
+	return unmarshal(payload, now)
+}
+
+func (s *ed25519Signer) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// marshal lays claims out as version, algorithm, id, expiry in unix seconds,
+// campaign id and max uses. The algorithm is signed along, so a token can't
+// be checked with another algorithm than it was issued with.
+func marshal(alg byte, claims *Claims) ([]byte, error) {
+	id, err := hex.DecodeString(claims.Id)
+	if err != nil || len(id) != idLength || claims.CampaignId < 0 || claims.MaxUses < 0 {
+		return nil, ErrMalformed
+	}
+
+	payload := make([]byte, payloadSize)
+	payload[0], payload[1] = version, alg
+	copy(payload[2:], id)
+	binary.BigEndian.PutUint64(payload[2+idLength:], uint64(claims.ExpiresAt.Unix()))
+	binary.BigEndian.PutUint32(payload[10+idLength:], uint32(claims.CampaignId))
+	binary.BigEndian.PutUint32(payload[14+idLength:], uint32(claims.MaxUses))
+	return payload, nil
+}
+
+func unmarshal(payload []byte, now time.Time) (*Claims, error) {
+	claims := Claims{
+		Id:         hex.EncodeToString(payload[2 : 2+idLength]),
+		ExpiresAt:  time.Unix(int64(binary.BigEndian.Uint64(payload[2+idLength:])), 0).UTC(),
+		CampaignId: int(binary.BigEndian.Uint32(payload[10+idLength:])),
+		MaxUses:    int(binary.BigEndian.Uint32(payload[14+idLength:])),
+	}
+
+	if !now.Before(claims.ExpiresAt) {
+		return &claims, ErrExpired
+	}
+
+	return &claims, nil
+}
+
+func encode(payload, sig []byte) string {
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func decode(token string, alg byte) ([]byte, []byte, error) {
+	if !IsSigned(token) {
+		return nil, nil, ErrMalformed
+	}
+
+	parts := strings.SplitN(token, ".", 2)
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(payload) != payloadSize || payload[0] != version {
+		return nil, nil, ErrMalformed
+	}
+	if payload[1] != alg {
+		return nil, nil, ErrSignatureInvalid
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, ErrMalformed
+	}
+
+	return payload, sig, nil
+}
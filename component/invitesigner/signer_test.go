@@ -0,0 +1,95 @@
+package invitesigner_test
+
+import (
+	"crypto/ed25519"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/component/invitesigner"
+)
+
+func newClaims(t *testing.T, expiresAt time.Time) *invitesigner.Claims {
+	id, err := invitesigner.NewId()
+	require.Nil(t, err)
+	return &invitesigner.Claims{Id: id, ExpiresAt: expiresAt.Truncate(time.Second).UTC(), CampaignId: 7, MaxUses: 3}
+}
+
+func TestInviteSigner_SignVerify(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.Nil(t, err)
+
+	signers := []invitesigner.Signer{
+		invitesigner.NewHMACSigner([]byte(strings.Repeat("s", 32))),
+		invitesigner.NewEd25519Signer(key),
+	}
+
+	now := time.Now()
+	for _, signer := range signers {
+		claims := newClaims(t, now.Add(time.Hour))
+
+		token, err := signer.Sign(claims)
+		require.Nil(t, err, signer.Algorithm())
+		assert.True(t, invitesigner.IsSigned(token), signer.Algorithm())
+
+		got, err := signer.Verify(token, now)
+		require.Nil(t, err, signer.Algorithm())
+		assert.Equal(t, claims, got, signer.Algorithm())
+
+		got, err = signer.Verify(token, now.Add(2*time.Hour))
+		assert.Equal(t, invitesigner.ErrExpired, err, signer.Algorithm())
+		assert.Equal(t, claims.Id, got.Id, signer.Algorithm())
+
+		// flip the last bit of the payload's max uses
+		parts := strings.Split(token, ".")
+		b := []byte(parts[0])
+		if b[len(b)-1] == 'A' {
+			b[len(b)-1] = 'E'
+		} else {
+			b[len(b)-1] = 'A'
+		}
+		_, err = signer.Verify(string(b)+"."+parts[1], now)
+		assert.Equal(t, invitesigner.ErrSignatureInvalid, err, signer.Algorithm())
+	}
+}
+
+func TestInviteSigner_VerifyRejectsOtherSigners(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.Nil(t, err)
+
+	hmacSigner := invitesigner.NewHMACSigner([]byte(strings.Repeat("s", 32)))
+	otherSecret := invitesigner.NewHMACSigner([]byte(strings.Repeat("o", 32)))
+	edSigner := invitesigner.NewEd25519Signer(key)
+
+	now := time.Now()
+	token, err := hmacSigner.Sign(newClaims(t, now.Add(time.Hour)))
+	require.Nil(t, err)
+
+	_, err = otherSecret.Verify(token, now)
+	assert.Equal(t, invitesigner.ErrSignatureInvalid, err)
+
+	_, err = edSigner.Verify(token, now)
+	assert.Equal(t, invitesigner.ErrSignatureInvalid, err)
+
+	_, err = hmacSigner.Verify("ABCD-EFGH-JKM7", now)
+	assert.Equal(t, invitesigner.ErrMalformed, err)
+}
+
+func TestInviteSigner_Load(t *testing.T) {
+	signer, err := invitesigner.Load("", "", "")
+	assert.Nil(t, err)
+	assert.Nil(t, signer)
+
+	_, err = invitesigner.Load(invitesigner.AlgorithmHMAC, "short", "")
+	assert.NotNil(t, err)
+
+	signer, err = invitesigner.Load(invitesigner.AlgorithmHMAC, strings.Repeat("s", 32), "")
+	require.Nil(t, err)
+	assert.Nil(t, signer.PublicKey())
+
+	_, err = invitesigner.Load("rsa", "", "")
+	assert.NotNil(t, err)
+}
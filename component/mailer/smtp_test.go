@@ -0,0 +1,40 @@
+package mailer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/component/mailer"
+)
+
+func TestMailer_BuildMessage(t *testing.T) {
+	var tcs = []struct {
+		msg      *mailer.Message
+		contains []string
+	}{
+		{
+			&mailer.Message{To: []string{"user@gmail.com"}, Subject: "Hi", Text: "hello"},
+			[]string{"To: user@gmail.com", "Subject: Hi", "Content-Type: text/plain; charset=UTF-8", "hello"},
+		},
+		{
+			&mailer.Message{To: []string{"user@gmail.com"}, Subject: "Hi", HTML: "<b>hello</b>"},
+			[]string{"Content-Type: text/html; charset=UTF-8", "<b>hello</b>"},
+		},
+		{
+			&mailer.Message{To: []string{"a@gmail.com", "b@gmail.com"}, Subject: "Hi", Text: "hello", HTML: "<b>hello</b>"},
+			[]string{"To: a@gmail.com, b@gmail.com", "multipart/alternative", "hello", "<b>hello</b>"},
+		},
+	}
+
+	for _, tc := range tcs {
+		output, err := mailer.BuildMessage("no-reply@evite.local", tc.msg)
+		require.Nil(t, err, err)
+		assert.True(t, strings.HasPrefix(string(output), "From: no-reply@evite.local\r\n"))
+		for _, s := range tc.contains {
+			assert.Contains(t, string(output), s)
+		}
+	}
+}
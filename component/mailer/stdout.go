@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+)
+
+// stdoutMailer prints every message instead of delivering it. Use it in dev.
+type stdoutMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+func NewStdoutMailer(from string) *stdoutMailer {
+	return &stdoutMailer{w: os.Stdout, from: from}
+}
+
+func (m *stdoutMailer) Send(_ context.Context, msg *Message) error {
+	body, err := BuildMessage(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err = m.w.Write(append(body, '\n'))
+	return err
+}
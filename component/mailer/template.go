@@ -0,0 +1,97 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htemplate "html/template"
+	"os"
+	ttemplate "text/template"
+)
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+// Template renders the subject and bodies of an email from the same data
+type Template struct {
+	subject *ttemplate.Template
+	text    *ttemplate.Template
+	html    *htemplate.Template
+}
+
+func NewTemplate(subject, text, html string) (*Template, error) {
+	t := &Template{}
+
+	var err error
+	if t.subject, err = ttemplate.New("subject").Parse(subject); err != nil {
+		return nil, err
+	}
+	if text != "" {
+		if t.text, err = ttemplate.New("text").Parse(text); err != nil {
+			return nil, err
+		}
+	}
+	if html != "" {
+		if t.html, err = htemplate.New("html").Parse(html); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// LoadTemplate reads the text and HTML bodies from the given paths. An empty
+// path falls back to the built-in `templates/<name>.txt` or `.html`.
+func LoadTemplate(name, subject, textPath, htmlPath string) (*Template, error) {
+	text, err := readTemplate(textPath, "templates/"+name+".txt")
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := readTemplate(htmlPath, "templates/"+name+".html")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTemplate(subject, text, html)
+}
+
+func readTemplate(path, fallback string) (string, error) {
+	if path != "" {
+		b, err := os.ReadFile(path)
+		return string(b), err
+	}
+
+	b, err := defaultTemplates.ReadFile(fallback)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(b), err
+}
+
+func (t *Template) Render(to []string, data interface{}) (*Message, error) {
+	msg := &Message{To: to}
+
+	var buf bytes.Buffer
+	if err := t.subject.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	msg.Subject = buf.String()
+
+	if t.text != nil {
+		buf.Reset()
+		if err := t.text.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		msg.Text = buf.String()
+	}
+
+	if t.html != nil {
+		buf.Reset()
+		if err := t.html.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		msg.HTML = buf.String()
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,32 @@
+package mailer_test
+
+import (
+	"html/template"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/component/mailer"
+)
+
+func TestTemplate_Render(t *testing.T) {
+	tpl, err := mailer.LoadTemplate("invitation", "Join {{.AppName}}", "", "")
+	require.Nil(t, err, err)
+
+	msg, err := tpl.Render([]string{"user@gmail.com"}, map[string]interface{}{
+		"AppName":   "evite",
+		"Token":     "AbC123",
+		"Link":      template.URL("myapp://invite?code=AbC123&a=b"),
+		"ExpiresAt": time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+	})
+	require.Nil(t, err, err)
+
+	assert.Equal(t, []string{"user@gmail.com"}, msg.To)
+	assert.Equal(t, "Join evite", msg.Subject)
+	assert.Contains(t, msg.Text, "AbC123")
+	assert.Contains(t, msg.Text, "myapp://invite?code=AbC123&a=b")
+	assert.Contains(t, msg.HTML, "myapp://invite?code=AbC123&amp;a=b")
+	assert.Contains(t, msg.HTML, "2022-05-01 00:00 UTC")
+}
@@ -0,0 +1,298 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrInvalidIdToken  = errors.New("invalid id token")
+)
+
+// ProviderConfig describes how we are registered at an upstream provider
+type ProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// IdTokenClaims are the claims we use from an upstream ID token
+type IdTokenClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// RelyingParty signs users in at an upstream OIDC provider with the
+// authorization code flow. Discovery and keys are fetched on first use, so the
+// service still starts while a provider is unreachable.
+type RelyingParty struct {
+	cfg    ProviderConfig
+	client *http.Client
+
+	mu   sync.Mutex
+	meta *discovery
+	keys map[string]*rsa.PublicKey
+}
+
+func NewRelyingParty(cfg ProviderConfig, client *http.Client) *RelyingParty {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email"}
+	}
+	cfg.Issuer = strings.TrimRight(cfg.Issuer, "/")
+
+	return &RelyingParty{cfg: cfg, client: client}
+}
+
+func (rp *RelyingParty) Name() string {
+	return rp.cfg.Name
+}
+
+// Registry looks relying parties up by provider name
+type Registry map[string]*RelyingParty
+
+func NewRegistry(configs []ProviderConfig, client *http.Client) Registry {
+	registry := Registry{}
+	for _, cfg := range configs {
+		registry[cfg.Name] = NewRelyingParty(cfg, client)
+	}
+	return registry
+}
+
+func (r Registry) Get(name string) (*RelyingParty, error) {
+	rp, ok := r[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return rp, nil
+}
+
+func (rp *RelyingParty) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := rp.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %d", u, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (rp *RelyingParty) discover(ctx context.Context) (*discovery, error) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if rp.meta != nil {
+		return rp.meta, nil
+	}
+
+	var meta discovery
+	if err := rp.getJSON(ctx, rp.cfg.Issuer+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimRight(meta.Issuer, "/") != rp.cfg.Issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match %q", meta.Issuer, rp.cfg.Issuer)
+	}
+
+	rp.meta = &meta
+	return rp.meta, nil
+}
+
+// AuthCodeURL is where the user is sent to sign in upstream
+func (rp *RelyingParty) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	meta, err := rp.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", rp.cfg.ClientId)
+	q.Set("redirect_uri", rp.cfg.RedirectURL)
+	q.Set("scope", strings.Join(rp.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// Exchange trades an authorization code for tokens and returns the claims of
+// the verified ID token
+func (rp *RelyingParty) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*IdTokenClaims, error) {
+	meta, err := rp.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {rp.cfg.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(rp.cfg.ClientId), url.QueryEscape(rp.cfg.ClientSecret))
+
+	resp, err := rp.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IdToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint: %s %s", body.Error, body.ErrorDescription)
+	}
+
+	return rp.verifyIdToken(ctx, meta, body.IdToken, nonce)
+}
+
+func (rp *RelyingParty) verifyIdToken(
+	ctx context.Context,
+	meta *discovery,
+	raw, nonce string,
+) (*IdTokenClaims, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return rp.publicKey(ctx, meta, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIdToken, err)
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimRight(iss, "/") != rp.cfg.Issuer {
+		return nil, fmt.Errorf("%w: issuer mismatch", ErrInvalidIdToken)
+	}
+
+	if !hasAudience(claims["aud"], rp.cfg.ClientId) {
+		return nil, fmt.Errorf("%w: audience mismatch", ErrInvalidIdToken)
+	}
+
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIdToken)
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIdToken)
+	}
+
+	email, _ := claims["email"].(string)
+	verified, _ := claims["email_verified"].(bool)
+
+	return &IdTokenClaims{Subject: sub, Email: email, EmailVerified: verified}, nil
+}
+
+// jwt-go only understands a single string audience
+func hasAudience(aud interface{}, clientId string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientId
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// publicKey refetches the key set once for an unknown kid, so key rotation
+// upstream doesn't need a restart
+func (rp *RelyingParty) publicKey(ctx context.Context, meta *discovery, kid string) (*rsa.PublicKey, error) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if key, ok := rp.keys[kid]; ok {
+		return key, nil
+	}
+
+	var set JWKS
+	if err := rp.getJSON(ctx, meta.JwksURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	rp.keys = keys
+
+	key, ok := rp.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("no key with kid %q", kid)
+	}
+	return key, nil
+}
@@ -0,0 +1,57 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/component/oidc"
+	"app-invite-service/mock"
+)
+
+const redirectURL = "http://127.0.0.1:5000/callback"
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestRelyingParty_Exchange(t *testing.T) {
+	server := mock.NewOIDCServer()
+	defer server.Close()
+
+	rp := oidc.NewRelyingParty(server.ProviderConfig(redirectURL), nil)
+	user := mock.OIDCUser{Subject: "u-1", Email: "staff@corp.com", EmailVerified: true}
+	verifier := "verifier-0123456789-0123456789-0123456789"
+
+	authURL, err := rp.AuthCodeURL(context.Background(), "state", "nonce", challenge(verifier))
+	require.Nil(t, err, err)
+
+	u, err := url.Parse(authURL)
+	require.Nil(t, err, err)
+	assert.Equal(t, server.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, mock.OIDCClientId, u.Query().Get("client_id"))
+	assert.Equal(t, redirectURL, u.Query().Get("redirect_uri"))
+	assert.Equal(t, "openid email", u.Query().Get("scope"))
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+
+	claims, err := rp.Exchange(context.Background(), server.SignIn(authURL, user), verifier, "nonce")
+	require.Nil(t, err, err)
+	assert.Equal(t, &oidc.IdTokenClaims{Subject: "u-1", Email: "staff@corp.com", EmailVerified: true}, claims)
+
+	_, err = rp.Exchange(context.Background(), server.SignIn(authURL, user), verifier, "other-nonce")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIdToken)
+
+	_, err = rp.Exchange(context.Background(), server.SignIn(authURL, user), "wrong-verifier", "nonce")
+	assert.NotNil(t, err)
+
+	cfg := server.ProviderConfig(redirectURL)
+	cfg.ClientId = "someone-else"
+	_, err = oidc.NewRelyingParty(cfg, nil).Exchange(context.Background(), server.SignIn(authURL, user), verifier, "nonce")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,103 @@
+package oidc
+
+import (
+	crand "crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Signer signs ID tokens with RS256, so relying parties can verify them
+// against the published JWKS without knowing any shared secret
+type Signer struct {
+	key *rsa.PrivateKey
+	kid string
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewSigner(key *rsa.PrivateKey) *Signer {
+	sum := sha256.Sum256(key.PublicKey.N.Bytes())
+	return &Signer{key: key, kid: base64.RawURLEncoding.EncodeToString(sum[:12])}
+}
+
+// LoadSigner reads a PEM encoded RSA private key (PKCS#1 or PKCS#8). With an
+// empty path it generates a key, which only lives as long as the process.
+func LoadSigner(path string) (*Signer, error) {
+	if path == "" {
+		key, err := rsa.GenerateKey(crand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		return NewSigner(key), nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM block found in signing key file")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return NewSigner(key), nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("signing key is not an RSA key")
+	}
+
+	return NewSigner(key), nil
+}
+
+func (s *Signer) KeyId() string {
+	return s.kid
+}
+
+func (s *Signer) PublicKey() *rsa.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s *Signer) Sign(claims jwt.Claims) (string, error) {
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	t.Header["kid"] = s.kid
+	return t.SignedString(s.key)
+}
+
+func (s *Signer) JWKS() *JWKS {
+	return &JWKS{Keys: []JWK{{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: s.kid,
+		N:   base64.RawURLEncoding.EncodeToString(s.key.PublicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.PublicKey.E)).Bytes()),
+	}}}
+}
@@ -0,0 +1,32 @@
+package oidc_test
+
+import (
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/component/oidc"
+)
+
+func TestSigner_Sign(t *testing.T) {
+	signer, err := oidc.LoadSigner("")
+	require.Nil(t, err, err)
+
+	token, err := signer.Sign(jwt.MapClaims{"sub": "1", "aud": "client"})
+	require.Nil(t, err, err)
+
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		assert.Equal(t, signer.KeyId(), token.Header["kid"])
+		return signer.PublicKey(), nil
+	})
+	require.Nil(t, err, err)
+	assert.True(t, parsed.Valid)
+	assert.Equal(t, "RS256", parsed.Method.Alg())
+
+	jwks := signer.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, signer.KeyId(), jwks.Keys[0].Kid)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+}
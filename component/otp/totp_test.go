@@ -0,0 +1,68 @@
+package otp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/component/otp"
+)
+
+// RFC 6238 appendix B, SHA1 secret, truncated to 6 digits
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTotp_GenerateCode(t *testing.T) {
+	var tcs = []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range tcs {
+		output, err := otp.GenerateCode(rfcSecret, otp.Step(time.Unix(tc.unix, 0)))
+		require.Nil(t, err, err)
+		assert.Equal(t, tc.expected, output, "they should be equal")
+	}
+}
+
+func TestTotp_Validate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	var tcs = []struct {
+		code     string
+		at       time.Time
+		expected bool
+	}{
+		{"081804", now, true},
+		{"081 804", now, true},
+		{"081804", now.Add(30 * time.Second), true},
+		{"081804", now.Add(90 * time.Second), false},
+		{"000000", now, false},
+		{"08180", now, false},
+	}
+
+	for _, tc := range tcs {
+		_, ok := otp.Validate(rfcSecret, tc.code, tc.at, 1)
+		assert.Equal(t, tc.expected, ok, tc.code)
+	}
+}
+
+func TestTotp_GenerateSecret(t *testing.T) {
+	secret, err := otp.GenerateSecret()
+	require.Nil(t, err, err)
+	assert.Len(t, secret, 32)
+
+	uri := otp.URI("evite", "admin@gmail.com", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/evite:admin@gmail.com?"))
+	assert.Contains(t, uri, "secret="+secret)
+	assert.Contains(t, uri, "issuer=evite")
+}
@@ -0,0 +1,71 @@
+package qrcode
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	qr "github.com/skip2/go-qrcode"
+)
+
+// PNG encodes content as a size x size pixel QR code
+func PNG(content string, size int) ([]byte, error) {
+	return qr.Encode(content, qr.Medium, size)
+}
+
+// PNGDataURI is PNG as a `data:` URI, ready for an <img> tag
+func PNGDataURI(content string, size int) (string, error) {
+	png, err := PNG(content, size)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+}
+
+// SVG encodes content as a size x size pixel QR code. Dark modules are drawn
+// as one path, so the code stays sharp at any scale.
+func SVG(content string, size int) ([]byte, error) {
+	bitmap, err := Bitmap(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var path strings.Builder
+	eachRun(bitmap, func(x, y, width int) {
+		fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", x, y, width, width)
+	})
+
+	n := len(bitmap)
+	return []byte(fmt.Sprintf(
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+			`<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="%s"/></svg>`,
+		size, size, n, n, n, n, path.String(),
+	)), nil
+}
+
+// Bitmap is the QR code of content as rows of modules, true for dark ones,
+// with the quiet zone around it
+func Bitmap(content string) ([][]bool, error) {
+	code, err := qr.New(content, qr.Medium)
+	if err != nil {
+		return nil, err
+	}
+	return code.Bitmap(), nil
+}
+
+// eachRun calls draw for every horizontal run of dark modules, so neighbours
+// are drawn as one shape without seams between them
+func eachRun(bitmap [][]bool, draw func(x, y, width int)) {
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			draw(start, y, x-start)
+		}
+	}
+}
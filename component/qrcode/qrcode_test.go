@@ -0,0 +1,58 @@
+package qrcode_test
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/component/qrcode"
+)
+
+func TestQRCode_SVG(t *testing.T) {
+	svg, err := qrcode.SVG("myapp://invite?code=ABCD-EFGH-JKM7", 256)
+	require.Nil(t, err)
+
+	bitmap, err := qrcode.Bitmap("myapp://invite?code=ABCD-EFGH-JKM7")
+	require.Nil(t, err)
+
+	assert.True(t, bytes.HasPrefix(svg, []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256"`)))
+	assert.Contains(t, string(svg), fmt.Sprintf(`viewBox="0 0 %d %d"`, len(bitmap), len(bitmap)))
+	assert.True(t, bytes.HasSuffix(svg, []byte("</svg>")))
+}
+
+func TestQRCode_PDFSheet(t *testing.T) {
+	var codes []qrcode.SheetCode
+	for i := 0; i < qrcode.SheetCodesPage+1; i++ {
+		code := fmt.Sprintf("code%d", i)
+		codes = append(codes, qrcode.SheetCode{Content: "myapp://invite?code=" + code, Label: code})
+	}
+
+	pdf, err := qrcode.PDFSheet("batch (spring)", codes)
+	require.Nil(t, err)
+
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), "/Count 2")
+	assert.Contains(t, string(pdf), `(batch \(spring\))`)
+	assert.Contains(t, string(pdf), "(code12)")
+
+	// every xref entry points at its object
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	require.NotNil(t, m)
+	xref, err := strconv.Atoi(string(m[1]))
+	require.Nil(t, err)
+	require.True(t, bytes.HasPrefix(pdf[xref:], []byte("xref\n0 8\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	require.Len(t, entries, 7)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(string(entry[1]))
+		require.Nil(t, err)
+		assert.True(t, bytes.HasPrefix(pdf[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+	}
+}
@@ -0,0 +1,144 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// A sheet is an A4 page, in points, with SheetColumns x SheetRows codes
+const (
+	SheetColumns   = 3
+	SheetRows      = 4
+	SheetCodesPage = SheetColumns * SheetRows
+
+	pageWidth   = 595.0
+	pageHeight  = 842.0
+	pageMargin  = 36.0
+	titleHeight = 28.0
+	codeSize    = 140.0
+	labelSize   = 10.0
+	titleSize   = 14.0
+	// Courier glyphs are all 0.6 em wide, so labels can be centred without
+	// font metrics
+	glyphWidth = 0.6
+)
+
+// SheetCode is a code on a sheet: the content it encodes and the label
+// printed below it
+type SheetCode struct {
+	Content string
+	Label   string
+}
+
+// PDFSheet lays codes out on as many printable A4 pages as they need, each
+// headed by title. The PDF is written by hand with the codes drawn as
+// vectors, so it needs no images or embedded fonts.
+func PDFSheet(title string, codes []SheetCode) ([]byte, error) {
+	var pages []string
+	for start := 0; start < len(codes) || start == 0; start += SheetCodesPage {
+		end := start + SheetCodesPage
+		if end > len(codes) {
+			end = len(codes)
+		}
+
+		content, err := sheetPage(title, codes[start:end])
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, content)
+	}
+
+	return writePDF(pages), nil
+}
+
+func sheetPage(title string, codes []SheetCode) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "BT /F1 %g Tf %g %g Td (%s) Tj ET\n",
+		titleSize, pageMargin, pageHeight-pageMargin-titleSize, pdfString(title))
+
+	cellWidth := (pageWidth - 2*pageMargin) / SheetColumns
+	cellHeight := (pageHeight - 2*pageMargin - titleHeight) / SheetRows
+
+	for i, code := range codes {
+		bitmap, err := Bitmap(code.Content)
+		if err != nil {
+			return "", err
+		}
+
+		left := pageMargin + float64(i%SheetColumns)*cellWidth + (cellWidth-codeSize)/2
+		top := pageHeight - pageMargin - titleHeight - float64(i/SheetColumns)*cellHeight
+		module := codeSize / float64(len(bitmap))
+
+		b.WriteString("0 g\n")
+		eachRun(bitmap, func(x, y, width int) {
+			fmt.Fprintf(&b, "%.3f %.3f %.3f %.3f re\n",
+				left+float64(x)*module, top-float64(y+1)*module, float64(width)*module, module)
+		})
+		b.WriteString("f\n")
+
+		labelWidth := float64(utf8.RuneCountInString(code.Label)) * glyphWidth * labelSize
+		fmt.Fprintf(&b, "BT /F1 %g Tf %.3f %.3f Td (%s) Tj ET\n",
+			labelSize, left+(codeSize-labelWidth)/2, top-codeSize-labelSize-4, pdfString(code.Label))
+	}
+
+	return b.String(), nil
+}
+
+// writePDF writes a PDF with one page per content stream, all set in Courier
+func writePDF(pages []string) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	// objects 1 to 3 are the catalog, page tree and font, then every page
+	// is followed by its content stream
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>")
+
+	for i, content := range pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*i,
+		))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+// pdfString escapes s for a PDF string literal. The standard fonts only
+// cover Latin-1, so anything outside printable ASCII becomes "?".
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
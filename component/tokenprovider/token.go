@@ -25,9 +25,21 @@ type Token struct {
 	Expiry  int       `json:"expiry"` // milliseconds
 }
 
+const (
+	// PurposeMfa marks the short-lived token handed out between the password
+	// and the MFA step of a login. It must never be accepted as an access token.
+	PurposeMfa = "mfa"
+	// PurposeOAuth marks access tokens issued to OAuth clients. They only
+	// grant access to the OIDC userinfo endpoint, not to the rest of the API.
+	PurposeOAuth = "oauth"
+)
+
 type TokenPayload struct {
 	UserId          int    `json:"user_id,omitempty"`
 	InvitationToken string `json:"invite_token,omitempty"`
+	Purpose         string `json:"purpose,omitempty"`
+	ClientId        string `json:"client_id,omitempty"`
+	Scope           string `json:"scope,omitempty"`
 }
 
 type TokenConfig struct {
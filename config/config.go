@@ -8,10 +8,22 @@ import (
 
 type (
 	Config struct {
-		App    `yaml:"app"`
-		Logger `yaml:"logger"`
-		MySQL  `yaml:"mysql"`
-		Redis  `yaml:"redis"`
+		App              `yaml:"app"`
+		Logger           `yaml:"logger"`
+		MySQL            `yaml:"mysql"`
+		Redis            `yaml:"redis"`
+		Auth             `yaml:"auth"`
+		Mail             `yaml:"mail"`
+		Invitation       `yaml:"invitation"`
+		LoginLimit       `yaml:"login_limit"`
+		Mfa              `yaml:"mfa"`
+		OAuth            `yaml:"oauth"`
+		Federation       `yaml:"federation"`
+		ApiKey           `yaml:"api_key"`
+		Referral         `yaml:"referral"`
+		InviteQuota      `yaml:"invite_quota"`
+		Waitlist         `yaml:"waitlist"`
+		SignedInvitation `yaml:"signed_invitation"`
 		//RMQ   `yaml:"rabbitmq"`
 	}
 
@@ -24,6 +36,7 @@ type (
 		ENV            string `env-required:"true" yaml:"env"                  env:"APP_ENV"`
 		AllowedOrigins string `env-required:"true" yaml:"allowed_origins"      env:"ALLOWED_ORIGINS"`
 		SecretKey      string `env-required:"true"                             env:"APP_SECRET_KEY"`
+		PublicURL      string `env-required:"true" yaml:"public_url"           env:"APP_PUBLIC_URL"`
 	}
 
 	Logger struct {
@@ -41,6 +54,121 @@ type (
 		Password string `env-required:"true" yaml:"password" env:"REDIS_PASSWORD"`
 	}
 
+	Auth struct {
+		RequireVerifiedEmail      bool   `                    yaml:"require_verified_email"      env:"AUTH_REQUIRE_VERIFIED_EMAIL"`
+		InviteOnlyRegistration    bool   `                    yaml:"invite_only_registration"    env:"AUTH_INVITE_ONLY_REGISTRATION"`
+		AllowedEmailDomains       string `                    yaml:"allowed_email_domains"       env:"AUTH_ALLOWED_EMAIL_DOMAINS"`
+		DeniedEmailDomains        string `                    yaml:"denied_email_domains"        env:"AUTH_DENIED_EMAIL_DOMAINS"`
+		EmailVerificationExpiry   int    `env-required:"true" yaml:"email_verification_expiry"   env:"AUTH_EMAIL_VERIFICATION_EXPIRY"`
+		EmailVerificationInterval int    `env-required:"true" yaml:"email_verification_interval" env:"AUTH_EMAIL_VERIFICATION_INTERVAL"`
+	}
+
+	LoginLimit struct {
+		MaxAccountFailures int `env-required:"true" yaml:"max_account_failures" env:"LOGIN_LIMIT_MAX_ACCOUNT_FAILURES"`
+		MaxIPFailures      int `env-required:"true" yaml:"max_ip_failures"      env:"LOGIN_LIMIT_MAX_IP_FAILURES"`
+		FailureWindow      int `env-required:"true" yaml:"failure_window"       env:"LOGIN_LIMIT_FAILURE_WINDOW"`
+		LockoutDuration    int `env-required:"true" yaml:"lockout_duration"     env:"LOGIN_LIMIT_LOCKOUT_DURATION"`
+		DelayAfter         int `                    yaml:"delay_after"          env:"LOGIN_LIMIT_DELAY_AFTER"`
+		BaseDelay          int `                    yaml:"base_delay"           env:"LOGIN_LIMIT_BASE_DELAY"`
+		MaxDelay           int `                    yaml:"max_delay"            env:"LOGIN_LIMIT_MAX_DELAY"`
+	}
+
+	Mfa struct {
+		Issuer          string `env-required:"true" yaml:"issuer"            env:"MFA_ISSUER"`
+		ChallengeExpiry int    `env-required:"true" yaml:"challenge_expiry"  env:"MFA_CHALLENGE_EXPIRY"`
+		RequireForAdmin bool   `                    yaml:"require_for_admin" env:"MFA_REQUIRE_FOR_ADMIN"`
+	}
+
+	OAuth struct {
+		Issuer         string `env-required:"true" yaml:"issuer"           env:"OAUTH_ISSUER"`
+		SigningKeyFile string `                    yaml:"signing_key_file" env:"OAUTH_SIGNING_KEY_FILE"`
+		CodeExpiry     int    `env-required:"true" yaml:"code_expiry"      env:"OAUTH_CODE_EXPIRY"`
+		TokenExpiry    int    `env-required:"true" yaml:"token_expiry"     env:"OAUTH_TOKEN_EXPIRY"`
+	}
+
+	ApiKey struct {
+		DefaultRateLimit int `env-required:"true" yaml:"default_rate_limit" env:"API_KEY_DEFAULT_RATE_LIMIT"`
+		LastUsedInterval int `env-required:"true" yaml:"last_used_interval" env:"API_KEY_LAST_USED_INTERVAL"`
+	}
+
+	Referral struct {
+		Enabled bool `yaml:"enabled" env:"REFERRAL_ENABLED"`
+	}
+
+	InviteQuota struct {
+		Roles map[string]RoleInviteQuota `yaml:"roles"`
+	}
+
+	Waitlist struct {
+		SignupLimit  int `                    yaml:"signup_limit"  env:"WAITLIST_SIGNUP_LIMIT"`
+		SignupWindow int `env-required:"true" yaml:"signup_window" env:"WAITLIST_SIGNUP_WINDOW"`
+	}
+
+	// SignedInvitation turns on stateless invitation tokens, signed with
+	// Algorithm: "hmac" with Secret or "ed25519" with the key in PrivateKeyFile.
+	// An empty Algorithm turns them off.
+	SignedInvitation struct {
+		Algorithm      string `yaml:"algorithm"        env:"SIGNED_INVITATION_ALGORITHM"`
+		Secret         string `yaml:"secret"           env:"SIGNED_INVITATION_SECRET"`
+		PrivateKeyFile string `yaml:"private_key_file" env:"SIGNED_INVITATION_PRIVATE_KEY_FILE"`
+		// MaxTTL caps how long a signed token may live, in seconds
+		MaxTTL int `env-required:"true" yaml:"max_ttl" env:"SIGNED_INVITATION_MAX_TTL"`
+	}
+
+	// RoleInviteQuota limits the invitations each user with the role may
+	// generate: Total in all, WindowLimit within any Window seconds. Zero
+	// means no limit.
+	RoleInviteQuota struct {
+		Total       int `yaml:"total"`
+		WindowLimit int `yaml:"window_limit"`
+		Window      int `yaml:"window"`
+	}
+
+	Federation struct {
+		StateExpiry int                `env-required:"true" yaml:"state_expiry" env:"FEDERATION_STATE_EXPIRY"`
+		Providers   []IdentityProvider `                    yaml:"providers"`
+	}
+
+	// IdentityProvider is an upstream OIDC provider users can sign in with.
+	// The client secret is read from the environment variable named by
+	// ClientSecretEnv, so it never has to live in the config file.
+	IdentityProvider struct {
+		Name            string   `yaml:"name"`
+		Issuer          string   `yaml:"issuer"`
+		ClientId        string   `yaml:"client_id"`
+		ClientSecretEnv string   `yaml:"client_secret_env"`
+		RedirectURL     string   `yaml:"redirect_url"`
+		Scopes          []string `yaml:"scopes"`
+	}
+
+	Mail struct {
+		Driver   string `env-required:"true" yaml:"driver"   env:"MAIL_DRIVER"`
+		Host     string `                    yaml:"host"     env:"MAIL_HOST"`
+		Port     int    `                    yaml:"port"     env:"MAIL_PORT"`
+		Username string `                    yaml:"username" env:"MAIL_USERNAME"`
+		Password string `                                    env:"MAIL_PASSWORD"`
+		From     string `env-required:"true" yaml:"from"     env:"MAIL_FROM"`
+		Dir      string `                    yaml:"dir"      env:"MAIL_DIR"`
+	}
+
+	Invitation struct {
+		DeepLink          string `env-required:"true" yaml:"deep_link"           env:"INVITATION_DEEP_LINK"`
+		EmailSubject      string `env-required:"true" yaml:"email_subject"       env:"INVITATION_EMAIL_SUBJECT"`
+		EmailTextTemplate string `                    yaml:"email_text_template" env:"INVITATION_EMAIL_TEXT_TEMPLATE"`
+		EmailHTMLTemplate string `                    yaml:"email_html_template" env:"INVITATION_EMAIL_HTML_TEMPLATE"`
+		CodeFormat        string `                    yaml:"code_format"         env:"INVITATION_CODE_FORMAT"`
+		// QRDeepLink is encoded in invitation QR codes, DeepLink if empty
+		QRDeepLink string `yaml:"qr_deep_link" env:"INVITATION_QR_DEEP_LINK"`
+		// LandingLimit is how many landing page lookups a client IP may make
+		// within LandingWindow seconds, 0 for no limit
+		LandingLimit  int `                    yaml:"landing_limit"  env:"INVITATION_LANDING_LIMIT"`
+		LandingWindow int `env-required:"true" yaml:"landing_window" env:"INVITATION_LANDING_WINDOW"`
+		// MinTTL and MaxTTL bound, in seconds from now, where an admin may
+		// move the expiry of a token
+		MinTTL int `                    yaml:"min_ttl"        env:"INVITATION_MIN_TTL"`
+		MaxTTL int `env-required:"true" yaml:"max_ttl"        env:"INVITATION_MAX_TTL"`
+	}
+
 	//RMQ struct {
 	//	ServerExchange string `env-required:"true" yaml:"rpc_server_exchange" env:"RMQ_RPC_SERVER"`
 	//	ClientExchange string `env-required:"true" yaml:"rpc_client_exchange" env:"RMQ_RPC_CLIENT"`
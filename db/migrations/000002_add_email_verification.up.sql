@@ -0,0 +1,4 @@
+ALTER TABLE `users` ADD COLUMN `email_verified_at` timestamp NULL DEFAULT NULL AFTER `email`;
+
+-- accounts created before email verification existed are treated as verified
+UPDATE `users` SET `email_verified_at` = `created_at` WHERE `email_verified_at` IS NULL;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `email_domain_rules` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `pattern` varchar(255) NOT NULL,
+    `type` ENUM ('allow', 'deny') NOT NULL,
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY `uq_email_domain_rules_pattern_type` (`pattern`, `type`)
+) ENGINE = InnoDB;
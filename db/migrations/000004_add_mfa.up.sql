@@ -0,0 +1,13 @@
+ALTER TABLE `users`
+    ADD COLUMN `mfa_secret` varchar(64) NULL DEFAULT NULL AFTER `salt`,
+    ADD COLUMN `mfa_enabled_at` timestamp NULL DEFAULT NULL AFTER `mfa_secret`;
+
+CREATE TABLE IF NOT EXISTS `mfa_recovery_codes` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `user_id` int NOT NULL,
+    `code_hash` varchar(64) NOT NULL,
+    `used_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    INDEX `idx_mfa_recovery_codes_user_id` (`user_id`),
+    CONSTRAINT `fk_mfa_recovery_codes_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `oauth_clients` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `client_id` varchar(64) UNIQUE NOT NULL,
+    `secret_hash` varchar(64) NOT NULL DEFAULT '',
+    `salt` varchar(50) NOT NULL DEFAULT '',
+    `name` varchar(100) NOT NULL,
+    `redirect_uris` text NOT NULL,
+    `public` tinyint(1) NOT NULL DEFAULT 0,
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE = InnoDB;
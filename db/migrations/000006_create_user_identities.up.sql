@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `user_identities` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `user_id` int NOT NULL,
+    `provider` varchar(50) NOT NULL,
+    `subject` varchar(255) NOT NULL,
+    `email` varchar(50) NOT NULL DEFAULT '',
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY `uq_user_identities_provider_subject` (`provider`, `subject`),
+    INDEX `idx_user_identities_user_id` (`user_id`),
+    CONSTRAINT `fk_user_identities_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE = InnoDB;
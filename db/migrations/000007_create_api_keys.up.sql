@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS `api_keys` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `user_id` int NOT NULL,
+    `name` varchar(100) NOT NULL,
+    `prefix` varchar(16) UNIQUE NOT NULL,
+    `key_hash` varchar(64) NOT NULL,
+    `scopes` text NOT NULL,
+    `rate_limit` int NOT NULL DEFAULT 0,
+    `expires_at` timestamp NULL DEFAULT NULL,
+    `last_used_at` timestamp NULL DEFAULT NULL,
+    `revoked_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX `idx_api_keys_user_id` (`user_id`),
+    CONSTRAINT `fk_api_keys_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS `audit_events` (
+    `id` bigint PRIMARY KEY AUTO_INCREMENT,
+    `actor_id` int NULL DEFAULT NULL,
+    `api_key_id` int NULL DEFAULT NULL,
+    `action` varchar(50) NOT NULL,
+    `target_type` varchar(50) NOT NULL,
+    `target_id` varchar(100) NOT NULL DEFAULT '',
+    `before` text NULL,
+    `after` text NULL,
+    `changes` text NULL,
+    `ip` varchar(45) NOT NULL DEFAULT '',
+    `user_agent` varchar(255) NOT NULL DEFAULT '',
+    `request_id` varchar(64) NOT NULL DEFAULT '',
+    `created_at` timestamp(3) DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX `idx_audit_events_actor_id` (`actor_id`),
+    INDEX `idx_audit_events_action` (`action`),
+    INDEX `idx_audit_events_target` (`target_type`, `target_id`),
+    INDEX `idx_audit_events_created_at` (`created_at`)
+) ENGINE = InnoDB;
+
+CREATE TRIGGER `audit_events_no_update` BEFORE UPDATE ON `audit_events`
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
+
+CREATE TRIGGER `audit_events_no_delete` BEFORE DELETE ON `audit_events`
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
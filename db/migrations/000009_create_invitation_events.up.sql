@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS `invitation_events` (
+    `id` bigint PRIMARY KEY AUTO_INCREMENT,
+    `token` varchar(64) NOT NULL,
+    `action` varchar(20) NOT NULL,
+    `outcome` varchar(20) NOT NULL,
+    `error_key` varchar(100) NOT NULL DEFAULT '',
+    `email` varchar(255) NOT NULL DEFAULT '',
+    `client_ip` varchar(45) NOT NULL DEFAULT '',
+    `user_agent` varchar(255) NOT NULL DEFAULT '',
+    `batch_id` varchar(64) NOT NULL DEFAULT '',
+    `created_by` int NULL DEFAULT NULL,
+    `token_created_at` timestamp(3) NULL DEFAULT NULL,
+    `created_at` timestamp(3) DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX `idx_invitation_events_token` (`token`, `action`, `outcome`),
+    INDEX `idx_invitation_events_action_created_at` (`action`, `created_at`),
+    INDEX `idx_invitation_events_batch_id` (`batch_id`),
+    INDEX `idx_invitation_events_created_by` (`created_by`)
+) ENGINE = InnoDB;
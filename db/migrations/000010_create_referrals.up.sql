@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `referrals` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `inviter_id` int NOT NULL,
+    `invitee_id` int NOT NULL,
+    `invitation_token` varchar(64) NOT NULL DEFAULT '',
+    `depth` int NOT NULL DEFAULT 1,
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY `uq_referrals_invitee_id` (`invitee_id`),
+    INDEX `idx_referrals_inviter_id` (`inviter_id`),
+    CONSTRAINT `fk_referrals_inviter_id` FOREIGN KEY (`inviter_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_referrals_invitee_id` FOREIGN KEY (`invitee_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `invite_quotas` (
+    `user_id` int PRIMARY KEY,
+    `total_limit` int NULL,
+    `window_limit` int NULL,
+    `extra` int NOT NULL DEFAULT 0,
+    `updated_at` timestamp DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    CONSTRAINT `fk_invite_quotas_user_id` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS `invitation_campaigns` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `name` varchar(100) UNIQUE NOT NULL,
+    `starts_at` timestamp NULL DEFAULT NULL,
+    `ends_at` timestamp NULL DEFAULT NULL,
+    `token_ttl` int NOT NULL DEFAULT 0,
+    `max_redemptions` int NOT NULL DEFAULT 0,
+    `redemptions` int NOT NULL DEFAULT 0,
+    `default_role` enum('user', 'admin') NOT NULL DEFAULT 'user',
+    `status` tinyint NOT NULL DEFAULT 1,
+    `created_by` int NULL DEFAULT NULL,
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS `campaign_redemptions` (
+    `campaign_id` int NOT NULL,
+    `token` varchar(64) NOT NULL,
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`campaign_id`, `token`),
+    CONSTRAINT `fk_campaign_redemptions_campaign_id` FOREIGN KEY (`campaign_id`) REFERENCES `invitation_campaigns` (`id`) ON DELETE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `waitlist_entries` (
+    `id` int PRIMARY KEY AUTO_INCREMENT,
+    `email` varchar(255) UNIQUE NOT NULL,
+    `reason` varchar(500) NOT NULL DEFAULT '',
+    `status` enum('pending', 'approved', 'rejected') NOT NULL DEFAULT 'pending',
+    `invitation_token` varchar(64) NULL DEFAULT NULL,
+    `reviewed_by` int NULL DEFAULT NULL,
+    `reviewed_at` timestamp NULL DEFAULT NULL,
+    `client_ip` varchar(45) NOT NULL DEFAULT '',
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY `idx_waitlist_entries_status_id` (`status`, `id`)
+) ENGINE = InnoDB;
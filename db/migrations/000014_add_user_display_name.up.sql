@@ -0,0 +1 @@
+ALTER TABLE `users` ADD COLUMN `display_name` varchar(100) NOT NULL DEFAULT '' AFTER `email`;
@@ -0,0 +1 @@
+ALTER TABLE `invitation_events` DROP COLUMN `token_expires_at`;
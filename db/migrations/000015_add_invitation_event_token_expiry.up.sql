@@ -0,0 +1 @@
+ALTER TABLE `invitation_events` ADD COLUMN `token_expires_at` timestamp(3) NULL DEFAULT NULL AFTER `token_created_at`;
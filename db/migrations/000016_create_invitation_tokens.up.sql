@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS `invitation_tokens` (
+    `token` varchar(64) PRIMARY KEY,
+    `status` tinyint NOT NULL,
+    `email` varchar(255) NOT NULL DEFAULT '',
+    `email_domain` varchar(255) NOT NULL DEFAULT '',
+    `delivery_status` varchar(20) NOT NULL DEFAULT '',
+    `delivery_error` text,
+    `delivered_at` timestamp NULL DEFAULT NULL,
+    `batch_id` varchar(64) NOT NULL DEFAULT '',
+    `campaign_id` int NOT NULL DEFAULT 0,
+    `created_by` int NOT NULL DEFAULT 0,
+    `created_at` timestamp NULL DEFAULT NULL,
+    `not_before` timestamp NULL DEFAULT NULL,
+    `expires_at` timestamp NOT NULL,
+    KEY `idx_invitation_tokens_expires_at` (`expires_at`),
+    KEY `idx_invitation_tokens_batch_id` (`batch_id`),
+    KEY `idx_invitation_tokens_campaign_id` (`campaign_id`),
+    KEY `idx_invitation_tokens_created_by` (`created_by`)
+) ENGINE = InnoDB;
@@ -0,0 +1 @@
+ALTER TABLE `invite_quotas` DROP COLUMN `used`;
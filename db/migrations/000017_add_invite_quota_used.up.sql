@@ -0,0 +1,6 @@
+ALTER TABLE `invite_quotas` ADD COLUMN `used` int NOT NULL DEFAULT 0 AFTER `extra`;
+-- the counts were only kept in Redis, so start from the stored tokens each user generated
+INSERT INTO `invite_quotas` (`user_id`, `used`)
+SELECT t.`created_by`, COUNT(*) FROM `invitation_tokens` t JOIN `users` u ON u.`id` = t.`created_by`
+GROUP BY t.`created_by`
+ON DUPLICATE KEY UPDATE `used` = VALUES(`used`);
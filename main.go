@@ -4,6 +4,7 @@ import (
 	"app-invite-service/config"
 	"app-invite-service/server"
 	"log"
+	"os"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -16,12 +17,21 @@ func main() {
 		log.Fatalf("Config error: %s", err)
 	}
 
+	server.RunMigration(cfg.MySQL.URL)
+
+	// maintenance commands run in place of the server
+	if len(os.Args) > 1 {
+		if err := server.RunCommand(cfg, os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("Command error: %s", err)
+		}
+		return
+	}
+
 	var serverReady = make(chan bool)
 	go func() {
 		<-serverReady
 		close(serverReady)
 	}()
 
-	server.RunMigration(cfg.MySQL.URL)
 	server.Start(serverReady, cfg)
 }
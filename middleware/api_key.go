@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"app-invite-service/component"
+	"app-invite-service/module/apikey/apikeybiz"
+	"app-invite-service/module/apikey/apikeymodel"
+	"app-invite-service/module/apikey/apikeystorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ApiKeyHeader = "X-API-Key"
+
+// authenticateApiKey resolves an api key, which must hold every scope the
+// route requires. The key then acts as the user who created it.
+func authenticateApiKey(
+	appCtx component.AppContext,
+	c *gin.Context,
+	rawKey string,
+	scopes []string,
+) *apikeymodel.ApiKey {
+	if len(scopes) == 0 {
+		panic(apikeymodel.ErrApiKeyScopeMissing)
+	}
+
+	cfg := appCtx.GetConfig().ApiKey
+	biz := apikeybiz.NewAuthenticateApiKeyBiz(
+		apikeystorage.NewSQLStore(appCtx.GetDBConn()),
+		apikeystorage.NewRedisUsageStore(appCtx.GetRedisConn()),
+		cfg.DefaultRateLimit,
+		cfg.LastUsedInterval,
+	)
+
+	key, err := biz.AuthenticateApiKey(c.Request.Context(), rawKey)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, scope := range scopes {
+		if !key.HasScope(scope) {
+			panic(apikeymodel.ErrApiKeyScopeMissing)
+		}
+	}
+
+	return key
+}
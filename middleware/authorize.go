@@ -3,9 +3,11 @@ package middleware
 import (
 	"app-invite-service/common"
 	"app-invite-service/component"
+	"app-invite-service/component/tokenprovider"
 	"app-invite-service/component/tokenprovider/jwt"
 	"app-invite-service/module/user/userstorage"
 	"errors"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -29,24 +31,42 @@ func ExtractTokenFromHeaderString(s string) (string, error) {
 	return parts[1], nil
 }
 
-func RequiredAuth(appCtx component.AppContext) func(c *gin.Context) {
+// RequiredAuth accepts a Bearer user token, or an `X-API-Key` header when the
+// route lists the api key scopes it needs. Routes without scopes don't accept
+// api keys at all.
+func RequiredAuth(appCtx component.AppContext, scopes ...string) func(c *gin.Context) {
 	tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey())
 
 	return func(c *gin.Context) {
-		token, err := ExtractTokenFromHeaderString(c.GetHeader("Authorization"))
-		if err != nil {
-			panic(err)
-		}
-
 		db := appCtx.GetDBConn()
 		store := userstorage.NewSQLStore(db)
 
-		payload, err := tokenProvider.Validate(token)
-		if err != nil {
-			panic(err)
+		var userId int
+
+		if rawKey := c.GetHeader(ApiKeyHeader); rawKey != "" {
+			key := authenticateApiKey(appCtx, c, rawKey, scopes)
+			c.Set(common.CurrentApiKey, key)
+			userId = key.UserId
+		} else {
+			token, err := ExtractTokenFromHeaderString(c.GetHeader("Authorization"))
+			if err != nil {
+				panic(err)
+			}
+
+			payload, err := tokenProvider.Validate(token)
+			if err != nil {
+				panic(err)
+			}
+
+			// e.g. MFA challenge tokens only work on their own endpoint
+			if payload.Purpose != "" {
+				panic(tokenprovider.ErrInvalidToken)
+			}
+
+			userId = payload.UserId
 		}
 
-		user, err := store.FindUser(c.Request.Context(), map[string]interface{}{"id": payload.UserId})
+		user, err := store.FindUser(c.Request.Context(), map[string]interface{}{"id": userId})
 		if err != nil {
 			panic(err)
 		}
@@ -60,14 +80,28 @@ func RequiredAuth(appCtx component.AppContext) func(c *gin.Context) {
 	}
 }
 
-func RequiredAdmin(_ component.AppContext) func(c *gin.Context) {
+func ErrMfaRequired(err error) *common.AppError {
+	return common.NewFullErrorResponse(
+		http.StatusForbidden,
+		err,
+		"multi-factor authentication must be enabled to access this route",
+		err.Error(),
+		"ErrMfaRequired",
+	)
+}
+
+func RequiredAdmin(appCtx component.AppContext) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		requester := c.MustGet(common.CurrentUser).(common.Requester)
 
-		if requester.GetRole() == "admin" {
-			c.Next()
-		} else {
+		if requester.GetRole() != "admin" {
 			panic(common.ErrNoPermission(errors.New("only admin can access this route")))
 		}
+
+		if appCtx.GetConfig().Mfa.RequireForAdmin && !requester.IsMfaEnabled() {
+			panic(ErrMfaRequired(errors.New("admin has not enabled mfa")))
+		}
+
+		c.Next()
 	}
 }
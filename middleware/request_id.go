@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"app-invite-service/common"
+	crand "crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+var validRequestId = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestId keeps the caller's request id when it looks sane, or makes one
+// up, and echoes it back so logs and audit events can be correlated
+func RequestId() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIdHeader)
+
+		if !validRequestId.MatchString(id) {
+			b := make([]byte, 16)
+			if _, err := crand.Read(b); err != nil {
+				panic(common.ErrInternal(err))
+			}
+			id = hex.EncodeToString(b)
+		}
+
+		c.Set(common.RequestId, id)
+		c.Header(RequestIdHeader, id)
+		c.Next()
+	}
+}
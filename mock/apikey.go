@@ -0,0 +1,89 @@
+package mock
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/apikey/apikeymodel"
+	"context"
+	"time"
+)
+
+type mockApiKeyStore struct {
+	keys []apikeymodel.ApiKey
+}
+
+func NewMockApiKeyStore() *mockApiKeyStore {
+	return &mockApiKeyStore{}
+}
+
+func (m *mockApiKeyStore) CreateApiKey(_ context.Context, data *apikeymodel.ApiKeyCreate) error {
+	data.Id = len(m.keys) + 1
+	m.keys = append(m.keys, apikeymodel.ApiKey{
+		Id:        data.Id,
+		UserId:    data.UserId,
+		Name:      data.Name,
+		Prefix:    data.Prefix,
+		KeyHash:   data.KeyHash,
+		Scopes:    data.Scopes,
+		RateLimit: data.RateLimit,
+		ExpiresAt: data.ExpiresAt,
+	})
+	return nil
+}
+
+func (m *mockApiKeyStore) FindApiKey(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*apikeymodel.ApiKey, error) {
+	for i := range m.keys {
+		if val, ok := conditions["prefix"]; ok && val.(string) == m.keys[i].Prefix {
+			return &m.keys[i], nil
+		}
+		if val, ok := conditions["id"]; ok && val.(int) == m.keys[i].Id {
+			return &m.keys[i], nil
+		}
+	}
+	return nil, common.ErrRecordNotFound
+}
+
+func (m *mockApiKeyStore) ListApiKeys(_ context.Context) ([]apikeymodel.ApiKey, error) {
+	return m.keys, nil
+}
+
+func (m *mockApiKeyStore) UpdateApiKey(_ context.Context, id int, data map[string]interface{}) error {
+	for i := range m.keys {
+		if m.keys[i].Id != id {
+			continue
+		}
+		if val, ok := data["revoked_at"]; ok {
+			t := val.(time.Time)
+			m.keys[i].RevokedAt = &t
+		}
+		if val, ok := data["last_used_at"]; ok {
+			t := val.(time.Time)
+			m.keys[i].LastUsedAt = &t
+		}
+	}
+	return nil
+}
+
+type mockApiKeyUsageStore struct {
+	counts map[int]int64
+	used   map[int]bool
+}
+
+func NewMockApiKeyUsageStore() *mockApiKeyUsageStore {
+	return &mockApiKeyUsageStore{counts: map[int]int64{}, used: map[int]bool{}}
+}
+
+func (m *mockApiKeyUsageStore) IncrementUsage(_ context.Context, keyId int, window time.Duration) (int64, time.Duration, error) {
+	m.counts[keyId]++
+	return m.counts[keyId], window, nil
+}
+
+func (m *mockApiKeyUsageStore) MarkUsed(_ context.Context, keyId int, _ time.Duration) (bool, error) {
+	if m.used[keyId] {
+		return false, nil
+	}
+	m.used[keyId] = true
+	return true, nil
+}
@@ -0,0 +1,88 @@
+package mock
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+	"context"
+	"time"
+)
+
+type mockAuditLogger struct {
+	entries []auditmodel.Entry
+}
+
+func NewMockAuditLogger() *mockAuditLogger {
+	return &mockAuditLogger{}
+}
+
+func (m *mockAuditLogger) Record(_ context.Context, entry *auditmodel.Entry) {
+	m.entries = append(m.entries, *entry)
+}
+
+// Entries lists what was recorded, oldest first
+func (m *mockAuditLogger) Entries() []auditmodel.Entry {
+	return m.entries
+}
+
+type mockAuditEventStore struct {
+	events []auditmodel.Event
+}
+
+func NewMockAuditEventStore() *mockAuditEventStore {
+	return &mockAuditEventStore{}
+}
+
+func (m *mockAuditEventStore) CreateEvent(_ context.Context, data *auditmodel.Event) error {
+	now := time.Now()
+	data.Id = int64(len(m.events) + 1)
+	data.CreatedAt = &now
+	m.events = append(m.events, *data)
+	return nil
+}
+
+func (m *mockAuditEventStore) matches(filter *auditmodel.EventFilter, e *auditmodel.Event) bool {
+	if filter.Action != "" && filter.Action != e.Action {
+		return false
+	}
+	if filter.TargetType != "" && filter.TargetType != e.TargetType {
+		return false
+	}
+	if filter.TargetId != "" && filter.TargetId != e.TargetId {
+		return false
+	}
+	return true
+}
+
+func (m *mockAuditEventStore) ListEvents(
+	_ context.Context,
+	filter *auditmodel.EventFilter,
+	paging *common.Paging,
+) ([]auditmodel.Event, error) {
+	var result []auditmodel.Event
+	for i := len(m.events) - 1; i >= 0; i-- {
+		if m.matches(filter, &m.events[i]) {
+			result = append(result, m.events[i])
+		}
+	}
+	paging.Total = int64(len(result))
+	return result, nil
+}
+
+func (m *mockAuditEventStore) EachEvents(
+	_ context.Context,
+	filter *auditmodel.EventFilter,
+	fn func(events []auditmodel.Event) error,
+) error {
+	var result []auditmodel.Event
+	for i := range m.events {
+		if m.matches(filter, &m.events[i]) {
+			result = append(result, m.events[i])
+		}
+	}
+	return fn(result)
+}
+
+// Events lists what was stored, oldest first
+func (m *mockAuditEventStore) Events() []auditmodel.Event {
+	return m.events
+}
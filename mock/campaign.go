@@ -0,0 +1,90 @@
+package mock
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/campaign/campaignmodel"
+	"context"
+	"errors"
+)
+
+type mockCampaignStore struct {
+	campaigns   []campaignmodel.Campaign
+	redemptions map[int]map[string]bool
+}
+
+func NewMockCampaignStore(campaigns ...campaignmodel.Campaign) *mockCampaignStore {
+	return &mockCampaignStore{campaigns: campaigns, redemptions: map[int]map[string]bool{}}
+}
+
+func (m *mockCampaignStore) CreateCampaign(_ context.Context, data *campaignmodel.CampaignCreate) error {
+	data.Id = len(m.campaigns) + 1
+	m.campaigns = append(m.campaigns, campaignmodel.Campaign{
+		Id:             data.Id,
+		Name:           data.Name,
+		StartsAt:       data.StartsAt,
+		EndsAt:         data.EndsAt,
+		TokenTTL:       data.TokenTTL,
+		MaxRedemptions: data.MaxRedemptions,
+		DefaultRole:    data.DefaultRole,
+		Status:         1,
+		CreatedBy:      data.CreatedBy,
+	})
+	return nil
+}
+
+func (m *mockCampaignStore) FindCampaign(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*campaignmodel.Campaign, error) {
+	for i := range m.campaigns {
+		if val, ok := conditions["id"]; ok && val.(int) == m.campaigns[i].Id {
+			c := m.campaigns[i]
+			return &c, nil
+		}
+		if val, ok := conditions["name"]; ok && val.(string) == m.campaigns[i].Name {
+			c := m.campaigns[i]
+			return &c, nil
+		}
+	}
+	return nil, common.ErrRecordNotFound
+}
+
+func (m *mockCampaignStore) ListCampaigns(_ context.Context, paging *common.Paging) ([]campaignmodel.Campaign, error) {
+	paging.Total = int64(len(m.campaigns))
+	return m.campaigns, nil
+}
+
+func (m *mockCampaignStore) UpdateCampaign(_ context.Context, id int, data map[string]interface{}) error {
+	for i := range m.campaigns {
+		if m.campaigns[i].Id == id {
+			if status, ok := data["status"]; ok {
+				m.campaigns[i].Status = status.(int)
+			}
+			return nil
+		}
+	}
+	return errors.New("campaign not found")
+}
+
+func (m *mockCampaignStore) Redeem(_ context.Context, campaignId int, token string) (bool, error) {
+	if m.redemptions[campaignId][token] {
+		return true, nil
+	}
+
+	for i := range m.campaigns {
+		c := &m.campaigns[i]
+		if c.Id != campaignId {
+			continue
+		}
+		if c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions {
+			return false, nil
+		}
+		c.Redemptions++
+		if m.redemptions[campaignId] == nil {
+			m.redemptions[campaignId] = map[string]bool{}
+		}
+		m.redemptions[campaignId][token] = true
+		return true, nil
+	}
+	return false, errors.New("campaign not found")
+}
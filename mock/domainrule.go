@@ -0,0 +1,78 @@
+package mock
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/domainrule/domainrulemodel"
+	"context"
+)
+
+type mockDomainRuleStore struct {
+	rules []domainrulemodel.EmailDomainRule
+}
+
+func NewMockDomainRuleStore(rules ...domainrulemodel.EmailDomainRule) *mockDomainRuleStore {
+	return &mockDomainRuleStore{rules: rules}
+}
+
+func (m *mockDomainRuleStore) CreateRule(_ context.Context, data *domainrulemodel.EmailDomainRuleCreate) error {
+	data.Id = len(m.rules) + 1
+	m.rules = append(m.rules, domainrulemodel.EmailDomainRule{Id: data.Id, Pattern: data.Pattern, Type: data.Type})
+	return nil
+}
+
+func (m *mockDomainRuleStore) FindRule(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*domainrulemodel.EmailDomainRule, error) {
+	for i := range m.rules {
+		rule := m.rules[i]
+		if id, ok := conditions["id"]; ok && id.(int) != rule.Id {
+			continue
+		}
+		if pattern, ok := conditions["pattern"]; ok && pattern.(string) != rule.Pattern {
+			continue
+		}
+		if ruleType, ok := conditions["type"]; ok && ruleType.(string) != rule.Type {
+			continue
+		}
+		return &rule, nil
+	}
+	return nil, common.ErrRecordNotFound
+}
+
+func (m *mockDomainRuleStore) ListRules(
+	_ context.Context,
+	filter *domainrulemodel.EmailDomainRuleFilter,
+) ([]domainrulemodel.EmailDomainRule, error) {
+	var rules []domainrulemodel.EmailDomainRule
+	for _, rule := range m.rules {
+		if filter == nil || filter.Type == "" || filter.Type == rule.Type {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (m *mockDomainRuleStore) DeleteRule(_ context.Context, id int) error {
+	for i, rule := range m.rules {
+		if rule.Id == id {
+			m.rules = append(m.rules[:i], m.rules[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+type mockDomainChecker struct{}
+
+func NewMockDomainChecker() *mockDomainChecker {
+	return &mockDomainChecker{}
+}
+
+// CheckEmailDomain only rejects blocked.com
+func (m *mockDomainChecker) CheckEmailDomain(_ context.Context, domain string) error {
+	if domain == "blocked.com" {
+		return domainrulemodel.ErrEmailDomainNotAllowed
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package mock
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"sort"
+	"time"
+)
+
+type mockInvitationTokenStore struct {
+	tokens map[string]usermodel.InvitationToken
+}
+
+// NewMockInvitationTokenStore holds the given tokens in memory, ignoring
+// those which expired like the real store does
+func NewMockInvitationTokenStore(tokens ...usermodel.InvitationToken) *mockInvitationTokenStore {
+	m := &mockInvitationTokenStore{tokens: map[string]usermodel.InvitationToken{}}
+	for _, token := range tokens {
+		m.tokens[token.Token] = token
+	}
+	return m
+}
+
+func (m *mockInvitationTokenStore) live(token string) (usermodel.InvitationToken, bool) {
+	found, ok := m.tokens[token]
+	if !ok || found.TTL(time.Now()) == 0 {
+		return usermodel.InvitationToken{}, false
+	}
+	return found, true
+}
+
+func (m *mockInvitationTokenStore) FindInvitationToken(_ context.Context, token string) (*usermodel.InvitationToken, error) {
+	found, ok := m.live(token)
+	if !ok {
+		return nil, common.ErrRecordNotFound
+	}
+	return &found, nil
+}
+
+func (m *mockInvitationTokenStore) CreateInvitationToken(_ context.Context, token *usermodel.InvitationToken) (bool, error) {
+	if _, ok := m.live(token.Token); ok {
+		return false, nil
+	}
+	m.tokens[token.Token] = *token
+	return true, nil
+}
+
+func (m *mockInvitationTokenStore) UpdateInvitationToken(_ context.Context, token *usermodel.InvitationToken) error {
+	if _, ok := m.tokens[token.Token]; !ok {
+		return common.ErrRecordNotFound
+	}
+	m.tokens[token.Token] = *token
+	return nil
+}
+
+func (m *mockInvitationTokenStore) DeleteInvitationToken(_ context.Context, token string) error {
+	delete(m.tokens, token)
+	return nil
+}
+
+func (m *mockInvitationTokenStore) ListInvitationTokens(_ context.Context) ([]usermodel.InvitationToken, error) {
+	var tokens []usermodel.InvitationToken
+	for key := range m.tokens {
+		if token, ok := m.live(key); ok {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Token < tokens[j].Token })
+	return tokens, nil
+}
+
+func (m *mockInvitationTokenStore) UpdateInvitationTokens(
+	ctx context.Context,
+	tokens []string,
+	change usermodel.InvitationTokenChange,
+) error {
+	var found []usermodel.InvitationToken
+	if tokens == nil {
+		found, _ = m.ListInvitationTokens(ctx)
+	}
+	for _, key := range tokens {
+		if token, ok := m.live(key); ok {
+			found = append(found, token)
+		}
+	}
+
+	saved, deleted, err := change(found)
+	if err != nil {
+		return err
+	}
+	for _, token := range saved {
+		m.tokens[token.Token] = token
+	}
+	for _, token := range deleted {
+		delete(m.tokens, token)
+	}
+	return nil
+}
+
+// Token returns a stored token as it is, expired or not
+func (m *mockInvitationTokenStore) Token(token string) (usermodel.InvitationToken, bool) {
+	found, ok := m.tokens[token]
+	return found, ok
+}
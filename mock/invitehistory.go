@@ -0,0 +1,68 @@
+package mock
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/invitehistory/invitehistorymodel"
+	"context"
+	"time"
+)
+
+type mockInvitationEventStore struct {
+	events      []invitehistorymodel.Event
+	redemptions []invitehistorymodel.TokenRedemption
+	attempts    []invitehistorymodel.AttemptCount
+}
+
+// NewMockInvitationEventStore answers analytics queries with the given rows
+func NewMockInvitationEventStore(
+	redemptions []invitehistorymodel.TokenRedemption,
+	attempts []invitehistorymodel.AttemptCount,
+) *mockInvitationEventStore {
+	return &mockInvitationEventStore{redemptions: redemptions, attempts: attempts}
+}
+
+func (m *mockInvitationEventStore) CreateEvent(_ context.Context, data *invitehistorymodel.Event) error {
+	now := time.Now()
+	data.Id = int64(len(m.events) + 1)
+	data.CreatedAt = &now
+	m.events = append(m.events, *data)
+	return nil
+}
+
+func (m *mockInvitationEventStore) ListEvents(
+	_ context.Context,
+	filter *invitehistorymodel.EventFilter,
+	paging *common.Paging,
+) ([]invitehistorymodel.Event, error) {
+	var result []invitehistorymodel.Event
+	for i := len(m.events) - 1; i >= 0; i-- {
+		if filter.Token != "" && filter.Token != m.events[i].Token {
+			continue
+		}
+		if filter.Action != "" && filter.Action != m.events[i].Action {
+			continue
+		}
+		result = append(result, m.events[i])
+	}
+	paging.Total = int64(len(result))
+	return result, nil
+}
+
+func (m *mockInvitationEventStore) ListTokenRedemptions(
+	_ context.Context,
+	_ *invitehistorymodel.AnalyticsFilter,
+) ([]invitehistorymodel.TokenRedemption, error) {
+	return m.redemptions, nil
+}
+
+func (m *mockInvitationEventStore) CountAttempts(
+	_ context.Context,
+	_ *invitehistorymodel.AnalyticsFilter,
+) ([]invitehistorymodel.AttemptCount, error) {
+	return m.attempts, nil
+}
+
+// Events lists what was stored, oldest first
+func (m *mockInvitationEventStore) Events() []invitehistorymodel.Event {
+	return m.events
+}
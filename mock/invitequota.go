@@ -0,0 +1,169 @@
+package mock
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/invitequota/invitequotamodel"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"fmt"
+	"time"
+)
+
+type mockInviteQuotaStore struct {
+	quotas map[int]invitequotamodel.UserQuota
+}
+
+func NewMockInviteQuotaStore(quotas ...invitequotamodel.UserQuota) *mockInviteQuotaStore {
+	m := &mockInviteQuotaStore{quotas: map[int]invitequotamodel.UserQuota{}}
+	for _, q := range quotas {
+		m.quotas[q.UserId] = q
+	}
+	return m
+}
+
+func (m *mockInviteQuotaStore) FindUserQuota(_ context.Context, userId int) (*invitequotamodel.UserQuota, error) {
+	q, ok := m.quotas[userId]
+	if !ok {
+		return nil, common.ErrRecordNotFound
+	}
+	return &q, nil
+}
+
+func (m *mockInviteQuotaStore) SaveLimits(_ context.Context, userId int, data *invitequotamodel.UserQuotaUpdate) error {
+	q := m.quotas[userId]
+	q.UserId = userId
+	q.TotalLimit = data.TotalLimit
+	q.WindowLimit = data.WindowLimit
+	m.quotas[userId] = q
+	return nil
+}
+
+func (m *mockInviteQuotaStore) AddExtra(_ context.Context, userId, amount int) error {
+	q := m.quotas[userId]
+	q.UserId = userId
+	q.Extra += amount
+	m.quotas[userId] = q
+	return nil
+}
+
+func (m *mockInviteQuotaStore) TakeInvite(_ context.Context, userId, total int) (bool, error) {
+	q := m.quotas[userId]
+	if total > 0 && q.Used >= total {
+		return false, nil
+	}
+	q.UserId = userId
+	q.Used++
+	m.quotas[userId] = q
+	return true, nil
+}
+
+func (m *mockInviteQuotaStore) ReturnInvite(_ context.Context, userId int) error {
+	if q, ok := m.quotas[userId]; ok && q.Used > 0 {
+		q.Used--
+		m.quotas[userId] = q
+	}
+	return nil
+}
+
+// Used is how many invitations the user generated
+func (m *mockInviteQuotaStore) Used(userId int) int {
+	return m.quotas[userId].Used
+}
+
+type mockReservation struct {
+	id string
+	at time.Time
+}
+
+type mockInviteUsageStore struct {
+	window map[int][]mockReservation
+}
+
+// NewMockInviteUsageStore keeps windows in memory the way the redis store does
+func NewMockInviteUsageStore() *mockInviteUsageStore {
+	return &mockInviteUsageStore{window: map[int][]mockReservation{}}
+}
+
+func (m *mockInviteUsageStore) inWindow(userId int, window time.Duration, now time.Time) []mockReservation {
+	var kept []mockReservation
+	for _, r := range m.window[userId] {
+		if r.at.After(now.Add(-window)) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func (m *mockInviteUsageStore) Reserve(
+	_ context.Context,
+	userId int,
+	limits invitequotamodel.Limits,
+	reservation string,
+	now time.Time,
+) (invitequotamodel.ReserveOutcome, time.Time, error) {
+	if limits.Window > 0 {
+		window := m.inWindow(userId, time.Duration(limits.Window)*time.Second, now)
+		if limits.WindowLimit > 0 && len(window) >= limits.WindowLimit {
+			return invitequotamodel.WindowExceeded, window[0].at, nil
+		}
+		m.window[userId] = append(window, mockReservation{id: reservation, at: now})
+	}
+
+	return invitequotamodel.Reserved, time.Time{}, nil
+}
+
+func (m *mockInviteUsageStore) Release(_ context.Context, userId int, reservation string) error {
+	var kept []mockReservation
+	for _, r := range m.window[userId] {
+		if r.id != reservation {
+			kept = append(kept, r)
+		}
+	}
+	m.window[userId] = kept
+	return nil
+}
+
+func (m *mockInviteUsageStore) GetUsage(
+	_ context.Context,
+	userId int,
+	window time.Duration,
+	now time.Time,
+) (*invitequotamodel.Usage, error) {
+	var usage invitequotamodel.Usage
+	if reservations := m.inWindow(userId, window, now); window > 0 && len(reservations) > 0 {
+		usage.WindowUsed = len(reservations)
+		usage.OldestInWindow = &reservations[0].at
+	}
+	return &usage, nil
+}
+
+// Age moves the user's reservations back in time, as if they were made d ago
+func (m *mockInviteUsageStore) Age(userId int, d time.Duration) {
+	for i := range m.window[userId] {
+		m.window[userId][i].at = m.window[userId][i].at.Add(-d)
+	}
+}
+
+type mockQuotaUserStore struct{}
+
+// NewMockQuotaUserStore knows the users with id 1 to 99; user 1 is an admin
+func NewMockQuotaUserStore() *mockQuotaUserStore {
+	return &mockQuotaUserStore{}
+}
+
+func (m *mockQuotaUserStore) FindUser(
+	_ context.Context,
+	conditions map[string]interface{},
+	_ ...string,
+) (*usermodel.User, error) {
+	id, _ := conditions["id"].(int)
+	if id < 1 || id > 99 {
+		return nil, common.ErrRecordNotFound
+	}
+
+	role := "user"
+	if id == 1 {
+		role = "admin"
+	}
+	return &usermodel.User{Id: id, Status: 1, Email: fmt.Sprintf("user%d@gmail.com", id), Role: role}, nil
+}
@@ -0,0 +1,52 @@
+package mock
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/mfa/mfamodel"
+	"context"
+	"time"
+)
+
+type mockRecoveryCodeStore struct {
+	codes []mfamodel.RecoveryCode
+}
+
+func NewMockRecoveryCodeStore() *mockRecoveryCodeStore {
+	return &mockRecoveryCodeStore{}
+}
+
+func (m *mockRecoveryCodeStore) ReplaceRecoveryCodes(_ context.Context, userId int, hashes []string) error {
+	m.codes = nil
+	for i, hash := range hashes {
+		m.codes = append(m.codes, mfamodel.RecoveryCode{Id: i + 1, UserId: userId, CodeHash: hash})
+	}
+	return nil
+}
+
+func (m *mockRecoveryCodeStore) FindUnusedRecoveryCode(
+	_ context.Context,
+	userId int,
+	hash string,
+) (*mfamodel.RecoveryCode, error) {
+	for i := range m.codes {
+		if m.codes[i].UserId == userId && m.codes[i].CodeHash == hash && m.codes[i].UsedAt == nil {
+			return &m.codes[i], nil
+		}
+	}
+	return nil, common.ErrRecordNotFound
+}
+
+func (m *mockRecoveryCodeStore) UseRecoveryCode(_ context.Context, id int) (bool, error) {
+	for i := range m.codes {
+		if m.codes[i].Id == id && m.codes[i].UsedAt == nil {
+			now := time.Now()
+			m.codes[i].UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRecoveryCodeStore) Codes() []mfamodel.RecoveryCode {
+	return m.codes
+}
@@ -0,0 +1,107 @@
+package mock
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/oauth/oauthmodel"
+	"context"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const OAuthRedirectURI = "https://app.example.com/callback"
+
+type mockOAuthClientStore struct {
+	clients []oauthmodel.Client
+}
+
+// NewMockOAuthClientStore knows a confidential client "confidential" with the
+// secret "secret" (for the identity mock hash) and a public client "public"
+func NewMockOAuthClientStore() *mockOAuthClientStore {
+	return &mockOAuthClientStore{clients: []oauthmodel.Client{
+		{Id: 1, ClientId: "confidential", SecretHash: "secret", Name: "Partner", RedirectURIs: []string{OAuthRedirectURI}},
+		{Id: 2, ClientId: "public", Name: "Mobile", RedirectURIs: []string{OAuthRedirectURI}, Public: true},
+	}}
+}
+
+func (m *mockOAuthClientStore) CreateClient(_ context.Context, data *oauthmodel.ClientCreate) error {
+	data.Id = len(m.clients) + 1
+	m.clients = append(m.clients, oauthmodel.Client{
+		Id:           data.Id,
+		ClientId:     data.ClientId,
+		SecretHash:   data.SecretHash,
+		Salt:         data.Salt,
+		Name:         data.Name,
+		RedirectURIs: data.RedirectURIs,
+		Public:       data.Public,
+	})
+	return nil
+}
+
+func (m *mockOAuthClientStore) FindClient(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*oauthmodel.Client, error) {
+	for i := range m.clients {
+		if val, ok := conditions["client_id"]; ok && val.(string) == m.clients[i].ClientId {
+			return &m.clients[i], nil
+		}
+		if val, ok := conditions["id"]; ok && val.(int) == m.clients[i].Id {
+			return &m.clients[i], nil
+		}
+	}
+	return nil, common.ErrRecordNotFound
+}
+
+func (m *mockOAuthClientStore) ListClients(_ context.Context) ([]oauthmodel.Client, error) {
+	return m.clients, nil
+}
+
+func (m *mockOAuthClientStore) DeleteClient(_ context.Context, id int) error {
+	for i := range m.clients {
+		if m.clients[i].Id == id {
+			m.clients = append(m.clients[:i], m.clients[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+type mockOAuthCodeStore struct {
+	codes map[string]oauthmodel.AuthorizationCode
+}
+
+func NewMockOAuthCodeStore() *mockOAuthCodeStore {
+	return &mockOAuthCodeStore{codes: map[string]oauthmodel.AuthorizationCode{}}
+}
+
+func (m *mockOAuthCodeStore) SaveCode(
+	_ context.Context,
+	code string,
+	data *oauthmodel.AuthorizationCode,
+	_ int,
+) error {
+	m.codes[code] = *data
+	return nil
+}
+
+func (m *mockOAuthCodeStore) TakeCode(_ context.Context, code string) (*oauthmodel.AuthorizationCode, error) {
+	data, ok := m.codes[code]
+	if !ok {
+		return nil, common.ErrRecordNotFound
+	}
+	delete(m.codes, code)
+	return &data, nil
+}
+
+type mockIdTokenSigner struct {
+	Claims jwt.MapClaims
+}
+
+func NewMockIdTokenSigner() *mockIdTokenSigner {
+	return &mockIdTokenSigner{}
+}
+
+func (m *mockIdTokenSigner) Sign(claims jwt.Claims) (string, error) {
+	m.Claims = claims.(jwt.MapClaims)
+	return "id-token", nil
+}
@@ -0,0 +1,163 @@
+package mock
+
+import (
+	"app-invite-service/component/oidc"
+	crand "crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	OIDCClientId     = "evite"
+	OIDCClientSecret = "evite-secret"
+)
+
+// OIDCUser is who signs in at the mock provider
+type OIDCUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type oidcGrant struct {
+	user          OIDCUser
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+}
+
+// OIDCServer is a minimal upstream OpenID provider for tests: discovery, JWKS,
+// and an authorization code + PKCE token endpoint
+type OIDCServer struct {
+	*httptest.Server
+	Signer *oidc.Signer
+
+	mu     sync.Mutex
+	grants map[string]oidcGrant
+}
+
+func NewOIDCServer() *OIDCServer {
+	key, err := rsa.GenerateKey(crand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &OIDCServer{Signer: oidc.NewSigner(key), grants: map[string]oidcGrant{}}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/.well-known/openid-configuration", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"issuer":                 s.URL,
+			"authorization_endpoint": s.URL + "/authorize",
+			"token_endpoint":         s.URL + "/token",
+			"jwks_uri":               s.URL + "/jwks",
+		})
+	})
+	r.GET("/jwks", func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.Signer.JWKS())
+	})
+	r.POST("/token", s.token)
+
+	s.Server = httptest.NewServer(r)
+	return s
+}
+
+func (s *OIDCServer) ProviderConfig(redirectURL string) oidc.ProviderConfig {
+	return oidc.ProviderConfig{
+		Name:         "mock",
+		Issuer:       s.URL,
+		ClientId:     OIDCClientId,
+		ClientSecret: OIDCClientSecret,
+		RedirectURL:  redirectURL,
+	}
+}
+
+// SignIn plays the user's part at the authorization endpoint: it signs user
+// in for the request in authURL and returns the code the provider would
+// redirect back with
+func (s *OIDCServer) SignIn(authURL string, user OIDCUser) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		panic(err)
+	}
+	q := u.Query()
+
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		panic(err)
+	}
+	code := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.grants[code] = oidcGrant{
+		user:          user,
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		redirectURI:   q.Get("redirect_uri"),
+	}
+
+	return code
+}
+
+func (s *OIDCServer) tokenError(c *gin.Context, code string) {
+	c.JSON(http.StatusBadRequest, gin.H{"error": code})
+}
+
+func (s *OIDCServer) token(c *gin.Context) {
+	clientId, secret, ok := c.Request.BasicAuth()
+	if !ok || clientId != OIDCClientId || secret != OIDCClientSecret {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	grant, ok := s.grants[c.PostForm("code")]
+	delete(s.grants, c.PostForm("code"))
+	s.mu.Unlock()
+
+	if !ok || grant.redirectURI != c.PostForm("redirect_uri") {
+		s.tokenError(c, "invalid_grant")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(c.PostForm("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != grant.codeChallenge {
+		s.tokenError(c, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	idToken, err := s.Signer.Sign(jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            grant.user.Subject,
+		"aud":            []string{OIDCClientId},
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Minute).Unix(),
+		"nonce":          grant.nonce,
+		"email":          grant.user.Email,
+		"email_verified": grant.user.EmailVerified,
+	})
+	if err != nil {
+		s.tokenError(c, "server_error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": "upstream-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
@@ -0,0 +1,207 @@
+package mock
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/referral/referralmodel"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"errors"
+	"fmt"
+)
+
+type mockReferralStore struct {
+	referrals []referralmodel.Referral
+	disabled  map[int]bool
+}
+
+// NewMockReferralStore starts with the given inviter→invitee edges; every
+// user id in them has the email "user<id>@gmail.com", and those from 90 up
+// are admins
+func NewMockReferralStore(referrals ...referralmodel.Referral) *mockReferralStore {
+	return &mockReferralStore{referrals: referrals, disabled: map[int]bool{}}
+}
+
+func (m *mockReferralStore) CreateReferral(_ context.Context, data *referralmodel.Referral) error {
+	for _, r := range m.referrals {
+		if r.InviteeId == data.InviteeId {
+			return errors.New("duplicate invitee")
+		}
+	}
+	data.Id = len(m.referrals) + 1
+	m.referrals = append(m.referrals, *data)
+	return nil
+}
+
+func (m *mockReferralStore) FindReferral(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*referralmodel.Referral, error) {
+	for i := range m.referrals {
+		if val, ok := conditions["invitee_id"]; ok && val.(int) == m.referrals[i].InviteeId {
+			return &m.referrals[i], nil
+		}
+	}
+	return nil, common.ErrRecordNotFound
+}
+
+func (m *mockReferralStore) ListInvitees(_ context.Context, inviterIds []int) ([]referralmodel.Invitee, error) {
+	inviters := map[int]bool{}
+	for _, id := range inviterIds {
+		inviters[id] = true
+	}
+
+	var invitees []referralmodel.Invitee
+	for _, r := range m.referrals {
+		if !inviters[r.InviterId] {
+			continue
+		}
+		status := 1
+		if m.disabled[r.InviteeId] {
+			status = 0
+		}
+		invitees = append(invitees, referralmodel.Invitee{
+			UserId:    r.InviteeId,
+			Email:     mockReferralEmail(r.InviteeId),
+			Status:    status,
+			InvitedBy: r.InviterId,
+			Role:      mockReferralRole(r.InviteeId),
+		})
+	}
+	return invitees, nil
+}
+
+func (m *mockReferralStore) DisableUsers(_ context.Context, userIds []int) error {
+	for _, id := range userIds {
+		m.disabled[id] = true
+	}
+	return nil
+}
+
+func (m *mockReferralStore) Referrals() []referralmodel.Referral {
+	return m.referrals
+}
+
+func (m *mockReferralStore) IsDisabled(userId int) bool {
+	return m.disabled[userId]
+}
+
+func mockReferralEmail(id int) string {
+	return fmt.Sprintf("user%d@gmail.com", id)
+}
+
+func mockReferralRole(id int) string {
+	if id >= 90 {
+		return "admin"
+	}
+	return "user"
+}
+
+type mockReferralUserStore struct{}
+
+// NewMockReferralUserStore knows the users with id 1 to 99, of which those
+// from 90 up are admins
+func NewMockReferralUserStore() *mockReferralUserStore {
+	return &mockReferralUserStore{}
+}
+
+func (m *mockReferralUserStore) FindUser(
+	_ context.Context,
+	conditions map[string]interface{},
+	_ ...string,
+) (*usermodel.User, error) {
+	id, _ := conditions["id"].(int)
+	if id < 1 || id > 99 {
+		return nil, common.ErrRecordNotFound
+	}
+	return &usermodel.User{Id: id, Status: 1, Email: mockReferralEmail(id), Role: mockReferralRole(id)}, nil
+}
+
+type mockReferralRecorder struct {
+	referrals []referralmodel.Referral
+}
+
+func NewMockReferralRecorder() *mockReferralRecorder {
+	return &mockReferralRecorder{}
+}
+
+func (m *mockReferralRecorder) RecordReferral(_ context.Context, inviterId, inviteeId int, invitationToken string) error {
+	m.referrals = append(m.referrals, referralmodel.Referral{
+		InviterId:       inviterId,
+		InviteeId:       inviteeId,
+		InvitationToken: invitationToken,
+	})
+	return nil
+}
+
+func (m *mockReferralRecorder) Referrals() []referralmodel.Referral {
+	return m.referrals
+}
+
+type mockTokenGenerator struct {
+	tokens []usermodel.InvitationToken
+}
+
+// NewMockTokenGenerator hands out tokens, except for recipients at
+// blocked.com
+func NewMockTokenGenerator() *mockTokenGenerator {
+	return &mockTokenGenerator{}
+}
+
+func (m *mockTokenGenerator) GenerateToken(
+	_ context.Context,
+	data *usermodel.InvitationTokenCreate,
+) (*usermodel.InvitationToken, error) {
+	if usermodel.EmailDomain(data.Email) == "blocked.com" {
+		return nil, errors.New("email domain not allowed")
+	}
+
+	token := usermodel.InvitationToken{
+		Token:     fmt.Sprintf("token%d", len(m.tokens)+1),
+		Status:    usermodel.InvitationStatusActive,
+		Email:     data.Email,
+		BatchId:   data.BatchId,
+		CreatedBy: data.CreatedBy,
+	}
+	m.tokens = append(m.tokens, token)
+	return &token, nil
+}
+
+func (m *mockTokenGenerator) Tokens() []usermodel.InvitationToken {
+	return m.tokens
+}
+
+type mockTokenDisabler struct {
+	tokens []usermodel.InvitationToken
+}
+
+// NewMockTokenDisabler holds the given tokens in memory
+func NewMockTokenDisabler(tokens ...usermodel.InvitationToken) *mockTokenDisabler {
+	return &mockTokenDisabler{tokens: tokens}
+}
+
+func (m *mockTokenDisabler) DisableTokensByCreator(_ context.Context, creatorIds []int) ([]string, error) {
+	creators := map[int]bool{}
+	for _, id := range creatorIds {
+		creators[id] = true
+	}
+
+	var disabled []string
+	for i := range m.tokens {
+		if m.tokens[i].Status.CanTransitionTo(usermodel.InvitationStatusDisabled) && creators[m.tokens[i].CreatedBy] {
+			m.tokens[i].Status = usermodel.InvitationStatusDisabled
+			disabled = append(disabled, m.tokens[i].Token)
+		}
+	}
+	return disabled, nil
+}
+
+func (m *mockTokenDisabler) DisableTokensByCampaign(_ context.Context, campaignId int) ([]string, error) {
+	var disabled []string
+	for i := range m.tokens {
+		if m.tokens[i].Status.CanTransitionTo(usermodel.InvitationStatusDisabled) && m.tokens[i].CampaignId == campaignId {
+			m.tokens[i].Status = usermodel.InvitationStatusDisabled
+			disabled = append(disabled, m.tokens[i].Token)
+		}
+	}
+	return disabled, nil
+}
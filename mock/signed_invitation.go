@@ -0,0 +1,54 @@
+package mock
+
+import (
+	"context"
+	"time"
+)
+
+type mockSignedInvitationStore struct {
+	revoked map[string]time.Time
+	uses    map[string]int
+}
+
+func NewMockSignedInvitationStore() *mockSignedInvitationStore {
+	return &mockSignedInvitationStore{revoked: map[string]time.Time{}, uses: map[string]int{}}
+}
+
+func (m *mockSignedInvitationStore) Revoke(_ context.Context, id string, until time.Time) error {
+	m.revoked[id] = until
+	return nil
+}
+
+func (m *mockSignedInvitationStore) IsRevoked(_ context.Context, id string) (bool, error) {
+	_, ok := m.revoked[id]
+	return ok, nil
+}
+
+func (m *mockSignedInvitationStore) CountUses(_ context.Context, id string) (int, error) {
+	return m.uses[id], nil
+}
+
+func (m *mockSignedInvitationStore) AddUse(_ context.Context, id string, maxUses int, _ time.Time) (bool, error) {
+	if m.uses[id] >= maxUses {
+		return false, nil
+	}
+	m.uses[id]++
+	return true, nil
+}
+
+type mockLandingThrottle struct {
+	counts map[string]int64
+}
+
+func NewMockLandingThrottle() *mockLandingThrottle {
+	return &mockLandingThrottle{counts: map[string]int64{}}
+}
+
+func (m *mockLandingThrottle) IncrementLookups(
+	_ context.Context,
+	clientIP string,
+	window time.Duration,
+) (int64, time.Duration, error) {
+	m.counts[clientIP]++
+	return m.counts[clientIP], window, nil
+}
@@ -3,23 +3,45 @@ package mock
 import (
 	"app-invite-service/common"
 	"app-invite-service/component/tokenprovider"
+	"app-invite-service/module/campaign/campaignmodel"
 	"app-invite-service/module/user/usermodel"
 	"context"
+	"errors"
 	"time"
 )
 
-type mockUserStore struct{}
+type mockUserStore struct {
+	identities []usermodel.UserIdentity
+	deleted    []int
+}
 
 func NewMockUserStore() *mockUserStore {
 	return &mockUserStore{}
 }
 
+var verifiedAt = time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
 func (m *mockUserStore) FindUser(_ context.Context, conditions map[string]interface{}, _ ...string) (*usermodel.User, error) {
 	if val, ok := conditions["email"]; ok && val.(string) == "user@gmail.com" {
-		return &usermodel.User{Email: val.(string), Password: "user@123", Status: 1, Salt: ""}, nil
+		return &usermodel.User{Id: 1, Email: val.(string), EmailVerifiedAt: &verifiedAt, Password: "user@123", Status: 1, Salt: ""}, nil
+	}
+	if val, ok := conditions["email"]; ok && val.(string) == "unverified@gmail.com" {
+		return &usermodel.User{Id: 4, Email: val.(string), Password: "user@123", Status: 1, Salt: ""}, nil
 	}
 	if val, ok := conditions["id"]; ok && val.(int) == 1 {
-		return &usermodel.User{Email: "user@gmail.com", Password: "user@123", Status: 1, Salt: ""}, nil
+		return &usermodel.User{Id: 1, Email: "user@gmail.com", Password: "user@123", Status: 1, Salt: ""}, nil
+	}
+	if val, ok := conditions["email"]; ok && val.(string) == "admin@gmail.com" {
+		return &usermodel.User{Id: 6, Email: val.(string), EmailVerifiedAt: &verifiedAt, Role: "admin", Password: "admin@123", Status: 1}, nil
+	}
+	if val, ok := conditions["id"]; ok && val.(int) == 4 {
+		return &usermodel.User{Id: 4, Email: "unverified@gmail.com", Password: "user@123", Status: 1, Salt: ""}, nil
+	}
+	if val, ok := conditions["email"]; ok && val.(string) == "mfa@gmail.com" {
+		return &usermodel.User{Id: 5, Email: val.(string), EmailVerifiedAt: &verifiedAt, MfaEnabledAt: &verifiedAt, Password: "user@123", Status: 1}, nil
+	}
+	if val, ok := conditions["id"]; ok && val.(int) == 5 {
+		return &usermodel.User{Id: 5, Email: "mfa@gmail.com", EmailVerifiedAt: &verifiedAt, MfaEnabledAt: &verifiedAt, Password: "user@123", Status: 1}, nil
 	}
 	if val, ok := conditions["id"]; ok && val.(int) == 2 {
 		return &usermodel.User{Email: "user2@gmail.com", Password: "user2@123", Status: 1, Salt: ""}, nil
@@ -27,11 +49,159 @@ func (m *mockUserStore) FindUser(_ context.Context, conditions map[string]interf
 	return nil, common.ErrRecordNotFound
 }
 
+// CreateUser creates everyone as user 3, except taken@gmail.com which fails
+// like a duplicate insert
 func (m *mockUserStore) CreateUser(_ context.Context, data *usermodel.UserCreate) error {
+	if data.Email == "taken@gmail.com" {
+		return errors.New("duplicate entry")
+	}
 	data.Id = 3
 	return nil
 }
 
+func (m *mockUserStore) DeleteUser(_ context.Context, id int) error {
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
+func (m *mockUserStore) Deleted() []int {
+	return m.deleted
+}
+
+func (m *mockUserStore) UpdateUser(_ context.Context, _ int, _ map[string]interface{}) error {
+	return nil
+}
+
+func (m *mockUserStore) CreateIdentity(_ context.Context, data *usermodel.UserIdentity) error {
+	data.Id = len(m.identities) + 1
+	m.identities = append(m.identities, *data)
+	return nil
+}
+
+func (m *mockUserStore) FindIdentity(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*usermodel.UserIdentity, error) {
+	for i := range m.identities {
+		if m.identities[i].Provider == conditions["provider"] && m.identities[i].Subject == conditions["subject"] {
+			return &m.identities[i], nil
+		}
+	}
+	return nil, common.ErrRecordNotFound
+}
+
+func (m *mockUserStore) Identities() []usermodel.UserIdentity {
+	return m.identities
+}
+
+type mockFederatedStateStore struct {
+	states map[string]usermodel.FederatedLoginState
+}
+
+func NewMockFederatedStateStore() *mockFederatedStateStore {
+	return &mockFederatedStateStore{states: map[string]usermodel.FederatedLoginState{}}
+}
+
+func (m *mockFederatedStateStore) SaveState(
+	_ context.Context,
+	state string,
+	data *usermodel.FederatedLoginState,
+	_ int,
+) error {
+	m.states[state] = *data
+	return nil
+}
+
+func (m *mockFederatedStateStore) TakeState(_ context.Context, state string) (*usermodel.FederatedLoginState, error) {
+	data, ok := m.states[state]
+	if !ok {
+		return nil, common.ErrRecordNotFound
+	}
+	delete(m.states, state)
+	return &data, nil
+}
+
+type mockEmailVerificationSender struct {
+	err  error
+	sent []string
+}
+
+func NewMockEmailVerificationSender() *mockEmailVerificationSender {
+	return &mockEmailVerificationSender{}
+}
+
+// NewFailingEmailVerificationSender fails every send with err
+func NewFailingEmailVerificationSender(err error) *mockEmailVerificationSender {
+	return &mockEmailVerificationSender{err: err}
+}
+
+func (m *mockEmailVerificationSender) SendEmailVerification(_ context.Context, _ int, email string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, email)
+	return nil
+}
+
+// Sent lists the addresses sent a verification email, oldest first
+func (m *mockEmailVerificationSender) Sent() []string {
+	return m.sent
+}
+
+type mockEmailVerificationTokens struct {
+	tokens    map[string]int
+	expiresAt map[string]time.Time
+	throttled map[string]bool
+}
+
+func NewMockEmailVerificationTokens() *mockEmailVerificationTokens {
+	return &mockEmailVerificationTokens{
+		tokens:    map[string]int{},
+		expiresAt: map[string]time.Time{},
+		throttled: map[string]bool{},
+	}
+}
+
+func (m *mockEmailVerificationTokens) SaveVerificationToken(
+	_ context.Context,
+	token string,
+	userId int,
+	ttl time.Duration,
+) error {
+	m.tokens[token] = userId
+	m.expiresAt[token] = time.Now().Add(ttl)
+	return nil
+}
+
+func (m *mockEmailVerificationTokens) TakeVerificationToken(_ context.Context, token string) (int, error) {
+	userId, ok := m.tokens[token]
+	expired := !time.Now().Before(m.expiresAt[token])
+	delete(m.tokens, token)
+	if !ok || expired {
+		return 0, common.ErrRecordNotFound
+	}
+	return userId, nil
+}
+
+func (m *mockEmailVerificationTokens) DeleteVerificationToken(_ context.Context, token string) error {
+	delete(m.tokens, token)
+	return nil
+}
+
+// ThrottleVerification lets every address through once; the interval never
+// runs out
+func (m *mockEmailVerificationTokens) ThrottleVerification(
+	_ context.Context,
+	email string,
+	_ time.Duration,
+) (bool, error) {
+	if m.throttled[email] {
+		return false, nil
+	}
+	m.throttled[email] = true
+	return true, nil
+}
+
 type mockProvider struct{}
 
 func NewMockProvider() *mockProvider {
@@ -46,7 +216,11 @@ func (m *mockProvider) Generate(_ tokenprovider.TokenPayload, expiry int) (*toke
 	}, nil
 }
 
-func (m *mockProvider) Validate(_ string) (*tokenprovider.TokenPayload, error) {
+// Validate treats "mfa-token" as an MFA challenge for user 5
+func (m *mockProvider) Validate(token string) (*tokenprovider.TokenPayload, error) {
+	if token == "mfa-token" {
+		return &tokenprovider.TokenPayload{UserId: 5, Purpose: tokenprovider.PurposeMfa}, nil
+	}
 	return &tokenprovider.TokenPayload{}, nil
 }
 
@@ -59,3 +233,106 @@ func NewMockHash() *mockHash {
 func (m *mockHash) Hash(data string) string {
 	return data
 }
+
+type mockInvitationChecker struct {
+	redeemed []string
+}
+
+func NewMockInvitationChecker() *mockInvitationChecker {
+	return &mockInvitationChecker{}
+}
+
+// ValidateInvitationTokenForEmail accepts "invite123" for anyone,
+// "referral123" created by user 1 for anyone, "campaign123" of a campaign
+// granting admin for anyone, "exhausted123" whose campaign runs out before it
+// is redeemed for anyone, and "bound123" only for user1@gmail.com
+func (m *mockInvitationChecker) ValidateInvitationTokenForEmail(
+	_ context.Context,
+	token, email string,
+) (*usermodel.InvitationToken, error) {
+	switch {
+	case token == "invite123":
+		return &usermodel.InvitationToken{Token: token, Status: usermodel.InvitationStatusActive}, nil
+	case token == "referral123":
+		return &usermodel.InvitationToken{Token: token, Status: usermodel.InvitationStatusActive, CreatedBy: 1}, nil
+	case token == "campaign123":
+		return &usermodel.InvitationToken{Token: token, Status: usermodel.InvitationStatusActive, CampaignId: 1}, nil
+	case token == "exhausted123":
+		return &usermodel.InvitationToken{Token: token, Status: usermodel.InvitationStatusActive, CampaignId: 2}, nil
+	case token == "bound123":
+		t := usermodel.InvitationToken{Token: token, Status: usermodel.InvitationStatusActive, Email: "user1@gmail.com"}
+		if !t.MatchesRecipient(email) {
+			return nil, errors.New("invite token was issued for a different email")
+		}
+		return &t, nil
+	default:
+		return nil, errors.New("invite token not existed")
+	}
+}
+
+func (m *mockInvitationChecker) CheckInvitationCampaign(
+	_ context.Context,
+	token *usermodel.InvitationToken,
+) (*campaignmodel.Campaign, error) {
+	if token.CampaignId == 0 {
+		return nil, nil
+	}
+	return &campaignmodel.Campaign{Id: token.CampaignId, Status: 1, DefaultRole: campaignmodel.RoleAdmin}, nil
+}
+
+func (m *mockInvitationChecker) RedeemInvitationToken(
+	ctx context.Context,
+	token *usermodel.InvitationToken,
+) (*campaignmodel.Campaign, error) {
+	if token.CampaignId == 2 {
+		return nil, campaignmodel.ErrCampaignExhausted
+	}
+	m.redeemed = append(m.redeemed, token.Token)
+	return m.CheckInvitationCampaign(ctx, token)
+}
+
+// Redeemed lists the tokens redeemed so far
+func (m *mockInvitationChecker) Redeemed() []string {
+	return m.redeemed
+}
+
+type mockLoginLimiter struct {
+	maxFailures int
+	failures    map[string]int
+}
+
+// NewMockLoginLimiter locks an email once it has failed maxFailures times
+func NewMockLoginLimiter(maxFailures int) *mockLoginLimiter {
+	return &mockLoginLimiter{maxFailures: maxFailures, failures: map[string]int{}}
+}
+
+func (m *mockLoginLimiter) Check(_ context.Context, email, _ string) error {
+	if m.failures[email] >= m.maxFailures {
+		return errors.New("account locked")
+	}
+	return nil
+}
+
+func (m *mockLoginLimiter) RecordFailure(_ context.Context, email, _ string) error {
+	m.failures[email]++
+	return nil
+}
+
+func (m *mockLoginLimiter) Reset(_ context.Context, email, _ string) error {
+	delete(m.failures, email)
+	return nil
+}
+
+type mockMfaVerifier struct{}
+
+func NewMockMfaVerifier() *mockMfaVerifier {
+	return &mockMfaVerifier{}
+}
+
+// VerifyMfaCode only accepts 123456
+func (m *mockMfaVerifier) VerifyMfaCode(_ context.Context, _ *usermodel.User, code string) error {
+	if code != "123456" {
+		return errors.New("mfa code is invalid")
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package mock
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/waitlist/waitlistmodel"
+	"context"
+	"errors"
+	"time"
+)
+
+type mockWaitlistStore struct {
+	entries []waitlistmodel.Entry
+}
+
+func NewMockWaitlistStore() *mockWaitlistStore {
+	return &mockWaitlistStore{}
+}
+
+func (m *mockWaitlistStore) CreateEntry(_ context.Context, data *waitlistmodel.EntryCreate) error {
+	data.Id = len(m.entries) + 1
+	m.entries = append(m.entries, waitlistmodel.Entry{
+		Id:       data.Id,
+		Email:    data.Email,
+		Reason:   data.Reason,
+		Status:   waitlistmodel.StatusPending,
+		ClientIP: data.ClientIP,
+	})
+	return nil
+}
+
+func (m *mockWaitlistStore) FindEntry(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*waitlistmodel.Entry, error) {
+	for i := range m.entries {
+		if val, ok := conditions["id"]; ok && val.(int) == m.entries[i].Id {
+			e := m.entries[i]
+			return &e, nil
+		}
+		if val, ok := conditions["email"]; ok && val.(string) == m.entries[i].Email {
+			e := m.entries[i]
+			return &e, nil
+		}
+	}
+	return nil, common.ErrRecordNotFound
+}
+
+func (m *mockWaitlistStore) ListEntries(
+	_ context.Context,
+	filter *waitlistmodel.EntryFilter,
+	paging *common.Paging,
+) ([]waitlistmodel.Entry, error) {
+	var entries []waitlistmodel.Entry
+	for _, e := range m.entries {
+		if filter.Status == "" || filter.Status == e.Status {
+			entries = append(entries, e)
+		}
+	}
+	paging.Total = int64(len(entries))
+	return entries, nil
+}
+
+func (m *mockWaitlistStore) CountPendingUpTo(_ context.Context, id int) (int, error) {
+	count := 0
+	for _, e := range m.entries {
+		if e.Status == waitlistmodel.StatusPending && e.Id <= id {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// UpdateEntry can't put entries at the stuck.com domain back in line
+func (m *mockWaitlistStore) UpdateEntry(_ context.Context, id int, data map[string]interface{}) error {
+	for i := range m.entries {
+		if m.entries[i].Id == id {
+			if data["status"] == waitlistmodel.StatusPending && usermodel.EmailDomain(m.entries[i].Email) == "stuck.com" {
+				return errors.New("cannot update waitlist entry")
+			}
+			m.update(&m.entries[i], data)
+			return nil
+		}
+	}
+	return errors.New("waitlist entry not found")
+}
+
+func (m *mockWaitlistStore) UpdatePendingEntry(_ context.Context, id int, data map[string]interface{}) (bool, error) {
+	for i := range m.entries {
+		if m.entries[i].Id == id && m.entries[i].Status == waitlistmodel.StatusPending {
+			m.update(&m.entries[i], data)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockWaitlistStore) update(e *waitlistmodel.Entry, data map[string]interface{}) {
+	if status, ok := data["status"]; ok {
+		e.Status = status.(string)
+	}
+	if token, ok := data["invitation_token"]; ok {
+		t := token.(string)
+		e.InvitationToken = &t
+	}
+	if reviewedBy, ok := data["reviewed_by"]; ok {
+		e.ReviewedBy = nil
+		if id, ok := reviewedBy.(int); ok {
+			e.ReviewedBy = &id
+		}
+	}
+}
+
+// Entry returns the stored entry with the id
+func (m *mockWaitlistStore) Entry(id int) waitlistmodel.Entry {
+	return m.entries[id-1]
+}
+
+type mockSignupThrottle struct {
+	counts map[string]int64
+}
+
+func NewMockSignupThrottle() *mockSignupThrottle {
+	return &mockSignupThrottle{counts: map[string]int64{}}
+}
+
+func (m *mockSignupThrottle) IncrementSignups(
+	_ context.Context,
+	clientIP string,
+	window time.Duration,
+) (int64, time.Duration, error) {
+	m.counts[clientIP]++
+	return m.counts[clientIP], window, nil
+}
+
+// mockInviter fails to invite emails at the fail.com and stuck.com domains
+type mockInviter struct {
+	invited []usermodel.InvitationEmailCreate
+}
+
+func NewMockInviter() *mockInviter {
+	return &mockInviter{}
+}
+
+func (m *mockInviter) InviteByEmail(
+	_ context.Context,
+	data *usermodel.InvitationEmailCreate,
+) (*usermodel.InvitationToken, error) {
+	if domain := usermodel.EmailDomain(data.Email); domain == "fail.com" || domain == "stuck.com" {
+		return nil, common.ErrInternal(errors.New("cannot generate token"))
+	}
+
+	m.invited = append(m.invited, *data)
+	return &usermodel.InvitationToken{
+		Token:          "waitlist" + data.Email,
+		Email:          data.Email,
+		BatchId:        data.BatchId,
+		CampaignId:     data.CampaignId,
+		DeliveryStatus: usermodel.DeliveryStatusSent,
+	}, nil
+}
+
+func (m *mockInviter) Invited() []usermodel.InvitationEmailCreate {
+	return m.invited
+}
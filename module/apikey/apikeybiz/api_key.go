@@ -0,0 +1,241 @@
+package apikeybiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/apikey/apikeymodel"
+	"app-invite-service/module/audit/auditmodel"
+	"context"
+	crand "crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// rate limits are counted per minute
+const rateLimitWindow = time.Minute
+
+type ApiKeyStore interface {
+	CreateApiKey(ctx context.Context, data *apikeymodel.ApiKeyCreate) error
+	FindApiKey(ctx context.Context, conditions map[string]interface{}) (*apikeymodel.ApiKey, error)
+	ListApiKeys(ctx context.Context) ([]apikeymodel.ApiKey, error)
+	UpdateApiKey(ctx context.Context, id int, data map[string]interface{}) error
+}
+
+type AuditLogger interface {
+	Record(ctx context.Context, entry *auditmodel.Entry)
+}
+
+type UsageStore interface {
+	IncrementUsage(ctx context.Context, keyId int, window time.Duration) (int64, time.Duration, error)
+	MarkUsed(ctx context.Context, keyId int, interval time.Duration) (bool, error)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Create api key
+
+type ICreateApiKeyBiz interface {
+	CreateApiKey(ctx context.Context, userId int, data *apikeymodel.ApiKeyCreate) (*apikeymodel.ApiKeyCreated, error)
+}
+
+type createApiKeyBiz struct {
+	store ApiKeyStore
+	audit AuditLogger
+}
+
+func NewCreateApiKeyBiz(store ApiKeyStore, audit AuditLogger) ICreateApiKeyBiz {
+	return &createApiKeyBiz{store: store, audit: audit}
+}
+
+// CreateApiKey issues a key acting as userId. Only its hash is stored, the
+// key itself is returned once.
+func (biz *createApiKeyBiz) CreateApiKey(
+	ctx context.Context,
+	userId int,
+	data *apikeymodel.ApiKeyCreate,
+) (*apikeymodel.ApiKeyCreated, error) {
+	if err := data.Validate(time.Now()); err != nil {
+		return nil, err
+	}
+
+	prefix, err := randomHex(4)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	key := fmt.Sprintf("%s_%s_%s", apikeymodel.KeyPrefix, prefix, secret)
+
+	data.UserId = userId
+	data.Prefix = prefix
+	data.KeyHash = apikeymodel.HashKey(key)
+
+	if err := biz.store.CreateApiKey(ctx, data); err != nil {
+		return nil, common.ErrCannotCreateEntity(apikeymodel.EntityName, err)
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionApiKeyCreate,
+		TargetType: auditmodel.TargetApiKey,
+		TargetId:   auditmodel.IntId(data.Id),
+		After:      map[string]interface{}{"prefix": prefix, "data": data},
+	})
+
+	return &apikeymodel.ApiKeyCreated{Id: data.Id, Key: key, Prefix: prefix}, nil
+}
+
+// List api keys
+
+type IListApiKeysBiz interface {
+	ListApiKeys(ctx context.Context) ([]apikeymodel.ApiKey, error)
+}
+
+type listApiKeysBiz struct {
+	store ApiKeyStore
+}
+
+func NewListApiKeysBiz(store ApiKeyStore) IListApiKeysBiz {
+	return &listApiKeysBiz{store: store}
+}
+
+func (biz *listApiKeysBiz) ListApiKeys(ctx context.Context) ([]apikeymodel.ApiKey, error) {
+	return biz.store.ListApiKeys(ctx)
+}
+
+// Revoke api key
+
+type IRevokeApiKeyBiz interface {
+	RevokeApiKey(ctx context.Context, id int) error
+}
+
+type revokeApiKeyBiz struct {
+	store ApiKeyStore
+	audit AuditLogger
+}
+
+func NewRevokeApiKeyBiz(store ApiKeyStore, audit AuditLogger) IRevokeApiKeyBiz {
+	return &revokeApiKeyBiz{store: store, audit: audit}
+}
+
+func (biz *revokeApiKeyBiz) RevokeApiKey(ctx context.Context, id int) error {
+	key, err := biz.store.FindApiKey(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return apikeymodel.ErrApiKeyNotFound
+		}
+		return err
+	}
+
+	// revoking twice keeps the original time
+	if key.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	if err := biz.store.UpdateApiKey(ctx, id, map[string]interface{}{"revoked_at": now}); err != nil {
+		return err
+	}
+
+	after := *key
+	after.RevokedAt = &now
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionApiKeyRevoke,
+		TargetType: auditmodel.TargetApiKey,
+		TargetId:   auditmodel.IntId(id),
+		Before:     key,
+		After:      after,
+	})
+
+	return nil
+}
+
+// Authenticate api key
+
+type IAuthenticateApiKeyBiz interface {
+	AuthenticateApiKey(ctx context.Context, rawKey string) (*apikeymodel.ApiKey, error)
+}
+
+type authenticateApiKeyBiz struct {
+	store            ApiKeyStore
+	usage            UsageStore
+	defaultRateLimit int
+	lastUsedInterval int
+}
+
+func NewAuthenticateApiKeyBiz(
+	store ApiKeyStore,
+	usage UsageStore,
+	defaultRateLimit int,
+	lastUsedInterval int,
+) IAuthenticateApiKeyBiz {
+	return &authenticateApiKeyBiz{
+		store:            store,
+		usage:            usage,
+		defaultRateLimit: defaultRateLimit,
+		lastUsedInterval: lastUsedInterval,
+	}
+}
+
+func (biz *authenticateApiKeyBiz) AuthenticateApiKey(ctx context.Context, rawKey string) (*apikeymodel.ApiKey, error) {
+	prefix, ok := apikeymodel.ParseKey(rawKey)
+	if !ok {
+		return nil, apikeymodel.ErrApiKeyInvalid
+	}
+
+	key, err := biz.store.FindApiKey(ctx, map[string]interface{}{"prefix": prefix})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, apikeymodel.ErrApiKeyInvalid
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apikeymodel.HashKey(rawKey)), []byte(key.KeyHash)) != 1 {
+		return nil, apikeymodel.ErrApiKeyInvalid
+	}
+
+	now := time.Now()
+	if !key.IsActive(now) {
+		return nil, apikeymodel.ErrApiKeyInvalid
+	}
+
+	limit := key.RateLimit
+	if limit == 0 {
+		limit = biz.defaultRateLimit
+	}
+
+	if limit > 0 {
+		count, retryAfter, err := biz.usage.IncrementUsage(ctx, key.Id, rateLimitWindow)
+		if err != nil {
+			return nil, err
+		}
+		if count > int64(limit) {
+			return nil, apikeymodel.ErrApiKeyRateLimited(retryAfter)
+		}
+	}
+
+	touch, err := biz.usage.MarkUsed(ctx, key.Id, time.Duration(biz.lastUsedInterval)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if touch {
+		if err := biz.store.UpdateApiKey(ctx, key.Id, map[string]interface{}{"last_used_at": now}); err != nil {
+			return nil, err
+		}
+		key.LastUsedAt = &now
+	}
+
+	return key, nil
+}
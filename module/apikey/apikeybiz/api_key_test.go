@@ -0,0 +1,94 @@
+package apikeybiz_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/mock"
+	"app-invite-service/module/apikey/apikeybiz"
+	"app-invite-service/module/apikey/apikeymodel"
+)
+
+func TestApiKeyBiz_CreateApiKey(t *testing.T) {
+	store := mock.NewMockApiKeyStore()
+	biz := apikeybiz.NewCreateApiKeyBiz(store, mock.NewMockAuditLogger())
+	past := time.Now().Add(-time.Hour)
+
+	tcs := []struct {
+		data        apikeymodel.ApiKeyCreate
+		expectedErr error
+	}{
+		{apikeymodel.ApiKeyCreate{Name: "crm"}, apikeymodel.ErrScopesInvalid},
+		{apikeymodel.ApiKeyCreate{Name: "crm", Scopes: []string{"admin"}}, apikeymodel.ErrScopesInvalid},
+		{apikeymodel.ApiKeyCreate{Name: "crm", Scopes: []string{apikeymodel.ScopeInvitationsWrite}, ExpiresAt: &past}, apikeymodel.ErrExpiresAtInvalid},
+		{apikeymodel.ApiKeyCreate{Name: "crm", Scopes: []string{apikeymodel.ScopeInvitationsWrite}, RateLimit: -1}, apikeymodel.ErrRateLimitInvalid},
+		{apikeymodel.ApiKeyCreate{Name: "crm", Scopes: []string{apikeymodel.ScopeInvitationsWrite}}, nil},
+	}
+
+	for _, tc := range tcs {
+		result, err := biz.CreateApiKey(nil, 1, &tc.data)
+		assert.Equal(t, tc.expectedErr, err)
+		if tc.expectedErr != nil {
+			continue
+		}
+
+		assert.True(t, strings.HasPrefix(result.Key, "evk_"+result.Prefix+"_"))
+
+		key, err := store.FindApiKey(nil, map[string]interface{}{"id": result.Id})
+		require.Nil(t, err, err)
+		assert.Equal(t, 1, key.UserId)
+		assert.Equal(t, apikeymodel.HashKey(result.Key), key.KeyHash)
+		assert.NotContains(t, key.KeyHash, result.Key)
+	}
+}
+
+func TestApiKeyBiz_AuthenticateApiKey(t *testing.T) {
+	store := mock.NewMockApiKeyStore()
+	usage := mock.NewMockApiKeyUsageStore()
+	create := apikeybiz.NewCreateApiKeyBiz(store, mock.NewMockAuditLogger())
+	revoke := apikeybiz.NewRevokeApiKeyBiz(store, mock.NewMockAuditLogger())
+	biz := apikeybiz.NewAuthenticateApiKeyBiz(store, usage, 2, 60)
+
+	created, err := create.CreateApiKey(nil, 1, &apikeymodel.ApiKeyCreate{
+		Name:   "crm",
+		Scopes: []string{apikeymodel.ScopeInvitationsWrite},
+	})
+	require.Nil(t, err, err)
+
+	tcs := []struct {
+		key         string
+		expectedErr error
+	}{
+		{"", apikeymodel.ErrApiKeyInvalid},
+		{"evk_" + created.Prefix, apikeymodel.ErrApiKeyInvalid},
+		{"evk_" + created.Prefix + "_wrong", apikeymodel.ErrApiKeyInvalid},
+		{"evk_unknown_" + strings.Repeat("0", 64), apikeymodel.ErrApiKeyInvalid},
+		{created.Key, nil},
+		{created.Key, nil},
+	}
+
+	for _, tc := range tcs {
+		key, err := biz.AuthenticateApiKey(nil, tc.key)
+		assert.Equal(t, tc.expectedErr, err, tc.key)
+		if tc.expectedErr == nil {
+			assert.Equal(t, created.Id, key.Id)
+			assert.NotNil(t, key.LastUsedAt)
+		}
+	}
+
+	// default limit is 2 requests per window
+	_, err = biz.AuthenticateApiKey(nil, created.Key)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "rate limited")
+
+	require.Nil(t, revoke.RevokeApiKey(nil, created.Id))
+	_, err = apikeybiz.NewAuthenticateApiKeyBiz(store, mock.NewMockApiKeyUsageStore(), 2, 60).
+		AuthenticateApiKey(nil, created.Key)
+	assert.Equal(t, apikeymodel.ErrApiKeyInvalid, err)
+
+	assert.Equal(t, apikeymodel.ErrApiKeyNotFound, revoke.RevokeApiKey(nil, 99))
+}
@@ -0,0 +1,68 @@
+package apikeystorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/apikey/apikeymodel"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type ISqlStore interface {
+	CreateApiKey(_ context.Context, data *apikeymodel.ApiKeyCreate) error
+	FindApiKey(_ context.Context, conditions map[string]interface{}) (*apikeymodel.ApiKey, error)
+	ListApiKeys(_ context.Context) ([]apikeymodel.ApiKey, error)
+	UpdateApiKey(_ context.Context, id int, data map[string]interface{}) error
+}
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+func NewSQLStore(db *gorm.DB) ISqlStore {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) CreateApiKey(_ context.Context, data *apikeymodel.ApiKeyCreate) error {
+	if err := s.db.Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) FindApiKey(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*apikeymodel.ApiKey, error) {
+	var key apikeymodel.ApiKey
+
+	if err := s.db.Table(key.TableName()).Where(conditions).First(&key).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &key, nil
+}
+
+func (s *sqlStore) ListApiKeys(_ context.Context) ([]apikeymodel.ApiKey, error) {
+	var keys []apikeymodel.ApiKey
+
+	if err := s.db.Table(apikeymodel.ApiKey{}.TableName()).Order("id").Find(&keys).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return keys, nil
+}
+
+func (s *sqlStore) UpdateApiKey(_ context.Context, id int, data map[string]interface{}) error {
+	if err := s.db.Table(apikeymodel.ApiKey{}.TableName()).
+		Where("id = ?", id).
+		Updates(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package apikeystorage
+
+import (
+	"app-invite-service/common"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	rateKeyPrefix = "api_key_rate:"
+	usedKeyPrefix = "api_key_used:"
+)
+
+type IUsageStore interface {
+	IncrementUsage(ctx context.Context, keyId int, window time.Duration) (int64, time.Duration, error)
+	MarkUsed(ctx context.Context, keyId int, interval time.Duration) (bool, error)
+}
+
+type redisUsageStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisUsageStore(rdb *redis.Client) IUsageStore {
+	return &redisUsageStore{rdb: rdb}
+}
+
+// IncrementUsage counts a request in the current fixed window and returns the
+// count so far and the time left in the window
+func (s *redisUsageStore) IncrementUsage(
+	ctx context.Context,
+	keyId int,
+	window time.Duration,
+) (int64, time.Duration, error) {
+	key := fmt.Sprintf("%s%d", rateKeyPrefix, keyId)
+
+	pipe := s.rdb.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, window)
+	ttl := pipe.TTL(ctx, key)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, common.ErrDB(err)
+	}
+
+	return incr.Val(), ttl.Val(), nil
+}
+
+// MarkUsed returns true at most once per interval, so last_used_at isn't
+// written to MySQL on every request
+func (s *redisUsageStore) MarkUsed(ctx context.Context, keyId int, interval time.Duration) (bool, error) {
+	ok, err := s.rdb.SetNX(ctx, fmt.Sprintf("%s%d", usedKeyPrefix, keyId), 1, interval).Result()
+	if err != nil {
+		return false, common.ErrDB(err)
+	}
+
+	return ok, nil
+}
@@ -0,0 +1,69 @@
+package ginapikey
+
+import (
+	"net/http"
+	"strconv"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/apikey/apikeybiz"
+	"app-invite-service/module/apikey/apikeymodel"
+	"app-invite-service/module/apikey/apikeystorage"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/user/usermodel"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CreateApiKey(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data apikeymodel.ApiKeyCreate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		store := apikeystorage.NewSQLStore(appCtx.GetDBConn())
+		biz := apikeybiz.NewCreateApiKeyBiz(store, ginaudit.NewRecorder(appCtx))
+
+		result, err := biz.CreateApiKey(c.Request.Context(), user.Id, &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func ListApiKeys(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := apikeystorage.NewSQLStore(appCtx.GetDBConn())
+		biz := apikeybiz.NewListApiKeysBiz(store)
+
+		result, err := biz.ListApiKeys(c.Request.Context())
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func RevokeApiKey(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := apikeystorage.NewSQLStore(appCtx.GetDBConn())
+		biz := apikeybiz.NewRevokeApiKeyBiz(store, ginaudit.NewRecorder(appCtx))
+
+		if err := biz.RevokeApiKey(c.Request.Context(), id); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
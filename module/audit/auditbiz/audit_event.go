@@ -0,0 +1,192 @@
+package auditbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"strconv"
+	"time"
+)
+
+type EventStore interface {
+	CreateEvent(ctx context.Context, data *auditmodel.Event) error
+	ListEvents(ctx context.Context, filter *auditmodel.EventFilter, paging *common.Paging) ([]auditmodel.Event, error)
+	EachEvents(ctx context.Context, filter *auditmodel.EventFilter, fn func(events []auditmodel.Event) error) error
+}
+
+// RequestInfoFunc tells who made the request behind ctx. It may return nil
+// outside of a request, e.g. for CLI commands.
+type RequestInfoFunc func(ctx context.Context) *auditmodel.RequestInfo
+
+// Record audit event
+
+type IRecordEventBiz interface {
+	Record(ctx context.Context, entry *auditmodel.Entry)
+}
+
+type recordEventBiz struct {
+	store       EventStore
+	requestInfo RequestInfoFunc
+}
+
+func NewRecordEventBiz(store EventStore, requestInfo RequestInfoFunc) IRecordEventBiz {
+	return &recordEventBiz{store: store, requestInfo: requestInfo}
+}
+
+// Record writes the event of a mutation which was made already. Failing the
+// request then would report a change that happened as one that didn't, and
+// clients retrying it would make it twice, so an event that can't be
+// written is only logged.
+func (biz *recordEventBiz) Record(ctx context.Context, entry *auditmodel.Entry) {
+	if err := biz.record(ctx, entry); err != nil {
+		log.Printf("audit: cannot record %s of %s %s: %v", entry.Action, entry.TargetType, entry.TargetId, err)
+	}
+}
+
+func (biz *recordEventBiz) record(ctx context.Context, entry *auditmodel.Entry) error {
+	before, err := auditmodel.ToMap(entry.Before)
+	if err != nil {
+		return common.ErrInternal(err)
+	}
+
+	after, err := auditmodel.ToMap(entry.After)
+	if err != nil {
+		return common.ErrInternal(err)
+	}
+
+	event := auditmodel.Event{
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetId:   entry.TargetId,
+		Before:     before,
+		After:      after,
+		Changes:    auditmodel.Diff(before, after),
+	}
+
+	if info := biz.requestInfo(ctx); info != nil {
+		event.ActorId = info.ActorId
+		event.ApiKeyId = info.ApiKeyId
+		event.IP = info.IP
+		event.UserAgent = info.UserAgent
+		event.RequestId = info.RequestId
+	}
+
+	if err := biz.store.CreateEvent(ctx, &event); err != nil {
+		return common.ErrCannotCreateEntity(auditmodel.EntityName, err)
+	}
+
+	return nil
+}
+
+// List audit events
+
+type IListEventsBiz interface {
+	ListEvents(
+		ctx context.Context,
+		filter *auditmodel.EventFilter,
+		paging *common.Paging,
+	) ([]auditmodel.Event, error)
+}
+
+type listEventsBiz struct {
+	store EventStore
+}
+
+func NewListEventsBiz(store EventStore) IListEventsBiz {
+	return &listEventsBiz{store: store}
+}
+
+func (biz *listEventsBiz) ListEvents(
+	ctx context.Context,
+	filter *auditmodel.EventFilter,
+	paging *common.Paging,
+) ([]auditmodel.Event, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	return biz.store.ListEvents(ctx, filter, paging)
+}
+
+// Export audit events
+
+var csvHeader = []string{
+	"id", "created_at", "actor_id", "api_key_id", "action", "target_type", "target_id",
+	"before", "after", "changes", "ip", "user_agent", "request_id",
+}
+
+type IExportEventsBiz interface {
+	ExportEvents(ctx context.Context, filter *auditmodel.EventFilter, w io.Writer) error
+}
+
+type exportEventsBiz struct {
+	store EventStore
+}
+
+func NewExportEventsBiz(store EventStore) IExportEventsBiz {
+	return &exportEventsBiz{store: store}
+}
+
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// jsonColumn renders a map column, leaving the cell empty when there is none.
+// The maps were decoded from JSON, so they always encode again.
+func jsonColumn(v interface{}, empty bool) string {
+	if empty {
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// ExportEvents writes every matching event to w as CSV, oldest first
+func (biz *exportEventsBiz) ExportEvents(ctx context.Context, filter *auditmodel.EventFilter, w io.Writer) error {
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	err := biz.store.EachEvents(ctx, filter, func(events []auditmodel.Event) error {
+		for _, e := range events {
+			createdAt := ""
+			if e.CreatedAt != nil {
+				createdAt = e.CreatedAt.UTC().Format(time.RFC3339)
+			}
+
+			if err := writer.Write([]string{
+				strconv.FormatInt(e.Id, 10), createdAt, optionalInt(e.ActorId), optionalInt(e.ApiKeyId),
+				e.Action, e.TargetType, e.TargetId,
+				jsonColumn(e.Before, e.Before == nil),
+				jsonColumn(e.After, e.After == nil),
+				jsonColumn(e.Changes, e.Changes == nil),
+				e.IP, e.UserAgent, e.RequestId,
+			}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		if appErr, ok := err.(*common.AppError); ok {
+			return appErr
+		}
+		return common.ErrInternal(err)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
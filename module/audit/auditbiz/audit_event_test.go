@@ -0,0 +1,107 @@
+package auditbiz_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/mock"
+	"app-invite-service/module/audit/auditbiz"
+	"app-invite-service/module/audit/auditmodel"
+)
+
+func TestAuditBiz_Record(t *testing.T) {
+	actorId := 7
+	store := mock.NewMockAuditEventStore()
+	biz := auditbiz.NewRecordEventBiz(store, func(ctx context.Context) *auditmodel.RequestInfo {
+		return &auditmodel.RequestInfo{ActorId: &actorId, IP: "10.0.0.1", UserAgent: "curl", RequestId: "req-1"}
+	})
+
+	biz.Record(nil, &auditmodel.Entry{
+		Action:     auditmodel.ActionInvitationUpdate,
+		TargetType: auditmodel.TargetInvitationToken,
+		TargetId:   "abc123",
+		Before:     map[string]interface{}{"status": 1, "token": "abc123"},
+		After:      map[string]interface{}{"status": 0, "token": "abc123"},
+	})
+
+	require.Len(t, store.Events(), 1)
+	event := store.Events()[0]
+	assert.Equal(t, &actorId, event.ActorId)
+	assert.Equal(t, "10.0.0.1", event.IP)
+	assert.Equal(t, "req-1", event.RequestId)
+	assert.Equal(t, map[string]auditmodel.Change{"status": {From: 1.0, To: 0.0}}, event.Changes)
+}
+
+func TestAuditBiz_RecordWithoutRequest(t *testing.T) {
+	store := mock.NewMockAuditEventStore()
+	biz := auditbiz.NewRecordEventBiz(store, func(ctx context.Context) *auditmodel.RequestInfo { return nil })
+
+	biz.Record(nil, &auditmodel.Entry{
+		Action:     auditmodel.ActionUserUnlock,
+		TargetType: auditmodel.TargetUser,
+		TargetId:   "user@gmail.com",
+	})
+
+	require.Len(t, store.Events(), 1)
+	assert.Nil(t, store.Events()[0].ActorId)
+	assert.Nil(t, store.Events()[0].Changes)
+}
+
+// failingEventStore can't write events
+type failingEventStore struct {
+	auditbiz.EventStore
+}
+
+func (failingEventStore) CreateEvent(context.Context, *auditmodel.Event) error {
+	return errors.New("db down")
+}
+
+func TestAuditBiz_RecordFailureDoesNotFailTheMutation(t *testing.T) {
+	biz := auditbiz.NewRecordEventBiz(
+		failingEventStore{EventStore: mock.NewMockAuditEventStore()},
+		func(ctx context.Context) *auditmodel.RequestInfo { return nil },
+	)
+
+	// the failure is only logged, nothing reaches the caller
+	assert.NotPanics(t, func() {
+		biz.Record(nil, &auditmodel.Entry{
+			Action:     auditmodel.ActionUserRegister,
+			TargetType: auditmodel.TargetUser,
+			TargetId:   "3",
+		})
+	})
+}
+
+func TestAuditBiz_ExportEvents(t *testing.T) {
+	store := mock.NewMockAuditEventStore()
+	record := auditbiz.NewRecordEventBiz(store, func(ctx context.Context) *auditmodel.RequestInfo { return nil })
+	for _, action := range []string{auditmodel.ActionApiKeyCreate, auditmodel.ActionApiKeyRevoke} {
+		record.Record(nil, &auditmodel.Entry{
+			Action:     action,
+			TargetType: auditmodel.TargetApiKey,
+			TargetId:   "1",
+			After:      map[string]interface{}{"name": "crm"},
+		})
+	}
+
+	biz := auditbiz.NewExportEventsBiz(store)
+
+	var buf bytes.Buffer
+	err := biz.ExportEvents(nil, &auditmodel.EventFilter{Action: auditmodel.ActionApiKeyRevoke}, &buf)
+	require.Nil(t, err, err)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.Nil(t, err, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "id", rows[0][0])
+	assert.Equal(t, "2", rows[1][0])
+	assert.Equal(t, auditmodel.ActionApiKeyRevoke, rows[1][4])
+	assert.Equal(t, "", rows[1][7])
+	assert.Equal(t, `{"name":"crm"}`, rows[1][8])
+}
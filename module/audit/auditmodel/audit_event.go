@@ -0,0 +1,155 @@
+package auditmodel
+
+import (
+	"app-invite-service/common"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const EntityName = "AuditEvent"
+
+const (
+	ActionInvitationGenerate = "invitation.generate"
+	ActionInvitationUpdate   = "invitation.update"
+	ActionInvitationEmail    = "invitation.email"
+	ActionInvitationRevoke   = "invitation.revoke"
+	ActionInvitationDelete   = "invitation.delete"
+	ActionInvitationExport   = "invitation.export"
+	ActionInvitationImport   = "invitation.import"
+	ActionUserRegister       = "user.register"
+	ActionUserProvision      = "user.provision"
+	ActionUserUnlock         = "user.unlock"
+	ActionUserVerifyEmail    = "user.verify_email"
+	ActionUserEnableMfa      = "user.enable_mfa"
+	ActionUserLinkIdentity   = "user.link_identity"
+	ActionDomainRuleCreate   = "email_domain_rule.create"
+	ActionDomainRuleDelete   = "email_domain_rule.delete"
+	ActionOAuthClientCreate  = "oauth_client.create"
+	ActionOAuthClientDelete  = "oauth_client.delete"
+	ActionApiKeyCreate       = "api_key.create"
+	ActionApiKeyRevoke       = "api_key.revoke"
+	ActionReferralRevoke     = "referral.revoke"
+	ActionInviteQuotaUpdate  = "invite_quota.update"
+	ActionInviteQuotaGrant   = "invite_quota.grant"
+	ActionCampaignCreate     = "campaign.create"
+	ActionCampaignDisable    = "campaign.disable"
+	ActionWaitlistApprove    = "waitlist.approve"
+	ActionWaitlistReject     = "waitlist.reject"
+)
+
+const (
+	TargetInvitationToken = "invitation_token"
+	TargetUser            = "user"
+	TargetDomainRule      = "email_domain_rule"
+	TargetOAuthClient     = "oauth_client"
+	TargetApiKey          = "api_key"
+	TargetInviteQuota     = "invite_quota"
+	TargetCampaign        = "campaign"
+	TargetWaitlistEntry   = "waitlist_entry"
+)
+
+// Entry is what a biz reports about a mutation. Before and After are
+// marshalled to JSON, so secrets must be kept out of them with `json:"-"`.
+type Entry struct {
+	Action     string
+	TargetType string
+	TargetId   string
+	Before     interface{}
+	After      interface{}
+}
+
+func IntId(id int) string {
+	return strconv.Itoa(id)
+}
+
+// RequestInfo describes who made the request an entry is recorded for
+type RequestInfo struct {
+	ActorId   *int
+	ApiKeyId  *int
+	IP        string
+	UserAgent string
+	RequestId string
+}
+
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type Event struct {
+	Id         int64                  `json:"id" gorm:"column:id;"`
+	ActorId    *int                   `json:"actor_id" gorm:"column:actor_id;"`
+	ApiKeyId   *int                   `json:"api_key_id,omitempty" gorm:"column:api_key_id;"`
+	Action     string                 `json:"action" gorm:"column:action;"`
+	TargetType string                 `json:"target_type" gorm:"column:target_type;"`
+	TargetId   string                 `json:"target_id" gorm:"column:target_id;"`
+	Before     map[string]interface{} `json:"before,omitempty" gorm:"column:before;serializer:json;"`
+	After      map[string]interface{} `json:"after,omitempty" gorm:"column:after;serializer:json;"`
+	Changes    map[string]Change      `json:"changes,omitempty" gorm:"column:changes;serializer:json;"`
+	IP         string                 `json:"ip" gorm:"column:ip;"`
+	UserAgent  string                 `json:"user_agent" gorm:"column:user_agent;"`
+	RequestId  string                 `json:"request_id" gorm:"column:request_id;"`
+	CreatedAt  *time.Time             `json:"created_at,omitempty" gorm:"column:created_at;"`
+}
+
+func (Event) TableName() string {
+	return "audit_events"
+}
+
+type EventFilter struct {
+	ActorId    *int       `json:"actor_id,omitempty" form:"actor_id"`
+	Action     string     `json:"action,omitempty" form:"action"`
+	TargetType string     `json:"target_type,omitempty" form:"target_type"`
+	TargetId   string     `json:"target_id,omitempty" form:"target_id"`
+	RequestId  string     `json:"request_id,omitempty" form:"request_id"`
+	From       *time.Time `json:"from,omitempty" form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         *time.Time `json:"to,omitempty" form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+func (f *EventFilter) Validate() error {
+	return common.ValidateTimeRange(f.From, f.To)
+}
+
+// ToMap turns an entry value into the JSON object stored for it
+func ToMap(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Diff lists the top level fields that differ between before and after
+func Diff(before, after map[string]interface{}) map[string]Change {
+	changes := map[string]Change{}
+
+	for k, from := range before {
+		if to, ok := after[k]; !ok || !reflect.DeepEqual(from, to) {
+			changes[k] = Change{From: from, To: after[k]}
+		}
+	}
+
+	for k, to := range after {
+		if _, ok := before[k]; !ok {
+			changes[k] = Change{To: to}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	return changes
+}
@@ -0,0 +1,81 @@
+package auditmodel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+)
+
+func TestAuditModel_Diff(t *testing.T) {
+	tcs := []struct {
+		before   map[string]interface{}
+		after    map[string]interface{}
+		expected map[string]auditmodel.Change
+	}{
+		{nil, nil, nil},
+		{map[string]interface{}{"status": 1.0}, map[string]interface{}{"status": 1.0}, nil},
+		{
+			map[string]interface{}{"status": 1.0, "email": "a@b.com"},
+			map[string]interface{}{"status": 0.0, "email": "a@b.com"},
+			map[string]auditmodel.Change{"status": {From: 1.0, To: 0.0}},
+		},
+		{
+			nil,
+			map[string]interface{}{"pattern": "customer.com"},
+			map[string]auditmodel.Change{"pattern": {To: "customer.com"}},
+		},
+		{
+			map[string]interface{}{"pattern": "customer.com"},
+			nil,
+			map[string]auditmodel.Change{"pattern": {From: "customer.com"}},
+		},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.expected, auditmodel.Diff(tc.before, tc.after))
+	}
+}
+
+func TestAuditModel_ToMap(t *testing.T) {
+	type secret struct {
+		Name     string `json:"name"`
+		Password string `json:"-"`
+	}
+
+	m, err := auditmodel.ToMap(secret{Name: "crm", Password: "hunter2"})
+	require.Nil(t, err, err)
+	assert.Equal(t, map[string]interface{}{"name": "crm"}, m)
+
+	m, err = auditmodel.ToMap(nil)
+	require.Nil(t, err, err)
+	assert.Nil(t, m)
+
+	var missing *secret
+	m, err = auditmodel.ToMap(missing)
+	require.Nil(t, err, err)
+	assert.Nil(t, m)
+}
+
+func TestAuditModel_EventFilterValidate(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	tcs := []struct {
+		filter      auditmodel.EventFilter
+		expectedErr error
+	}{
+		{auditmodel.EventFilter{}, nil},
+		{auditmodel.EventFilter{From: &now}, nil},
+		{auditmodel.EventFilter{From: &now, To: &later}, nil},
+		{auditmodel.EventFilter{From: &later, To: &now}, common.ErrTimeRangeInvalid},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.expectedErr, tc.filter.Validate())
+	}
+}
@@ -0,0 +1,118 @@
+package auditstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many events are read at a time while exporting
+const exportBatchSize = 500
+
+// ISqlStore has no update or delete on purpose, audit events are append-only
+type ISqlStore interface {
+	CreateEvent(_ context.Context, data *auditmodel.Event) error
+	ListEvents(
+		_ context.Context,
+		filter *auditmodel.EventFilter,
+		paging *common.Paging,
+	) ([]auditmodel.Event, error)
+	EachEvents(
+		_ context.Context,
+		filter *auditmodel.EventFilter,
+		fn func(events []auditmodel.Event) error,
+	) error
+}
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+func NewSQLStore(db *gorm.DB) ISqlStore {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) CreateEvent(_ context.Context, data *auditmodel.Event) error {
+	if err := s.db.Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) filtered(filter *auditmodel.EventFilter) *gorm.DB {
+	db := s.db.Table(auditmodel.Event{}.TableName())
+
+	if filter == nil {
+		return db
+	}
+
+	if filter.ActorId != nil {
+		db = db.Where("actor_id = ?", *filter.ActorId)
+	}
+	if filter.Action != "" {
+		db = db.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		db = db.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetId != "" {
+		db = db.Where("target_id = ?", filter.TargetId)
+	}
+	if filter.RequestId != "" {
+		db = db.Where("request_id = ?", filter.RequestId)
+	}
+	if filter.From != nil {
+		db = db.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		db = db.Where("created_at < ?", *filter.To)
+	}
+
+	return db
+}
+
+func (s *sqlStore) ListEvents(
+	_ context.Context,
+	filter *auditmodel.EventFilter,
+	paging *common.Paging,
+) ([]auditmodel.Event, error) {
+	db := s.filtered(filter)
+
+	if err := db.Count(&paging.Total).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	var events []auditmodel.Event
+	if err := db.Order("id desc").
+		Offset((paging.Page - 1) * paging.Limit).
+		Limit(paging.Limit).
+		Find(&events).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return events, nil
+}
+
+// EachEvents calls fn with every matching event, oldest first, in batches
+func (s *sqlStore) EachEvents(
+	_ context.Context,
+	filter *auditmodel.EventFilter,
+	fn func(events []auditmodel.Event) error,
+) error {
+	var events []auditmodel.Event
+
+	result := s.filtered(filter).FindInBatches(&events, exportBatchSize, func(_ *gorm.DB, _ int) error {
+		return fn(events)
+	})
+	if result.Error != nil {
+		if _, ok := result.Error.(*common.AppError); ok {
+			return result.Error
+		}
+		return common.ErrDB(result.Error)
+	}
+
+	return nil
+}
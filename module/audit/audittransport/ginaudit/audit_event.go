@@ -0,0 +1,109 @@
+package ginaudit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/apikey/apikeymodel"
+	"app-invite-service/module/audit/auditbiz"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/audit/auditstorage"
+	"app-invite-service/module/user/usermodel"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestInfo reads the actor and request details from the gin context that
+// GinContextToContextMiddleware puts into every request context
+func requestInfo(ctx context.Context) *auditmodel.RequestInfo {
+	if ctx == nil {
+		return nil
+	}
+
+	c, ok := ctx.Value("GinContextKey").(*gin.Context)
+	if !ok {
+		return nil
+	}
+
+	info := auditmodel.RequestInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestId: c.GetString(common.RequestId),
+	}
+
+	if user, ok := c.Get(common.CurrentUser); ok {
+		id := user.(*usermodel.User).Id
+		info.ActorId = &id
+	}
+
+	if key, ok := c.Get(common.CurrentApiKey); ok {
+		id := key.(*apikeymodel.ApiKey).Id
+		info.ApiKeyId = &id
+	}
+
+	return &info
+}
+
+// NewRecorder returns the audit logger handed to every mutating biz
+func NewRecorder(appCtx component.AppContext) auditbiz.IRecordEventBiz {
+	return auditbiz.NewRecordEventBiz(auditstorage.NewSQLStore(appCtx.GetDBConn()), requestInfo)
+}
+
+func ListEvents(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter auditmodel.EventFilter
+		if err := c.ShouldBind(&filter); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		var paging common.Paging
+		if err := c.ShouldBind(&paging); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		paging.Fulfill()
+
+		store := auditstorage.NewSQLStore(appCtx.GetDBConn())
+		biz := auditbiz.NewListEventsBiz(store)
+
+		result, err := biz.ListEvents(c.Request.Context(), &filter, &paging)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.NewSuccessResponse(result, paging, filter))
+	}
+}
+
+func ExportEvents(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter auditmodel.EventFilter
+		if err := c.ShouldBind(&filter); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		if err := filter.Validate(); err != nil {
+			panic(err)
+		}
+
+		store := auditstorage.NewSQLStore(appCtx.GetDBConn())
+		biz := auditbiz.NewExportEventsBiz(store)
+
+		filename := fmt.Sprintf("audit-events-%s.csv", time.Now().UTC().Format("20060102-150405"))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Status(http.StatusOK)
+
+		// the header is already sent once rows are streamed, so a failure
+		// part way can only cut the file short
+		if err := biz.ExportEvents(c.Request.Context(), &filter, c.Writer); err != nil {
+			if !c.Writer.Written() {
+				panic(err)
+			}
+			_ = c.Error(err)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package campaignbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/campaign/campaignmodel"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"time"
+)
+
+type CampaignStore interface {
+	CreateCampaign(ctx context.Context, data *campaignmodel.CampaignCreate) error
+	FindCampaign(ctx context.Context, conditions map[string]interface{}) (*campaignmodel.Campaign, error)
+	ListCampaigns(ctx context.Context, paging *common.Paging) ([]campaignmodel.Campaign, error)
+	UpdateCampaign(ctx context.Context, id int, data map[string]interface{}) error
+	Redeem(ctx context.Context, campaignId int, token string) (bool, error)
+}
+
+type TokenDisabler interface {
+	DisableTokensByCampaign(ctx context.Context, campaignId int) ([]string, error)
+}
+
+type AuditLogger interface {
+	Record(ctx context.Context, entry *auditmodel.Entry)
+}
+
+func findCampaign(ctx context.Context, store CampaignStore, id int) (*campaignmodel.Campaign, error) {
+	campaign, err := store.FindCampaign(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, campaignmodel.ErrCampaignNotFound
+		}
+		return nil, err
+	}
+
+	return campaign, nil
+}
+
+// Create campaign
+
+type ICreateCampaignBiz interface {
+	CreateCampaign(ctx context.Context, data *campaignmodel.CampaignCreate) (*campaignmodel.Campaign, error)
+}
+
+type createCampaignBiz struct {
+	store CampaignStore
+	audit AuditLogger
+}
+
+func NewCreateCampaignBiz(store CampaignStore, audit AuditLogger) ICreateCampaignBiz {
+	return &createCampaignBiz{store: store, audit: audit}
+}
+
+func (biz *createCampaignBiz) CreateCampaign(
+	ctx context.Context,
+	data *campaignmodel.CampaignCreate,
+) (*campaignmodel.Campaign, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	existing, err := biz.store.FindCampaign(ctx, map[string]interface{}{"name": data.Name})
+	if err != nil && err != common.ErrRecordNotFound {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, common.ErrEntityExisted(campaignmodel.EntityName, nil)
+	}
+
+	if err := biz.store.CreateCampaign(ctx, data); err != nil {
+		return nil, common.ErrCannotCreateEntity(campaignmodel.EntityName, err)
+	}
+
+	campaign, err := findCampaign(ctx, biz.store, data.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionCampaignCreate,
+		TargetType: auditmodel.TargetCampaign,
+		TargetId:   auditmodel.IntId(campaign.Id),
+		After:      campaign,
+	})
+
+	return campaign, nil
+}
+
+// List campaigns
+
+type IListCampaignsBiz interface {
+	ListCampaigns(ctx context.Context, paging *common.Paging) ([]campaignmodel.Campaign, error)
+}
+
+type listCampaignsBiz struct {
+	store CampaignStore
+}
+
+func NewListCampaignsBiz(store CampaignStore) IListCampaignsBiz {
+	return &listCampaignsBiz{store: store}
+}
+
+func (biz *listCampaignsBiz) ListCampaigns(ctx context.Context, paging *common.Paging) ([]campaignmodel.Campaign, error) {
+	return biz.store.ListCampaigns(ctx, paging)
+}
+
+// Get campaign
+
+type IGetCampaignBiz interface {
+	GetCampaign(ctx context.Context, id int) (*campaignmodel.Campaign, error)
+}
+
+type getCampaignBiz struct {
+	store CampaignStore
+}
+
+func NewGetCampaignBiz(store CampaignStore) IGetCampaignBiz {
+	return &getCampaignBiz{store: store}
+}
+
+func (biz *getCampaignBiz) GetCampaign(ctx context.Context, id int) (*campaignmodel.Campaign, error) {
+	return findCampaign(ctx, biz.store, id)
+}
+
+// Disable campaign
+
+type IDisableCampaignBiz interface {
+	DisableCampaign(ctx context.Context, id int) (*campaignmodel.CampaignDisabled, error)
+}
+
+type disableCampaignBiz struct {
+	store  CampaignStore
+	tokens TokenDisabler
+	audit  AuditLogger
+}
+
+func NewDisableCampaignBiz(store CampaignStore, tokens TokenDisabler, audit AuditLogger) IDisableCampaignBiz {
+	return &disableCampaignBiz{store: store, tokens: tokens, audit: audit}
+}
+
+// DisableCampaign stops the campaign and disables every token generated
+// under it. Disabling it again catches tokens a previous call missed.
+func (biz *disableCampaignBiz) DisableCampaign(ctx context.Context, id int) (*campaignmodel.CampaignDisabled, error) {
+	campaign, err := findCampaign(ctx, biz.store, id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *campaign
+	if campaign.Status != 0 {
+		if err := biz.store.UpdateCampaign(ctx, id, map[string]interface{}{"status": 0}); err != nil {
+			return nil, err
+		}
+		campaign.Status = 0
+	}
+
+	tokens, err := biz.tokens.DisableTokensByCampaign(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionCampaignDisable,
+		TargetType: auditmodel.TargetCampaign,
+		TargetId:   auditmodel.IntId(id),
+		Before:     before,
+		After:      map[string]interface{}{"status": campaign.Status, "token_ids": usermodel.InvitationTokenIds(tokens)},
+	})
+
+	return &campaignmodel.CampaignDisabled{Campaign: campaign, Tokens: tokens}, nil
+}
+
+// Campaign policy
+
+// ICampaignPolicyBiz is what invitation bizs ask about the campaign a token
+// belongs to
+type ICampaignPolicyBiz interface {
+	CampaignForIssue(ctx context.Context, id int) (*campaignmodel.Campaign, error)
+	CheckRedeemable(ctx context.Context, id int) (*campaignmodel.Campaign, error)
+	Redeem(ctx context.Context, id int, token string) (*campaignmodel.Campaign, error)
+}
+
+type campaignPolicyBiz struct {
+	store CampaignStore
+}
+
+func NewCampaignPolicyBiz(store CampaignStore) ICampaignPolicyBiz {
+	return &campaignPolicyBiz{store: store}
+}
+
+func (biz *campaignPolicyBiz) CampaignForIssue(ctx context.Context, id int) (*campaignmodel.Campaign, error) {
+	campaign, err := findCampaign(ctx, biz.store, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := campaign.CheckIssuable(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return campaign, nil
+}
+
+func (biz *campaignPolicyBiz) CheckRedeemable(ctx context.Context, id int) (*campaignmodel.Campaign, error) {
+	campaign, err := findCampaign(ctx, biz.store, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := campaign.CheckRedeemable(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return campaign, nil
+}
+
+// Redeem counts token against the campaign's redemptions; a token counts
+// once however often it is redeemed
+func (biz *campaignPolicyBiz) Redeem(ctx context.Context, id int, token string) (*campaignmodel.Campaign, error) {
+	campaign, err := findCampaign(ctx, biz.store, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the store checks the count, so tokens redeemed before still pass
+	if err := campaign.CheckActive(time.Now()); err != nil {
+		return nil, err
+	}
+
+	ok, err := biz.store.Redeem(ctx, id, token)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, campaignmodel.ErrCampaignExhausted
+	}
+
+	return campaign, nil
+}
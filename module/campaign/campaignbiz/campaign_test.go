@@ -0,0 +1,133 @@
+package campaignbiz_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/common"
+	"app-invite-service/mock"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/campaign/campaignbiz"
+	"app-invite-service/module/campaign/campaignmodel"
+	"app-invite-service/module/user/usermodel"
+)
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestCreateCampaignBiz_CreateCampaign(t *testing.T) {
+	store := mock.NewMockCampaignStore(campaignmodel.Campaign{Id: 1, Name: "launch", Status: 1})
+	audit := mock.NewMockAuditLogger()
+	biz := campaignbiz.NewCreateCampaignBiz(store, audit)
+
+	tcs := []struct {
+		data        campaignmodel.CampaignCreate
+		expectedErr string
+	}{
+		{campaignmodel.CampaignCreate{Name: " "}, "ErrNameInvalid"},
+		{campaignmodel.CampaignCreate{Name: "past", EndsAt: timePtr(time.Now().Add(-time.Hour))}, "ErrWindowInvalid"},
+		{campaignmodel.CampaignCreate{Name: "ttl", TokenTTL: campaignmodel.MaxTokenTTL + 1}, "ErrTokenTTLInvalid"},
+		{campaignmodel.CampaignCreate{Name: "max", MaxRedemptions: -1}, "ErrMaxRedemptionsInvalid"},
+		{campaignmodel.CampaignCreate{Name: "role", DefaultRole: "owner"}, "ErrDefaultRoleInvalid"},
+		{campaignmodel.CampaignCreate{Name: "launch"}, "ErrCampaignAlreadyExists"},
+		{campaignmodel.CampaignCreate{Name: " partners ", DefaultRole: "Admin", MaxRedemptions: 10}, ""},
+	}
+
+	for _, tc := range tcs {
+		campaign, err := biz.CreateCampaign(nil, &tc.data)
+		if tc.expectedErr != "" {
+			require.Error(t, err, tc.data.Name)
+			assert.Equal(t, tc.expectedErr, err.(*common.AppError).Key, tc.data.Name)
+			continue
+		}
+		require.Nil(t, err, err)
+
+		assert.Equal(t, "partners", campaign.Name)
+		assert.Equal(t, campaignmodel.RoleAdmin, campaign.DefaultRole)
+		assert.Equal(t, 1, campaign.Status)
+	}
+
+	require.Len(t, audit.Entries(), 1)
+	assert.Equal(t, auditmodel.ActionCampaignCreate, audit.Entries()[0].Action)
+}
+
+func TestDisableCampaignBiz_DisableCampaign(t *testing.T) {
+	store := mock.NewMockCampaignStore(campaignmodel.Campaign{Id: 1, Name: "launch", Status: 1})
+	tokens := mock.NewMockTokenDisabler(
+		usermodel.InvitationToken{Token: "a", Status: 1, CampaignId: 1},
+		usermodel.InvitationToken{Token: "b", Status: 0, CampaignId: 1},
+		usermodel.InvitationToken{Token: "c", Status: 1, CampaignId: 2},
+		usermodel.InvitationToken{Token: "d", Status: 1},
+	)
+	audit := mock.NewMockAuditLogger()
+	biz := campaignbiz.NewDisableCampaignBiz(store, tokens, audit)
+
+	_, err := biz.DisableCampaign(nil, 2)
+	assert.Equal(t, campaignmodel.ErrCampaignNotFound, err)
+
+	result, err := biz.DisableCampaign(nil, 1)
+	require.Nil(t, err, err)
+	assert.Equal(t, 0, result.Campaign.Status)
+	assert.Equal(t, []string{"a"}, result.Tokens)
+
+	campaign, err := store.FindCampaign(nil, map[string]interface{}{"id": 1})
+	require.Nil(t, err)
+	assert.Equal(t, 0, campaign.Status)
+
+	require.Len(t, audit.Entries(), 1)
+	assert.Equal(t, auditmodel.ActionCampaignDisable, audit.Entries()[0].Action)
+	assert.Equal(t, []string{usermodel.InvitationTokenId("a")}, audit.Entries()[0].After.(map[string]interface{})["token_ids"])
+
+	// new tokens are refused once the campaign is disabled
+	_, err = campaignbiz.NewCampaignPolicyBiz(store).CampaignForIssue(nil, 1)
+	assert.Equal(t, campaignmodel.ErrCampaignDisabled, err)
+}
+
+func TestCampaignPolicyBiz_Redeem(t *testing.T) {
+	now := time.Now()
+	store := mock.NewMockCampaignStore(
+		campaignmodel.Campaign{Id: 1, Name: "limited", Status: 1, MaxRedemptions: 2},
+		campaignmodel.Campaign{Id: 2, Name: "upcoming", Status: 1, StartsAt: timePtr(now.Add(time.Hour))},
+		campaignmodel.Campaign{Id: 3, Name: "over", Status: 1, EndsAt: timePtr(now.Add(-time.Hour))},
+		campaignmodel.Campaign{Id: 4, Name: "off", Status: 0},
+	)
+	biz := campaignbiz.NewCampaignPolicyBiz(store)
+
+	tcs := []struct {
+		campaignId  int
+		token       string
+		expectedErr string
+	}{
+		{1, "a", ""},
+		// a token counts once
+		{1, "a", ""},
+		{1, "b", ""},
+		{1, "c", "ErrCampaignExhausted"},
+		{1, "a", ""},
+		{2, "a", "ErrCampaignNotStarted"},
+		{3, "a", "ErrCampaignEnded"},
+		{4, "a", "ErrCampaignDisabled"},
+		{5, "a", "ErrCampaignNotFound"},
+	}
+
+	for _, tc := range tcs {
+		_, err := biz.Redeem(nil, tc.campaignId, tc.token)
+		if tc.expectedErr == "" {
+			assert.Nil(t, err, err)
+			continue
+		}
+		require.Error(t, err)
+		assert.Equal(t, tc.expectedErr, err.(*common.AppError).Key, tc.campaignId)
+	}
+
+	_, err := biz.CheckRedeemable(nil, 1)
+	assert.Equal(t, campaignmodel.ErrCampaignExhausted, err)
+
+	// tokens can be generated before the campaign starts
+	_, err = biz.CampaignForIssue(nil, 2)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,198 @@
+package campaignmodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const EntityName = "Campaign"
+
+// MaxTokenTTL is the longest a campaign can make its tokens live, in seconds
+const MaxTokenTTL = 365 * 24 * 3600
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+var (
+	ErrCampaignNotFound = common.NewFullErrorResponse(
+		http.StatusNotFound,
+		errors.New("campaign not found"),
+		"campaign not found",
+		"campaign not found",
+		"ErrCampaignNotFound",
+	)
+	ErrCampaignDisabled = common.NewCustomError(
+		errors.New("campaign disabled"),
+		"the campaign of this invitation is disabled",
+		"ErrCampaignDisabled",
+	)
+	ErrCampaignEnded = common.NewCustomError(
+		errors.New("campaign ended"),
+		"the campaign of this invitation has ended",
+		"ErrCampaignEnded",
+	)
+	ErrCampaignExhausted = common.NewCustomError(
+		errors.New("campaign exhausted"),
+		"the campaign of this invitation has no redemptions left",
+		"ErrCampaignExhausted",
+	)
+	ErrNameInvalid = common.NewCustomError(
+		errors.New("name invalid"),
+		"name must be between 1 and 100 characters",
+		"ErrNameInvalid",
+	)
+	ErrWindowInvalid = common.NewCustomError(
+		errors.New("window invalid"),
+		"ends_at must be after starts_at and in the future",
+		"ErrWindowInvalid",
+	)
+	ErrTokenTTLInvalid = common.NewCustomError(
+		errors.New("token ttl invalid"),
+		fmt.Sprintf("token_ttl must be between 0 and %d seconds", MaxTokenTTL),
+		"ErrTokenTTLInvalid",
+	)
+	ErrMaxRedemptionsInvalid = common.NewCustomError(
+		errors.New("max redemptions invalid"),
+		"max_redemptions must not be negative",
+		"ErrMaxRedemptionsInvalid",
+	)
+	ErrDefaultRoleInvalid = common.NewCustomError(
+		errors.New("default role invalid"),
+		"default_role must be one of: user, admin",
+		"ErrDefaultRoleInvalid",
+	)
+)
+
+func ErrCampaignNotStarted(startsAt time.Time) *common.AppError {
+	msg := fmt.Sprintf("the campaign of this invitation starts at %s", startsAt.UTC().Format(time.RFC3339))
+	return common.NewCustomError(errors.New("campaign not started"), msg, "ErrCampaignNotStarted")
+}
+
+// Campaign groups invitation tokens. Tokens generated under it live TokenTTL
+// seconds, but never past EndsAt, and redeeming one grants DefaultRole. Zero
+// TokenTTL and MaxRedemptions mean the default TTL and no limit.
+type Campaign struct {
+	Id             int        `json:"id" gorm:"column:id;"`
+	Name           string     `json:"name" gorm:"column:name;"`
+	StartsAt       *time.Time `json:"starts_at" gorm:"column:starts_at;"`
+	EndsAt         *time.Time `json:"ends_at" gorm:"column:ends_at;"`
+	TokenTTL       int        `json:"token_ttl" gorm:"column:token_ttl;"`
+	MaxRedemptions int        `json:"max_redemptions" gorm:"column:max_redemptions;"`
+	Redemptions    int        `json:"redemptions" gorm:"column:redemptions;"`
+	DefaultRole    string     `json:"default_role" gorm:"column:default_role;"`
+	Status         int        `json:"status" gorm:"column:status;"`
+	CreatedBy      *int       `json:"created_by" gorm:"column:created_by;"`
+	CreatedAt      *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+}
+
+func (Campaign) TableName() string {
+	return "invitation_campaigns"
+}
+
+// CheckIssuable rejects generating tokens for a disabled or finished
+// campaign. Tokens may be generated ahead of the start.
+func (c *Campaign) CheckIssuable(now time.Time) error {
+	if c.Status == 0 {
+		return ErrCampaignDisabled
+	}
+	if c.EndsAt != nil && !now.Before(*c.EndsAt) {
+		return ErrCampaignEnded
+	}
+	return nil
+}
+
+// CheckActive rejects redeeming tokens outside the campaign's window
+func (c *Campaign) CheckActive(now time.Time) error {
+	if err := c.CheckIssuable(now); err != nil {
+		return err
+	}
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return ErrCampaignNotStarted(*c.StartsAt)
+	}
+	return nil
+}
+
+// CheckRedeemable also rejects new redemptions once the campaign ran out of
+// them. Taking a redemption checks the count again.
+func (c *Campaign) CheckRedeemable(now time.Time) error {
+	if err := c.CheckActive(now); err != nil {
+		return err
+	}
+	if c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions {
+		return ErrCampaignExhausted
+	}
+	return nil
+}
+
+// TokenExpiry is how long a token generated now lives
+func (c *Campaign) TokenExpiry(now time.Time, defaultTTL time.Duration) time.Duration {
+	ttl := defaultTTL
+	if c.TokenTTL > 0 {
+		ttl = time.Duration(c.TokenTTL) * time.Second
+	}
+	if c.EndsAt != nil && now.Add(ttl).After(*c.EndsAt) {
+		ttl = c.EndsAt.Sub(now)
+	}
+	return ttl
+}
+
+type CampaignCreate struct {
+	Id             int        `json:"-" gorm:"column:id;"`
+	Name           string     `json:"name" form:"name" binding:"required" gorm:"column:name;"`
+	StartsAt       *time.Time `json:"starts_at" form:"starts_at" gorm:"column:starts_at;"`
+	EndsAt         *time.Time `json:"ends_at" form:"ends_at" gorm:"column:ends_at;"`
+	TokenTTL       int        `json:"token_ttl" form:"token_ttl" gorm:"column:token_ttl;"`
+	MaxRedemptions int        `json:"max_redemptions" form:"max_redemptions" gorm:"column:max_redemptions;"`
+	DefaultRole    string     `json:"default_role" form:"default_role" gorm:"column:default_role;"`
+	CreatedBy      *int       `json:"-" form:"-" gorm:"column:created_by;"`
+}
+
+func (CampaignCreate) TableName() string {
+	return Campaign{}.TableName()
+}
+
+func (data *CampaignCreate) Validate() error {
+	data.Name = strings.TrimSpace(data.Name)
+	data.DefaultRole = strings.ToLower(strings.TrimSpace(data.DefaultRole))
+
+	if data.Name == "" || len(data.Name) > 100 {
+		return ErrNameInvalid
+	}
+
+	if data.EndsAt != nil {
+		if !data.EndsAt.After(time.Now()) || (data.StartsAt != nil && !data.EndsAt.After(*data.StartsAt)) {
+			return ErrWindowInvalid
+		}
+	}
+
+	if data.TokenTTL < 0 || data.TokenTTL > MaxTokenTTL {
+		return ErrTokenTTLInvalid
+	}
+
+	if data.MaxRedemptions < 0 {
+		return ErrMaxRedemptionsInvalid
+	}
+
+	switch data.DefaultRole {
+	case "":
+		data.DefaultRole = RoleUser
+	case RoleUser, RoleAdmin:
+	default:
+		return ErrDefaultRoleInvalid
+	}
+
+	return nil
+}
+
+// CampaignDisabled is what disabling a campaign did to its tokens
+type CampaignDisabled struct {
+	Campaign *Campaign `json:"campaign"`
+	Tokens   []string  `json:"tokens"`
+}
@@ -0,0 +1,31 @@
+package campaignmodel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"app-invite-service/module/campaign/campaignmodel"
+)
+
+func TestCampaign_TokenExpiry(t *testing.T) {
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	endsAt := now.Add(36 * time.Hour)
+	defaultTTL := 7 * 24 * time.Hour
+
+	tcs := []struct {
+		campaign campaignmodel.Campaign
+		expected time.Duration
+	}{
+		{campaignmodel.Campaign{}, defaultTTL},
+		{campaignmodel.Campaign{TokenTTL: 3600}, time.Hour},
+		// tokens don't outlive the campaign
+		{campaignmodel.Campaign{EndsAt: &endsAt}, 36 * time.Hour},
+		{campaignmodel.Campaign{TokenTTL: 3600, EndsAt: &endsAt}, time.Hour},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.expected, tc.campaign.TokenExpiry(now, defaultTTL))
+	}
+}
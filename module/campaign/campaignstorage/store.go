@@ -0,0 +1,119 @@
+package campaignstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/campaign/campaignmodel"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errExhausted rolls back a redemption the campaign has no room for
+var errExhausted = errors.New("campaign exhausted")
+
+type ISqlStore interface {
+	CreateCampaign(ctx context.Context, data *campaignmodel.CampaignCreate) error
+	FindCampaign(ctx context.Context, conditions map[string]interface{}) (*campaignmodel.Campaign, error)
+	ListCampaigns(ctx context.Context, paging *common.Paging) ([]campaignmodel.Campaign, error)
+	UpdateCampaign(ctx context.Context, id int, data map[string]interface{}) error
+	Redeem(ctx context.Context, campaignId int, token string) (bool, error)
+}
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+func NewSQLStore(db *gorm.DB) ISqlStore {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) CreateCampaign(_ context.Context, data *campaignmodel.CampaignCreate) error {
+	if err := s.db.Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) FindCampaign(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*campaignmodel.Campaign, error) {
+	var campaign campaignmodel.Campaign
+
+	if err := s.db.Table(campaign.TableName()).Where(conditions).First(&campaign).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &campaign, nil
+}
+
+func (s *sqlStore) ListCampaigns(_ context.Context, paging *common.Paging) ([]campaignmodel.Campaign, error) {
+	db := s.db.Table(campaignmodel.Campaign{}.TableName())
+
+	if err := db.Count(&paging.Total).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	var campaigns []campaignmodel.Campaign
+	if err := db.Order("id desc").
+		Offset((paging.Page - 1) * paging.Limit).
+		Limit(paging.Limit).
+		Find(&campaigns).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return campaigns, nil
+}
+
+func (s *sqlStore) UpdateCampaign(_ context.Context, id int, data map[string]interface{}) error {
+	if err := s.db.Table(campaignmodel.Campaign{}.TableName()).
+		Where("id = ?", id).
+		Updates(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// Redeem counts the first redemption of token against the campaign. It
+// returns false when the campaign has no redemptions left; tokens redeemed
+// before are let through without counting again.
+func (s *sqlStore) Redeem(_ context.Context, campaignId int, token string) (bool, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		insert := tx.Table("campaign_redemptions").
+			Clauses(clause.Insert{Modifier: "IGNORE"}).
+			Create(map[string]interface{}{"campaign_id": campaignId, "token": token})
+		if insert.Error != nil {
+			return insert.Error
+		}
+		if insert.RowsAffected == 0 {
+			return nil
+		}
+
+		update := tx.Table(campaignmodel.Campaign{}.TableName()).
+			Where("id = ? AND (max_redemptions = 0 OR redemptions < max_redemptions)", campaignId).
+			Update("redemptions", gorm.Expr("redemptions + 1"))
+		if update.Error != nil {
+			return update.Error
+		}
+		if update.RowsAffected == 0 {
+			return errExhausted
+		}
+
+		return nil
+	})
+	if err == errExhausted {
+		return false, nil
+	}
+	if err != nil {
+		return false, common.ErrDB(err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,109 @@
+package gincampaign
+
+import (
+	"net/http"
+	"strconv"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/campaign/campaignbiz"
+	"app-invite-service/module/campaign/campaignmodel"
+	"app-invite-service/module/campaign/campaignstorage"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewPolicy returns the campaign rules handed to the invitation bizs
+func NewPolicy(appCtx component.AppContext) campaignbiz.ICampaignPolicyBiz {
+	return campaignbiz.NewCampaignPolicyBiz(campaignstorage.NewSQLStore(appCtx.GetDBConn()))
+}
+
+func CreateCampaign(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data campaignmodel.CampaignCreate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		createdBy := c.MustGet(common.CurrentUser).(*usermodel.User).Id
+		data.CreatedBy = &createdBy
+
+		biz := campaignbiz.NewCreateCampaignBiz(
+			campaignstorage.NewSQLStore(appCtx.GetDBConn()),
+			ginaudit.NewRecorder(appCtx),
+		)
+
+		result, err := biz.CreateCampaign(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func ListCampaigns(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var paging common.Paging
+		if err := c.ShouldBind(&paging); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		paging.Fulfill()
+
+		biz := campaignbiz.NewListCampaignsBiz(campaignstorage.NewSQLStore(appCtx.GetDBConn()))
+
+		result, err := biz.ListCampaigns(c.Request.Context(), &paging)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.NewSuccessResponse(result, paging, nil))
+	}
+}
+
+func GetCampaign(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		biz := campaignbiz.NewGetCampaignBiz(campaignstorage.NewSQLStore(appCtx.GetDBConn()))
+
+		result, err := biz.GetCampaign(c.Request.Context(), id)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func DisableCampaign(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		audit := ginaudit.NewRecorder(appCtx)
+		biz := campaignbiz.NewDisableCampaignBiz(
+			campaignstorage.NewSQLStore(appCtx.GetDBConn()),
+			userbiz.NewDisableTokensByCampaignBiz(
+				userstorage.NewCachedInvitationTokenStore(appCtx.GetDBConn(), appCtx.GetRedisConn()),
+				audit,
+			),
+			audit,
+		)
+
+		result, err := biz.DisableCampaign(c.Request.Context(), id)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
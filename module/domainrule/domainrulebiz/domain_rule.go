@@ -0,0 +1,184 @@
+package domainrulebiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/domainrule/domainrulemodel"
+	"context"
+	"errors"
+	"net/http"
+)
+
+var ErrRuleNotFound = common.NewFullErrorResponse(
+	http.StatusNotFound,
+	errors.New("email domain rule not found"),
+	"email domain rule not found",
+	"email domain rule not found",
+	"ErrEmailDomainRuleNotFound",
+)
+
+type RuleStore interface {
+	CreateRule(ctx context.Context, data *domainrulemodel.EmailDomainRuleCreate) error
+	FindRule(ctx context.Context, conditions map[string]interface{}) (*domainrulemodel.EmailDomainRule, error)
+	ListRules(ctx context.Context, filter *domainrulemodel.EmailDomainRuleFilter) ([]domainrulemodel.EmailDomainRule, error)
+	DeleteRule(ctx context.Context, id int) error
+}
+
+type AuditLogger interface {
+	Record(ctx context.Context, entry *auditmodel.Entry)
+}
+
+// Check an email domain against the allow and deny lists
+
+type ICheckEmailDomainBiz interface {
+	CheckEmailDomain(ctx context.Context, domain string) error
+}
+
+type checkEmailDomainBiz struct {
+	store        RuleStore
+	staticAllow  []string
+	staticDenied []string
+}
+
+// NewCheckEmailDomainBiz merges the rules stored in MySQL with the static ones
+// from config. Deny rules always win; once any allow rule exists, a domain
+// has to match one of them.
+func NewCheckEmailDomainBiz(store RuleStore, staticAllow, staticDenied []string) ICheckEmailDomainBiz {
+	return &checkEmailDomainBiz{store: store, staticAllow: staticAllow, staticDenied: staticDenied}
+}
+
+func (biz *checkEmailDomainBiz) CheckEmailDomain(ctx context.Context, domain string) error {
+	rules, err := biz.store.ListRules(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	allow := append([]string{}, biz.staticAllow...)
+	deny := append([]string{}, biz.staticDenied...)
+	for _, rule := range rules {
+		if rule.Type == domainrulemodel.RuleTypeAllow {
+			allow = append(allow, rule.Pattern)
+		} else {
+			deny = append(deny, rule.Pattern)
+		}
+	}
+
+	for _, pattern := range deny {
+		if domainrulemodel.MatchDomain(pattern, domain) {
+			return domainrulemodel.ErrEmailDomainNotAllowed
+		}
+	}
+
+	if len(allow) == 0 {
+		return nil
+	}
+
+	for _, pattern := range allow {
+		if domainrulemodel.MatchDomain(pattern, domain) {
+			return nil
+		}
+	}
+
+	return domainrulemodel.ErrEmailDomainNotAllowed
+}
+
+// Create rule
+
+type ICreateRuleBiz interface {
+	CreateRule(ctx context.Context, data *domainrulemodel.EmailDomainRuleCreate) error
+}
+
+type createRuleBiz struct {
+	store RuleStore
+	audit AuditLogger
+}
+
+func NewCreateRuleBiz(store RuleStore, audit AuditLogger) ICreateRuleBiz {
+	return &createRuleBiz{store: store, audit: audit}
+}
+
+func (biz *createRuleBiz) CreateRule(ctx context.Context, data *domainrulemodel.EmailDomainRuleCreate) error {
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	rule, err := biz.store.FindRule(ctx, map[string]interface{}{"pattern": data.Pattern, "type": data.Type})
+	if rule != nil {
+		return common.ErrEntityExisted(domainrulemodel.EntityName, nil)
+	}
+	if err != common.ErrRecordNotFound {
+		return err
+	}
+
+	if err := biz.store.CreateRule(ctx, data); err != nil {
+		return common.ErrCannotCreateEntity(domainrulemodel.EntityName, err)
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionDomainRuleCreate,
+		TargetType: auditmodel.TargetDomainRule,
+		TargetId:   auditmodel.IntId(data.Id),
+		After:      data,
+	})
+
+	return nil
+}
+
+// List rules
+
+type IListRulesBiz interface {
+	ListRules(ctx context.Context, filter *domainrulemodel.EmailDomainRuleFilter) ([]domainrulemodel.EmailDomainRule, error)
+}
+
+type listRulesBiz struct {
+	store RuleStore
+}
+
+func NewListRulesBiz(store RuleStore) IListRulesBiz {
+	return &listRulesBiz{store: store}
+}
+
+func (biz *listRulesBiz) ListRules(
+	ctx context.Context,
+	filter *domainrulemodel.EmailDomainRuleFilter,
+) ([]domainrulemodel.EmailDomainRule, error) {
+	return biz.store.ListRules(ctx, filter)
+}
+
+// Delete rule
+
+type IDeleteRuleBiz interface {
+	DeleteRule(ctx context.Context, id int) error
+}
+
+type deleteRuleBiz struct {
+	store RuleStore
+	audit AuditLogger
+}
+
+func NewDeleteRuleBiz(store RuleStore, audit AuditLogger) IDeleteRuleBiz {
+	return &deleteRuleBiz{store: store, audit: audit}
+}
+
+func (biz *deleteRuleBiz) DeleteRule(ctx context.Context, id int) error {
+	rule, err := biz.store.FindRule(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return ErrRuleNotFound
+		}
+		return err
+	}
+
+	if err := biz.store.DeleteRule(ctx, id); err != nil {
+		return err
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionDomainRuleDelete,
+		TargetType: auditmodel.TargetDomainRule,
+		TargetId:   auditmodel.IntId(id),
+		Before:     rule,
+	})
+
+	return nil
+}
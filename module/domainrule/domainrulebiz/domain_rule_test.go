@@ -0,0 +1,91 @@
+package domainrulebiz_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/common"
+	"app-invite-service/mock"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/domainrule/domainrulebiz"
+	"app-invite-service/module/domainrule/domainrulemodel"
+)
+
+func TestDomainRuleBiz_CheckEmailDomain(t *testing.T) {
+	tcs := []struct {
+		rules       []domainrulemodel.EmailDomainRule
+		allow       []string
+		deny        []string
+		domain      string
+		expectedErr error
+	}{
+		{nil, nil, nil, "gmail.com", nil},
+		{nil, []string{"customer.com"}, nil, "customer.com", nil},
+		{nil, []string{"customer.com"}, nil, "gmail.com", domainrulemodel.ErrEmailDomainNotAllowed},
+		{nil, []string{"*.customer.com"}, nil, "eu.customer.com", nil},
+		{nil, nil, []string{"mailinator.com"}, "mailinator.com", domainrulemodel.ErrEmailDomainNotAllowed},
+		{
+			[]domainrulemodel.EmailDomainRule{{Id: 1, Pattern: "customer.com", Type: domainrulemodel.RuleTypeAllow}},
+			nil, nil, "gmail.com", domainrulemodel.ErrEmailDomainNotAllowed,
+		},
+		{
+			[]domainrulemodel.EmailDomainRule{{Id: 1, Pattern: "*.customer.com", Type: domainrulemodel.RuleTypeDeny}},
+			[]string{"*"}, nil, "test.customer.com", domainrulemodel.ErrEmailDomainNotAllowed,
+		},
+		{
+			[]domainrulemodel.EmailDomainRule{{Id: 1, Pattern: "*.customer.com", Type: domainrulemodel.RuleTypeDeny}},
+			[]string{"*"}, nil, "customer.com", nil,
+		},
+	}
+
+	for _, tc := range tcs {
+		biz := domainrulebiz.NewCheckEmailDomainBiz(mock.NewMockDomainRuleStore(tc.rules...), tc.allow, tc.deny)
+		assert.Equal(t, tc.expectedErr, biz.CheckEmailDomain(nil, tc.domain), tc.domain)
+	}
+}
+
+func TestDomainRuleBiz_CreateRule(t *testing.T) {
+	store := mock.NewMockDomainRuleStore(
+		domainrulemodel.EmailDomainRule{Id: 1, Pattern: "customer.com", Type: domainrulemodel.RuleTypeAllow},
+	)
+	biz := domainrulebiz.NewCreateRuleBiz(store, mock.NewMockAuditLogger())
+
+	tcs := []struct {
+		pattern     string
+		ruleType    string
+		expectedErr error
+	}{
+		{"partner.com", "allow", nil},
+		{"@Customer.com", "allow", common.ErrEntityExisted(domainrulemodel.EntityName, nil)},
+		{"customer.com", "deny", nil},
+		{"bad domain", "deny", domainrulemodel.ErrPatternInvalid},
+	}
+
+	for _, tc := range tcs {
+		err := biz.CreateRule(nil, &domainrulemodel.EmailDomainRuleCreate{Pattern: tc.pattern, Type: tc.ruleType})
+		if tc.expectedErr != nil {
+			assert.Error(t, err)
+			assert.Equal(t, tc.expectedErr.Error(), err.Error())
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+}
+
+func TestDomainRuleBiz_DeleteRule(t *testing.T) {
+	store := mock.NewMockDomainRuleStore(
+		domainrulemodel.EmailDomainRule{Id: 1, Pattern: "customer.com", Type: domainrulemodel.RuleTypeAllow},
+	)
+	audit := mock.NewMockAuditLogger()
+	biz := domainrulebiz.NewDeleteRuleBiz(store, audit)
+
+	assert.Nil(t, biz.DeleteRule(nil, 1))
+	assert.Equal(t, domainrulebiz.ErrRuleNotFound, biz.DeleteRule(nil, 1))
+
+	require.Len(t, audit.Entries(), 1)
+	assert.Equal(t, auditmodel.ActionDomainRuleDelete, audit.Entries()[0].Action)
+	assert.Equal(t, "1", audit.Entries()[0].TargetId)
+	assert.Nil(t, audit.Entries()[0].After)
+}
@@ -0,0 +1,114 @@
+package domainrulemodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const EntityName = "EmailDomainRule"
+
+const (
+	RuleTypeAllow = "allow"
+	RuleTypeDeny  = "deny"
+)
+
+var (
+	ErrPatternInvalid = common.NewCustomError(
+		errors.New("email domain pattern invalid"),
+		"email domain pattern may only contain letters, digits, '-', '.' and '*'",
+		"ErrEmailDomainPatternInvalid",
+	)
+	ErrRuleTypeInvalid = common.NewCustomError(
+		errors.New("email domain rule type invalid"),
+		"email domain rule type must be 'allow' or 'deny'",
+		"ErrEmailDomainRuleTypeInvalid",
+	)
+	ErrEmailDomainNotAllowed = common.NewCustomError(
+		errors.New("email domain not allowed"),
+		"email domain is not allowed",
+		"ErrEmailDomainNotAllowed",
+	)
+)
+
+var patternRegexp = regexp.MustCompile(`^[a-z0-9*][a-z0-9.*-]*$`)
+
+type EmailDomainRule struct {
+	Id        int        `json:"id" gorm:"column:id;"`
+	Pattern   string     `json:"pattern" gorm:"column:pattern;"`
+	Type      string     `json:"type" gorm:"column:type;"`
+	CreatedAt *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+}
+
+func (EmailDomainRule) TableName() string {
+	return "email_domain_rules"
+}
+
+type EmailDomainRuleCreate struct {
+	Id      int    `json:"-" gorm:"column:id;"`
+	Pattern string `json:"pattern" form:"pattern" binding:"required" gorm:"column:pattern;"`
+	Type    string `json:"type" form:"type" binding:"required" gorm:"column:type;"`
+}
+
+func (EmailDomainRuleCreate) TableName() string {
+	return EmailDomainRule{}.TableName()
+}
+
+func (r *EmailDomainRuleCreate) Validate() error {
+	r.Pattern = NormalizePattern(r.Pattern)
+	r.Type = strings.ToLower(strings.TrimSpace(r.Type))
+
+	if !patternRegexp.MatchString(r.Pattern) {
+		return ErrPatternInvalid
+	}
+
+	if r.Type != RuleTypeAllow && r.Type != RuleTypeDeny {
+		return ErrRuleTypeInvalid
+	}
+
+	return nil
+}
+
+type EmailDomainRuleFilter struct {
+	Type string `json:"type,omitempty" form:"type"`
+}
+
+// NormalizePattern lower-cases a domain pattern and strips a leading "@"
+func NormalizePattern(pattern string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(pattern), "@"))
+}
+
+// SplitPatterns parses a comma separated list of patterns from config
+func SplitPatterns(s string) []string {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		if p = NormalizePattern(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// MatchDomain reports whether domain matches pattern. As with the CORS origin
+// matcher, "*" is a wildcard, so "*.customer.com" matches every subdomain of
+// customer.com (but not customer.com itself) and "*" matches every domain.
+func MatchDomain(pattern, domain string) bool {
+	pattern = NormalizePattern(pattern)
+	domain = strings.ToLower(strings.TrimSpace(domain))
+
+	if pattern == "" || domain == "" {
+		return false
+	}
+
+	if !strings.Contains(pattern, "*") {
+		return pattern == domain
+	}
+
+	expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `[a-z0-9.-]*`)
+	matched, _ := regexp.MatchString("^"+expr+"$", domain)
+
+	return matched
+}
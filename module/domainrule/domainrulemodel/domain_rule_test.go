@@ -0,0 +1,54 @@
+package domainrulemodel_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"app-invite-service/module/domainrule/domainrulemodel"
+)
+
+func TestDomainRule_MatchDomain(t *testing.T) {
+	var tcs = []struct {
+		pattern  string
+		domain   string
+		expected bool
+	}{
+		{"customer.com", "customer.com", true},
+		{"@Customer.com", "CUSTOMER.COM", true},
+		{"customer.com", "mail.customer.com", false},
+		{"*.customer.com", "mail.customer.com", true},
+		{"*.customer.com", "a.b.customer.com", true},
+		{"*.customer.com", "customer.com", false},
+		{"*.customer.com", "evilcustomer.com", false},
+		{"*.customer.com", "customer.com.evil.io", false},
+		{"customer.*", "customer.io", true},
+		{"*", "gmail.com", true},
+		{"", "gmail.com", false},
+		{"gmail.com", "", false},
+	}
+
+	for _, tc := range tcs {
+		output := domainrulemodel.MatchDomain(tc.pattern, tc.domain)
+		assert.Equal(t, tc.expected, output, "%s ~ %s", tc.pattern, tc.domain)
+	}
+}
+
+func TestDomainRule_Validate(t *testing.T) {
+	var tcs = []struct {
+		pattern  string
+		ruleType string
+		expected error
+	}{
+		{"customer.com", "allow", nil},
+		{" @*.Customer.com ", "DENY", nil},
+		{"", "allow", domainrulemodel.ErrPatternInvalid},
+		{"cust omer.com", "allow", domainrulemodel.ErrPatternInvalid},
+		{"customer.com", "block", domainrulemodel.ErrRuleTypeInvalid},
+	}
+
+	for _, tc := range tcs {
+		data := domainrulemodel.EmailDomainRuleCreate{Pattern: tc.pattern, Type: tc.ruleType}
+		assert.Equal(t, tc.expected, data.Validate(), "they should be equal")
+	}
+}
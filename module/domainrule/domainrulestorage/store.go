@@ -0,0 +1,76 @@
+package domainrulestorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/domainrule/domainrulemodel"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type ISqlStore interface {
+	CreateRule(_ context.Context, data *domainrulemodel.EmailDomainRuleCreate) error
+	FindRule(_ context.Context, conditions map[string]interface{}) (*domainrulemodel.EmailDomainRule, error)
+	ListRules(_ context.Context, filter *domainrulemodel.EmailDomainRuleFilter) ([]domainrulemodel.EmailDomainRule, error)
+	DeleteRule(_ context.Context, id int) error
+}
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+func NewSQLStore(db *gorm.DB) ISqlStore {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) CreateRule(_ context.Context, data *domainrulemodel.EmailDomainRuleCreate) error {
+	if err := s.db.Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) FindRule(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*domainrulemodel.EmailDomainRule, error) {
+	var rule domainrulemodel.EmailDomainRule
+
+	if err := s.db.Table(rule.TableName()).Where(conditions).First(&rule).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &rule, nil
+}
+
+func (s *sqlStore) ListRules(
+	_ context.Context,
+	filter *domainrulemodel.EmailDomainRuleFilter,
+) ([]domainrulemodel.EmailDomainRule, error) {
+	db := s.db.Table(domainrulemodel.EmailDomainRule{}.TableName())
+
+	if filter != nil && filter.Type != "" {
+		db = db.Where("type = ?", filter.Type)
+	}
+
+	var rules []domainrulemodel.EmailDomainRule
+	if err := db.Order("id").Find(&rules).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return rules, nil
+}
+
+func (s *sqlStore) DeleteRule(_ context.Context, id int) error {
+	if err := s.db.Table(domainrulemodel.EmailDomainRule{}.TableName()).
+		Where("id = ?", id).
+		Delete(nil).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package gindomainrule
+
+import (
+	"net/http"
+	"strconv"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/domainrule/domainrulebiz"
+	"app-invite-service/module/domainrule/domainrulemodel"
+	"app-invite-service/module/domainrule/domainrulestorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CreateRule(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data domainrulemodel.EmailDomainRuleCreate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := domainrulestorage.NewSQLStore(appCtx.GetDBConn())
+		biz := domainrulebiz.NewCreateRuleBiz(store, ginaudit.NewRecorder(appCtx))
+
+		if err := biz.CreateRule(c.Request.Context(), &data); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(data.Id))
+	}
+}
+
+func ListRules(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter domainrulemodel.EmailDomainRuleFilter
+		if err := c.ShouldBind(&filter); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := domainrulestorage.NewSQLStore(appCtx.GetDBConn())
+		biz := domainrulebiz.NewListRulesBiz(store)
+
+		result, err := biz.ListRules(c.Request.Context(), &filter)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.NewSuccessResponse(result, nil, filter))
+	}
+}
+
+func DeleteRule(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := domainrulestorage.NewSQLStore(appCtx.GetDBConn())
+		biz := domainrulebiz.NewDeleteRuleBiz(store, ginaudit.NewRecorder(appCtx))
+
+		if err := biz.DeleteRule(c.Request.Context(), id); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
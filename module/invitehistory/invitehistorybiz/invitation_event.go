@@ -0,0 +1,267 @@
+package invitehistorybiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/invitehistory/invitehistorymodel"
+	"context"
+	"math"
+	"sort"
+	"strconv"
+)
+
+type EventStore interface {
+	CreateEvent(ctx context.Context, data *invitehistorymodel.Event) error
+	ListEvents(
+		ctx context.Context,
+		filter *invitehistorymodel.EventFilter,
+		paging *common.Paging,
+	) ([]invitehistorymodel.Event, error)
+	ListTokenRedemptions(
+		ctx context.Context,
+		filter *invitehistorymodel.AnalyticsFilter,
+	) ([]invitehistorymodel.TokenRedemption, error)
+	CountAttempts(
+		ctx context.Context,
+		filter *invitehistorymodel.AnalyticsFilter,
+	) ([]invitehistorymodel.AttemptCount, error)
+}
+
+// RequestInfoFunc tells which client made the request behind ctx. It may
+// return nil outside of a request.
+type RequestInfoFunc func(ctx context.Context) *invitehistorymodel.RequestInfo
+
+// Record invitation event
+
+type IRecordEventBiz interface {
+	Record(ctx context.Context, event *invitehistorymodel.Event) error
+}
+
+type recordEventBiz struct {
+	store       EventStore
+	requestInfo RequestInfoFunc
+}
+
+func NewRecordEventBiz(store EventStore, requestInfo RequestInfoFunc) IRecordEventBiz {
+	return &recordEventBiz{store: store, requestInfo: requestInfo}
+}
+
+func (biz *recordEventBiz) Record(ctx context.Context, event *invitehistorymodel.Event) error {
+	if info := biz.requestInfo(ctx); info != nil {
+		event.ClientIP = info.ClientIP
+		event.UserAgent = info.UserAgent
+	}
+	event.Truncate()
+
+	if err := biz.store.CreateEvent(ctx, event); err != nil {
+		return common.ErrCannotCreateEntity(invitehistorymodel.EntityName, err)
+	}
+
+	return nil
+}
+
+// List invitation events
+
+type IListEventsBiz interface {
+	ListEvents(
+		ctx context.Context,
+		filter *invitehistorymodel.EventFilter,
+		paging *common.Paging,
+	) ([]invitehistorymodel.Event, error)
+}
+
+type listEventsBiz struct {
+	store EventStore
+}
+
+func NewListEventsBiz(store EventStore) IListEventsBiz {
+	return &listEventsBiz{store: store}
+}
+
+func (biz *listEventsBiz) ListEvents(
+	ctx context.Context,
+	filter *invitehistorymodel.EventFilter,
+	paging *common.Paging,
+) ([]invitehistorymodel.Event, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	return biz.store.ListEvents(ctx, filter, paging)
+}
+
+// Invitation analytics
+
+// durationBuckets are the upper bounds of the time to redeem histogram
+var durationBuckets = []invitehistorymodel.DurationBucket{
+	{Label: "< 1 hour", UpTo: 3600},
+	{Label: "< 1 day", UpTo: 24 * 3600},
+	{Label: "< 7 days", UpTo: 7 * 24 * 3600},
+	{Label: "< 30 days", UpTo: 30 * 24 * 3600},
+	{Label: ">= 30 days"},
+}
+
+type IGetAnalyticsBiz interface {
+	GetAnalytics(ctx context.Context, filter *invitehistorymodel.AnalyticsFilter) (*invitehistorymodel.Analytics, error)
+}
+
+type getAnalyticsBiz struct {
+	store EventStore
+}
+
+func NewGetAnalyticsBiz(store EventStore) IGetAnalyticsBiz {
+	return &getAnalyticsBiz{store: store}
+}
+
+func (biz *getAnalyticsBiz) GetAnalytics(
+	ctx context.Context,
+	filter *invitehistorymodel.AnalyticsFilter,
+) (*invitehistorymodel.Analytics, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	tokens, err := biz.store.ListTokenRedemptions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := biz.store.CountAttempts(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := invitehistorymodel.Analytics{
+		From:       filter.From,
+		To:         filter.To,
+		Conversion: conversion(tokens),
+		Attempts:   attempts,
+		ByBatch:    []invitehistorymodel.BatchConversion{},
+		ByCreator:  []invitehistorymodel.CreatorConversion{},
+	}
+	if result.Attempts == nil {
+		result.Attempts = []invitehistorymodel.AttemptCount{}
+	}
+
+	durations := redeemDurations(tokens)
+	result.TimeToRedeem = invitehistorymodel.TimeToRedeem{
+		Mean:    mean(durations),
+		Median:  percentile(durations, 0.5),
+		P90:     percentile(durations, 0.9),
+		Buckets: histogram(durations),
+	}
+
+	byBatch := map[string][]invitehistorymodel.TokenRedemption{}
+	byCreator := map[string][]invitehistorymodel.TokenRedemption{}
+	creators := map[string]*int{}
+	for _, t := range tokens {
+		byBatch[t.BatchId] = append(byBatch[t.BatchId], t)
+
+		key := ""
+		if t.CreatedBy != nil {
+			key = strconv.Itoa(*t.CreatedBy)
+		}
+		byCreator[key] = append(byCreator[key], t)
+		creators[key] = t.CreatedBy
+	}
+
+	for batchId, group := range byBatch {
+		result.ByBatch = append(result.ByBatch, invitehistorymodel.BatchConversion{
+			BatchId:    batchId,
+			Conversion: conversion(group),
+		})
+	}
+	sort.Slice(result.ByBatch, func(i, j int) bool {
+		return result.ByBatch[i].BatchId < result.ByBatch[j].BatchId
+	})
+
+	for key, group := range byCreator {
+		result.ByCreator = append(result.ByCreator, invitehistorymodel.CreatorConversion{
+			CreatedBy:  creators[key],
+			Conversion: conversion(group),
+		})
+	}
+	sort.Slice(result.ByCreator, func(i, j int) bool {
+		a, b := result.ByCreator[i].CreatedBy, result.ByCreator[j].CreatedBy
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return *a < *b
+	})
+
+	return &result, nil
+}
+
+func conversion(tokens []invitehistorymodel.TokenRedemption) invitehistorymodel.Conversion {
+	durations := redeemDurations(tokens)
+
+	c := invitehistorymodel.Conversion{
+		Issued:             int64(len(tokens)),
+		Redeemed:           int64(len(durations)),
+		MedianTimeToRedeem: percentile(durations, 0.5),
+	}
+	if c.Issued > 0 {
+		c.ConversionRate = math.Round(float64(c.Redeemed)/float64(c.Issued)*10000) / 10000
+	}
+
+	return c
+}
+
+// redeemDurations returns the sorted seconds between issue and first login
+// of every redeemed token
+func redeemDurations(tokens []invitehistorymodel.TokenRedemption) []int64 {
+	var durations []int64
+	for _, t := range tokens {
+		if t.RedeemedAt == nil {
+			continue
+		}
+		d := int64(t.RedeemedAt.Sub(t.IssuedAt).Seconds())
+		if d < 0 {
+			d = 0
+		}
+		durations = append(durations, d)
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return durations
+}
+
+func mean(sorted []int64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	var sum int64
+	for _, d := range sorted {
+		sum += d
+	}
+	return sum / int64(len(sorted))
+}
+
+// percentile uses the nearest-rank method
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func histogram(sorted []int64) []invitehistorymodel.DurationBucket {
+	buckets := make([]invitehistorymodel.DurationBucket, len(durationBuckets))
+	copy(buckets, durationBuckets)
+
+	for _, d := range sorted {
+		for i := range buckets {
+			if buckets[i].UpTo == 0 || d < buckets[i].UpTo {
+				buckets[i].Count++
+				break
+			}
+		}
+	}
+
+	return buckets
+}
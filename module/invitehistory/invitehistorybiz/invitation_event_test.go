@@ -0,0 +1,100 @@
+package invitehistorybiz_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/common"
+	"app-invite-service/mock"
+	"app-invite-service/module/invitehistory/invitehistorybiz"
+	"app-invite-service/module/invitehistory/invitehistorymodel"
+)
+
+func TestInviteHistoryBiz_Record(t *testing.T) {
+	store := mock.NewMockInvitationEventStore(nil, nil)
+	biz := invitehistorybiz.NewRecordEventBiz(store, func(ctx context.Context) *invitehistorymodel.RequestInfo {
+		return &invitehistorymodel.RequestInfo{ClientIP: "10.0.0.1", UserAgent: "curl"}
+	})
+
+	event := invitehistorymodel.Event{
+		Token:  strings.Repeat("x", 100),
+		Action: invitehistorymodel.ActionValidate,
+	}
+	event.Fail(common.NewCustomError(nil, "invite token not existed", "ErrInviteTokenNotExisted"))
+	require.Nil(t, biz.Record(nil, &event))
+
+	require.Len(t, store.Events(), 1)
+	stored := store.Events()[0]
+	assert.Equal(t, invitehistorymodel.OutcomeFailure, stored.Outcome)
+	assert.Equal(t, "ErrInviteTokenNotExisted", stored.ErrorKey)
+	assert.Equal(t, "10.0.0.1", stored.ClientIP)
+	assert.Equal(t, "curl", stored.UserAgent)
+	assert.Len(t, stored.Token, 64)
+}
+
+func TestInviteHistoryBiz_GetAnalytics(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	at := func(d time.Duration) *time.Time {
+		v := from.Add(d)
+		return &v
+	}
+	admin, partner := 1, 2
+
+	store := mock.NewMockInvitationEventStore(
+		[]invitehistorymodel.TokenRedemption{
+			{Token: "a", BatchId: "launch", CreatedBy: &admin, IssuedAt: from, RedeemedAt: at(30 * time.Minute)},
+			{Token: "b", BatchId: "launch", CreatedBy: &admin, IssuedAt: from, RedeemedAt: at(2 * time.Hour)},
+			{Token: "c", BatchId: "launch", CreatedBy: &partner, IssuedAt: from},
+			{Token: "d", CreatedBy: &partner, IssuedAt: from, RedeemedAt: at(40 * 24 * time.Hour)},
+			{Token: "e", IssuedAt: from},
+		},
+		[]invitehistorymodel.AttemptCount{
+			{Action: invitehistorymodel.ActionLogin, Outcome: invitehistorymodel.OutcomeSuccess, Count: 3},
+		},
+	)
+	biz := invitehistorybiz.NewGetAnalyticsBiz(store)
+
+	_, err := biz.GetAnalytics(nil, &invitehistorymodel.AnalyticsFilter{From: to, To: from})
+	assert.Equal(t, common.ErrTimeRangeInvalid, err)
+
+	_, err = biz.GetAnalytics(nil, &invitehistorymodel.AnalyticsFilter{From: from, To: from.AddDate(2, 0, 0)})
+	assert.Equal(t, invitehistorymodel.ErrTimeRangeTooLong, err)
+
+	result, err := biz.GetAnalytics(nil, &invitehistorymodel.AnalyticsFilter{From: from, To: to})
+	require.Nil(t, err, err)
+
+	assert.Equal(t, int64(5), result.Issued)
+	assert.Equal(t, int64(3), result.Redeemed)
+	assert.Equal(t, 0.6, result.ConversionRate)
+	assert.Equal(t, int64(7200), result.MedianTimeToRedeem)
+	assert.Equal(t, int64(7200), result.TimeToRedeem.Median)
+	assert.Equal(t, int64(40*24*3600), result.TimeToRedeem.P90)
+
+	counts := []int64{}
+	for _, b := range result.TimeToRedeem.Buckets {
+		counts = append(counts, b.Count)
+	}
+	assert.Equal(t, []int64{1, 1, 0, 0, 1}, counts)
+
+	require.Len(t, result.ByBatch, 2)
+	assert.Equal(t, "", result.ByBatch[0].BatchId)
+	assert.Equal(t, int64(2), result.ByBatch[0].Issued)
+	assert.Equal(t, "launch", result.ByBatch[1].BatchId)
+	assert.Equal(t, int64(2), result.ByBatch[1].Redeemed)
+	assert.Equal(t, 0.6667, result.ByBatch[1].ConversionRate)
+
+	require.Len(t, result.ByCreator, 3)
+	assert.Nil(t, result.ByCreator[0].CreatedBy)
+	assert.Equal(t, &admin, result.ByCreator[1].CreatedBy)
+	assert.Equal(t, 1.0, result.ByCreator[1].ConversionRate)
+	assert.Equal(t, &partner, result.ByCreator[2].CreatedBy)
+	assert.Equal(t, 0.5, result.ByCreator[2].ConversionRate)
+
+	assert.Len(t, result.Attempts, 1)
+}
@@ -0,0 +1,179 @@
+package invitehistorymodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"time"
+)
+
+const EntityName = "InvitationEvent"
+
+const (
+	ActionIssue        = "issue"
+	ActionValidate     = "validate"
+	ActionLogin        = "login"
+	ActionChangeExpiry = "change_expiry"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// MaxAnalyticsRange keeps analytics queries from scanning the whole table
+const MaxAnalyticsRange = 366 * 24 * time.Hour
+
+// maxTokenLength matches the token column, anything longer is not one of ours
+const maxTokenLength = 64
+
+var (
+	ErrTimeRangeTooLong = common.NewCustomError(
+		errors.New("time range too long"),
+		"from and to must be at most 366 days apart",
+		"ErrTimeRangeTooLong",
+	)
+)
+
+// Event is one issue, validation or login attempt of an invitation token, or
+// a change of its expiry. The token's batch, creator, creation time and
+// expiry are copied in, because the token itself expires from redis.
+type Event struct {
+	Id             int64      `json:"id" gorm:"column:id;"`
+	Token          string     `json:"token" gorm:"column:token;"`
+	Action         string     `json:"action" gorm:"column:action;"`
+	Outcome        string     `json:"outcome" gorm:"column:outcome;"`
+	ErrorKey       string     `json:"error_key,omitempty" gorm:"column:error_key;"`
+	Email          string     `json:"email,omitempty" gorm:"column:email;"`
+	ClientIP       string     `json:"client_ip" gorm:"column:client_ip;"`
+	UserAgent      string     `json:"user_agent" gorm:"column:user_agent;"`
+	BatchId        string     `json:"batch_id,omitempty" gorm:"column:batch_id;"`
+	CreatedBy      *int       `json:"created_by,omitempty" gorm:"column:created_by;"`
+	TokenCreatedAt *time.Time `json:"token_created_at,omitempty" gorm:"column:token_created_at;"`
+	TokenExpiresAt *time.Time `json:"token_expires_at,omitempty" gorm:"column:token_expires_at;"`
+	CreatedAt      *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+}
+
+func (Event) TableName() string {
+	return "invitation_events"
+}
+
+// Fail marks the event as failed with the error key of err
+func (e *Event) Fail(err error) {
+	e.Outcome = OutcomeFailure
+	e.ErrorKey = "ErrInternal"
+	if appErr, ok := err.(*common.AppError); ok {
+		e.ErrorKey = appErr.Key
+	}
+}
+
+// Truncate cuts user supplied values down to their column size
+func (e *Event) Truncate() {
+	e.Token = truncate(e.Token, maxTokenLength)
+	e.Email = truncate(e.Email, 255)
+	e.UserAgent = truncate(e.UserAgent, 255)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// RequestInfo describes the client behind an attempt
+type RequestInfo struct {
+	ClientIP  string
+	UserAgent string
+}
+
+type EventFilter struct {
+	Token     string     `json:"token,omitempty" form:"token"`
+	Action    string     `json:"action,omitempty" form:"action"`
+	Outcome   string     `json:"outcome,omitempty" form:"outcome"`
+	BatchId   string     `json:"batch_id,omitempty" form:"batch_id"`
+	CreatedBy *int       `json:"created_by,omitempty" form:"created_by"`
+	From      *time.Time `json:"from,omitempty" form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        *time.Time `json:"to,omitempty" form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+func (f *EventFilter) Validate() error {
+	return common.ValidateTimeRange(f.From, f.To)
+}
+
+// AnalyticsFilter selects the tokens issued between From and To
+type AnalyticsFilter struct {
+	From      time.Time `json:"from" form:"from" time_format:"2006-01-02T15:04:05Z07:00" binding:"required"`
+	To        time.Time `json:"to" form:"to" time_format:"2006-01-02T15:04:05Z07:00" binding:"required"`
+	BatchId   string    `json:"batch_id,omitempty" form:"batch_id"`
+	CreatedBy *int      `json:"created_by,omitempty" form:"created_by"`
+}
+
+func (f *AnalyticsFilter) Validate() error {
+	if err := common.ValidateTimeRange(&f.From, &f.To); err != nil {
+		return err
+	}
+	if f.To.Sub(f.From) > MaxAnalyticsRange {
+		return ErrTimeRangeTooLong
+	}
+	return nil
+}
+
+// TokenRedemption is an issued token and when it was first used to log in
+type TokenRedemption struct {
+	Token      string     `gorm:"column:token;"`
+	BatchId    string     `gorm:"column:batch_id;"`
+	CreatedBy  *int       `gorm:"column:created_by;"`
+	IssuedAt   time.Time  `gorm:"column:issued_at;"`
+	RedeemedAt *time.Time `gorm:"column:redeemed_at;"`
+}
+
+// AttemptCount counts validation and login attempts by outcome
+type AttemptCount struct {
+	Action   string `json:"action" gorm:"column:action;"`
+	Outcome  string `json:"outcome" gorm:"column:outcome;"`
+	ErrorKey string `json:"error_key,omitempty" gorm:"column:error_key;"`
+	Count    int64  `json:"count" gorm:"column:count;"`
+}
+
+type DurationBucket struct {
+	Label string `json:"label"`
+	// UpTo is the exclusive upper bound in seconds, 0 for the last bucket
+	UpTo  int64 `json:"up_to,omitempty"`
+	Count int64 `json:"count"`
+}
+
+// TimeToRedeem describes how long issued tokens took to be used, in seconds
+type TimeToRedeem struct {
+	Mean    int64            `json:"mean"`
+	Median  int64            `json:"median"`
+	P90     int64            `json:"p90"`
+	Buckets []DurationBucket `json:"buckets"`
+}
+
+type Conversion struct {
+	Issued         int64   `json:"issued"`
+	Redeemed       int64   `json:"redeemed"`
+	ConversionRate float64 `json:"conversion_rate"`
+	// MedianTimeToRedeem is in seconds
+	MedianTimeToRedeem int64 `json:"median_time_to_redeem"`
+}
+
+type BatchConversion struct {
+	BatchId string `json:"batch_id"`
+	Conversion
+}
+
+type CreatorConversion struct {
+	CreatedBy *int `json:"created_by"`
+	Conversion
+}
+
+type Analytics struct {
+	From         time.Time           `json:"from"`
+	To           time.Time           `json:"to"`
+	Conversion                       // tokens issued in the range
+	TimeToRedeem TimeToRedeem        `json:"time_to_redeem"`
+	Attempts     []AttemptCount      `json:"attempts"`
+	ByBatch      []BatchConversion   `json:"by_batch"`
+	ByCreator    []CreatorConversion `json:"by_creator"`
+}
@@ -0,0 +1,142 @@
+package invitehistorystorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/invitehistory/invitehistorymodel"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type ISqlStore interface {
+	CreateEvent(_ context.Context, data *invitehistorymodel.Event) error
+	ListEvents(
+		_ context.Context,
+		filter *invitehistorymodel.EventFilter,
+		paging *common.Paging,
+	) ([]invitehistorymodel.Event, error)
+	ListTokenRedemptions(
+		_ context.Context,
+		filter *invitehistorymodel.AnalyticsFilter,
+	) ([]invitehistorymodel.TokenRedemption, error)
+	CountAttempts(
+		_ context.Context,
+		filter *invitehistorymodel.AnalyticsFilter,
+	) ([]invitehistorymodel.AttemptCount, error)
+}
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+func NewSQLStore(db *gorm.DB) ISqlStore {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) CreateEvent(_ context.Context, data *invitehistorymodel.Event) error {
+	if err := s.db.Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) ListEvents(
+	_ context.Context,
+	filter *invitehistorymodel.EventFilter,
+	paging *common.Paging,
+) ([]invitehistorymodel.Event, error) {
+	db := s.db.Table(invitehistorymodel.Event{}.TableName())
+
+	if filter.Token != "" {
+		db = db.Where("token = ?", filter.Token)
+	}
+	if filter.Action != "" {
+		db = db.Where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		db = db.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.BatchId != "" {
+		db = db.Where("batch_id = ?", filter.BatchId)
+	}
+	if filter.CreatedBy != nil {
+		db = db.Where("created_by = ?", *filter.CreatedBy)
+	}
+	if filter.From != nil {
+		db = db.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		db = db.Where("created_at < ?", *filter.To)
+	}
+
+	if err := db.Count(&paging.Total).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	var events []invitehistorymodel.Event
+	if err := db.Order("id desc").
+		Offset((paging.Page - 1) * paging.Limit).
+		Limit(paging.Limit).
+		Find(&events).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return events, nil
+}
+
+// ListTokenRedemptions lists the tokens issued in the filter's range with the
+// time of their first successful invite login, if any
+func (s *sqlStore) ListTokenRedemptions(
+	_ context.Context,
+	filter *invitehistorymodel.AnalyticsFilter,
+) ([]invitehistorymodel.TokenRedemption, error) {
+	db := s.db.Table(invitehistorymodel.Event{}.TableName()+" AS i").
+		Select("i.token, i.batch_id, i.created_by, i.created_at AS issued_at, MIN(l.created_at) AS redeemed_at").
+		Joins(
+			"LEFT JOIN "+invitehistorymodel.Event{}.TableName()+" AS l ON l.token = i.token AND l.action = ? AND l.outcome = ?",
+			invitehistorymodel.ActionLogin,
+			invitehistorymodel.OutcomeSuccess,
+		).
+		Where("i.action = ?", invitehistorymodel.ActionIssue).
+		Where("i.created_at >= ? AND i.created_at < ?", filter.From, filter.To)
+
+	if filter.BatchId != "" {
+		db = db.Where("i.batch_id = ?", filter.BatchId)
+	}
+	if filter.CreatedBy != nil {
+		db = db.Where("i.created_by = ?", *filter.CreatedBy)
+	}
+
+	var rows []invitehistorymodel.TokenRedemption
+	if err := db.Group("i.id, i.token, i.batch_id, i.created_by, i.created_at").Scan(&rows).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return rows, nil
+}
+
+// CountAttempts counts the validations and logins made in the filter's range
+func (s *sqlStore) CountAttempts(
+	_ context.Context,
+	filter *invitehistorymodel.AnalyticsFilter,
+) ([]invitehistorymodel.AttemptCount, error) {
+	db := s.db.Table(invitehistorymodel.Event{}.TableName()).
+		Select("action, outcome, error_key, COUNT(*) AS count").
+		Where("action IN ?", []string{invitehistorymodel.ActionValidate, invitehistorymodel.ActionLogin}).
+		Where("created_at >= ? AND created_at < ?", filter.From, filter.To)
+
+	if filter.BatchId != "" {
+		db = db.Where("batch_id = ?", filter.BatchId)
+	}
+	if filter.CreatedBy != nil {
+		db = db.Where("created_by = ?", *filter.CreatedBy)
+	}
+
+	var rows []invitehistorymodel.AttemptCount
+	if err := db.Group("action, outcome, error_key").Order("action, outcome, error_key").Scan(&rows).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return rows, nil
+}
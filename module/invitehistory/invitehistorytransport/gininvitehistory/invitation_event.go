@@ -0,0 +1,81 @@
+package gininvitehistory
+
+import (
+	"context"
+	"net/http"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/invitehistory/invitehistorybiz"
+	"app-invite-service/module/invitehistory/invitehistorymodel"
+	"app-invite-service/module/invitehistory/invitehistorystorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestInfo reads the client from the gin context that
+// GinContextToContextMiddleware puts into every request context
+func requestInfo(ctx context.Context) *invitehistorymodel.RequestInfo {
+	if ctx == nil {
+		return nil
+	}
+
+	c, ok := ctx.Value("GinContextKey").(*gin.Context)
+	if !ok {
+		return nil
+	}
+
+	return &invitehistorymodel.RequestInfo{
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// NewRecorder returns the history handed to the invitation bizs
+func NewRecorder(appCtx component.AppContext) invitehistorybiz.IRecordEventBiz {
+	return invitehistorybiz.NewRecordEventBiz(invitehistorystorage.NewSQLStore(appCtx.GetDBConn()), requestInfo)
+}
+
+func ListEvents(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter invitehistorymodel.EventFilter
+		if err := c.ShouldBind(&filter); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		var paging common.Paging
+		if err := c.ShouldBind(&paging); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		paging.Fulfill()
+
+		store := invitehistorystorage.NewSQLStore(appCtx.GetDBConn())
+		biz := invitehistorybiz.NewListEventsBiz(store)
+
+		result, err := biz.ListEvents(c.Request.Context(), &filter, &paging)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.NewSuccessResponse(result, paging, filter))
+	}
+}
+
+func GetAnalytics(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter invitehistorymodel.AnalyticsFilter
+		if err := c.ShouldBind(&filter); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := invitehistorystorage.NewSQLStore(appCtx.GetDBConn())
+		biz := invitehistorybiz.NewGetAnalyticsBiz(store)
+
+		result, err := biz.GetAnalytics(c.Request.Context(), &filter)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
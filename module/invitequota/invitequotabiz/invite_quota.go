@@ -0,0 +1,277 @@
+package invitequotabiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/invitequota/invitequotamodel"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+)
+
+type QuotaStore interface {
+	FindUserQuota(ctx context.Context, userId int) (*invitequotamodel.UserQuota, error)
+	SaveLimits(ctx context.Context, userId int, data *invitequotamodel.UserQuotaUpdate) error
+	AddExtra(ctx context.Context, userId, amount int) error
+	TakeInvite(ctx context.Context, userId, total int) (bool, error)
+	ReturnInvite(ctx context.Context, userId int) error
+}
+
+type UsageStore interface {
+	Reserve(
+		ctx context.Context,
+		userId int,
+		limits invitequotamodel.Limits,
+		reservation string,
+		now time.Time,
+	) (invitequotamodel.ReserveOutcome, time.Time, error)
+	Release(ctx context.Context, userId int, reservation string) error
+	GetUsage(ctx context.Context, userId int, window time.Duration, now time.Time) (*invitequotamodel.Usage, error)
+}
+
+type UserStore interface {
+	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+}
+
+type AuditLogger interface {
+	Record(ctx context.Context, entry *auditmodel.Entry)
+}
+
+// quotaOf looks up the user and the quota they get from their role and overrides
+func quotaOf(
+	ctx context.Context,
+	store QuotaStore,
+	userStore UserStore,
+	roles map[string]invitequotamodel.Limits,
+	userId int,
+) (*usermodel.User, *invitequotamodel.UserQuota, invitequotamodel.Limits, error) {
+	user, err := userStore.FindUser(ctx, map[string]interface{}{"id": userId})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, nil, invitequotamodel.Limits{}, invitequotamodel.ErrUserNotFound
+		}
+		return nil, nil, invitequotamodel.Limits{}, err
+	}
+
+	quota, err := store.FindUserQuota(ctx, userId)
+	if err != nil && err != common.ErrRecordNotFound {
+		return nil, nil, invitequotamodel.Limits{}, err
+	}
+
+	return user, quota, quota.Resolve(roles[user.GetRole()]), nil
+}
+
+func findUser(ctx context.Context, userStore UserStore, userId int) error {
+	if _, err := userStore.FindUser(ctx, map[string]interface{}{"id": userId}); err != nil {
+		if err == common.ErrRecordNotFound {
+			return invitequotamodel.ErrUserNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Reserve invite
+
+type IReserveInviteBiz interface {
+	ReserveInvite(ctx context.Context, userId int) (string, error)
+	ReleaseInvite(ctx context.Context, userId int, reservation string) error
+}
+
+type reserveInviteBiz struct {
+	store     QuotaStore
+	usage     UsageStore
+	userStore UserStore
+	roles     map[string]invitequotamodel.Limits
+}
+
+func NewReserveInviteBiz(
+	store QuotaStore,
+	usage UsageStore,
+	userStore UserStore,
+	roles map[string]invitequotamodel.Limits,
+) IReserveInviteBiz {
+	return &reserveInviteBiz{store: store, usage: usage, userStore: userStore, roles: roles}
+}
+
+// ReserveInvite takes one invitation from the user's quota and returns the
+// reservation to release if the invitation isn't handed out after all
+func (biz *reserveInviteBiz) ReserveInvite(ctx context.Context, userId int) (string, error) {
+	_, _, limits, err := quotaOf(ctx, biz.store, biz.userStore, biz.roles, userId)
+	if err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", common.ErrInternal(err)
+	}
+	reservation := hex.EncodeToString(b)
+
+	// the total is taken first and given back when the window is full, so
+	// a used up total is what the user is told about
+	taken, err := biz.store.TakeInvite(ctx, userId, limits.Total)
+	if err != nil {
+		return "", err
+	}
+	if !taken {
+		return "", invitequotamodel.ErrInviteQuotaExceeded
+	}
+
+	outcome, oldest, err := biz.usage.Reserve(ctx, userId, limits, reservation, time.Now())
+	if err != nil || outcome == invitequotamodel.WindowExceeded {
+		if returnErr := biz.store.ReturnInvite(ctx, userId); returnErr != nil {
+			log.Printf("invite quota: cannot give back an invitation of user %d: %v", userId, returnErr)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if outcome == invitequotamodel.WindowExceeded {
+		return "", invitequotamodel.ErrInviteWindowQuotaExceeded(oldest.Add(time.Duration(limits.Window) * time.Second))
+	}
+
+	return reservation, nil
+}
+
+func (biz *reserveInviteBiz) ReleaseInvite(ctx context.Context, userId int, reservation string) error {
+	if err := biz.store.ReturnInvite(ctx, userId); err != nil {
+		return err
+	}
+
+	return biz.usage.Release(ctx, userId, reservation)
+}
+
+// Get allowance
+
+type IGetAllowanceBiz interface {
+	GetAllowance(ctx context.Context, userId int) (*invitequotamodel.Allowance, error)
+}
+
+type getAllowanceBiz struct {
+	store     QuotaStore
+	usage     UsageStore
+	userStore UserStore
+	roles     map[string]invitequotamodel.Limits
+}
+
+func NewGetAllowanceBiz(
+	store QuotaStore,
+	usage UsageStore,
+	userStore UserStore,
+	roles map[string]invitequotamodel.Limits,
+) IGetAllowanceBiz {
+	return &getAllowanceBiz{store: store, usage: usage, userStore: userStore, roles: roles}
+}
+
+func (biz *getAllowanceBiz) GetAllowance(ctx context.Context, userId int) (*invitequotamodel.Allowance, error) {
+	user, quota, limits, err := quotaOf(ctx, biz.store, biz.userStore, biz.roles, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := biz.usage.GetUsage(ctx, userId, time.Duration(limits.Window)*time.Second, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	extra := 0
+	if quota != nil {
+		extra = quota.Extra
+		usage.Used = quota.Used
+	}
+
+	return invitequotamodel.NewAllowance(userId, user.GetRole(), limits, extra, usage), nil
+}
+
+// Set user quota
+
+type ISetUserQuotaBiz interface {
+	SetUserQuota(ctx context.Context, userId int, data *invitequotamodel.UserQuotaUpdate) error
+}
+
+type setUserQuotaBiz struct {
+	store     QuotaStore
+	userStore UserStore
+	audit     AuditLogger
+}
+
+func NewSetUserQuotaBiz(store QuotaStore, userStore UserStore, audit AuditLogger) ISetUserQuotaBiz {
+	return &setUserQuotaBiz{store: store, userStore: userStore, audit: audit}
+}
+
+func (biz *setUserQuotaBiz) SetUserQuota(ctx context.Context, userId int, data *invitequotamodel.UserQuotaUpdate) error {
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	if err := findUser(ctx, biz.userStore, userId); err != nil {
+		return err
+	}
+
+	before, err := biz.store.FindUserQuota(ctx, userId)
+	if err != nil && err != common.ErrRecordNotFound {
+		return err
+	}
+
+	if err := biz.store.SaveLimits(ctx, userId, data); err != nil {
+		return err
+	}
+
+	entry := auditmodel.Entry{
+		Action:     auditmodel.ActionInviteQuotaUpdate,
+		TargetType: auditmodel.TargetInviteQuota,
+		TargetId:   auditmodel.IntId(userId),
+		After:      data,
+	}
+	if before != nil {
+		entry.Before = invitequotamodel.UserQuotaUpdate{TotalLimit: before.TotalLimit, WindowLimit: before.WindowLimit}
+	}
+
+	biz.audit.Record(ctx, &entry)
+
+	return nil
+}
+
+// Grant quota
+
+type IGrantQuotaBiz interface {
+	GrantQuota(ctx context.Context, userId int, data *invitequotamodel.QuotaGrant) error
+}
+
+type grantQuotaBiz struct {
+	store     QuotaStore
+	userStore UserStore
+	audit     AuditLogger
+}
+
+func NewGrantQuotaBiz(store QuotaStore, userStore UserStore, audit AuditLogger) IGrantQuotaBiz {
+	return &grantQuotaBiz{store: store, userStore: userStore, audit: audit}
+}
+
+func (biz *grantQuotaBiz) GrantQuota(ctx context.Context, userId int, data *invitequotamodel.QuotaGrant) error {
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	if err := findUser(ctx, biz.userStore, userId); err != nil {
+		return err
+	}
+
+	if err := biz.store.AddExtra(ctx, userId, data.Amount); err != nil {
+		return err
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionInviteQuotaGrant,
+		TargetType: auditmodel.TargetInviteQuota,
+		TargetId:   auditmodel.IntId(userId),
+		After:      data,
+	})
+
+	return nil
+}
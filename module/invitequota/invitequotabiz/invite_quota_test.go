@@ -0,0 +1,176 @@
+package invitequotabiz_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/common"
+	"app-invite-service/mock"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/invitequota/invitequotabiz"
+	"app-invite-service/module/invitequota/invitequotamodel"
+)
+
+var roles = map[string]invitequotamodel.Limits{
+	"user": {Total: 3, WindowLimit: 2, Window: 3600},
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestReserveInviteBiz_ReserveInvite(t *testing.T) {
+	store := mock.NewMockInviteQuotaStore(
+		invitequotamodel.UserQuota{UserId: 3, TotalLimit: intPtr(1)},
+		invitequotamodel.UserQuota{UserId: 4, WindowLimit: intPtr(0), Extra: 2},
+	)
+	usage := mock.NewMockInviteUsageStore()
+	biz := invitequotabiz.NewReserveInviteBiz(store, usage, mock.NewMockQuotaUserStore(), roles)
+
+	tcs := []struct {
+		name        string
+		userId      int
+		reserve     int
+		expectedErr string
+	}{
+		// admins have no limits in roles
+		{"admin", 1, 10, ""},
+		{"window", 2, 3, "ErrInviteWindowQuotaExceeded"},
+		{"user total override", 3, 2, "ErrInviteQuotaExceeded"},
+		{"extra without window", 4, 6, "ErrInviteQuotaExceeded"},
+		{"unknown user", 100, 1, "ErrUserNotFound"},
+	}
+
+	for _, tc := range tcs {
+		var err error
+		for i := 0; i < tc.reserve && err == nil; i++ {
+			_, err = biz.ReserveInvite(nil, tc.userId)
+		}
+
+		if tc.expectedErr == "" {
+			assert.Nil(t, err, tc.name)
+			continue
+		}
+		require.Error(t, err, tc.name)
+		assert.Equal(t, tc.expectedErr, err.(*common.AppError).Key, tc.name)
+	}
+
+	// the window frees up, the total doesn't
+	usage.Age(2, time.Hour)
+	_, err := biz.ReserveInvite(nil, 2)
+	assert.Nil(t, err)
+	_, err = biz.ReserveInvite(nil, 2)
+	assert.Equal(t, invitequotamodel.ErrInviteQuotaExceeded, err)
+
+	// a full window gives back what it took from the total
+	assert.Equal(t, 3, store.Used(2))
+
+	// released invitations can be generated again
+	reservation, err := biz.ReserveInvite(nil, 5)
+	require.Nil(t, err)
+	require.Nil(t, biz.ReleaseInvite(nil, 5, reservation))
+	assert.Equal(t, 0, store.Used(5))
+
+	// losing the windows loses no total
+	biz = invitequotabiz.NewReserveInviteBiz(store, mock.NewMockInviteUsageStore(), mock.NewMockQuotaUserStore(), roles)
+	_, err = biz.ReserveInvite(nil, 2)
+	assert.Equal(t, invitequotamodel.ErrInviteQuotaExceeded, err)
+}
+
+func TestGetAllowanceBiz_GetAllowance(t *testing.T) {
+	store := mock.NewMockInviteQuotaStore(invitequotamodel.UserQuota{UserId: 3, Extra: 2})
+	usage := mock.NewMockInviteUsageStore()
+	userStore := mock.NewMockQuotaUserStore()
+	reserver := invitequotabiz.NewReserveInviteBiz(store, usage, userStore, roles)
+	biz := invitequotabiz.NewGetAllowanceBiz(store, usage, userStore, roles)
+
+	for _, userId := range []int{1, 3, 3} {
+		_, err := reserver.ReserveInvite(nil, userId)
+		require.Nil(t, err)
+	}
+
+	tcs := []struct {
+		userId                  int
+		expectedTotal           int
+		expectedRemaining       *int
+		expectedWindowRemaining *int
+		expectedResets          bool
+	}{
+		{1, 0, nil, nil, false},
+		{2, 3, intPtr(3), intPtr(2), false},
+		{3, 5, intPtr(3), intPtr(0), true},
+	}
+
+	for _, tc := range tcs {
+		allowance, err := biz.GetAllowance(nil, tc.userId)
+		require.Nil(t, err, err)
+
+		assert.Equal(t, tc.expectedTotal, allowance.TotalLimit)
+		assert.Equal(t, tc.expectedRemaining, allowance.Remaining)
+		assert.Equal(t, tc.expectedWindowRemaining, allowance.WindowRemaining)
+		assert.Equal(t, tc.expectedResets, allowance.WindowResetsAt != nil)
+	}
+
+	_, err := biz.GetAllowance(nil, 100)
+	assert.Equal(t, invitequotamodel.ErrUserNotFound, err)
+}
+
+func TestSetUserQuotaBiz_SetUserQuota(t *testing.T) {
+	store := mock.NewMockInviteQuotaStore(invitequotamodel.UserQuota{UserId: 2, TotalLimit: intPtr(1), Extra: 4})
+	audit := mock.NewMockAuditLogger()
+	biz := invitequotabiz.NewSetUserQuotaBiz(store, mock.NewMockQuotaUserStore(), audit)
+
+	tcs := []struct {
+		userId      int
+		data        invitequotamodel.UserQuotaUpdate
+		expectedErr error
+	}{
+		{2, invitequotamodel.UserQuotaUpdate{TotalLimit: intPtr(-1)}, invitequotamodel.ErrLimitInvalid},
+		{100, invitequotamodel.UserQuotaUpdate{}, invitequotamodel.ErrUserNotFound},
+		{2, invitequotamodel.UserQuotaUpdate{WindowLimit: intPtr(10)}, nil},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.expectedErr, biz.SetUserQuota(nil, tc.userId, &tc.data))
+	}
+
+	quota, err := store.FindUserQuota(nil, 2)
+	require.Nil(t, err)
+	assert.Nil(t, quota.TotalLimit)
+	assert.Equal(t, intPtr(10), quota.WindowLimit)
+	assert.Equal(t, 4, quota.Extra)
+
+	require.Len(t, audit.Entries(), 1)
+	assert.Equal(t, auditmodel.ActionInviteQuotaUpdate, audit.Entries()[0].Action)
+	assert.Equal(t, "2", audit.Entries()[0].TargetId)
+}
+
+func TestGrantQuotaBiz_GrantQuota(t *testing.T) {
+	store := mock.NewMockInviteQuotaStore()
+	audit := mock.NewMockAuditLogger()
+	biz := invitequotabiz.NewGrantQuotaBiz(store, mock.NewMockQuotaUserStore(), audit)
+
+	tcs := []struct {
+		userId      int
+		amount      int
+		expectedErr error
+	}{
+		{2, 0, invitequotamodel.ErrGrantAmountInvalid},
+		{2, invitequotamodel.MaxGrant + 1, invitequotamodel.ErrGrantAmountInvalid},
+		{100, 1, invitequotamodel.ErrUserNotFound},
+		{2, 2, nil},
+		{2, 3, nil},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.expectedErr, biz.GrantQuota(nil, tc.userId, &invitequotamodel.QuotaGrant{Amount: tc.amount}))
+	}
+
+	quota, err := store.FindUserQuota(nil, 2)
+	require.Nil(t, err)
+	assert.Equal(t, 5, quota.Extra)
+	assert.Len(t, audit.Entries(), 2)
+}
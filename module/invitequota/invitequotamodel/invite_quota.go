@@ -0,0 +1,194 @@
+package invitequotamodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const EntityName = "InviteQuota"
+
+// MaxGrant caps a single grant, so a typo can't hand out unlimited invites
+const MaxGrant = 10000
+
+var (
+	ErrInviteQuotaExceeded = common.NewFullErrorResponse(
+		http.StatusForbidden,
+		errors.New("invite quota exceeded"),
+		"you have used all of your invitations",
+		"invite quota exceeded",
+		"ErrInviteQuotaExceeded",
+	)
+	ErrUserNotFound = common.NewFullErrorResponse(
+		http.StatusNotFound,
+		errors.New("user not found"),
+		"user not found",
+		"user not found",
+		"ErrUserNotFound",
+	)
+	ErrLimitInvalid = common.NewCustomError(
+		errors.New("limit invalid"),
+		"total_limit and window_limit must not be negative",
+		"ErrLimitInvalid",
+	)
+	ErrGrantAmountInvalid = common.NewCustomError(
+		errors.New("grant amount invalid"),
+		fmt.Sprintf("amount must be between 1 and %d", MaxGrant),
+		"ErrGrantAmountInvalid",
+	)
+)
+
+func ErrInviteWindowQuotaExceeded(retryAt time.Time) *common.AppError {
+	msg := fmt.Sprintf(
+		"you have used all of your invitations for now, the next one is available at %s",
+		retryAt.UTC().Format(time.RFC3339),
+	)
+	return common.NewFullErrorResponse(
+		http.StatusTooManyRequests,
+		errors.New("invite window quota exceeded"),
+		msg,
+		msg,
+		"ErrInviteWindowQuotaExceeded",
+	)
+}
+
+// Limits is how many invitations a user may generate: Total over the account's
+// lifetime and WindowLimit within any Window seconds. A zero limit means no limit.
+type Limits struct {
+	Total       int `json:"total"`
+	WindowLimit int `json:"window_limit"`
+	Window      int `json:"window"`
+}
+
+// UserQuota overrides the limits of the user's role. Nil limits fall back to
+// the role; Extra is granted on top of the total. Used counts every invitation
+// the user generated, against the total.
+type UserQuota struct {
+	UserId      int        `json:"user_id" gorm:"column:user_id;primaryKey"`
+	TotalLimit  *int       `json:"total_limit" gorm:"column:total_limit;"`
+	WindowLimit *int       `json:"window_limit" gorm:"column:window_limit;"`
+	Extra       int        `json:"extra" gorm:"column:extra;"`
+	Used        int        `json:"used" gorm:"column:used;"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+}
+
+func (UserQuota) TableName() string {
+	return "invite_quotas"
+}
+
+// Resolve combines the role's limits with the user's overrides and grants
+func (q *UserQuota) Resolve(role Limits) Limits {
+	limits := role
+	if q == nil {
+		return limits
+	}
+
+	if q.TotalLimit != nil {
+		limits.Total = *q.TotalLimit
+	}
+	if q.WindowLimit != nil {
+		limits.WindowLimit = *q.WindowLimit
+	}
+	// extra invites only matter while the total is limited
+	if limits.Total > 0 {
+		limits.Total += q.Extra
+	}
+
+	return limits
+}
+
+// UserQuotaUpdate replaces a user's overrides; omitted limits go back to the role's
+type UserQuotaUpdate struct {
+	TotalLimit  *int `json:"total_limit" form:"total_limit"`
+	WindowLimit *int `json:"window_limit" form:"window_limit"`
+}
+
+func (data *UserQuotaUpdate) Validate() error {
+	if (data.TotalLimit != nil && *data.TotalLimit < 0) || (data.WindowLimit != nil && *data.WindowLimit < 0) {
+		return ErrLimitInvalid
+	}
+
+	return nil
+}
+
+type QuotaGrant struct {
+	Amount int `json:"amount" form:"amount"`
+}
+
+func (data *QuotaGrant) Validate() error {
+	if data.Amount < 1 || data.Amount > MaxGrant {
+		return ErrGrantAmountInvalid
+	}
+
+	return nil
+}
+
+// Usage is what a user has generated so far. OldestInWindow is when the
+// oldest invitation still counting towards the window was generated.
+type Usage struct {
+	Used           int
+	WindowUsed     int
+	OldestInWindow *time.Time
+}
+
+// ReserveOutcome says whether an invitation could be taken from a window
+type ReserveOutcome int
+
+const (
+	Reserved ReserveOutcome = iota
+	WindowExceeded
+)
+
+// Allowance is a user's limits and what is left of them. Remaining values are
+// null when the limit is off; WindowResetsAt is when the next invitation
+// drops out of the window.
+type Allowance struct {
+	UserId          int        `json:"user_id"`
+	Role            string     `json:"role"`
+	TotalLimit      int        `json:"total_limit"`
+	Extra           int        `json:"extra"`
+	Used            int        `json:"used"`
+	Remaining       *int       `json:"remaining"`
+	Window          int        `json:"window"`
+	WindowLimit     int        `json:"window_limit"`
+	WindowUsed      int        `json:"window_used"`
+	WindowRemaining *int       `json:"window_remaining"`
+	WindowResetsAt  *time.Time `json:"window_resets_at,omitempty"`
+}
+
+func remaining(limit, used int) *int {
+	if limit <= 0 {
+		return nil
+	}
+
+	left := limit - used
+	if left < 0 {
+		left = 0
+	}
+	return &left
+}
+
+// NewAllowance reports usage against limits
+func NewAllowance(userId int, role string, limits Limits, extra int, usage *Usage) *Allowance {
+	allowance := Allowance{
+		UserId:          userId,
+		Role:            role,
+		TotalLimit:      limits.Total,
+		Extra:           extra,
+		Used:            usage.Used,
+		Remaining:       remaining(limits.Total, usage.Used),
+		Window:          limits.Window,
+		WindowLimit:     limits.WindowLimit,
+		WindowUsed:      usage.WindowUsed,
+		WindowRemaining: remaining(limits.WindowLimit, usage.WindowUsed),
+	}
+
+	if usage.OldestInWindow != nil && limits.Window > 0 {
+		resetsAt := usage.OldestInWindow.Add(time.Duration(limits.Window) * time.Second).UTC()
+		allowance.WindowResetsAt = &resetsAt
+	}
+
+	return &allowance
+}
@@ -0,0 +1,112 @@
+package invitequotastorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/invitequota/invitequotamodel"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ISqlStore interface {
+	FindUserQuota(ctx context.Context, userId int) (*invitequotamodel.UserQuota, error)
+	SaveLimits(ctx context.Context, userId int, data *invitequotamodel.UserQuotaUpdate) error
+	AddExtra(ctx context.Context, userId, amount int) error
+	TakeInvite(ctx context.Context, userId, total int) (bool, error)
+	ReturnInvite(ctx context.Context, userId int) error
+}
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+func NewSQLStore(db *gorm.DB) ISqlStore {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) FindUserQuota(_ context.Context, userId int) (*invitequotamodel.UserQuota, error) {
+	var quota invitequotamodel.UserQuota
+
+	if err := s.db.Table(quota.TableName()).Where("user_id = ?", userId).First(&quota).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &quota, nil
+}
+
+// SaveLimits replaces the user's overrides and keeps what was granted
+func (s *sqlStore) SaveLimits(_ context.Context, userId int, data *invitequotamodel.UserQuotaUpdate) error {
+	quota := invitequotamodel.UserQuota{
+		UserId:      userId,
+		TotalLimit:  data.TotalLimit,
+		WindowLimit: data.WindowLimit,
+	}
+
+	if err := s.db.Table(quota.TableName()).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"total_limit", "window_limit"}),
+	}).Omit("updated_at").Create(&quota).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// AddExtra grants amount more invitations in a single statement, so
+// concurrent grants add up
+func (s *sqlStore) AddExtra(_ context.Context, userId, amount int) error {
+	quota := invitequotamodel.UserQuota{UserId: userId, Extra: amount}
+
+	if err := s.db.Table(quota.TableName()).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{"extra": gorm.Expr("extra + ?", amount)}),
+	}).Omit("updated_at").Create(&quota).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// TakeInvite counts one more invitation against the user's total and returns
+// false instead when total, if not 0, is used up. The check and the count are
+// one statement, so concurrent requests can't overspend the total.
+func (s *sqlStore) TakeInvite(_ context.Context, userId, total int) (bool, error) {
+	var taken bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		quota := invitequotamodel.UserQuota{UserId: userId}
+		if err := tx.Table(quota.TableName()).
+			Clauses(clause.Insert{Modifier: "IGNORE"}).
+			Omit("updated_at").
+			Create(&quota).Error; err != nil {
+			return err
+		}
+
+		update := tx.Table(quota.TableName()).
+			Where("user_id = ? AND (? = 0 OR used < ?)", userId, total, total).
+			Update("used", gorm.Expr("used + 1"))
+		if update.Error != nil {
+			return update.Error
+		}
+		taken = update.RowsAffected == 1
+		return nil
+	})
+	if err != nil {
+		return false, common.ErrDB(err)
+	}
+
+	return taken, nil
+}
+
+// ReturnInvite gives back an invitation taken from the total, without going
+// below zero
+func (s *sqlStore) ReturnInvite(_ context.Context, userId int) error {
+	if err := s.db.Table(invitequotamodel.UserQuota{}.TableName()).
+		Where("user_id = ? AND used > 0", userId).
+		Update("used", gorm.Expr("used - 1")).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
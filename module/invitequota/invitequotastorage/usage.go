@@ -0,0 +1,135 @@
+package invitequotastorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/invitequota/invitequotamodel"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const windowKeyPrefix = "invite_quota_window:"
+
+// reserveScript checks the window limit and takes one invitation in a single
+// step, so concurrent requests can't overspend a window. The window is a
+// sorted set of reservations scored by time in milliseconds.
+//
+// KEYS: window set
+// ARGV: window limit, window ms, now ms, reservation id
+// Returns {0, 0} when reserved and {1, oldest} when the window is full.
+var reserveScript = redis.NewScript(`
+local window_limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+if window_limit > 0 and redis.call("ZCARD", KEYS[1]) >= window_limit then
+	local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+	return {1, tonumber(oldest[2])}
+end
+redis.call("ZADD", KEYS[1], now, ARGV[4])
+redis.call("PEXPIRE", KEYS[1], window)
+
+return {0, 0}
+`)
+
+// IUsageStore keeps the invitations each user generated within their rolling
+// window. The window only needs to outlive itself, so losing it frees up at
+// most a window's worth; the lifetime total is kept with the quota in MySQL.
+type IUsageStore interface {
+	Reserve(
+		ctx context.Context,
+		userId int,
+		limits invitequotamodel.Limits,
+		reservation string,
+		now time.Time,
+	) (invitequotamodel.ReserveOutcome, time.Time, error)
+	Release(ctx context.Context, userId int, reservation string) error
+	GetUsage(ctx context.Context, userId int, window time.Duration, now time.Time) (*invitequotamodel.Usage, error)
+}
+
+type redisUsageStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisUsageStore(rdb *redis.Client) IUsageStore {
+	return &redisUsageStore{rdb: rdb}
+}
+
+func windowKey(userId int) string {
+	return fmt.Sprintf("%s%d", windowKeyPrefix, userId)
+}
+
+// Reserve takes one invitation from the user's window. When the window is
+// full it also returns when the oldest invitation in it was generated.
+func (s *redisUsageStore) Reserve(
+	ctx context.Context,
+	userId int,
+	limits invitequotamodel.Limits,
+	reservation string,
+	now time.Time,
+) (invitequotamodel.ReserveOutcome, time.Time, error) {
+	if limits.Window <= 0 {
+		return invitequotamodel.Reserved, time.Time{}, nil
+	}
+
+	res, err := reserveScript.Run(
+		ctx,
+		s.rdb,
+		[]string{windowKey(userId)},
+		limits.WindowLimit,
+		(time.Duration(limits.Window) * time.Second).Milliseconds(),
+		now.UnixMilli(),
+		reservation,
+	).Int64Slice()
+	if err != nil {
+		return 0, time.Time{}, common.ErrDB(err)
+	}
+
+	outcome := invitequotamodel.ReserveOutcome(res[0])
+	if outcome == invitequotamodel.WindowExceeded {
+		return outcome, time.UnixMilli(res[1]), nil
+	}
+
+	return outcome, time.Time{}, nil
+}
+
+// Release gives back the window's part of an invitation whose token could not
+// be generated
+func (s *redisUsageStore) Release(ctx context.Context, userId int, reservation string) error {
+	if err := s.rdb.ZRem(ctx, windowKey(userId), reservation).Err(); err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// GetUsage tells how much of the window is used; Used is left to the quota
+func (s *redisUsageStore) GetUsage(
+	ctx context.Context,
+	userId int,
+	window time.Duration,
+	now time.Time,
+) (*invitequotamodel.Usage, error) {
+	var usage invitequotamodel.Usage
+	if window <= 0 {
+		return &usage, nil
+	}
+
+	min := strconv.FormatInt(now.Add(-window).UnixMilli()+1, 10)
+	inWindow, err := s.rdb.ZRangeByScoreWithScores(ctx, windowKey(userId), &redis.ZRangeBy{Min: min, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	if len(inWindow) > 0 {
+		usage.WindowUsed = len(inWindow)
+		oldest := time.UnixMilli(int64(inWindow[0].Score))
+		usage.OldestInWindow = &oldest
+	}
+
+	return &usage, nil
+}
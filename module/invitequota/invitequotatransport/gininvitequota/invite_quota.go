@@ -0,0 +1,126 @@
+package gininvitequota
+
+import (
+	"net/http"
+	"strconv"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/invitequota/invitequotabiz"
+	"app-invite-service/module/invitequota/invitequotamodel"
+	"app-invite-service/module/invitequota/invitequotastorage"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// roleLimits are the quotas from config by role
+func roleLimits(appCtx component.AppContext) map[string]invitequotamodel.Limits {
+	roles := map[string]invitequotamodel.Limits{}
+	for role, quota := range appCtx.GetConfig().InviteQuota.Roles {
+		roles[role] = invitequotamodel.Limits{Total: quota.Total, WindowLimit: quota.WindowLimit, Window: quota.Window}
+	}
+	return roles
+}
+
+// NewReserver returns the quota handed to the invitation generation biz
+func NewReserver(appCtx component.AppContext) invitequotabiz.IReserveInviteBiz {
+	db := appCtx.GetDBConn()
+
+	return invitequotabiz.NewReserveInviteBiz(
+		invitequotastorage.NewSQLStore(db),
+		invitequotastorage.NewRedisUsageStore(appCtx.GetRedisConn()),
+		userstorage.NewSQLStore(db),
+		roleLimits(appCtx),
+	)
+}
+
+func getAllowance(appCtx component.AppContext, c *gin.Context, userId int) {
+	db := appCtx.GetDBConn()
+	biz := invitequotabiz.NewGetAllowanceBiz(
+		invitequotastorage.NewSQLStore(db),
+		invitequotastorage.NewRedisUsageStore(appCtx.GetRedisConn()),
+		userstorage.NewSQLStore(db),
+		roleLimits(appCtx),
+	)
+
+	result, err := biz.GetAllowance(c.Request.Context(), userId)
+	if err != nil {
+		panic(err)
+	}
+
+	c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+}
+
+func GetMyAllowance(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		getAllowance(appCtx, c, c.MustGet(common.CurrentUser).(*usermodel.User).Id)
+	}
+}
+
+func GetUserAllowance(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		getAllowance(appCtx, c, id)
+	}
+}
+
+func SetUserQuota(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		var data invitequotamodel.UserQuotaUpdate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		db := appCtx.GetDBConn()
+		biz := invitequotabiz.NewSetUserQuotaBiz(
+			invitequotastorage.NewSQLStore(db),
+			userstorage.NewSQLStore(db),
+			ginaudit.NewRecorder(appCtx),
+		)
+
+		if err := biz.SetUserQuota(c.Request.Context(), id, &data); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
+
+func GrantQuota(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		var data invitequotamodel.QuotaGrant
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		db := appCtx.GetDBConn()
+		biz := invitequotabiz.NewGrantQuotaBiz(
+			invitequotastorage.NewSQLStore(db),
+			userstorage.NewSQLStore(db),
+			ginaudit.NewRecorder(appCtx),
+		)
+
+		if err := biz.GrantQuota(c.Request.Context(), id, &data); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
@@ -0,0 +1,230 @@
+package mfabiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/otp"
+	"app-invite-service/component/qrcode"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/mfa/mfamodel"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	crand "crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// codes are accepted one step either side of the current one
+const totpSkew = 1
+
+const qrCodeSize = 256
+
+type UserStore interface {
+	UpdateUser(ctx context.Context, id int, data map[string]interface{}) error
+}
+
+type RecoveryCodeStore interface {
+	ReplaceRecoveryCodes(ctx context.Context, userId int, hashes []string) error
+	FindUnusedRecoveryCode(ctx context.Context, userId int, hash string) (*mfamodel.RecoveryCode, error)
+	UseRecoveryCode(ctx context.Context, id int) (bool, error)
+}
+
+type Hash interface {
+	Hash(data string) string
+}
+
+type AuditLogger interface {
+	Record(ctx context.Context, entry *auditmodel.Entry)
+}
+
+// GenerateRecoveryCodes returns n random codes like `k7m2p-x9qrt`, avoiding
+// characters that are easy to confuse
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	const letters = "abcdefghjkmnpqrstuvwxyz23456789"
+
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 10)
+		for j := range b {
+			num, err := crand.Int(crand.Reader, big.NewInt(int64(len(letters))))
+			if err != nil {
+				return nil, err
+			}
+			b[j] = letters[num.Int64()]
+		}
+		codes[i] = fmt.Sprintf("%s-%s", b[:5], b[5:])
+	}
+
+	return codes, nil
+}
+
+// Start TOTP enrollment
+
+type IEnrollTotpBiz interface {
+	EnrollTotp(ctx context.Context, user *usermodel.User) (*mfamodel.TotpEnrollment, error)
+}
+
+type enrollTotpBiz struct {
+	store  UserStore
+	issuer string
+}
+
+func NewEnrollTotpBiz(store UserStore, issuer string) IEnrollTotpBiz {
+	return &enrollTotpBiz{store: store, issuer: issuer}
+}
+
+func (biz *enrollTotpBiz) EnrollTotp(ctx context.Context, user *usermodel.User) (*mfamodel.TotpEnrollment, error) {
+	if user.IsMfaEnabled() {
+		return nil, mfamodel.ErrMfaAlreadyEnabled
+	}
+
+	secret, err := otp.GenerateSecret()
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	// the secret stays pending until a first code is confirmed
+	if err := biz.store.UpdateUser(ctx, user.Id, map[string]interface{}{"mfa_secret": secret}); err != nil {
+		return nil, err
+	}
+
+	uri := otp.URI(biz.issuer, user.Email, secret)
+
+	qr, err := qrcode.PNGDataURI(uri, qrCodeSize)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	return &mfamodel.TotpEnrollment{Secret: secret, URI: uri, QRCode: qr}, nil
+}
+
+// Confirm TOTP enrollment
+
+type IConfirmTotpBiz interface {
+	ConfirmTotp(ctx context.Context, user *usermodel.User, data *mfamodel.TotpConfirm) (*mfamodel.RecoveryCodes, error)
+}
+
+type confirmTotpBiz struct {
+	userStore UserStore
+	codeStore RecoveryCodeStore
+	hash      Hash
+	audit     AuditLogger
+}
+
+func NewConfirmTotpBiz(
+	userStore UserStore,
+	codeStore RecoveryCodeStore,
+	hash Hash,
+	audit AuditLogger,
+) IConfirmTotpBiz {
+	return &confirmTotpBiz{userStore: userStore, codeStore: codeStore, hash: hash, audit: audit}
+}
+
+func (biz *confirmTotpBiz) ConfirmTotp(
+	ctx context.Context,
+	user *usermodel.User,
+	data *mfamodel.TotpConfirm,
+) (*mfamodel.RecoveryCodes, error) {
+	if user.IsMfaEnabled() {
+		return nil, mfamodel.ErrMfaAlreadyEnabled
+	}
+
+	if user.MfaSecret == "" {
+		return nil, mfamodel.ErrMfaNotEnrolled
+	}
+
+	if _, ok := otp.Validate(user.MfaSecret, data.Code, time.Now(), totpSkew); !ok {
+		return nil, mfamodel.ErrMfaCodeInvalid
+	}
+
+	codes, err := GenerateRecoveryCodes(mfamodel.RecoveryCodeCount)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	hashes := make([]string, len(codes))
+	for i := range codes {
+		hashes[i] = biz.hash.Hash(mfamodel.NormalizeRecoveryCode(codes[i]))
+	}
+
+	if err := biz.codeStore.ReplaceRecoveryCodes(ctx, user.Id, hashes); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if err := biz.userStore.UpdateUser(ctx, user.Id, map[string]interface{}{
+		"mfa_enabled_at": now,
+	}); err != nil {
+		return nil, err
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionUserEnableMfa,
+		TargetType: auditmodel.TargetUser,
+		TargetId:   auditmodel.IntId(user.Id),
+		Before:     map[string]interface{}{"mfa_enabled_at": nil},
+		After:      map[string]interface{}{"mfa_enabled_at": now},
+	})
+
+	// the plain codes are only ever shown here
+	return &mfamodel.RecoveryCodes{Codes: codes}, nil
+}
+
+// Verify the MFA step of a login
+
+type IVerifyMfaBiz interface {
+	VerifyMfaCode(ctx context.Context, user *usermodel.User, code string) error
+}
+
+type verifyMfaBiz struct {
+	codeStore RecoveryCodeStore
+	redis     *redis.Client
+	hash      Hash
+}
+
+func NewVerifyMfaBiz(codeStore RecoveryCodeStore, redis *redis.Client, hash Hash) IVerifyMfaBiz {
+	return &verifyMfaBiz{codeStore: codeStore, redis: redis, hash: hash}
+}
+
+func (biz *verifyMfaBiz) VerifyMfaCode(ctx context.Context, user *usermodel.User, code string) error {
+	if !user.IsMfaEnabled() {
+		return mfamodel.ErrMfaNotEnrolled
+	}
+
+	if step, ok := otp.Validate(user.MfaSecret, code, time.Now(), totpSkew); ok {
+		// a TOTP code can only be used once
+		key := fmt.Sprintf("mfa_totp_used:%d:%d", user.Id, step)
+		fresh, err := biz.redis.SetNX(ctx, key, 1, (2*totpSkew+1)*otp.Period*time.Second).Result()
+		if err != nil {
+			return common.ErrInternal(err)
+		}
+		if !fresh {
+			return mfamodel.ErrMfaCodeInvalid
+		}
+		return nil
+	}
+
+	recoveryCode, err := biz.codeStore.FindUnusedRecoveryCode(
+		ctx,
+		user.Id,
+		biz.hash.Hash(mfamodel.NormalizeRecoveryCode(code)),
+	)
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return mfamodel.ErrMfaCodeInvalid
+		}
+		return err
+	}
+
+	used, err := biz.codeStore.UseRecoveryCode(ctx, recoveryCode.Id)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return mfamodel.ErrMfaCodeInvalid
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package mfabiz_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/component/otp"
+	"app-invite-service/mock"
+	"app-invite-service/module/mfa/mfabiz"
+	"app-invite-service/module/mfa/mfamodel"
+	"app-invite-service/module/user/usermodel"
+)
+
+func TestTotpBiz_GenerateRecoveryCodes(t *testing.T) {
+	codes, err := mfabiz.GenerateRecoveryCodes(10)
+	require.Nil(t, err, err)
+	assert.Len(t, codes, 10)
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Len(t, code, 11)
+		assert.Equal(t, "-", code[5:6])
+		assert.False(t, strings.ContainsAny(code, "01ilo"))
+		seen[code] = true
+	}
+	assert.Len(t, seen, 10)
+}
+
+func TestTotpBiz_EnrollTotp(t *testing.T) {
+	biz := mfabiz.NewEnrollTotpBiz(mock.NewMockUserStore(), "evite")
+
+	result, err := biz.EnrollTotp(nil, &usermodel.User{Id: 1, Email: "user@gmail.com"})
+	require.Nil(t, err, err)
+	assert.NotEmpty(t, result.Secret)
+	assert.True(t, strings.HasPrefix(result.URI, "otpauth://totp/evite:user@gmail.com?"))
+	assert.True(t, strings.HasPrefix(result.QRCode, "data:image/png;base64,"))
+
+	now := time.Now()
+	_, err = biz.EnrollTotp(nil, &usermodel.User{Id: 1, Email: "user@gmail.com", MfaEnabledAt: &now})
+	assert.Equal(t, mfamodel.ErrMfaAlreadyEnabled, err)
+}
+
+func TestTotpBiz_ConfirmTotp(t *testing.T) {
+	secret, err := otp.GenerateSecret()
+	require.Nil(t, err, err)
+	code, err := otp.GenerateCode(secret, otp.Step(time.Now()))
+	require.Nil(t, err, err)
+
+	now := time.Now()
+	tcs := []struct {
+		user        *usermodel.User
+		code        string
+		expectedErr error
+	}{
+		{&usermodel.User{Id: 1}, code, mfamodel.ErrMfaNotEnrolled},
+		{&usermodel.User{Id: 1, MfaSecret: secret, MfaEnabledAt: &now}, code, mfamodel.ErrMfaAlreadyEnabled},
+		{&usermodel.User{Id: 1, MfaSecret: secret}, "000000", mfamodel.ErrMfaCodeInvalid},
+		{&usermodel.User{Id: 1, MfaSecret: secret}, code, nil},
+	}
+
+	for _, tc := range tcs {
+		codeStore := mock.NewMockRecoveryCodeStore()
+		biz := mfabiz.NewConfirmTotpBiz(mock.NewMockUserStore(), codeStore, mock.NewMockHash(), mock.NewMockAuditLogger())
+
+		result, err := biz.ConfirmTotp(nil, tc.user, &mfamodel.TotpConfirm{Code: tc.code})
+		if tc.expectedErr != nil {
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Nil(t, result)
+			continue
+		}
+
+		require.Nil(t, err, err)
+		assert.Len(t, result.Codes, mfamodel.RecoveryCodeCount)
+		require.Len(t, codeStore.Codes(), mfamodel.RecoveryCodeCount)
+		// only normalised hashes are stored
+		assert.Equal(t, mfamodel.NormalizeRecoveryCode(result.Codes[0]), codeStore.Codes()[0].CodeHash)
+	}
+}
@@ -0,0 +1,65 @@
+package mfamodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const EntityName = "RecoveryCode"
+
+const RecoveryCodeCount = 10
+
+var (
+	ErrMfaCodeInvalid = common.NewUnauthorized(
+		errors.New("mfa code invalid"),
+		"mfa code is invalid",
+		"ErrMfaCodeInvalid",
+	)
+	ErrMfaAlreadyEnabled = common.NewCustomError(
+		errors.New("mfa already enabled"),
+		"mfa is already enabled",
+		"ErrMfaAlreadyEnabled",
+	)
+	ErrMfaNotEnrolled = common.NewFullErrorResponse(
+		http.StatusConflict,
+		errors.New("mfa not enrolled"),
+		"start the mfa enrollment first",
+		"mfa not enrolled",
+		"ErrMfaNotEnrolled",
+	)
+)
+
+type RecoveryCode struct {
+	Id        int        `json:"-" gorm:"column:id;"`
+	UserId    int        `json:"-" gorm:"column:user_id;"`
+	CodeHash  string     `json:"-" gorm:"column:code_hash;"`
+	UsedAt    *time.Time `json:"used_at,omitempty" gorm:"column:used_at;"`
+	CreatedAt *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
+
+type TotpEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	// QRCode is a PNG `data:` URI of URI
+	QRCode string `json:"qr_code"`
+}
+
+type TotpConfirm struct {
+	Code string `json:"code" form:"code" binding:"required"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// NormalizeRecoveryCode makes recovery codes case and dash insensitive
+func NormalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
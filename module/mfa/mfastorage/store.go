@@ -0,0 +1,84 @@
+package mfastorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/mfa/mfamodel"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ISqlStore interface {
+	ReplaceRecoveryCodes(_ context.Context, userId int, hashes []string) error
+	FindUnusedRecoveryCode(_ context.Context, userId int, hash string) (*mfamodel.RecoveryCode, error)
+	UseRecoveryCode(_ context.Context, id int) (bool, error)
+}
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+func NewSQLStore(db *gorm.DB) ISqlStore {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) ReplaceRecoveryCodes(_ context.Context, userId int, hashes []string) error {
+	db := s.db.Begin()
+
+	if err := db.Table(mfamodel.RecoveryCode{}.TableName()).
+		Where("user_id = ?", userId).
+		Delete(nil).Error; err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	codes := make([]mfamodel.RecoveryCode, len(hashes))
+	for i := range hashes {
+		codes[i] = mfamodel.RecoveryCode{UserId: userId, CodeHash: hashes[i]}
+	}
+
+	if err := db.Table(mfamodel.RecoveryCode{}.TableName()).Create(&codes).Error; err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		db.Rollback()
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) FindUnusedRecoveryCode(
+	_ context.Context,
+	userId int,
+	hash string,
+) (*mfamodel.RecoveryCode, error) {
+	var code mfamodel.RecoveryCode
+
+	if err := s.db.Table(code.TableName()).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, hash).
+		First(&code).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &code, nil
+}
+
+// UseRecoveryCode marks a code as used. It returns false when the code was
+// already used by a concurrent request.
+func (s *sqlStore) UseRecoveryCode(_ context.Context, id int) (bool, error) {
+	res := s.db.Table(mfamodel.RecoveryCode{}.TableName()).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now().UTC())
+	if res.Error != nil {
+		return false, common.ErrDB(res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
@@ -0,0 +1,59 @@
+package ginmfa
+
+import (
+	"net/http"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/hash"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/mfa/mfabiz"
+	"app-invite-service/module/mfa/mfamodel"
+	"app-invite-service/module/mfa/mfastorage"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func EnrollTotp(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		store := userstorage.NewSQLStore(appCtx.GetDBConn())
+		biz := mfabiz.NewEnrollTotpBiz(store, appCtx.GetConfig().Mfa.Issuer)
+
+		result, err := biz.EnrollTotp(c.Request.Context(), user)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func ConfirmTotp(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data mfamodel.TotpConfirm
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		db := appCtx.GetDBConn()
+		biz := mfabiz.NewConfirmTotpBiz(
+			userstorage.NewSQLStore(db),
+			mfastorage.NewSQLStore(db),
+			hash.NewMd5Hash(),
+			ginaudit.NewRecorder(appCtx),
+		)
+
+		result, err := biz.ConfirmTotp(c.Request.Context(), user, &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
@@ -0,0 +1,115 @@
+package oauthbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/oauth/oauthmodel"
+	"context"
+	"net/url"
+	"strings"
+)
+
+type CodeStore interface {
+	SaveCode(ctx context.Context, code string, data *oauthmodel.AuthorizationCode, expiry int) error
+	TakeCode(ctx context.Context, code string) (*oauthmodel.AuthorizationCode, error)
+}
+
+// redirectWith appends params to a registered redirect uri, keeping any query
+// it already has
+func redirectWith(redirectURI string, params url.Values) string {
+	u, _ := url.Parse(redirectURI)
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func redirectError(req *oauthmodel.AuthorizeRequest, code, description string) *oauthmodel.AuthorizeResponse {
+	params := url.Values{"error": {code}, "error_description": {description}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	return &oauthmodel.AuthorizeResponse{RedirectTo: redirectWith(req.RedirectURI, params)}
+}
+
+// Authorize
+
+type IAuthorizeBiz interface {
+	Authorize(ctx context.Context, userId int, req *oauthmodel.AuthorizeRequest) (*oauthmodel.AuthorizeResponse, error)
+}
+
+type authorizeBiz struct {
+	clientStore ClientStore
+	codeStore   CodeStore
+	codeExpiry  int
+}
+
+func NewAuthorizeBiz(clientStore ClientStore, codeStore CodeStore, codeExpiry int) IAuthorizeBiz {
+	return &authorizeBiz{clientStore: clientStore, codeStore: codeStore, codeExpiry: codeExpiry}
+}
+
+// Authorize issues an authorization code for the signed in user. Until the
+// redirect uri is known to belong to the client, errors are returned to the
+// caller; after that they are reported to the client through the redirect,
+// as RFC 6749 section 4.1.2.1 requires.
+func (biz *authorizeBiz) Authorize(
+	ctx context.Context,
+	userId int,
+	req *oauthmodel.AuthorizeRequest,
+) (*oauthmodel.AuthorizeResponse, error) {
+	client, err := biz.clientStore.FindClient(ctx, map[string]interface{}{"client_id": req.ClientId})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, oauthmodel.ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	if !client.HasRedirectURI(req.RedirectURI) {
+		return nil, oauthmodel.ErrInvalidRedirectURI
+	}
+
+	if req.ResponseType != "code" {
+		return redirectError(req, "unsupported_response_type", "only the code response type is supported"), nil
+	}
+
+	scopes := oauthmodel.ParseScope(req.Scope)
+	if !oauthmodel.HasScope(strings.Join(scopes, " "), oauthmodel.ScopeOpenId) {
+		return redirectError(req, "invalid_scope", "the openid scope is required"), nil
+	}
+
+	if req.CodeChallenge == "" && client.Public {
+		return redirectError(req, "invalid_request", "public clients must use PKCE"), nil
+	}
+
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != oauthmodel.CodeChallengeMethodS256 {
+		return redirectError(req, "invalid_request", "code_challenge_method must be S256"), nil
+	}
+
+	code, err := randomHex(32)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	data := oauthmodel.AuthorizationCode{
+		ClientId:            client.ClientId,
+		UserId:              userId,
+		RedirectURI:         req.RedirectURI,
+		Scope:               strings.Join(scopes, " "),
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}
+
+	if err := biz.codeStore.SaveCode(ctx, code, &data, biz.codeExpiry); err != nil {
+		return nil, err
+	}
+
+	params := url.Values{"code": {code}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	return &oauthmodel.AuthorizeResponse{RedirectTo: redirectWith(req.RedirectURI, params)}, nil
+}
@@ -0,0 +1,179 @@
+package oauthbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/oauth/oauthmodel"
+	"context"
+	crand "crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+type Hash interface {
+	Hash(data string) string
+}
+
+type AuditLogger interface {
+	Record(ctx context.Context, entry *auditmodel.Entry)
+}
+
+type ClientStore interface {
+	CreateClient(ctx context.Context, data *oauthmodel.ClientCreate) error
+	FindClient(ctx context.Context, conditions map[string]interface{}) (*oauthmodel.Client, error)
+	ListClients(ctx context.Context) ([]oauthmodel.Client, error)
+	DeleteClient(ctx context.Context, id int) error
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// authenticateClient checks the client secret of confidential clients. Public
+// clients have no secret and rely on PKCE instead.
+func authenticateClient(
+	ctx context.Context,
+	store ClientStore,
+	hash Hash,
+	clientId, secret string,
+) (*oauthmodel.Client, error) {
+	if clientId == "" {
+		return nil, oauthmodel.ErrInvalidClient
+	}
+
+	client, err := store.FindClient(ctx, map[string]interface{}{"client_id": clientId})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, oauthmodel.ErrInvalidClient
+		}
+		return nil, err
+	}
+
+	if client.Public {
+		return client, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash.Hash(secret+client.Salt)), []byte(client.SecretHash)) != 1 {
+		return nil, oauthmodel.ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+// Create client
+
+type ICreateClientBiz interface {
+	CreateClient(ctx context.Context, data *oauthmodel.ClientCreate) (*oauthmodel.ClientCredentials, error)
+}
+
+type createClientBiz struct {
+	store ClientStore
+	hash  Hash
+	audit AuditLogger
+}
+
+func NewCreateClientBiz(store ClientStore, hash Hash, audit AuditLogger) ICreateClientBiz {
+	return &createClientBiz{store: store, hash: hash, audit: audit}
+}
+
+func (biz *createClientBiz) CreateClient(
+	ctx context.Context,
+	data *oauthmodel.ClientCreate,
+) (*oauthmodel.ClientCredentials, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	clientId, err := randomHex(16)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	credentials := oauthmodel.ClientCredentials{ClientId: clientId}
+	data.ClientId = clientId
+
+	if !data.Public {
+		secret, err := randomHex(32)
+		if err != nil {
+			return nil, common.ErrInternal(err)
+		}
+
+		salt := common.GenSalt(50)
+		data.SecretHash = biz.hash.Hash(secret + salt)
+		data.Salt = salt
+		credentials.ClientSecret = secret
+	}
+
+	if err := biz.store.CreateClient(ctx, data); err != nil {
+		return nil, common.ErrCannotCreateEntity(oauthmodel.EntityName, err)
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionOAuthClientCreate,
+		TargetType: auditmodel.TargetOAuthClient,
+		TargetId:   clientId,
+		After:      data,
+	})
+
+	return &credentials, nil
+}
+
+// List clients
+
+type IListClientsBiz interface {
+	ListClients(ctx context.Context) ([]oauthmodel.Client, error)
+}
+
+type listClientsBiz struct {
+	store ClientStore
+}
+
+func NewListClientsBiz(store ClientStore) IListClientsBiz {
+	return &listClientsBiz{store: store}
+}
+
+func (biz *listClientsBiz) ListClients(ctx context.Context) ([]oauthmodel.Client, error) {
+	return biz.store.ListClients(ctx)
+}
+
+// Delete client
+
+type IDeleteClientBiz interface {
+	DeleteClient(ctx context.Context, id int) error
+}
+
+type deleteClientBiz struct {
+	store ClientStore
+	audit AuditLogger
+}
+
+func NewDeleteClientBiz(store ClientStore, audit AuditLogger) IDeleteClientBiz {
+	return &deleteClientBiz{store: store, audit: audit}
+}
+
+func (biz *deleteClientBiz) DeleteClient(ctx context.Context, id int) error {
+	client, err := biz.store.FindClient(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return oauthmodel.ErrClientNotFound
+		}
+		return err
+	}
+
+	if err := biz.store.DeleteClient(ctx, id); err != nil {
+		return err
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionOAuthClientDelete,
+		TargetType: auditmodel.TargetOAuthClient,
+		TargetId:   client.ClientId,
+		Before:     client,
+	})
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package oauthbiz_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/mock"
+	"app-invite-service/module/oauth/oauthbiz"
+	"app-invite-service/module/oauth/oauthmodel"
+)
+
+const (
+	codeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	codeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestOAuthBiz_CreateClient(t *testing.T) {
+	store := mock.NewMockOAuthClientStore()
+	biz := oauthbiz.NewCreateClientBiz(store, mock.NewMockHash(), mock.NewMockAuditLogger())
+
+	result, err := biz.CreateClient(nil, &oauthmodel.ClientCreate{
+		Name:         "CRM",
+		RedirectURIs: []string{"https://crm.example.com/callback"},
+	})
+	require.Nil(t, err, err)
+	assert.Len(t, result.ClientId, 32)
+	assert.Len(t, result.ClientSecret, 64)
+
+	client, err := store.FindClient(nil, map[string]interface{}{"client_id": result.ClientId})
+	require.Nil(t, err, err)
+	assert.NotEqual(t, result.ClientSecret, client.SecretHash)
+	assert.Equal(t, result.ClientSecret+client.Salt, client.SecretHash)
+
+	result, err = biz.CreateClient(nil, &oauthmodel.ClientCreate{
+		Name:         "Mobile",
+		RedirectURIs: []string{"myapp://callback"},
+		Public:       true,
+	})
+	require.Nil(t, err, err)
+	assert.Empty(t, result.ClientSecret)
+}
+
+func authorize(t *testing.T, codes oauthbiz.CodeStore, req *oauthmodel.AuthorizeRequest) url.Values {
+	biz := oauthbiz.NewAuthorizeBiz(mock.NewMockOAuthClientStore(), codes, 60)
+
+	result, err := biz.Authorize(nil, 1, req)
+	require.Nil(t, err, err)
+
+	u, err := url.Parse(result.RedirectTo)
+	require.Nil(t, err, err)
+	assert.Equal(t, mock.OAuthRedirectURI, u.Scheme+"://"+u.Host+u.Path)
+
+	return u.Query()
+}
+
+func TestOAuthBiz_Authorize(t *testing.T) {
+	biz := oauthbiz.NewAuthorizeBiz(mock.NewMockOAuthClientStore(), mock.NewMockOAuthCodeStore(), 60)
+
+	_, err := biz.Authorize(nil, 1, &oauthmodel.AuthorizeRequest{
+		ResponseType: "code", ClientId: "unknown", RedirectURI: mock.OAuthRedirectURI, Scope: "openid",
+	})
+	assert.Equal(t, oauthmodel.ErrInvalidClient, err)
+
+	_, err = biz.Authorize(nil, 1, &oauthmodel.AuthorizeRequest{
+		ResponseType: "code", ClientId: "confidential", RedirectURI: "https://evil.example.com/", Scope: "openid",
+	})
+	assert.Equal(t, oauthmodel.ErrInvalidRedirectURI, err)
+
+	var tcs = []struct {
+		req      oauthmodel.AuthorizeRequest
+		expected string
+	}{
+		{oauthmodel.AuthorizeRequest{ResponseType: "token", ClientId: "confidential", Scope: "openid"}, "unsupported_response_type"},
+		{oauthmodel.AuthorizeRequest{ResponseType: "code", ClientId: "confidential", Scope: "email"}, "invalid_scope"},
+		{oauthmodel.AuthorizeRequest{ResponseType: "code", ClientId: "public", Scope: "openid"}, "invalid_request"},
+		{
+			oauthmodel.AuthorizeRequest{
+				ResponseType: "code", ClientId: "public", Scope: "openid",
+				CodeChallenge: codeChallenge, CodeChallengeMethod: "plain",
+			},
+			"invalid_request",
+		},
+	}
+
+	for _, tc := range tcs {
+		tc.req.RedirectURI = mock.OAuthRedirectURI
+		tc.req.State = "xyz"
+		query := authorize(t, mock.NewMockOAuthCodeStore(), &tc.req)
+		assert.Equal(t, tc.expected, query.Get("error"), tc.req.ClientId)
+		assert.Equal(t, "xyz", query.Get("state"))
+		assert.Empty(t, query.Get("code"))
+	}
+}
+
+func TestOAuthBiz_ExchangeCode(t *testing.T) {
+	codes := mock.NewMockOAuthCodeStore()
+	signer := mock.NewMockIdTokenSigner()
+	biz := oauthbiz.NewExchangeCodeBiz(
+		mock.NewMockOAuthClientStore(),
+		codes,
+		mock.NewMockUserStore(),
+		mock.NewMockHash(),
+		mock.NewMockProvider(),
+		signer,
+		"https://evite.example.com",
+		3600,
+	)
+
+	query := authorize(t, codes, &oauthmodel.AuthorizeRequest{
+		ResponseType: "code", ClientId: "confidential", RedirectURI: mock.OAuthRedirectURI,
+		Scope: "openid email", State: "xyz", Nonce: "n-0S6",
+	})
+	assert.Equal(t, "xyz", query.Get("state"))
+
+	req := oauthmodel.TokenRequest{
+		GrantType: "authorization_code", Code: query.Get("code"), RedirectURI: mock.OAuthRedirectURI,
+		ClientId: "confidential", ClientSecret: "wrong",
+	}
+	_, err := biz.ExchangeCode(nil, &req)
+	assert.Equal(t, oauthmodel.ErrInvalidClient, err)
+
+	req.ClientSecret = "secret"
+	result, err := biz.ExchangeCode(nil, &req)
+	require.Nil(t, err, err)
+	assert.Equal(t, "id-token", result.IdToken)
+	assert.Equal(t, "Bearer", result.TokenType)
+	assert.Equal(t, "openid email", result.Scope)
+	assert.Equal(t, "confidential", signer.Claims["aud"])
+	assert.Equal(t, "n-0S6", signer.Claims["nonce"])
+	assert.Equal(t, "user@gmail.com", signer.Claims["email"])
+
+	// codes are single use
+	_, err = biz.ExchangeCode(nil, &req)
+	assert.Equal(t, oauthmodel.ErrInvalidGrant, err)
+
+	query = authorize(t, codes, &oauthmodel.AuthorizeRequest{
+		ResponseType: "code", ClientId: "public", RedirectURI: mock.OAuthRedirectURI, Scope: "openid",
+		CodeChallenge: codeChallenge, CodeChallengeMethod: oauthmodel.CodeChallengeMethodS256,
+	})
+	req = oauthmodel.TokenRequest{
+		GrantType: "authorization_code", Code: query.Get("code"), RedirectURI: mock.OAuthRedirectURI,
+		ClientId: "public", CodeVerifier: "wrong",
+	}
+	_, err = biz.ExchangeCode(nil, &req)
+	assert.Equal(t, oauthmodel.ErrInvalidGrant, err)
+
+	query = authorize(t, codes, &oauthmodel.AuthorizeRequest{
+		ResponseType: "code", ClientId: "public", RedirectURI: mock.OAuthRedirectURI, Scope: "openid",
+		CodeChallenge: codeChallenge, CodeChallengeMethod: oauthmodel.CodeChallengeMethodS256,
+	})
+	req.Code, req.CodeVerifier = query.Get("code"), codeVerifier
+	result, err = biz.ExchangeCode(nil, &req)
+	require.Nil(t, err, err)
+	assert.NotContains(t, signer.Claims, "email")
+
+	req.GrantType = "password"
+	_, err = biz.ExchangeCode(nil, &req)
+	assert.Equal(t, oauthmodel.ErrUnsupportedGrantType, err)
+}
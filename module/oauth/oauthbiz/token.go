@@ -0,0 +1,195 @@
+package oauthbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/module/oauth/oauthmodel"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type UserStore interface {
+	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+}
+
+type IdTokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+}
+
+// Exchange authorization code
+
+type IExchangeCodeBiz interface {
+	ExchangeCode(ctx context.Context, req *oauthmodel.TokenRequest) (*oauthmodel.TokenResponse, error)
+}
+
+type exchangeCodeBiz struct {
+	clientStore   ClientStore
+	codeStore     CodeStore
+	userStore     UserStore
+	hash          Hash
+	tokenProvider tokenprovider.Provider
+	signer        IdTokenSigner
+	issuer        string
+	tokenExpiry   int
+}
+
+func NewExchangeCodeBiz(
+	clientStore ClientStore,
+	codeStore CodeStore,
+	userStore UserStore,
+	hash Hash,
+	tokenProvider tokenprovider.Provider,
+	signer IdTokenSigner,
+	issuer string,
+	tokenExpiry int,
+) IExchangeCodeBiz {
+	return &exchangeCodeBiz{
+		clientStore:   clientStore,
+		codeStore:     codeStore,
+		userStore:     userStore,
+		hash:          hash,
+		tokenProvider: tokenProvider,
+		signer:        signer,
+		issuer:        issuer,
+		tokenExpiry:   tokenExpiry,
+	}
+}
+
+func (biz *exchangeCodeBiz) ExchangeCode(
+	ctx context.Context,
+	req *oauthmodel.TokenRequest,
+) (*oauthmodel.TokenResponse, error) {
+	if req.GrantType != "authorization_code" {
+		return nil, oauthmodel.ErrUnsupportedGrantType
+	}
+
+	client, err := authenticateClient(ctx, biz.clientStore, biz.hash, req.ClientId, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := biz.codeStore.TakeCode(ctx, req.Code)
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, oauthmodel.ErrInvalidGrant
+		}
+		return nil, err
+	}
+
+	if code.ClientId != client.ClientId || code.RedirectURI != req.RedirectURI {
+		return nil, oauthmodel.ErrInvalidGrant
+	}
+
+	if code.CodeChallenge != "" && !oauthmodel.VerifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return nil, oauthmodel.ErrInvalidGrant
+	}
+
+	user, err := biz.userStore.FindUser(ctx, map[string]interface{}{"id": code.UserId})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, oauthmodel.ErrInvalidGrant
+		}
+		return nil, err
+	}
+
+	if user.Status == 0 {
+		return nil, oauthmodel.ErrInvalidGrant
+	}
+
+	accessToken, err := biz.tokenProvider.Generate(tokenprovider.TokenPayload{
+		UserId:   user.Id,
+		Purpose:  tokenprovider.PurposeOAuth,
+		ClientId: client.ClientId,
+		Scope:    code.Scope,
+	}, biz.tokenExpiry)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": biz.issuer,
+		"sub": strconv.Itoa(user.Id),
+		"aud": client.ClientId,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Duration(biz.tokenExpiry) * time.Second).Unix(),
+	}
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+	if oauthmodel.HasScope(code.Scope, oauthmodel.ScopeEmail) {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.IsEmailVerified()
+	}
+
+	idToken, err := biz.signer.Sign(claims)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	return &oauthmodel.TokenResponse{
+		AccessToken: accessToken.Token,
+		TokenType:   "Bearer",
+		ExpiresIn:   biz.tokenExpiry,
+		IdToken:     idToken,
+		Scope:       code.Scope,
+	}, nil
+}
+
+// Get user info
+
+type IUserInfoBiz interface {
+	GetUserInfo(ctx context.Context, accessToken string) (*oauthmodel.UserInfo, error)
+}
+
+type userInfoBiz struct {
+	userStore     UserStore
+	tokenProvider tokenprovider.Provider
+}
+
+func NewUserInfoBiz(userStore UserStore, tokenProvider tokenprovider.Provider) IUserInfoBiz {
+	return &userInfoBiz{userStore: userStore, tokenProvider: tokenProvider}
+}
+
+// GetUserInfo only accepts access tokens issued by ExchangeCode, and returns
+// the claims their scope allows
+func (biz *userInfoBiz) GetUserInfo(ctx context.Context, accessToken string) (*oauthmodel.UserInfo, error) {
+	payload, err := biz.tokenProvider.Validate(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Purpose != tokenprovider.PurposeOAuth {
+		return nil, tokenprovider.ErrInvalidToken
+	}
+
+	user, err := biz.userStore.FindUser(ctx, map[string]interface{}{"id": payload.UserId})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, tokenprovider.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if user.Status == 0 {
+		return nil, tokenprovider.ErrInvalidToken
+	}
+
+	info := oauthmodel.UserInfo{Sub: strconv.Itoa(user.Id)}
+
+	if oauthmodel.HasScope(payload.Scope, oauthmodel.ScopeEmail) {
+		verified := user.IsEmailVerified()
+		info.Email = user.Email
+		info.EmailVerified = &verified
+	}
+
+	if oauthmodel.HasScope(payload.Scope, oauthmodel.ScopeProfile) {
+		info.Role = user.Role
+	}
+
+	return &info, nil
+}
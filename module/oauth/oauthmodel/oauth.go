@@ -0,0 +1,246 @@
+package oauthmodel
+
+import (
+	"app-invite-service/common"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const EntityName = "OAuthClient"
+
+const (
+	ScopeOpenId  = "openid"
+	ScopeEmail   = "email"
+	ScopeProfile = "profile"
+
+	CodeChallengeMethodS256 = "S256"
+)
+
+var SupportedScopes = []string{ScopeOpenId, ScopeEmail, ScopeProfile}
+
+// Error keys are the error codes of RFC 6749 section 5.2, so they can be
+// handed to OAuth clients unchanged
+
+func ErrOAuth(statusCode int, code, description string) *common.AppError {
+	return common.NewFullErrorResponse(statusCode, errors.New(description), description, description, code)
+}
+
+var (
+	ErrInvalidClient        = ErrOAuth(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+	ErrInvalidGrant         = ErrOAuth(http.StatusBadRequest, "invalid_grant", "authorization code is invalid or expired")
+	ErrUnsupportedGrantType = ErrOAuth(http.StatusBadRequest, "unsupported_grant_type", "grant type is not supported")
+	ErrInvalidRedirectURI   = ErrOAuth(http.StatusBadRequest, "invalid_request", "redirect_uri is not registered for this client")
+	ErrClientNotFound       = common.NewFullErrorResponse(
+		http.StatusNotFound,
+		errors.New("oauth client not found"),
+		"oauth client not found",
+		"oauth client not found",
+		"ErrClientNotFound",
+	)
+	ErrRedirectURIsInvalid = common.NewCustomError(
+		errors.New("redirect uris invalid"),
+		"redirect uris must be absolute URLs without a fragment",
+		"ErrRedirectURIsInvalid",
+	)
+)
+
+type Client struct {
+	Id           int        `json:"id" gorm:"column:id;"`
+	ClientId     string     `json:"client_id" gorm:"column:client_id;"`
+	SecretHash   string     `json:"-" gorm:"column:secret_hash;"`
+	Salt         string     `json:"-" gorm:"column:salt;"`
+	Name         string     `json:"name" gorm:"column:name;"`
+	RedirectURIs []string   `json:"redirect_uris" gorm:"column:redirect_uris;serializer:json;"`
+	Public       bool       `json:"public" gorm:"column:public;"`
+	CreatedAt    *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+}
+
+func (Client) TableName() string {
+	return "oauth_clients"
+}
+
+func (c *Client) HasRedirectURI(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+type ClientCreate struct {
+	Id           int      `json:"-" gorm:"column:id;"`
+	ClientId     string   `json:"-" gorm:"column:client_id;"`
+	SecretHash   string   `json:"-" gorm:"column:secret_hash;"`
+	Salt         string   `json:"-" gorm:"column:salt;"`
+	Name         string   `json:"name" form:"name" binding:"required" gorm:"column:name;"`
+	RedirectURIs []string `json:"redirect_uris" form:"redirect_uris" binding:"required" gorm:"column:redirect_uris;serializer:json;"`
+	// Public clients (SPAs, mobile apps) can't keep a secret and must use PKCE
+	Public bool `json:"public" form:"public" gorm:"column:public;"`
+}
+
+func (ClientCreate) TableName() string {
+	return Client{}.TableName()
+}
+
+func (c *ClientCreate) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+
+	if len(c.RedirectURIs) == 0 {
+		return ErrRedirectURIsInvalid
+	}
+
+	for i := range c.RedirectURIs {
+		c.RedirectURIs[i] = strings.TrimSpace(c.RedirectURIs[i])
+		u, err := url.Parse(c.RedirectURIs[i])
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return ErrRedirectURIsInvalid
+		}
+	}
+
+	return nil
+}
+
+// ClientCredentials is only returned once, when the client is registered
+type ClientCredentials struct {
+	ClientId     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type" form:"response_type" binding:"required"`
+	ClientId            string `json:"client_id" form:"client_id" binding:"required"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri" binding:"required"`
+	Scope               string `json:"scope" form:"scope"`
+	State               string `json:"state" form:"state"`
+	Nonce               string `json:"nonce" form:"nonce"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method"`
+}
+
+type AuthorizeResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// AuthorizationCode is what an issued code points to in redis
+type AuthorizationCode struct {
+	ClientId            string `json:"client_id"`
+	UserId              int    `json:"user_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	Nonce               string `json:"nonce,omitempty"`
+	CodeChallenge       string `json:"code_challenge,omitempty"`
+	CodeChallengeMethod string `json:"code_challenge_method,omitempty"`
+}
+
+func (c *AuthorizationCode) MarshalBinary() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func (c *AuthorizationCode) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, c)
+}
+
+type TokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	CodeVerifier string `form:"code_verifier"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IdToken     string `json:"id_token,omitempty"`
+	Scope       string `json:"scope"`
+}
+
+type UserInfo struct {
+	Sub           string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Role          string `json:"role,omitempty"`
+}
+
+// ParseScope drops duplicated and unsupported scopes, keeping the order
+func ParseScope(scope string) []string {
+	var scopes []string
+	seen := map[string]bool{}
+	for _, s := range strings.Fields(scope) {
+		if seen[s] {
+			continue
+		}
+		for _, supported := range SupportedScopes {
+			if s == supported {
+				scopes = append(scopes, s)
+				seen[s] = true
+				break
+			}
+		}
+	}
+	return scopes
+}
+
+func HasScope(scope, want string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyCodeChallenge checks a PKCE code_verifier against the S256 challenge
+func VerifyCodeChallenge(verifier, challenge string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// ProviderMetadata is the OpenID Connect discovery document
+type ProviderMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IdTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+func NewProviderMetadata(issuer string) *ProviderMetadata {
+	issuer = strings.TrimRight(issuer, "/")
+
+	return &ProviderMetadata{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/api/v1/oauth/authorize",
+		TokenEndpoint:                     issuer + "/api/v1/oauth/token",
+		UserInfoEndpoint:                  issuer + "/api/v1/oauth/userinfo",
+		JwksURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IdTokenSigningAlgValuesSupported:  []string{"RS256"},
+		ScopesSupported:                   SupportedScopes,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{CodeChallengeMethodS256},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "nonce", "email", "email_verified", "role"},
+	}
+}
@@ -0,0 +1,54 @@
+package oauthmodel_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"app-invite-service/module/oauth/oauthmodel"
+)
+
+func TestOAuth_VerifyCodeChallenge(t *testing.T) {
+	// example from RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	assert.True(t, oauthmodel.VerifyCodeChallenge(verifier, challenge))
+	assert.False(t, oauthmodel.VerifyCodeChallenge(verifier+"x", challenge))
+	assert.False(t, oauthmodel.VerifyCodeChallenge("", challenge))
+}
+
+func TestOAuth_ParseScope(t *testing.T) {
+	var tcs = []struct {
+		scope    string
+		expected []string
+	}{
+		{"openid", []string{"openid"}},
+		{"openid email openid", []string{"openid", "email"}},
+		{"  profile   admin openid ", []string{"profile", "openid"}},
+		{"", nil},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.expected, oauthmodel.ParseScope(tc.scope), tc.scope)
+	}
+}
+
+func TestOAuth_ClientCreateValidate(t *testing.T) {
+	var tcs = []struct {
+		uris     []string
+		expected error
+	}{
+		{[]string{"https://app.example.com/callback"}, nil},
+		{[]string{"myapp://callback"}, nil},
+		{[]string{" https://app.example.com/callback "}, nil},
+		{nil, oauthmodel.ErrRedirectURIsInvalid},
+		{[]string{"/callback"}, oauthmodel.ErrRedirectURIsInvalid},
+		{[]string{"https://app.example.com/callback#frag"}, oauthmodel.ErrRedirectURIsInvalid},
+	}
+
+	for _, tc := range tcs {
+		data := oauthmodel.ClientCreate{Name: "app", RedirectURIs: tc.uris}
+		assert.Equal(t, tc.expected, data.Validate(), "%v", tc.uris)
+	}
+}
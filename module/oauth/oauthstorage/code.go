@@ -0,0 +1,54 @@
+package oauthstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/oauth/oauthmodel"
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const codeKeyPrefix = "oauth_code:"
+
+type ICodeStore interface {
+	SaveCode(ctx context.Context, code string, data *oauthmodel.AuthorizationCode, expiry int) error
+	TakeCode(ctx context.Context, code string) (*oauthmodel.AuthorizationCode, error)
+}
+
+type redisCodeStore struct {
+	rdb *redis.Client
+}
+
+// NewRedisCodeStore keeps authorization codes in redis until they expire or
+// are exchanged, whichever comes first
+func NewRedisCodeStore(rdb *redis.Client) ICodeStore {
+	return &redisCodeStore{rdb: rdb}
+}
+
+func (s *redisCodeStore) SaveCode(
+	ctx context.Context,
+	code string,
+	data *oauthmodel.AuthorizationCode,
+	expiry int,
+) error {
+	if err := s.rdb.Set(ctx, codeKeyPrefix+code, data, time.Duration(expiry)*time.Second).Err(); err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// TakeCode reads and deletes a code in one step, so it can only be used once
+func (s *redisCodeStore) TakeCode(ctx context.Context, code string) (*oauthmodel.AuthorizationCode, error) {
+	var data oauthmodel.AuthorizationCode
+
+	if err := s.rdb.GetDel(ctx, codeKeyPrefix+code).Scan(&data); err != nil {
+		if err == redis.Nil {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &data, nil
+}
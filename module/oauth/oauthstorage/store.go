@@ -0,0 +1,68 @@
+package oauthstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/oauth/oauthmodel"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type ISqlStore interface {
+	CreateClient(_ context.Context, data *oauthmodel.ClientCreate) error
+	FindClient(_ context.Context, conditions map[string]interface{}) (*oauthmodel.Client, error)
+	ListClients(_ context.Context) ([]oauthmodel.Client, error)
+	DeleteClient(_ context.Context, id int) error
+}
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+func NewSQLStore(db *gorm.DB) ISqlStore {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) CreateClient(_ context.Context, data *oauthmodel.ClientCreate) error {
+	if err := s.db.Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) FindClient(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*oauthmodel.Client, error) {
+	var client oauthmodel.Client
+
+	if err := s.db.Table(client.TableName()).Where(conditions).First(&client).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &client, nil
+}
+
+func (s *sqlStore) ListClients(_ context.Context) ([]oauthmodel.Client, error) {
+	var clients []oauthmodel.Client
+
+	if err := s.db.Table(oauthmodel.Client{}.TableName()).Order("id").Find(&clients).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return clients, nil
+}
+
+func (s *sqlStore) DeleteClient(_ context.Context, id int) error {
+	if err := s.db.Table(oauthmodel.Client{}.TableName()).
+		Where("id = ?", id).
+		Delete(nil).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package ginoauth
+
+import (
+	"net/http"
+	"strconv"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/hash"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/oauth/oauthbiz"
+	"app-invite-service/module/oauth/oauthmodel"
+	"app-invite-service/module/oauth/oauthstorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CreateClient(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data oauthmodel.ClientCreate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := oauthstorage.NewSQLStore(appCtx.GetDBConn())
+		biz := oauthbiz.NewCreateClientBiz(store, hash.NewMd5Hash(), ginaudit.NewRecorder(appCtx))
+
+		result, err := biz.CreateClient(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func ListClients(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := oauthstorage.NewSQLStore(appCtx.GetDBConn())
+		biz := oauthbiz.NewListClientsBiz(store)
+
+		result, err := biz.ListClients(c.Request.Context())
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func DeleteClient(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		store := oauthstorage.NewSQLStore(appCtx.GetDBConn())
+		biz := oauthbiz.NewDeleteClientBiz(store, ginaudit.NewRecorder(appCtx))
+
+		if err := biz.DeleteClient(c.Request.Context(), id); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
@@ -0,0 +1,124 @@
+package ginoauth
+
+import (
+	"net/http"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/hash"
+	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/middleware"
+	"app-invite-service/module/oauth/oauthbiz"
+	"app-invite-service/module/oauth/oauthmodel"
+	"app-invite-service/module/oauth/oauthstorage"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abortWithOAuthError renders errors the way RFC 6749 section 5.2 expects,
+// since OAuth client libraries don't understand our usual error body
+func abortWithOAuthError(c *gin.Context, err error) {
+	appErr, ok := err.(*common.AppError)
+	if !ok {
+		panic(err)
+	}
+
+	c.AbortWithStatusJSON(appErr.StatusCode, gin.H{
+		"error":             appErr.Key,
+		"error_description": appErr.Message,
+	})
+}
+
+func Discovery(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, oauthmodel.NewProviderMetadata(appCtx.GetConfig().OAuth.Issuer))
+	}
+}
+
+func Jwks(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, appCtx.GetIDTokenSigner().JWKS())
+	}
+}
+
+// Authorize is called by our own login page once the user is signed in. It
+// answers with the client redirect instead of redirecting, so the page can
+// ask for consent first.
+func Authorize(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req oauthmodel.AuthorizeRequest
+		if err := c.ShouldBind(&req); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		clientStore := oauthstorage.NewSQLStore(appCtx.GetDBConn())
+		codeStore := oauthstorage.NewRedisCodeStore(appCtx.GetRedisConn())
+		biz := oauthbiz.NewAuthorizeBiz(clientStore, codeStore, appCtx.GetConfig().OAuth.CodeExpiry)
+
+		result, err := biz.Authorize(c.Request.Context(), user.Id, &req)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func Token(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+
+		var req oauthmodel.TokenRequest
+		if err := c.ShouldBind(&req); err != nil {
+			abortWithOAuthError(c, oauthmodel.ErrOAuth(http.StatusBadRequest, "invalid_request", err.Error()))
+			return
+		}
+
+		if clientId, secret, ok := c.Request.BasicAuth(); ok {
+			req.ClientId, req.ClientSecret = clientId, secret
+		}
+
+		cfg := appCtx.GetConfig().OAuth
+		biz := oauthbiz.NewExchangeCodeBiz(
+			oauthstorage.NewSQLStore(appCtx.GetDBConn()),
+			oauthstorage.NewRedisCodeStore(appCtx.GetRedisConn()),
+			userstorage.NewSQLStore(appCtx.GetDBConn()),
+			hash.NewMd5Hash(),
+			jwt.NewTokenJWTProvider(appCtx.SecretKey()),
+			appCtx.GetIDTokenSigner(),
+			cfg.Issuer,
+			cfg.TokenExpiry,
+		)
+
+		result, err := biz.ExchangeCode(c.Request.Context(), &req)
+		if err != nil {
+			abortWithOAuthError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UserInfo(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := middleware.ExtractTokenFromHeaderString(c.GetHeader("Authorization"))
+		if err != nil {
+			panic(err)
+		}
+
+		store := userstorage.NewSQLStore(appCtx.GetDBConn())
+		biz := oauthbiz.NewUserInfoBiz(store, jwt.NewTokenJWTProvider(appCtx.SecretKey()))
+
+		result, err := biz.GetUserInfo(c.Request.Context(), token)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,289 @@
+package referralbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/referral/referralmodel"
+	"app-invite-service/module/user/usermodel"
+	"context"
+)
+
+type ReferralStore interface {
+	CreateReferral(ctx context.Context, data *referralmodel.Referral) error
+	FindReferral(ctx context.Context, conditions map[string]interface{}) (*referralmodel.Referral, error)
+	ListInvitees(ctx context.Context, inviterIds []int) ([]referralmodel.Invitee, error)
+	DisableUsers(ctx context.Context, userIds []int) error
+}
+
+type UserStore interface {
+	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+}
+
+type TokenGenerator interface {
+	GenerateToken(ctx context.Context, data *usermodel.InvitationTokenCreate) (*usermodel.InvitationToken, error)
+}
+
+type TokenDisabler interface {
+	DisableTokensByCreator(ctx context.Context, creatorIds []int) ([]string, error)
+}
+
+type AuditLogger interface {
+	Record(ctx context.Context, entry *auditmodel.Entry)
+}
+
+// Record referral
+
+type IRecordReferralBiz interface {
+	RecordReferral(ctx context.Context, inviterId, inviteeId int, invitationToken string) error
+}
+
+type recordReferralBiz struct {
+	store ReferralStore
+}
+
+func NewRecordReferralBiz(store ReferralStore) IRecordReferralBiz {
+	return &recordReferralBiz{store: store}
+}
+
+func (biz *recordReferralBiz) RecordReferral(ctx context.Context, inviterId, inviteeId int, invitationToken string) error {
+	depth := 1
+
+	inviter, err := biz.store.FindReferral(ctx, map[string]interface{}{"invitee_id": inviterId})
+	if err != nil && err != common.ErrRecordNotFound {
+		return err
+	}
+	if inviter != nil {
+		depth = inviter.Depth + 1
+	}
+
+	if err := biz.store.CreateReferral(ctx, &referralmodel.Referral{
+		InviterId:       inviterId,
+		InviteeId:       inviteeId,
+		InvitationToken: invitationToken,
+		Depth:           depth,
+	}); err != nil {
+		return common.ErrCannotCreateEntity(referralmodel.EntityName, err)
+	}
+
+	return nil
+}
+
+// Generate personal invitation
+
+type IGeneratePersonalInvitationBiz interface {
+	GeneratePersonalInvitation(
+		ctx context.Context,
+		user *usermodel.User,
+		data *referralmodel.PersonalInvitationCreate,
+	) (*usermodel.InvitationToken, error)
+}
+
+type generatePersonalInvitationBiz struct {
+	generator TokenGenerator
+	enabled   bool
+}
+
+// NewGeneratePersonalInvitationBiz hands out tokens created by the user, so the
+// generator enforces their invite quota
+func NewGeneratePersonalInvitationBiz(generator TokenGenerator, enabled bool) IGeneratePersonalInvitationBiz {
+	return &generatePersonalInvitationBiz{generator: generator, enabled: enabled}
+}
+
+func (biz *generatePersonalInvitationBiz) GeneratePersonalInvitation(
+	ctx context.Context,
+	user *usermodel.User,
+	data *referralmodel.PersonalInvitationCreate,
+) (*usermodel.InvitationToken, error) {
+	if !biz.enabled {
+		return nil, referralmodel.ErrReferralDisabled
+	}
+
+	token, err := biz.generator.GenerateToken(ctx, &usermodel.InvitationTokenCreate{
+		Email:     data.Email,
+		CreatedBy: user.Id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, common.ErrCannotCreateEntity("InvitationToken", nil)
+	}
+
+	return token, nil
+}
+
+// Get downline
+
+type IGetDownlineBiz interface {
+	GetDownline(ctx context.Context, userId int) (*referralmodel.Downline, error)
+}
+
+type getDownlineBiz struct {
+	store     ReferralStore
+	userStore UserStore
+}
+
+func NewGetDownlineBiz(store ReferralStore, userStore UserStore) IGetDownlineBiz {
+	return &getDownlineBiz{store: store, userStore: userStore}
+}
+
+func (biz *getDownlineBiz) GetDownline(ctx context.Context, userId int) (*referralmodel.Downline, error) {
+	if _, err := biz.userStore.FindUser(ctx, map[string]interface{}{"id": userId}); err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, referralmodel.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	downline := referralmodel.Downline{UserId: userId, Members: []referralmodel.Invitee{}}
+
+	referral, err := biz.store.FindReferral(ctx, map[string]interface{}{"invitee_id": userId})
+	if err != nil && err != common.ErrRecordNotFound {
+		return nil, err
+	}
+	if referral != nil {
+		downline.Depth = referral.Depth
+		downline.InvitedBy = &referral.InviterId
+	}
+
+	members, _, err := collectDownline(ctx, biz.store, userId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	downline.Members = append(downline.Members, members...)
+	downline.Size = len(members)
+	if len(members) > 0 {
+		downline.Levels = members[len(members)-1].Level
+	}
+
+	return &downline, nil
+}
+
+// collectDownline walks the referral tree below userId one level at a time.
+// Invitees skip reports true for are returned apart, and the walk doesn't go
+// below them.
+func collectDownline(
+	ctx context.Context,
+	store ReferralStore,
+	userId int,
+	skip func(referralmodel.Invitee) bool,
+) (members, skipped []referralmodel.Invitee, err error) {
+	seen := map[int]bool{userId: true}
+	frontier := []int{userId}
+
+	for level := 1; len(frontier) > 0; level++ {
+		invitees, err := store.ListInvitees(ctx, frontier)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		frontier = nil
+		for _, invitee := range invitees {
+			if seen[invitee.UserId] {
+				continue
+			}
+			seen[invitee.UserId] = true
+
+			invitee.Level = level
+			if skip != nil && skip(invitee) {
+				skipped = append(skipped, invitee)
+				continue
+			}
+			members = append(members, invitee)
+			frontier = append(frontier, invitee.UserId)
+		}
+	}
+
+	return members, skipped, nil
+}
+
+// Revoke subtree
+
+type IRevokeSubtreeBiz interface {
+	RevokeSubtree(ctx context.Context, callerId, userId int) (*referralmodel.RevokeResult, error)
+}
+
+type revokeSubtreeBiz struct {
+	store     ReferralStore
+	userStore UserStore
+	tokens    TokenDisabler
+	audit     AuditLogger
+}
+
+func NewRevokeSubtreeBiz(
+	store ReferralStore,
+	userStore UserStore,
+	tokens TokenDisabler,
+	audit AuditLogger,
+) IRevokeSubtreeBiz {
+	return &revokeSubtreeBiz{store: store, userStore: userStore, tokens: tokens, audit: audit}
+}
+
+// RevokeSubtree disables userId, everyone in their downline, and every
+// invitation token they created that is still valid. The caller and admins
+// can't be revoked; admins in the downline are left enabled along with
+// their own invitees.
+func (biz *revokeSubtreeBiz) RevokeSubtree(
+	ctx context.Context,
+	callerId, userId int,
+) (*referralmodel.RevokeResult, error) {
+	if userId == callerId {
+		return nil, referralmodel.ErrRevokeSelf
+	}
+
+	user, err := biz.userStore.FindUser(ctx, map[string]interface{}{"id": userId})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, referralmodel.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.GetRole() == "admin" {
+		return nil, referralmodel.ErrRevokeAdmin
+	}
+
+	members, admins, err := collectDownline(ctx, biz.store, userId, func(invitee referralmodel.Invitee) bool {
+		return invitee.Role == "admin"
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userIds := []int{userId}
+	for _, m := range members {
+		userIds = append(userIds, m.UserId)
+	}
+
+	skipped := []int{}
+	for _, a := range admins {
+		skipped = append(skipped, a.UserId)
+	}
+
+	if err := biz.store.DisableUsers(ctx, userIds); err != nil {
+		return nil, err
+	}
+
+	tokens, err := biz.tokens.DisableTokensByCreator(ctx, userIds)
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		tokens = []string{}
+	}
+
+	result := referralmodel.RevokeResult{UserIds: userIds, Tokens: tokens, SkippedAdminIds: skipped}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionReferralRevoke,
+		TargetType: auditmodel.TargetUser,
+		TargetId:   auditmodel.IntId(userId),
+		After: map[string]interface{}{
+			"user_ids":          result.UserIds,
+			"token_ids":         usermodel.InvitationTokenIds(result.Tokens),
+			"skipped_admin_ids": result.SkippedAdminIds,
+		},
+	})
+
+	return &result, nil
+}
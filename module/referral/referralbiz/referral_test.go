@@ -0,0 +1,198 @@
+package referralbiz_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/mock"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/referral/referralbiz"
+	"app-invite-service/module/referral/referralmodel"
+	"app-invite-service/module/user/usermodel"
+)
+
+// referralTree is
+//
+//	1 ─┬─ 2 ── 4 ── 5
+//	   └─ 3
+//	6 ── 7
+func referralTree() []referralmodel.Referral {
+	return []referralmodel.Referral{
+		{InviterId: 1, InviteeId: 2, Depth: 1},
+		{InviterId: 1, InviteeId: 3, Depth: 1},
+		{InviterId: 2, InviteeId: 4, Depth: 2},
+		{InviterId: 4, InviteeId: 5, Depth: 3},
+		{InviterId: 6, InviteeId: 7, Depth: 1},
+	}
+}
+
+func TestReferralBiz_RecordReferral(t *testing.T) {
+	store := mock.NewMockReferralStore(referralTree()...)
+	biz := referralbiz.NewRecordReferralBiz(store)
+
+	tcs := []struct {
+		inviterId     int
+		inviteeId     int
+		expectedDepth int
+		hasErr        bool
+	}{
+		{1, 8, 1, false},
+		{5, 9, 4, false},
+		{8, 10, 2, false},
+		{1, 9, 0, true},
+	}
+
+	for _, tc := range tcs {
+		err := biz.RecordReferral(nil, tc.inviterId, tc.inviteeId, "token")
+		if tc.hasErr {
+			assert.Error(t, err)
+			continue
+		}
+		require.Nil(t, err, err)
+
+		referrals := store.Referrals()
+		assert.Equal(t, tc.inviteeId, referrals[len(referrals)-1].InviteeId)
+		assert.Equal(t, tc.expectedDepth, referrals[len(referrals)-1].Depth)
+	}
+}
+
+func TestReferralBiz_GeneratePersonalInvitation(t *testing.T) {
+	user := &usermodel.User{Id: 2}
+
+	biz := referralbiz.NewGeneratePersonalInvitationBiz(mock.NewMockTokenGenerator(), false)
+	_, err := biz.GeneratePersonalInvitation(nil, user, &referralmodel.PersonalInvitationCreate{})
+	assert.Equal(t, referralmodel.ErrReferralDisabled, err)
+
+	generator := mock.NewMockTokenGenerator()
+	biz = referralbiz.NewGeneratePersonalInvitationBiz(generator, true)
+
+	tcs := []struct {
+		email       string
+		expectedErr error
+	}{
+		{"", nil},
+		{"friend@blocked.com", errors.New("email domain not allowed")},
+		{"friend@gmail.com", nil},
+	}
+
+	for _, tc := range tcs {
+		token, err := biz.GeneratePersonalInvitation(nil, user, &referralmodel.PersonalInvitationCreate{Email: tc.email})
+		assert.Equal(t, tc.expectedErr, err)
+		if tc.expectedErr == nil {
+			assert.Equal(t, user.Id, token.CreatedBy)
+			assert.Equal(t, tc.email, token.Email)
+		}
+	}
+
+	assert.Len(t, generator.Tokens(), 2)
+}
+
+func TestReferralBiz_GetDownline(t *testing.T) {
+	biz := referralbiz.NewGetDownlineBiz(mock.NewMockReferralStore(referralTree()...), mock.NewMockReferralUserStore())
+
+	_, err := biz.GetDownline(nil, 100)
+	assert.Equal(t, referralmodel.ErrUserNotFound, err)
+
+	tcs := []struct {
+		userId         int
+		depth          int
+		invitedBy      *int
+		size           int
+		levels         int
+		expectedLevels map[int]int
+	}{
+		{1, 0, nil, 4, 3, map[int]int{2: 1, 3: 1, 4: 2, 5: 3}},
+		{2, 1, intPtr(1), 2, 2, map[int]int{4: 1, 5: 2}},
+		{5, 3, intPtr(4), 0, 0, map[int]int{}},
+	}
+
+	for _, tc := range tcs {
+		downline, err := biz.GetDownline(nil, tc.userId)
+		require.Nil(t, err, err)
+
+		assert.Equal(t, tc.depth, downline.Depth)
+		assert.Equal(t, tc.invitedBy, downline.InvitedBy)
+		assert.Equal(t, tc.size, downline.Size)
+		assert.Equal(t, tc.levels, downline.Levels)
+
+		levels := map[int]int{}
+		for _, m := range downline.Members {
+			levels[m.UserId] = m.Level
+		}
+		assert.Equal(t, tc.expectedLevels, levels)
+	}
+}
+
+func TestReferralBiz_RevokeSubtree(t *testing.T) {
+	// an admin, 90, joined through 4 and invited 91
+	store := mock.NewMockReferralStore(append(
+		referralTree(),
+		referralmodel.Referral{InviterId: 4, InviteeId: 90, Depth: 3},
+		referralmodel.Referral{InviterId: 90, InviteeId: 91, Depth: 4},
+	)...)
+	tokens := mock.NewMockTokenDisabler(
+		usermodel.InvitationToken{Token: "from2", Status: 1, CreatedBy: 2},
+		usermodel.InvitationToken{Token: "from5", Status: 1, CreatedBy: 5},
+		usermodel.InvitationToken{Token: "from3", Status: 1, CreatedBy: 3},
+		usermodel.InvitationToken{Token: "from90", Status: 1, CreatedBy: 90},
+		usermodel.InvitationToken{Token: "admin", Status: 1},
+	)
+	audit := mock.NewMockAuditLogger()
+	biz := referralbiz.NewRevokeSubtreeBiz(store, mock.NewMockReferralUserStore(), tokens, audit)
+
+	_, err := biz.RevokeSubtree(nil, 99, 100)
+	assert.Equal(t, referralmodel.ErrUserNotFound, err)
+
+	result, err := biz.RevokeSubtree(nil, 99, 2)
+	require.Nil(t, err, err)
+
+	assert.ElementsMatch(t, []int{2, 4, 5}, result.UserIds)
+	assert.ElementsMatch(t, []string{"from2", "from5"}, result.Tokens)
+	assert.Equal(t, []int{90}, result.SkippedAdminIds)
+	for id, disabled := range map[int]bool{1: false, 2: true, 3: false, 4: true, 5: true, 7: false, 90: false, 91: false} {
+		assert.Equal(t, disabled, store.IsDisabled(id), id)
+	}
+
+	require.Len(t, audit.Entries(), 1)
+	assert.Equal(t, auditmodel.ActionReferralRevoke, audit.Entries()[0].Action)
+	assert.ElementsMatch(t,
+		[]string{usermodel.InvitationTokenId("from2"), usermodel.InvitationTokenId("from5")},
+		audit.Entries()[0].After.(map[string]interface{})["token_ids"],
+	)
+}
+
+func TestReferralBiz_RevokeSubtreeRefused(t *testing.T) {
+	tcs := []struct {
+		name     string
+		callerId int
+		userId   int
+		expected error
+	}{
+		{"self", 3, 3, referralmodel.ErrRevokeSelf},
+		{"admin", 99, 90, referralmodel.ErrRevokeAdmin},
+		{"admin revoking self", 90, 90, referralmodel.ErrRevokeSelf},
+	}
+
+	for _, tc := range tcs {
+		store := mock.NewMockReferralStore(append(
+			referralTree(),
+			referralmodel.Referral{InviterId: 90, InviteeId: 91, Depth: 1},
+		)...)
+		audit := mock.NewMockAuditLogger()
+		biz := referralbiz.NewRevokeSubtreeBiz(store, mock.NewMockReferralUserStore(), mock.NewMockTokenDisabler(), audit)
+
+		_, err := biz.RevokeSubtree(nil, tc.callerId, tc.userId)
+		assert.Equal(t, tc.expected, err, tc.name)
+		for _, id := range []int{tc.userId, 91} {
+			assert.False(t, store.IsDisabled(id), tc.name)
+		}
+		assert.Empty(t, audit.Entries(), tc.name)
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,91 @@
+package referralmodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const EntityName = "Referral"
+
+var (
+	ErrReferralDisabled = common.NewFullErrorResponse(
+		http.StatusForbidden,
+		errors.New("referral disabled"),
+		"inviting other users is not enabled",
+		"referral disabled",
+		"ErrReferralDisabled",
+	)
+	ErrUserNotFound = common.NewFullErrorResponse(
+		http.StatusNotFound,
+		errors.New("user not found"),
+		"user not found",
+		"user not found",
+		"ErrUserNotFound",
+	)
+	ErrRevokeSelf = common.NewFullErrorResponse(
+		http.StatusForbidden,
+		errors.New("cannot revoke self"),
+		"you cannot revoke your own referral tree",
+		"cannot revoke self",
+		"ErrRevokeSelf",
+	)
+	ErrRevokeAdmin = common.NewFullErrorResponse(
+		http.StatusForbidden,
+		errors.New("cannot revoke admin"),
+		"admins cannot be revoked with their referral tree",
+		"cannot revoke admin",
+		"ErrRevokeAdmin",
+	)
+)
+
+// Referral is an inviter→invitee edge. Depth is how many referrals separate
+// the invitee from a user nobody invited.
+type Referral struct {
+	Id              int        `json:"id" gorm:"column:id;"`
+	InviterId       int        `json:"inviter_id" gorm:"column:inviter_id;"`
+	InviteeId       int        `json:"invitee_id" gorm:"column:invitee_id;"`
+	InvitationToken string     `json:"invitation_token" gorm:"column:invitation_token;"`
+	Depth           int        `json:"depth" gorm:"column:depth;"`
+	CreatedAt       *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+}
+
+func (Referral) TableName() string {
+	return "referrals"
+}
+
+// Invitee is a user someone invited, as shown in a downline
+type Invitee struct {
+	UserId    int        `json:"user_id" gorm:"column:user_id;"`
+	Email     string     `json:"email" gorm:"column:email;"`
+	Status    int        `json:"status" gorm:"column:status;"`
+	InvitedBy int        `json:"invited_by" gorm:"column:invited_by;"`
+	JoinedAt  *time.Time `json:"joined_at,omitempty" gorm:"column:joined_at;"`
+	Role      string     `json:"-" gorm:"column:role;"`
+	// Level is 1 for direct invitees, 2 for theirs and so on
+	Level int `json:"level" gorm:"-"`
+}
+
+// Downline is everyone a user brought in, directly or through their invitees
+type Downline struct {
+	UserId    int       `json:"user_id"`
+	Depth     int       `json:"depth"`
+	InvitedBy *int      `json:"invited_by"`
+	Size      int       `json:"size"`
+	Levels    int       `json:"levels"`
+	Members   []Invitee `json:"members"`
+}
+
+type PersonalInvitationCreate struct {
+	// Email optionally binds the invitation to its recipient
+	Email string `json:"email,omitempty" form:"email"`
+}
+
+type RevokeResult struct {
+	UserIds []int    `json:"user_ids"`
+	Tokens  []string `json:"tokens"`
+	// SkippedAdminIds are the admins found in the downline, which were left
+	// enabled along with everyone they invited
+	SkippedAdminIds []int `json:"skipped_admin_ids"`
+}
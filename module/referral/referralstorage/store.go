@@ -0,0 +1,73 @@
+package referralstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/referral/referralmodel"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type ISqlStore interface {
+	CreateReferral(_ context.Context, data *referralmodel.Referral) error
+	FindReferral(_ context.Context, conditions map[string]interface{}) (*referralmodel.Referral, error)
+	ListInvitees(_ context.Context, inviterIds []int) ([]referralmodel.Invitee, error)
+	DisableUsers(_ context.Context, userIds []int) error
+}
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+func NewSQLStore(db *gorm.DB) ISqlStore {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) CreateReferral(_ context.Context, data *referralmodel.Referral) error {
+	if err := s.db.Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) FindReferral(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*referralmodel.Referral, error) {
+	var referral referralmodel.Referral
+
+	if err := s.db.Table(referralmodel.Referral{}.TableName()).Where(conditions).First(&referral).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &referral, nil
+}
+
+// ListInvitees lists the users directly invited by any of inviterIds
+func (s *sqlStore) ListInvitees(_ context.Context, inviterIds []int) ([]referralmodel.Invitee, error) {
+	var invitees []referralmodel.Invitee
+
+	if err := s.db.Table(referralmodel.Referral{}.TableName()+" AS r").
+		Select("u.id AS user_id, u.email, u.status, u.role, r.inviter_id AS invited_by, r.created_at AS joined_at").
+		Joins("JOIN users AS u ON u.id = r.invitee_id").
+		Where("r.inviter_id IN ?", inviterIds).
+		Order("r.id").
+		Scan(&invitees).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return invitees, nil
+}
+
+// DisableUsers sets the status of every user in userIds to 0 in one statement
+func (s *sqlStore) DisableUsers(_ context.Context, userIds []int) error {
+	if err := s.db.Table("users").Where("id IN ?", userIds).Update("status", 0).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package ginreferral
+
+import (
+	"net/http"
+	"strconv"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/referral/referralbiz"
+	"app-invite-service/module/referral/referralmodel"
+	"app-invite-service/module/referral/referralstorage"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"app-invite-service/module/user/usertransport/ginuser"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GeneratePersonalInvitation(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data referralmodel.PersonalInvitationCreate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		user := c.MustGet(common.CurrentUser).(*usermodel.User)
+
+		biz := referralbiz.NewGeneratePersonalInvitationBiz(
+			ginuser.NewTokenGenerator(appCtx),
+			appCtx.GetConfig().Referral.Enabled,
+		)
+
+		result, err := biz.GeneratePersonalInvitation(c.Request.Context(), user, &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func getDownline(appCtx component.AppContext, c *gin.Context, userId int) {
+	db := appCtx.GetDBConn()
+	biz := referralbiz.NewGetDownlineBiz(referralstorage.NewSQLStore(db), userstorage.NewSQLStore(db))
+
+	result, err := biz.GetDownline(c.Request.Context(), userId)
+	if err != nil {
+		panic(err)
+	}
+
+	c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+}
+
+func GetMyDownline(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		getDownline(appCtx, c, c.MustGet(common.CurrentUser).(*usermodel.User).Id)
+	}
+}
+
+func GetUserDownline(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		getDownline(appCtx, c, id)
+	}
+}
+
+func RevokeSubtree(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		db := appCtx.GetDBConn()
+		audit := ginaudit.NewRecorder(appCtx)
+		biz := referralbiz.NewRevokeSubtreeBiz(
+			referralstorage.NewSQLStore(db),
+			userstorage.NewSQLStore(db),
+			userbiz.NewDisableTokensByCreatorBiz(userstorage.NewCachedInvitationTokenStore(db, appCtx.GetRedisConn()), audit),
+			audit,
+		)
+
+		callerId := c.MustGet(common.CurrentUser).(*usermodel.User).Id
+		result, err := biz.RevokeSubtree(c.Request.Context(), callerId, id)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
@@ -0,0 +1,227 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/mailer"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// EmailVerificationTokens keeps the single use tokens sent in verification
+// emails and throttles how often an address is sent one
+type EmailVerificationTokens interface {
+	SaveVerificationToken(ctx context.Context, token string, userId int, ttl time.Duration) error
+	TakeVerificationToken(ctx context.Context, token string) (int, error)
+	DeleteVerificationToken(ctx context.Context, token string) error
+	ThrottleVerification(ctx context.Context, email string, interval time.Duration) (bool, error)
+}
+
+var (
+	ErrEmailVerificationTokenInvalid = common.NewCustomError(
+		errors.New("email verification token invalid"),
+		"email verification token is invalid or expired",
+		"ErrEmailVerificationTokenInvalid",
+	)
+	ErrEmailVerificationThrottled = common.NewFullErrorResponse(
+		http.StatusTooManyRequests,
+		errors.New("email verification requested too often"),
+		"please wait before requesting another verification email",
+		"email verification requested too often",
+		"ErrEmailVerificationThrottled",
+	)
+)
+
+func ErrCannotSendVerificationEmail(err error) *common.AppError {
+	return common.NewCustomError(err, "cannot send verification email", "ErrCannotSendVerificationEmail")
+}
+
+func generateVerificationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Send email verification
+
+type ISendEmailVerificationBiz interface {
+	SendEmailVerification(ctx context.Context, userId int, email string) error
+}
+
+type sendEmailVerificationBiz struct {
+	tokens    EmailVerificationTokens
+	mailer    mailer.Mailer
+	publicURL string
+	expiry    int
+}
+
+func NewSendEmailVerificationBiz(
+	tokens EmailVerificationTokens,
+	mailer mailer.Mailer,
+	publicURL string,
+	expiry int,
+) ISendEmailVerificationBiz {
+	return &sendEmailVerificationBiz{tokens: tokens, mailer: mailer, publicURL: publicURL, expiry: expiry}
+}
+
+func (biz *sendEmailVerificationBiz) SendEmailVerification(ctx context.Context, userId int, email string) error {
+	token, err := generateVerificationToken()
+	if err != nil {
+		return common.ErrInternal(err)
+	}
+
+	if err := biz.tokens.SaveVerificationToken(ctx, token, userId, time.Duration(biz.expiry)*time.Second); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf(
+		"%s/api/v1/email/verification?token=%s",
+		strings.TrimRight(biz.publicURL, "/"),
+		url.QueryEscape(token),
+	)
+
+	msg := &mailer.Message{
+		To:      []string{email},
+		Subject: "Verify your email address",
+		Text:    fmt.Sprintf("Open the link below to verify your email address:\n\n%s\n", link),
+	}
+
+	if err := biz.mailer.Send(ctx, msg); err != nil {
+		_ = biz.tokens.DeleteVerificationToken(ctx, token)
+		return ErrCannotSendVerificationEmail(err)
+	}
+
+	return nil
+}
+
+// Confirm email with verification token
+
+type EmailVerificationStore interface {
+	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+	UpdateUser(ctx context.Context, id int, data map[string]interface{}) error
+}
+
+type IConfirmEmailBiz interface {
+	ConfirmEmail(ctx context.Context, token string) error
+}
+
+type confirmEmailBiz struct {
+	store  EmailVerificationStore
+	tokens EmailVerificationTokens
+	audit  AuditLogger
+}
+
+func NewConfirmEmailBiz(
+	store EmailVerificationStore,
+	tokens EmailVerificationTokens,
+	audit AuditLogger,
+) IConfirmEmailBiz {
+	return &confirmEmailBiz{store: store, tokens: tokens, audit: audit}
+}
+
+func (biz *confirmEmailBiz) ConfirmEmail(ctx context.Context, token string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return ErrEmailVerificationTokenInvalid
+	}
+
+	userId, err := biz.tokens.TakeVerificationToken(ctx, token)
+	if err == common.ErrRecordNotFound {
+		return ErrEmailVerificationTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	user, err := biz.store.FindUser(ctx, map[string]interface{}{"id": userId})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return ErrEmailVerificationTokenInvalid
+		}
+		return err
+	}
+
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	if err := biz.store.UpdateUser(ctx, user.Id, map[string]interface{}{"email_verified_at": now}); err != nil {
+		return err
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionUserVerifyEmail,
+		TargetType: auditmodel.TargetUser,
+		TargetId:   auditmodel.IntId(user.Id),
+		Before:     map[string]interface{}{"email_verified_at": nil},
+		After:      map[string]interface{}{"email_verified_at": now},
+	})
+
+	return nil
+}
+
+// Resend email verification
+
+type IResendEmailVerificationBiz interface {
+	ResendEmailVerification(ctx context.Context, data *usermodel.EmailVerificationResend) error
+}
+
+type resendEmailVerificationBiz struct {
+	store    EmailVerificationStore
+	tokens   EmailVerificationTokens
+	sender   ISendEmailVerificationBiz
+	interval int
+}
+
+func NewResendEmailVerificationBiz(
+	store EmailVerificationStore,
+	tokens EmailVerificationTokens,
+	sender ISendEmailVerificationBiz,
+	interval int,
+) IResendEmailVerificationBiz {
+	return &resendEmailVerificationBiz{store: store, tokens: tokens, sender: sender, interval: interval}
+}
+
+func (biz *resendEmailVerificationBiz) ResendEmailVerification(
+	ctx context.Context,
+	data *usermodel.EmailVerificationResend,
+) error {
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	// throttle by email before looking the user up, so unknown and known
+	// addresses behave the same way
+	ok, err := biz.tokens.ThrottleVerification(ctx, data.Email, time.Duration(biz.interval)*time.Second)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrEmailVerificationThrottled
+	}
+
+	user, err := biz.store.FindUser(ctx, map[string]interface{}{"email": data.Email})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	return biz.sender.SendEmailVerification(ctx, user.Id, user.Email)
+}
@@ -0,0 +1,86 @@
+package userbiz_test
+
+import (
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmEmailBiz_ConfirmEmail(t *testing.T) {
+	tokens := mock.NewMockEmailVerificationTokens()
+	require.Nil(t, tokens.SaveVerificationToken(nil, "valid", 4, time.Hour))
+	require.Nil(t, tokens.SaveVerificationToken(nil, "expired", 4, -time.Second))
+	audit := mock.NewMockAuditLogger()
+	biz := userbiz.NewConfirmEmailBiz(mock.NewMockUserStore(), tokens, audit)
+
+	tcs := []struct {
+		name    string
+		token   string
+		err     string
+		entries int
+	}{
+		{"valid", "valid", "", 1},
+		{"reused", "valid", "ErrEmailVerificationTokenInvalid", 1},
+		{"expired", "expired", "ErrEmailVerificationTokenInvalid", 1},
+		{"unknown", "unknown", "ErrEmailVerificationTokenInvalid", 1},
+		{"empty", " ", "ErrEmailVerificationTokenInvalid", 1},
+	}
+
+	for _, tc := range tcs {
+		err := biz.ConfirmEmail(nil, tc.token)
+		assert.Equal(t, tc.err, errKey(err), tc.name)
+		assert.Len(t, audit.Entries(), tc.entries, tc.name)
+	}
+}
+
+func TestResendEmailVerificationBiz_ResendEmailVerification(t *testing.T) {
+	sender := mock.NewMockEmailVerificationSender()
+	biz := userbiz.NewResendEmailVerificationBiz(
+		mock.NewMockUserStore(),
+		mock.NewMockEmailVerificationTokens(),
+		sender,
+		60,
+	)
+
+	tcs := []struct {
+		name  string
+		email string
+		err   string
+		sent  []string
+	}{
+		{"unverified", "unverified@gmail.com", "", []string{"unverified@gmail.com"}},
+		{"throttled", "unverified@gmail.com", "ErrEmailVerificationThrottled", []string{"unverified@gmail.com"}},
+		{"verified", "user@gmail.com", "", []string{"unverified@gmail.com"}},
+		{"unknown", "nobody@gmail.com", "", []string{"unverified@gmail.com"}},
+		{"unknown throttled too", "nobody@gmail.com", "ErrEmailVerificationThrottled", []string{"unverified@gmail.com"}},
+	}
+
+	for _, tc := range tcs {
+		err := biz.ResendEmailVerification(nil, &usermodel.EmailVerificationResend{Email: tc.email})
+		assert.Equal(t, tc.err, errKey(err), tc.name)
+		assert.Equal(t, tc.sent, sender.Sent(), tc.name)
+	}
+}
+
+func TestRegisterBiz_SucceedsWhenVerificationEmailFails(t *testing.T) {
+	biz := userbiz.NewRegisterBiz(
+		mock.NewMockUserStore(),
+		mock.NewMockHash(),
+		mock.NewFailingEmailVerificationSender(errors.New("smtp down")),
+		mock.NewMockInvitationChecker(),
+		mock.NewMockDomainChecker(),
+		mock.NewMockAuditLogger(),
+		mock.NewMockReferralRecorder(),
+		false,
+	)
+
+	data := usermodel.UserCreate{Email: "user1@gmail.com", Password: "user@123"}
+	require.Nil(t, biz.Register(nil, &data))
+	assert.NotZero(t, data.Id)
+}
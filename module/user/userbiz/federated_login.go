@@ -0,0 +1,333 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/oidc"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+type FederatedProvider interface {
+	Name() string
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*oidc.IdTokenClaims, error)
+}
+
+type FederatedStateStore interface {
+	SaveState(ctx context.Context, state string, data *usermodel.FederatedLoginState, expiry int) error
+	TakeState(ctx context.Context, state string) (*usermodel.FederatedLoginState, error)
+}
+
+type FederatedLoginStore interface {
+	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+	CreateUser(ctx context.Context, data *usermodel.UserCreate) error
+	DeleteUser(ctx context.Context, id int) error
+	FindIdentity(ctx context.Context, conditions map[string]interface{}) (*usermodel.UserIdentity, error)
+	CreateIdentity(ctx context.Context, data *usermodel.UserIdentity) error
+}
+
+// Start federated login
+
+type IStartFederatedLoginBiz interface {
+	StartFederatedLogin(
+		ctx context.Context,
+		data *usermodel.FederatedLoginStart,
+	) (*usermodel.FederatedLoginRedirect, error)
+}
+
+type startFederatedLoginBiz struct {
+	provider    FederatedProvider
+	states      FederatedStateStore
+	stateExpiry int
+}
+
+func NewStartFederatedLoginBiz(
+	provider FederatedProvider,
+	states FederatedStateStore,
+	stateExpiry int,
+) IStartFederatedLoginBiz {
+	return &startFederatedLoginBiz{provider: provider, states: states, stateExpiry: stateExpiry}
+}
+
+func (biz *startFederatedLoginBiz) StartFederatedLogin(
+	ctx context.Context,
+	data *usermodel.FederatedLoginStart,
+) (*usermodel.FederatedLoginRedirect, error) {
+	var values [3]string
+	for i := range values {
+		v, err := generateVerificationToken()
+		if err != nil {
+			return nil, common.ErrInternal(err)
+		}
+		values[i] = v
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	redirectTo, err := biz.provider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return nil, usermodel.ErrFederatedLoginFailed(err)
+	}
+
+	if err := biz.states.SaveState(ctx, state, &usermodel.FederatedLoginState{
+		Provider:        biz.provider.Name(),
+		Nonce:           nonce,
+		CodeVerifier:    verifier,
+		InvitationToken: strings.TrimSpace(data.InvitationToken),
+		LinkUserId:      data.LinkUserId,
+	}, biz.stateExpiry); err != nil {
+		return nil, err
+	}
+
+	return &usermodel.FederatedLoginRedirect{RedirectTo: redirectTo}, nil
+}
+
+// Finish federated login
+
+type IFederatedLoginBiz interface {
+	FederatedLogin(ctx context.Context, data *usermodel.FederatedLoginCallback) (*usermodel.Account, error)
+}
+
+type federatedLoginBiz struct {
+	provider           FederatedProvider
+	states             FederatedStateStore
+	store              FederatedLoginStore
+	hash               Hash
+	invites            InvitationChecker
+	domains            DomainChecker
+	audit              AuditLogger
+	referrals          ReferralRecorder
+	tokenProvider      tokenprovider.Provider
+	tokenConfig        *tokenprovider.TokenConfig
+	mfaChallengeExpiry int
+}
+
+func NewFederatedLoginBiz(
+	provider FederatedProvider,
+	states FederatedStateStore,
+	store FederatedLoginStore,
+	hash Hash,
+	invites InvitationChecker,
+	domains DomainChecker,
+	audit AuditLogger,
+	referrals ReferralRecorder,
+	tokenProvider tokenprovider.Provider,
+	tokenConfig *tokenprovider.TokenConfig,
+	mfaChallengeExpiry int,
+) IFederatedLoginBiz {
+	return &federatedLoginBiz{
+		provider:           provider,
+		states:             states,
+		store:              store,
+		hash:               hash,
+		invites:            invites,
+		domains:            domains,
+		audit:              audit,
+		referrals:          referrals,
+		tokenProvider:      tokenProvider,
+		tokenConfig:        tokenConfig,
+		mfaChallengeExpiry: mfaChallengeExpiry,
+	}
+}
+
+// FederatedLogin signs in the user an upstream identity is linked to. An
+// identity without a link is attached to the signed in user who started
+// linking it, or, given a valid invitation token, to a newly provisioned
+// user. It is never attached to an existing user just because the emails
+// match: whoever controls that address upstream would take over the account
+// and skip its password and MFA.
+func (biz *federatedLoginBiz) FederatedLogin(
+	ctx context.Context,
+	data *usermodel.FederatedLoginCallback,
+) (*usermodel.Account, error) {
+	state, err := biz.states.TakeState(ctx, data.State)
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, usermodel.ErrFederatedStateInvalid
+		}
+		return nil, err
+	}
+
+	if state.Provider != biz.provider.Name() {
+		return nil, usermodel.ErrFederatedStateInvalid
+	}
+
+	claims, err := biz.provider.Exchange(ctx, data.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		return nil, usermodel.ErrFederatedLoginFailed(err)
+	}
+
+	user, err := biz.findLinkedUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case user != nil && state.LinkUserId != 0 && user.Id != state.LinkUserId:
+		return nil, usermodel.ErrFederatedIdentityLinked
+	case user == nil && state.LinkUserId != 0:
+		user, err = biz.store.FindUser(ctx, map[string]interface{}{"id": state.LinkUserId})
+		if err != nil {
+			return nil, err
+		}
+		if err := biz.linkUser(ctx, claims, user); err != nil {
+			return nil, err
+		}
+	case user == nil:
+		if user, err = biz.provisionLinkedUser(ctx, claims, state.InvitationToken); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.Status == 0 {
+		return nil, common.ErrNoPermission(errors.New("user has been deleted or banned"))
+	}
+
+	// the upstream provider may not do MFA, so ours still applies
+	if user.IsMfaEnabled() {
+		return issueMfaChallenge(biz.tokenProvider, user.Id, biz.mfaChallengeExpiry)
+	}
+
+	return issueAccount(biz.tokenProvider, biz.tokenConfig, user.Id)
+}
+
+func (biz *federatedLoginBiz) findLinkedUser(
+	ctx context.Context,
+	claims *oidc.IdTokenClaims,
+) (*usermodel.User, error) {
+	identity, err := biz.store.FindIdentity(ctx, map[string]interface{}{
+		"provider": biz.provider.Name(),
+		"subject":  claims.Subject,
+	})
+	if err == common.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return biz.store.FindUser(ctx, map[string]interface{}{"id": identity.UserId})
+}
+
+// provisionLinkedUser creates the user of an identity seen for the first
+// time and links the identity to it
+func (biz *federatedLoginBiz) provisionLinkedUser(
+	ctx context.Context,
+	claims *oidc.IdTokenClaims,
+	invitationToken string,
+) (*usermodel.User, error) {
+	// the new user gets the upstream email as a verified one
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, usermodel.ErrFederatedEmailNotVerified
+	}
+
+	existing, err := biz.store.FindUser(ctx, map[string]interface{}{"email": claims.Email})
+	if err != nil && err != common.ErrRecordNotFound {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, usermodel.ErrFederatedAccountExists
+	}
+
+	user, err := biz.provisionUser(ctx, claims.Email, invitationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := biz.linkUser(ctx, claims, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (biz *federatedLoginBiz) linkUser(
+	ctx context.Context,
+	claims *oidc.IdTokenClaims,
+	user *usermodel.User,
+) error {
+	identity := usermodel.UserIdentity{
+		UserId:   user.Id,
+		Provider: biz.provider.Name(),
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	if err := biz.store.CreateIdentity(ctx, &identity); err != nil {
+		return common.ErrCannotCreateEntity("UserIdentity", err)
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionUserLinkIdentity,
+		TargetType: auditmodel.TargetUser,
+		TargetId:   auditmodel.IntId(user.Id),
+		After:      identity,
+	})
+
+	return nil
+}
+
+func (biz *federatedLoginBiz) provisionUser(
+	ctx context.Context,
+	email, invitationToken string,
+) (*usermodel.User, error) {
+	if invitationToken == "" {
+		return nil, usermodel.ErrFederatedInvitationRequired
+	}
+
+	if err := biz.domains.CheckEmailDomain(ctx, usermodel.EmailDomain(email)); err != nil {
+		return nil, err
+	}
+
+	invitation, err := biz.invites.ValidateInvitationTokenForEmail(ctx, invitationToken, email)
+	if err != nil {
+		return nil, err
+	}
+
+	// federated users sign in upstream, so they get a password nobody knows
+	password, err := generateVerificationToken()
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	salt := common.GenSalt(50)
+	now := time.Now()
+	data := usermodel.UserCreate{
+		Email:           email,
+		Password:        biz.hash.Hash(password + salt),
+		Salt:            salt,
+		EmailVerifiedAt: &now,
+	}
+
+	if err := createInvitedUser(ctx, biz.store, biz.invites, invitation, &data); err != nil {
+		return nil, err
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionUserProvision,
+		TargetType: auditmodel.TargetUser,
+		TargetId:   auditmodel.IntId(data.Id),
+		After:      map[string]interface{}{"email": email, "invitation_token_id": invitationTokenId(invitation)},
+	})
+
+	if err := recordReferral(ctx, biz.referrals, invitation, data.Id); err != nil {
+		return nil, err
+	}
+
+	return &usermodel.User{
+		Id:              data.Id,
+		Status:          1,
+		Email:           data.Email,
+		Role:            data.Role,
+		EmailVerifiedAt: data.EmailVerifiedAt,
+	}, nil
+}
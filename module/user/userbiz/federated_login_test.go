@@ -0,0 +1,106 @@
+package userbiz_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/component/oidc"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+)
+
+func TestFederatedLoginBiz_FederatedLogin(t *testing.T) {
+	server := mock.NewOIDCServer()
+	defer server.Close()
+
+	provider := oidc.NewRelyingParty(server.ProviderConfig("http://127.0.0.1:5000/callback"), nil)
+	states := mock.NewMockFederatedStateStore()
+	store := mock.NewMockUserStore()
+
+	start := userbiz.NewStartFederatedLoginBiz(provider, states, 600)
+	biz := userbiz.NewFederatedLoginBiz(
+		provider,
+		states,
+		store,
+		mock.NewMockHash(),
+		mock.NewMockInvitationChecker(),
+		mock.NewMockDomainChecker(),
+		mock.NewMockAuditLogger(),
+		mock.NewMockReferralRecorder(),
+		mock.NewMockProvider(),
+		&tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800},
+		300,
+	)
+
+	tcs := []struct {
+		name            string
+		user            mock.OIDCUser
+		invitationToken string
+		linkUserId      int
+		expectedErr     error
+		expectedMfa     bool
+	}{
+		{"unverified email", mock.OIDCUser{Subject: "s-1", Email: "new@corp.com"}, "invite123", 0, usermodel.ErrFederatedEmailNotVerified, false},
+		{"existing user is not linked by email", mock.OIDCUser{Subject: "s-1", Email: "user@gmail.com", EmailVerified: true}, "", 0, usermodel.ErrFederatedAccountExists, false},
+		{"existing user with invitation", mock.OIDCUser{Subject: "s-1", Email: "user@gmail.com", EmailVerified: true}, "invite123", 0, usermodel.ErrFederatedAccountExists, false},
+		{"admin is not linked by email", mock.OIDCUser{Subject: "s-9", Email: "admin@gmail.com", EmailVerified: true}, "", 0, usermodel.ErrFederatedAccountExists, false},
+		{"signed in user links", mock.OIDCUser{Subject: "s-1", Email: "other@corp.com"}, "", 1, nil, false},
+		// linked by subject now, the email upstream no longer matters
+		{"linked identity", mock.OIDCUser{Subject: "s-1", Email: "renamed@corp.com"}, "", 0, nil, false},
+		{"identity linked to someone else", mock.OIDCUser{Subject: "s-1", Email: "renamed@corp.com"}, "", 5, usermodel.ErrFederatedIdentityLinked, false},
+		{"new user without invitation", mock.OIDCUser{Subject: "s-2", Email: "new@corp.com", EmailVerified: true}, "", 0, usermodel.ErrFederatedInvitationRequired, false},
+		{"new user with bad invitation", mock.OIDCUser{Subject: "s-2", Email: "new@corp.com", EmailVerified: true}, "nope", 0, errors.New("invite token not existed"), false},
+		{"new user in blocked domain", mock.OIDCUser{Subject: "s-2", Email: "new@blocked.com", EmailVerified: true}, "invite123", 0, errors.New("email domain not allowed"), false},
+		{"provisions new user", mock.OIDCUser{Subject: "s-2", Email: "new@corp.com", EmailVerified: true}, "invite123", 0, nil, false},
+		{"mfa user links", mock.OIDCUser{Subject: "s-3", Email: "mfa@gmail.com", EmailVerified: true}, "", 5, nil, true},
+		{"mfa user signs in", mock.OIDCUser{Subject: "s-3", Email: "mfa@gmail.com", EmailVerified: true}, "", 0, nil, true},
+	}
+
+	for _, tc := range tcs {
+		redirect, err := start.StartFederatedLogin(context.Background(), &usermodel.FederatedLoginStart{
+			InvitationToken: tc.invitationToken,
+			LinkUserId:      tc.linkUserId,
+		})
+		require.Nil(t, err, tc.name)
+
+		u, err := url.Parse(redirect.RedirectTo)
+		require.Nil(t, err, tc.name)
+
+		code := server.SignIn(redirect.RedirectTo, tc.user)
+		state := u.Query().Get("state")
+
+		account, err := biz.FederatedLogin(context.Background(), &usermodel.FederatedLoginCallback{Code: code, State: state})
+		if tc.expectedErr != nil {
+			require.NotNil(t, err, tc.name)
+			assert.Contains(t, err.Error(), tc.expectedErr.Error(), tc.name)
+			continue
+		}
+
+		require.Nil(t, err, "%s: %v", tc.name, err)
+		if tc.expectedMfa {
+			assert.NotNil(t, account.MfaToken, tc.name)
+			assert.Nil(t, account.AccessToken, tc.name)
+		} else {
+			assert.NotNil(t, account.AccessToken, tc.name)
+		}
+	}
+
+	identities := store.Identities()
+	require.Len(t, identities, 3)
+	assert.Equal(t, 1, identities[0].UserId)
+	assert.Equal(t, "s-2", identities[1].Subject)
+	assert.Equal(t, 3, identities[1].UserId)
+	for _, identity := range identities {
+		assert.NotEqual(t, 6, identity.UserId, "admin must not be linked")
+	}
+
+	_, err := biz.FederatedLogin(context.Background(), &usermodel.FederatedLoginCallback{Code: "code", State: "unknown"})
+	assert.Equal(t, usermodel.ErrFederatedStateInvalid, err)
+}
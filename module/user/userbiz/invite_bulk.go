@@ -0,0 +1,168 @@
+package userbiz
+
+import (
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/user/usermodel"
+	"context"
+)
+
+// Delete invitation token
+
+type IDeleteInvitationTokenBiz interface {
+	DeleteInvitationToken(ctx context.Context, token string) error
+}
+
+type deleteInvitationTokenBiz struct {
+	store InvitationTokenStore
+	audit AuditLogger
+}
+
+func NewDeleteInvitationTokenBiz(store InvitationTokenStore, audit AuditLogger) IDeleteInvitationTokenBiz {
+	return &deleteInvitationTokenBiz{store: store, audit: audit}
+}
+
+func (biz *deleteInvitationTokenBiz) DeleteInvitationToken(ctx context.Context, token string) error {
+	foundToken, err := findInvitationToken(ctx, biz.store, token)
+	if err != nil {
+		return err
+	}
+
+	if err := biz.store.DeleteInvitationToken(ctx, foundToken.Token); err != nil {
+		return err
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionInvitationDelete,
+		TargetType: auditmodel.TargetInvitationToken,
+		TargetId:   usermodel.InvitationTokenId(foundToken.Token),
+		Before:     foundToken.Audited(),
+	})
+
+	return nil
+}
+
+// Bulk update invitation tokens
+
+type IBulkUpdateInvitationTokensBiz interface {
+	BulkUpdateInvitationTokens(
+		ctx context.Context,
+		data *usermodel.InvitationTokenBulkUpdate,
+	) ([]usermodel.InvitationTokenBulkResult, error)
+}
+
+type bulkUpdateInvitationTokensBiz struct {
+	store InvitationTokenStore
+	audit AuditLogger
+}
+
+func NewBulkUpdateInvitationTokensBiz(store InvitationTokenStore, audit AuditLogger) IBulkUpdateInvitationTokensBiz {
+	return &bulkUpdateInvitationTokensBiz{store: store, audit: audit}
+}
+
+// bulkChange is a token a bulk update changes, as it was before
+type bulkChange struct {
+	before usermodel.InvitationToken
+	after  *usermodel.InvitationToken
+}
+
+// BulkUpdateInvitationTokens disables or deletes every token at once: the
+// tokens are locked while they are read and changed in one transaction, so
+// either all of them change or none does.
+func (biz *bulkUpdateInvitationTokensBiz) BulkUpdateInvitationTokens(
+	ctx context.Context,
+	data *usermodel.InvitationTokenBulkUpdate,
+) ([]usermodel.InvitationTokenBulkResult, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	// a filter looks at every token
+	tokens := data.Tokens
+	if data.Filter != nil {
+		tokens = nil
+	}
+
+	var results []usermodel.InvitationTokenBulkResult
+	var changes []bulkChange
+	if err := biz.store.UpdateInvitationTokens(ctx, tokens, func(
+		found []usermodel.InvitationToken,
+	) ([]usermodel.InvitationToken, []string, error) {
+		results, changes = biz.plan(data, found)
+
+		var saved []usermodel.InvitationToken
+		var deleted []string
+		for _, change := range changes {
+			if change.after == nil {
+				deleted = append(deleted, change.before.Token)
+				continue
+			}
+			saved = append(saved, *change.after)
+		}
+		return saved, deleted, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		entry := auditmodel.Entry{
+			Action:     auditmodel.ActionInvitationDelete,
+			TargetType: auditmodel.TargetInvitationToken,
+			TargetId:   usermodel.InvitationTokenId(change.before.Token),
+			Before:     change.before.Audited(),
+		}
+		if change.after != nil {
+			entry.Action = auditmodel.ActionInvitationUpdate
+			entry.After = change.after.Audited()
+		}
+		biz.audit.Record(ctx, &entry)
+	}
+
+	return results, nil
+}
+
+// plan decides what happens to every token found, and reports listed tokens
+// which weren't found
+func (biz *bulkUpdateInvitationTokensBiz) plan(
+	data *usermodel.InvitationTokenBulkUpdate,
+	found []usermodel.InvitationToken,
+) ([]usermodel.InvitationTokenBulkResult, []bulkChange) {
+	byToken := make(map[string]usermodel.InvitationToken, len(found))
+	keys := data.Tokens
+	for _, token := range found {
+		byToken[token.Token] = token
+		if data.Filter != nil {
+			keys = append(keys, token.Token)
+		}
+	}
+
+	results := []usermodel.InvitationTokenBulkResult{}
+	var changes []bulkChange
+	for _, key := range keys {
+		token, ok := byToken[key]
+		if !ok {
+			results = append(results, usermodel.InvitationTokenBulkResult{Token: key, Result: usermodel.BulkResultNotFound})
+			continue
+		}
+
+		if data.Filter != nil && !data.Filter.Matches(&token) {
+			continue
+		}
+
+		result := usermodel.InvitationTokenBulkResult{Token: key}
+		switch {
+		case data.Action == usermodel.BulkActionDelete:
+			result.Result = usermodel.BulkResultDeleted
+			changes = append(changes, bulkChange{before: token})
+		case !token.Status.CanTransitionTo(usermodel.InvitationStatusDisabled):
+			result.Result = usermodel.BulkResultUnchanged
+		default:
+			result.Result = usermodel.BulkResultDisabled
+			after := token
+			after.Status = usermodel.InvitationStatusDisabled
+			changes = append(changes, bulkChange{before: token, after: &after})
+		}
+		results = append(results, result)
+	}
+
+	return results, changes
+}
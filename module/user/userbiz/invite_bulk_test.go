@@ -0,0 +1,52 @@
+package userbiz_test
+
+import (
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkUpdateInvitationTokensBiz_BulkUpdateInvitationTokens(t *testing.T) {
+	spring := newStoredToken("Spring01", usermodel.InvitationStatusActive, time.Hour)
+	spring.BatchId = "spring"
+	redeemed := newStoredToken("Spring02", usermodel.InvitationStatusRedeemed, time.Hour)
+	redeemed.BatchId = "spring"
+	store := mock.NewMockInvitationTokenStore(spring, redeemed, newStoredToken("Autumn01", usermodel.InvitationStatusActive, time.Hour))
+	audit := mock.NewMockAuditLogger()
+	biz := userbiz.NewBulkUpdateInvitationTokensBiz(store, audit)
+
+	results, err := biz.BulkUpdateInvitationTokens(nil, &usermodel.InvitationTokenBulkUpdate{
+		Action: usermodel.BulkActionDisable,
+		Filter: &usermodel.InvitationTokenBulkFilter{BatchId: "spring"},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, []usermodel.InvitationTokenBulkResult{
+		{Token: "Spring01", Result: usermodel.BulkResultDisabled},
+		{Token: "Spring02", Result: usermodel.BulkResultUnchanged},
+	}, results)
+	disabled, _ := store.Token("Spring01")
+	assert.Equal(t, usermodel.InvitationStatusDisabled, disabled.Status)
+	require.Len(t, audit.Entries(), 1)
+	// tokens are bearer credentials and are audited by their id only
+	entry := audit.Entries()[0]
+	assert.Equal(t, usermodel.InvitationTokenId("Spring01"), entry.TargetId)
+	assert.Equal(t, entry.TargetId, entry.After.(usermodel.InvitationToken).Token)
+
+	results, err = biz.BulkUpdateInvitationTokens(nil, &usermodel.InvitationTokenBulkUpdate{
+		Action: usermodel.BulkActionDelete,
+		Tokens: []string{"Autumn01", "Missing1"},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, []usermodel.InvitationTokenBulkResult{
+		{Token: "Autumn01", Result: usermodel.BulkResultDeleted},
+		{Token: "Missing1", Result: usermodel.BulkResultNotFound},
+	}, results)
+	_, ok := store.Token("Autumn01")
+	assert.False(t, ok)
+	assert.Len(t, audit.Entries(), 2)
+}
@@ -0,0 +1,35 @@
+package userbiz
+
+import (
+	"app-invite-service/module/user/usermodel"
+	"context"
+)
+
+// InvitationTokenCache is the redis copy of the invitation tokens kept in MySQL
+type InvitationTokenCache interface {
+	RebuildCache(ctx context.Context, backfill bool) (*usermodel.InvitationTokenCacheRebuild, error)
+}
+
+// Rebuild invitation token cache
+
+type IRebuildInvitationTokenCacheBiz interface {
+	RebuildInvitationTokenCache(ctx context.Context, backfill bool) (*usermodel.InvitationTokenCacheRebuild, error)
+}
+
+type rebuildInvitationTokenCacheBiz struct {
+	cache InvitationTokenCache
+}
+
+func NewRebuildInvitationTokenCacheBiz(cache InvitationTokenCache) IRebuildInvitationTokenCacheBiz {
+	return &rebuildInvitationTokenCacheBiz{cache: cache}
+}
+
+// RebuildInvitationTokenCache repopulates redis from MySQL, after redis was
+// flushed or evicted tokens. Tokens issued before they were kept in MySQL
+// are only in redis; backfill saves them to MySQL instead of dropping them.
+func (biz *rebuildInvitationTokenCacheBiz) RebuildInvitationTokenCache(
+	ctx context.Context,
+	backfill bool,
+) (*usermodel.InvitationTokenCacheRebuild, error) {
+	return biz.cache.RebuildCache(ctx, backfill)
+}
@@ -0,0 +1,160 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/mailer"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/user/usermodel"
+	"bytes"
+	"context"
+	"html/template"
+	"net/url"
+	ttemplate "text/template"
+	"time"
+)
+
+func ErrCannotSendInvitationEmail(err error) *common.AppError {
+	return common.NewCustomError(err, "cannot send invitation email", "ErrCannotSendInvitationEmail")
+}
+
+type invitationEmailData struct {
+	AppName   string
+	Email     string
+	Token     string
+	Link      template.URL
+	ExpiresAt time.Time
+}
+
+// RenderDeepLink executes the configured deep link template, e.g.
+// `myapp://invite?code={{.Token}}`, with a query-escaped token.
+func RenderDeepLink(deepLink, token string) (string, error) {
+	tpl, err := ttemplate.New("deep_link").Parse(deepLink)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, map[string]string{"Token": url.QueryEscape(token)}); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Invite by email
+
+type IInviteByEmailBiz interface {
+	InviteByEmail(ctx context.Context, data *usermodel.InvitationEmailCreate) (*usermodel.InvitationToken, error)
+}
+
+type inviteByEmailBiz struct {
+	generator IGenerateTokenBiz
+	store     InvitationTokenStore
+	mailer    mailer.Mailer
+	template  *mailer.Template
+	audit     AuditLogger
+	appName   string
+	deepLink  string
+}
+
+func NewInviteByEmailBiz(
+	generator IGenerateTokenBiz,
+	store InvitationTokenStore,
+	mailer mailer.Mailer,
+	template *mailer.Template,
+	audit AuditLogger,
+	appName string,
+	deepLink string,
+) IInviteByEmailBiz {
+	return &inviteByEmailBiz{
+		generator: generator,
+		store:     store,
+		mailer:    mailer,
+		template:  template,
+		audit:     audit,
+		appName:   appName,
+		deepLink:  deepLink,
+	}
+}
+
+func (biz *inviteByEmailBiz) InviteByEmail(
+	ctx context.Context,
+	data *usermodel.InvitationEmailCreate,
+) (*usermodel.InvitationToken, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	token, err := biz.generator.GenerateToken(ctx, &usermodel.InvitationTokenCreate{
+		Email:      data.Email,
+		BatchId:    data.BatchId,
+		CampaignId: data.CampaignId,
+		CreatedBy:  data.CreatedBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, common.ErrCannotCreateEntity("InvitationToken", nil)
+	}
+
+	link, err := RenderDeepLink(biz.deepLink, token.Token)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	expiresAt := time.Now().UTC().Add(common.InviteTokenExpirySecond * time.Second)
+	if token.ExpiresAt != nil {
+		expiresAt = *token.ExpiresAt
+	}
+
+	msg, err := biz.template.Render([]string{data.Email}, &invitationEmailData{
+		AppName:   biz.appName,
+		Email:     data.Email,
+		Token:     token.Token,
+		Link:      template.URL(link),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	// the invitation shows it is being sent until the outcome is recorded
+	// on it, so a send that never finishes isn't mistaken for no send
+	token.DeliveryStatus = usermodel.DeliveryStatusPending
+	if err := updateInvitationToken(ctx, biz.store, token); err != nil {
+		return nil, err
+	}
+
+	// record the delivery outcome on the invitation itself
+	sendErr := biz.mailer.Send(ctx, msg)
+	if sendErr != nil {
+		token.DeliveryStatus = usermodel.DeliveryStatusFailed
+		token.DeliveryError = sendErr.Error()
+	} else {
+		now := time.Now().UTC()
+		token.DeliveryStatus = usermodel.DeliveryStatusSent
+		token.DeliveredAt = &now
+	}
+
+	if err := updateInvitationToken(ctx, biz.store, token); err != nil {
+		return nil, err
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionInvitationEmail,
+		TargetType: auditmodel.TargetInvitationToken,
+		TargetId:   usermodel.InvitationTokenId(token.Token),
+		After: map[string]interface{}{
+			"email":           data.Email,
+			"delivery_status": token.DeliveryStatus,
+			"delivery_error":  token.DeliveryError,
+		},
+	})
+
+	if sendErr != nil {
+		return nil, ErrCannotSendInvitationEmail(sendErr)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,108 @@
+package userbiz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/component/mailer"
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+)
+
+func TestInviteEmail_RenderDeepLink(t *testing.T) {
+	var tcs = []struct {
+		deepLink string
+		token    string
+		expected string
+		hasErr   bool
+	}{
+		{"myapp://invite?code={{.Token}}", "AbC123", "myapp://invite?code=AbC123", false},
+		{"https://evite.io/invite/{{.Token}}", "AbC123", "https://evite.io/invite/AbC123", false},
+		{"myapp://invite?code={{.Token}}", "a b&c", "myapp://invite?code=a+b%26c", false},
+		{"myapp://invite?code={{.Token", "AbC123", "", true},
+	}
+
+	for _, tc := range tcs {
+		output, err := userbiz.RenderDeepLink(tc.deepLink, tc.token)
+		if tc.hasErr {
+			assert.Error(t, err)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, output, "they should be equal")
+	}
+}
+
+// pendingCheckMailer fails the send unless the invitation is stored as
+// pending while it is sent
+type pendingCheckMailer struct {
+	store interface {
+		Token(token string) (usermodel.InvitationToken, bool)
+	}
+	token string
+}
+
+func (m *pendingCheckMailer) Send(_ context.Context, _ *mailer.Message) error {
+	token, _ := m.store.Token(m.token)
+	if token.DeliveryStatus != usermodel.DeliveryStatusPending {
+		return errors.New("invitation is not pending while it is sent")
+	}
+	return nil
+}
+
+func TestInviteByEmailBiz_InviteByEmail(t *testing.T) {
+	tmpl, err := mailer.NewTemplate("Invitation", "{{.Link}}", "")
+	require.Nil(t, err)
+
+	// the generator stores the token it makes
+	store := mock.NewMockInvitationTokenStore(newStoredToken("token1", usermodel.InvitationStatusActive, time.Hour))
+	audit := mock.NewMockAuditLogger()
+	biz := userbiz.NewInviteByEmailBiz(
+		mock.NewMockTokenGenerator(),
+		store,
+		&pendingCheckMailer{store: store, token: "token1"},
+		tmpl,
+		audit,
+		"evite",
+		"myapp://invite?code={{.Token}}",
+	)
+
+	token, err := biz.InviteByEmail(nil, &usermodel.InvitationEmailCreate{Email: "friend@gmail.com"})
+	require.Nil(t, err)
+	assert.Equal(t, usermodel.DeliveryStatusSent, token.DeliveryStatus)
+	assert.NotNil(t, token.DeliveredAt)
+
+	stored, ok := store.Token("token1")
+	require.True(t, ok)
+	assert.Equal(t, usermodel.DeliveryStatusSent, stored.DeliveryStatus)
+	assert.Len(t, audit.Entries(), 1)
+}
+
+func TestInviteByEmailBiz_TokenDeletedMeanwhile(t *testing.T) {
+	tmpl, err := mailer.NewTemplate("Invitation", "{{.Link}}", "")
+	require.Nil(t, err)
+
+	// the generated token is gone before it is marked pending, and must not
+	// be stored again by sending it
+	store := mock.NewMockInvitationTokenStore()
+	biz := userbiz.NewInviteByEmailBiz(
+		mock.NewMockTokenGenerator(),
+		store,
+		&pendingCheckMailer{store: store, token: "token1"},
+		tmpl,
+		mock.NewMockAuditLogger(),
+		"evite",
+		"myapp://invite?code={{.Token}}",
+	)
+
+	_, err = biz.InviteByEmail(nil, &usermodel.InvitationEmailCreate{Email: "friend@gmail.com"})
+	assert.Equal(t, "ErrInviteTokenNotExisted", errKey(err))
+	_, ok := store.Token("token1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,478 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/campaign/campaignmodel"
+	"app-invite-service/module/user/usermodel"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var tokenCSVHeader = []string{
+	"token", "status", "email", "email_domain", "delivery_status", "delivery_error", "delivered_at",
+	"batch_id", "campaign_id", "created_by", "created_at", "not_before", "ttl",
+}
+
+// maxImportLine is the longest line a JSON Lines import may have
+const maxImportLine = 1 << 20
+
+// Export invitation tokens
+
+type IExportInvitationTokensBiz interface {
+	ExportInvitationTokens(ctx context.Context, query *usermodel.InvitationTokenExportQuery, w io.Writer) error
+}
+
+type exportInvitationTokensBiz struct {
+	store InvitationTokenStore
+	audit AuditLogger
+}
+
+func NewExportInvitationTokensBiz(store InvitationTokenStore, audit AuditLogger) IExportInvitationTokensBiz {
+	return &exportInvitationTokensBiz{store: store, audit: audit}
+}
+
+// ExportInvitationTokens writes every token which hasn't expired to w,
+// oldest first, with the seconds it has left
+func (biz *exportInvitationTokensBiz) ExportInvitationTokens(
+	ctx context.Context,
+	query *usermodel.InvitationTokenExportQuery,
+	w io.Writer,
+) error {
+	if err := query.Validate(); err != nil {
+		return err
+	}
+
+	tokens, err := biz.store.ListInvitationTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	records := make([]usermodel.InvitationTokenRecord, 0, len(tokens))
+	for i := range tokens {
+		if record := usermodel.NewInvitationTokenRecord(&tokens[i], now); record.TTL > 0 {
+			records = append(records, record)
+		}
+	}
+
+	if err := writeInvitationTokenRecords(query.Format, records, w); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionInvitationExport,
+		TargetType: auditmodel.TargetInvitationToken,
+		After:      map[string]interface{}{"format": query.Format, "tokens": len(records)},
+	})
+
+	return nil
+}
+
+// Import invitation tokens
+
+type IImportInvitationTokensBiz interface {
+	ImportInvitationTokens(
+		ctx context.Context,
+		data *usermodel.InvitationTokenImport,
+		r io.Reader,
+	) (*usermodel.InvitationTokenImportResult, error)
+}
+
+type importInvitationTokensBiz struct {
+	store     InvitationTokenStore
+	campaigns CampaignPolicy
+	audit     AuditLogger
+	maxTTL    time.Duration
+}
+
+// NewImportInvitationTokensBiz imports tokens living at most maxTTL, the
+// furthest an admin may move the expiry of a token
+func NewImportInvitationTokensBiz(
+	store InvitationTokenStore,
+	campaigns CampaignPolicy,
+	audit AuditLogger,
+	maxTTL time.Duration,
+) IImportInvitationTokensBiz {
+	return &importInvitationTokensBiz{store: store, campaigns: campaigns, audit: audit, maxTTL: maxTTL}
+}
+
+// importChange is a token an import saves, with the token it overwrites
+type importChange struct {
+	before *usermodel.InvitationToken
+	after  usermodel.InvitationToken
+}
+
+// ImportInvitationTokens saves the exported tokens read from r, each
+// expiring as many seconds from now as it had left when exported. The
+// tokens which exist already are locked while the import is made in one
+// transaction, so either every token is imported or none is.
+func (biz *importInvitationTokensBiz) ImportInvitationTokens(
+	ctx context.Context,
+	data *usermodel.InvitationTokenImport,
+	r io.Reader,
+) (*usermodel.InvitationTokenImportResult, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	campaigns := map[int]*campaignmodel.Campaign{}
+	records, err := readInvitationTokenRecords(data.Format, r, func(record *usermodel.InvitationTokenRecord) error {
+		return biz.checkRecord(ctx, record, now, campaigns)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(records))
+	for i := range records {
+		keys[i] = records[i].Token
+	}
+
+	var result usermodel.InvitationTokenImportResult
+	var changes []importChange
+	if err := biz.store.UpdateInvitationTokens(ctx, keys, func(
+		found []usermodel.InvitationToken,
+	) ([]usermodel.InvitationToken, []string, error) {
+		result, changes = usermodel.InvitationTokenImportResult{DryRun: data.DryRun}, nil
+
+		existing := make(map[string]*usermodel.InvitationToken, len(found))
+		for i := range found {
+			existing[found[i].Token] = &found[i]
+		}
+
+		var conflicts []string
+		for i := range records {
+			before := existing[records[i].Token]
+			switch {
+			case before == nil:
+				result.Created++
+			case data.Conflict == usermodel.ImportConflictFail:
+				conflicts = append(conflicts, before.Token)
+				continue
+			case data.Conflict == usermodel.ImportConflictSkip:
+				result.Skipped++
+				continue
+			default:
+				result.Overwritten++
+			}
+			changes = append(changes, importChange{before: before, after: records[i].InvitationToken(now)})
+		}
+
+		if len(conflicts) > 0 {
+			return nil, nil, usermodel.ErrImportConflict(conflicts)
+		}
+
+		if data.DryRun {
+			return nil, nil, nil
+		}
+
+		saved := make([]usermodel.InvitationToken, len(changes))
+		for i := range changes {
+			saved[i] = changes[i].after
+		}
+		return saved, nil, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if data.DryRun {
+		return &result, nil
+	}
+
+	for _, change := range changes {
+		entry := auditmodel.Entry{
+			Action:     auditmodel.ActionInvitationImport,
+			TargetType: auditmodel.TargetInvitationToken,
+			TargetId:   usermodel.InvitationTokenId(change.after.Token),
+			After:      change.after.Audited(),
+		}
+		if change.before != nil {
+			entry.Before = change.before.Audited()
+		}
+		biz.audit.Record(ctx, &entry)
+	}
+
+	return &result, nil
+}
+
+// checkRecord holds a record to what generating its token would: it may not
+// live longer than maxTTL, and its campaign must still issue tokens, which
+// then expire when the campaign has them expire at the latest. campaigns
+// keeps the campaigns looked up so far.
+func (biz *importInvitationTokensBiz) checkRecord(
+	ctx context.Context,
+	record *usermodel.InvitationTokenRecord,
+	now time.Time,
+	campaigns map[int]*campaignmodel.Campaign,
+) error {
+	ttl := time.Duration(record.TTL) * time.Second
+	if ttl > biz.maxTTL {
+		return usermodel.ErrImportRecordTTLTooLong(biz.maxTTL)
+	}
+
+	if record.CampaignId == 0 {
+		return nil
+	}
+
+	campaign, ok := campaigns[record.CampaignId]
+	if !ok {
+		var err error
+		if campaign, err = biz.campaigns.CampaignForIssue(ctx, record.CampaignId); err != nil {
+			return err
+		}
+		campaigns[record.CampaignId] = campaign
+	}
+
+	if limit := campaign.TokenExpiry(now, ttl); limit < ttl {
+		record.TTL = int(limit / time.Second)
+	}
+	if record.TTL <= 0 {
+		return usermodel.ErrImportRecordTTLInvalid
+	}
+
+	return nil
+}
+
+// writeInvitationTokenRecords encodes records to w in format
+func writeInvitationTokenRecords(format string, records []usermodel.InvitationTokenRecord, w io.Writer) error {
+	if format == usermodel.ExportFormatJSONL {
+		encoder := json.NewEncoder(w)
+		for i := range records {
+			if err := encoder.Encode(&records[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(tokenCSVHeader); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		if err := writer.Write([]string{
+			r.Token, r.Status.String(), r.Email, r.EmailDomain, r.DeliveryStatus, r.DeliveryError,
+			timeColumn(r.DeliveredAt), r.BatchId, strconv.Itoa(r.CampaignId), strconv.Itoa(r.CreatedBy),
+			timeColumn(r.CreatedAt), timeColumn(r.NotBefore), strconv.Itoa(r.TTL),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// timeColumn renders an optional time, leaving the cell empty when there is none
+func timeColumn(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// readInvitationTokenRecords decodes and validates the records of an import,
+// which check may also reject or adjust. Errors tell the line of the record
+// which is wrong.
+func readInvitationTokenRecords(
+	format string,
+	r io.Reader,
+	check func(*usermodel.InvitationTokenRecord) error,
+) ([]usermodel.InvitationTokenRecord, error) {
+	var records []usermodel.InvitationTokenRecord
+	seen := map[string]bool{}
+
+	add := func(line int, record usermodel.InvitationTokenRecord) error {
+		if err := record.Validate(); err != nil {
+			return usermodel.ErrImportRecordInvalid(line, err)
+		}
+		if err := check(&record); err != nil {
+			return usermodel.ErrImportRecordInvalid(line, err)
+		}
+		if seen[record.Token] {
+			return usermodel.ErrImportRecordInvalid(line, fmt.Errorf("token %s is imported twice", record.Token))
+		}
+		if len(records) == usermodel.MaxImportTokens {
+			return usermodel.ErrImportTooLarge
+		}
+		seen[record.Token] = true
+		records = append(records, record)
+		return nil
+	}
+
+	var err error
+	if format == usermodel.ExportFormatJSONL {
+		err = readJSONLRecords(r, add)
+	} else {
+		err = readCSVRecords(r, add)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func readJSONLRecords(r io.Reader, add func(int, usermodel.InvitationTokenRecord) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		// tokens are active unless the record says otherwise, as in CSV
+		record := usermodel.InvitationTokenRecord{Status: usermodel.InvitationStatusActive}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return usermodel.ErrImportRecordInvalid(line, err)
+		}
+		if err := add(line, record); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return common.ErrInvalidRequest(err)
+	}
+
+	return nil
+}
+
+// readCSVRecords reads the columns named in the header, which must include
+// token and ttl and may leave out the others
+func readCSVRecords(r io.Reader, add func(int, usermodel.InvitationTokenRecord) error) error {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return usermodel.ErrImportRecordInvalid(1, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isTokenCSVColumn(name) {
+			return usermodel.ErrImportRecordInvalid(1, fmt.Errorf("unknown column %q", name))
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"token", "ttl"} {
+		if _, ok := columns[name]; !ok {
+			return usermodel.ErrImportRecordInvalid(1, fmt.Errorf("column %q is missing", name))
+		}
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return usermodel.ErrImportRecordInvalid(parseErr.StartLine, parseErr.Err)
+			}
+			return common.ErrInvalidRequest(err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		record, err := csvRecord(columns, row)
+		if err != nil {
+			return usermodel.ErrImportRecordInvalid(line, err)
+		}
+		if err := add(line, record); err != nil {
+			return err
+		}
+	}
+}
+
+func isTokenCSVColumn(name string) bool {
+	for _, column := range tokenCSVHeader {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// csvRecord reads a record from the cells of row. Empty cells are left unset.
+func csvRecord(columns map[string]int, row []string) (usermodel.InvitationTokenRecord, error) {
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	record := usermodel.InvitationTokenRecord{
+		Token:          cell("token"),
+		Status:         usermodel.InvitationStatusActive,
+		Email:          cell("email"),
+		EmailDomain:    cell("email_domain"),
+		DeliveryStatus: cell("delivery_status"),
+		DeliveryError:  cell("delivery_error"),
+		BatchId:        cell("batch_id"),
+	}
+
+	if s := cell("status"); s != "" {
+		status, err := usermodel.ParseInvitationStatus(s)
+		if err != nil {
+			return record, err
+		}
+		record.Status = status
+	}
+
+	numbers := []struct {
+		name  string
+		value *int
+	}{
+		{"campaign_id", &record.CampaignId},
+		{"created_by", &record.CreatedBy},
+		{"ttl", &record.TTL},
+	}
+	for _, n := range numbers {
+		if s := cell(n.name); s != "" {
+			v, err := strconv.Atoi(s)
+			if err != nil {
+				return record, fmt.Errorf("%s must be a number", n.name)
+			}
+			*n.value = v
+		}
+	}
+
+	times := []struct {
+		name  string
+		value **time.Time
+	}{
+		{"delivered_at", &record.DeliveredAt},
+		{"created_at", &record.CreatedAt},
+		{"not_before", &record.NotBefore},
+	}
+	for _, t := range times {
+		if s := cell(t.name); s != "" {
+			v, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return record, fmt.Errorf("%s must be an RFC 3339 time", t.name)
+			}
+			v = v.UTC()
+			*t.value = &v
+		}
+	}
+
+	return record, nil
+}
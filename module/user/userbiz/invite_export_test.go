@@ -0,0 +1,209 @@
+package userbiz_test
+
+import (
+	"app-invite-service/mock"
+	"app-invite-service/module/campaign/campaignbiz"
+	"app-invite-service/module/campaign/campaignmodel"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newImportBiz imports tokens living at most a day, under the active
+// campaigns 3 and 5, the latter having its tokens live 30 seconds
+func newImportBiz(store userbiz.InvitationTokenStore) userbiz.IImportInvitationTokensBiz {
+	campaigns := campaignbiz.NewCampaignPolicyBiz(mock.NewMockCampaignStore(
+		campaignmodel.Campaign{Id: 3, Name: "spring", Status: 1},
+		campaignmodel.Campaign{Id: 4, Name: "winter", Status: 0},
+		campaignmodel.Campaign{Id: 5, Name: "flash", Status: 1, TokenTTL: 30},
+	))
+	return userbiz.NewImportInvitationTokensBiz(store, campaigns, mock.NewMockAuditLogger(), 24*time.Hour)
+}
+
+func TestExportInvitationTokensBiz_RoundTrip(t *testing.T) {
+	for _, format := range []string{usermodel.ExportFormatJSONL, usermodel.ExportFormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			notBefore := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+			pending := newStoredToken("Pending1", usermodel.InvitationStatusActive, 2*time.Hour)
+			pending.NotBefore = &notBefore
+			pending.Email = "user@gmail.com"
+			pending.DeliveryError = "mailbox full,\nretrying"
+			disabled := newStoredToken("Disabled", usermodel.InvitationStatusDisabled, time.Hour)
+			disabled.BatchId = "spring"
+			disabled.CampaignId = 3
+
+			var exported bytes.Buffer
+			err := userbiz.NewExportInvitationTokensBiz(
+				mock.NewMockInvitationTokenStore(pending, disabled),
+				mock.NewMockAuditLogger(),
+			).ExportInvitationTokens(nil, &usermodel.InvitationTokenExportQuery{Format: format}, &exported)
+			require.Nil(t, err)
+
+			store := mock.NewMockInvitationTokenStore()
+			result, err := newImportBiz(store).
+				ImportInvitationTokens(nil, &usermodel.InvitationTokenImport{Format: format}, &exported)
+			require.Nil(t, err)
+			assert.Equal(t, &usermodel.InvitationTokenImportResult{Created: 2}, result)
+
+			for _, want := range []usermodel.InvitationToken{pending, disabled} {
+				got, ok := store.Token(want.Token)
+				require.True(t, ok)
+				assert.WithinDuration(t, *want.ExpiresAt, *got.ExpiresAt, 2*time.Second)
+				got.ExpiresAt = want.ExpiresAt
+				assert.Equal(t, want, got)
+			}
+		})
+	}
+}
+
+func TestImportInvitationTokensBiz_ImportInvitationTokens(t *testing.T) {
+	file := "{\"token\":\"Existing\",\"status\":\"disabled\",\"ttl\":60}\n\n{\"token\":\"Created1\",\"ttl\":60}\n"
+
+	tcs := []struct {
+		name     string
+		data     usermodel.InvitationTokenImport
+		result   *usermodel.InvitationTokenImportResult
+		err      string
+		status   usermodel.InvitationStatus
+		imported bool
+	}{
+		{
+			name:   "conflicts fail by default",
+			err:    "ErrImportConflict",
+			status: usermodel.InvitationStatusActive,
+		},
+		{
+			name:     "skip",
+			data:     usermodel.InvitationTokenImport{Conflict: usermodel.ImportConflictSkip},
+			result:   &usermodel.InvitationTokenImportResult{Created: 1, Skipped: 1},
+			status:   usermodel.InvitationStatusActive,
+			imported: true,
+		},
+		{
+			name:     "overwrite",
+			data:     usermodel.InvitationTokenImport{Conflict: usermodel.ImportConflictOverwrite},
+			result:   &usermodel.InvitationTokenImportResult{Created: 1, Overwritten: 1},
+			status:   usermodel.InvitationStatusDisabled,
+			imported: true,
+		},
+		{
+			name:   "dry run",
+			data:   usermodel.InvitationTokenImport{Conflict: usermodel.ImportConflictOverwrite, DryRun: true},
+			result: &usermodel.InvitationTokenImportResult{DryRun: true, Created: 1, Overwritten: 1},
+			status: usermodel.InvitationStatusActive,
+		},
+		{
+			name:   "unknown policy",
+			data:   usermodel.InvitationTokenImport{Conflict: "merge"},
+			err:    "ErrImportConflictPolicyInvalid",
+			status: usermodel.InvitationStatusActive,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			store := mock.NewMockInvitationTokenStore(newStoredToken("Existing", usermodel.InvitationStatusActive, time.Hour))
+			result, err := newImportBiz(store).
+				ImportInvitationTokens(nil, &tc.data, strings.NewReader(file))
+			assert.Equal(t, tc.err, errKey(err))
+			assert.Equal(t, tc.result, result)
+
+			existing, _ := store.Token("Existing")
+			assert.Equal(t, tc.status, existing.Status)
+			created, ok := store.Token("Created1")
+			assert.Equal(t, tc.imported, ok)
+			if ok {
+				assert.Equal(t, usermodel.InvitationStatusActive, created.Status)
+				assert.WithinDuration(t, time.Now().Add(time.Minute), *created.ExpiresAt, 2*time.Second)
+			}
+		})
+	}
+}
+
+func TestImportInvitationTokensBiz_InvalidRecords(t *testing.T) {
+	tcs := []struct {
+		name   string
+		format string
+		file   string
+		err    string
+	}{
+		{
+			name: "expired",
+			file: `{"token":"Expired1","ttl":0}`,
+			err:  "ErrImportRecordInvalid",
+		},
+		{
+			name: "ttl over the max",
+			file: `{"token":"Valid123","ttl":86401}`,
+			err:  "ErrImportRecordInvalid",
+		},
+		{
+			name: "unknown campaign",
+			file: `{"token":"Valid123","ttl":60,"campaign_id":9}`,
+			err:  "ErrImportRecordInvalid",
+		},
+		{
+			name: "disabled campaign",
+			file: `{"token":"Valid123","ttl":60,"campaign_id":4}`,
+			err:  "ErrImportRecordInvalid",
+		},
+		{
+			name: "malformed token",
+			file: "{\"token\":\"Valid123\",\"ttl\":60}\n{\"token\":\"no!\",\"ttl\":60}",
+			err:  "ErrImportRecordInvalid",
+		},
+		{
+			name: "twice",
+			file: "{\"token\":\"Valid123\",\"ttl\":60}\n{\"token\":\"Valid123\",\"ttl\":60}",
+			err:  "ErrImportRecordInvalid",
+		},
+		{
+			name:   "unknown status",
+			format: usermodel.ExportFormatCSV,
+			file:   "token,status,ttl\nValid123,lost,60\n",
+			err:    "ErrImportRecordInvalid",
+		},
+		{
+			name:   "no ttl column",
+			format: usermodel.ExportFormatCSV,
+			file:   "token,status\nValid123,active\n",
+			err:    "ErrImportRecordInvalid",
+		},
+		{
+			name:   "unknown format",
+			format: "xml",
+			err:    "ErrExportFormatInvalid",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			store := mock.NewMockInvitationTokenStore()
+			_, err := newImportBiz(store).
+				ImportInvitationTokens(nil, &usermodel.InvitationTokenImport{Format: tc.format}, strings.NewReader(tc.file))
+			assert.Equal(t, tc.err, errKey(err))
+			_, ok := store.Token("Valid123")
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestImportInvitationTokensBiz_CampaignTTL(t *testing.T) {
+	store := mock.NewMockInvitationTokenStore()
+	file := "{\"token\":\"Flash123\",\"ttl\":3600,\"campaign_id\":5}\n{\"token\":\"Spring12\",\"ttl\":3600,\"campaign_id\":3}\n"
+	_, err := newImportBiz(store).ImportInvitationTokens(nil, &usermodel.InvitationTokenImport{}, strings.NewReader(file))
+	require.Nil(t, err)
+
+	flash, ok := store.Token("Flash123")
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), *flash.ExpiresAt, 2*time.Second)
+	spring, ok := store.Token("Spring12")
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *spring.ExpiresAt, 2*time.Second)
+}
@@ -0,0 +1,134 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/campaign/campaignmodel"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"time"
+)
+
+type InviterStore interface {
+	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+}
+
+type LandingThrottle interface {
+	IncrementLookups(ctx context.Context, clientIP string, window time.Duration) (int64, time.Duration, error)
+}
+
+// Get invitation landing
+
+type IGetInvitationLandingBiz interface {
+	GetInvitationLanding(ctx context.Context, data *usermodel.InvitationLandingQuery) (*usermodel.InvitationLanding, error)
+}
+
+type getInvitationLandingBiz struct {
+	store     InvitationTokenStore
+	signed    SignedTokenChecker
+	campaigns CampaignPolicy
+	users     InviterStore
+	throttle  LandingThrottle
+	limit     int
+	window    time.Duration
+}
+
+// NewGetInvitationLandingBiz allows limit lookups per client IP within
+// window, so tokens can't be guessed through it. A zero limit turns
+// throttling off.
+func NewGetInvitationLandingBiz(
+	store InvitationTokenStore,
+	signed SignedTokenChecker,
+	campaigns CampaignPolicy,
+	users InviterStore,
+	throttle LandingThrottle,
+	limit int,
+	window time.Duration,
+) IGetInvitationLandingBiz {
+	return &getInvitationLandingBiz{
+		store:     store,
+		signed:    signed,
+		campaigns: campaigns,
+		users:     users,
+		throttle:  throttle,
+		limit:     limit,
+		window:    window,
+	}
+}
+
+// GetInvitationLanding checks the token like ValidateInvitationToken and
+// tells what the invitee may know about it. Admin-only details such as the
+// batch or the recipient's address are left out.
+func (biz *getInvitationLandingBiz) GetInvitationLanding(
+	ctx context.Context,
+	data *usermodel.InvitationLandingQuery,
+) (*usermodel.InvitationLanding, error) {
+	if biz.limit > 0 && data.ClientIP != "" {
+		count, retryAfter, err := biz.throttle.IncrementLookups(ctx, data.ClientIP, biz.window)
+		if err != nil {
+			return nil, err
+		}
+		if count > int64(biz.limit) {
+			return nil, usermodel.ErrLandingThrottled(retryAfter)
+		}
+	}
+
+	token, err := lookupInvitationToken(ctx, biz.store, biz.signed, data.InvitationToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTokenStatus(token); err != nil {
+		return nil, err
+	}
+
+	// pending tokens are shown with the time they become active
+	landing := usermodel.InvitationLanding{
+		NotBefore:     token.NotBefore,
+		ExpiresAt:     token.ExpiresAt,
+		RecipientHint: token.RecipientHint(),
+	}
+
+	if token.MaxUses > 0 {
+		landing.RemainingUses = remaining(token.MaxUses, token.Uses)
+	}
+
+	if token.CampaignId != 0 {
+		campaign, err := biz.campaigns.CheckRedeemable(ctx, token.CampaignId)
+		if err != nil {
+			return nil, err
+		}
+		landing.CampaignName = campaign.Name
+		withCampaignLimit(&landing, campaign)
+	}
+
+	if token.CreatedBy != 0 {
+		inviter, err := biz.users.FindUser(ctx, map[string]interface{}{"id": token.CreatedBy})
+		if err != nil && err != common.ErrRecordNotFound {
+			return nil, err
+		}
+		if inviter != nil {
+			landing.InviterName = inviter.DisplayName
+		}
+	}
+
+	return &landing, nil
+}
+
+// withCampaignLimit lowers the remaining uses to what the campaign has left
+func withCampaignLimit(landing *usermodel.InvitationLanding, campaign *campaignmodel.Campaign) {
+	if campaign.MaxRedemptions == 0 {
+		return
+	}
+
+	left := remaining(campaign.MaxRedemptions, campaign.Redemptions)
+	if landing.RemainingUses == nil || *left < *landing.RemainingUses {
+		landing.RemainingUses = left
+	}
+}
+
+func remaining(max, used int) *int {
+	left := max - used
+	if left < 0 {
+		left = 0
+	}
+	return &left
+}
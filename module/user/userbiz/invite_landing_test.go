@@ -0,0 +1,92 @@
+package userbiz_test
+
+import (
+	"app-invite-service/component/invitesigner"
+	"app-invite-service/mock"
+	"app-invite-service/module/campaign/campaignbiz"
+	"app-invite-service/module/campaign/campaignmodel"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetInvitationLandingBiz_GetInvitationLanding(t *testing.T) {
+	signer := newTestSigner()
+	store := mock.NewMockSignedInvitationStore()
+	campaigns := campaignbiz.NewCampaignPolicyBiz(mock.NewMockCampaignStore(
+		campaignmodel.Campaign{Id: 1, Name: "Spring launch", Status: 1, MaxRedemptions: 10, Redemptions: 9},
+		campaignmodel.Campaign{Id: 2, Name: "Closed", Status: 0},
+	))
+	biz := userbiz.NewGetInvitationLandingBiz(
+		nil,
+		userbiz.NewSignedTokenBiz(signer, store),
+		campaigns,
+		mock.NewMockUserStore(),
+		mock.NewMockLandingThrottle(),
+		3,
+		time.Minute,
+	)
+
+	sign := func(campaignId, maxUses int) string {
+		id, err := invitesigner.NewId()
+		require.Nil(t, err)
+		token, err := signer.Sign(&invitesigner.Claims{
+			Id:         id,
+			ExpiresAt:  time.Now().Add(time.Hour).Truncate(time.Second),
+			CampaignId: campaignId,
+			MaxUses:    maxUses,
+		})
+		require.Nil(t, err)
+		return token
+	}
+
+	used := sign(0, 3)
+	claims, err := signer.Verify(used, time.Now())
+	require.Nil(t, err)
+	_, err = store.AddUse(nil, claims.Id, 3, claims.ExpiresAt)
+	require.Nil(t, err)
+
+	tcs := []struct {
+		name          string
+		token         string
+		clientIP      string
+		remainingUses *int
+		campaignName  string
+		expectedErr   error
+	}{
+		{"unlimited", sign(0, 0), "", nil, "", nil},
+		{"token uses", used, "", intPtr(2), "", nil},
+		{"campaign redemptions", sign(1, 5), "", intPtr(1), "Spring launch", nil},
+		{"campaign disabled", sign(2, 0), "", nil, "", campaignmodel.ErrCampaignDisabled},
+		{"malformed", "abc", "", nil, "", usermodel.ErrInviteTokenMalformed},
+		{"first lookup", sign(0, 0), "10.0.0.1", nil, "", nil},
+		{"second lookup", sign(0, 0), "10.0.0.1", nil, "", nil},
+		{"third lookup", sign(0, 0), "10.0.0.1", nil, "", nil},
+		{"throttled", sign(0, 0), "10.0.0.1", nil, "", usermodel.ErrLandingThrottled(time.Minute)},
+	}
+
+	for _, tc := range tcs {
+		landing, err := biz.GetInvitationLanding(nil, &usermodel.InvitationLandingQuery{
+			InvitationToken: tc.token,
+			ClientIP:        tc.clientIP,
+		})
+		if tc.expectedErr != nil {
+			require.Error(t, err, tc.name)
+			assert.Equal(t, tc.expectedErr.Error(), err.Error(), tc.name)
+			continue
+		}
+
+		require.Nil(t, err, tc.name)
+		assert.Equal(t, tc.remainingUses, landing.RemainingUses, tc.name)
+		assert.Equal(t, tc.campaignName, landing.CampaignName, tc.name)
+		assert.NotNil(t, landing.ExpiresAt, tc.name)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
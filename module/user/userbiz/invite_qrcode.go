@@ -0,0 +1,135 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/qrcode"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"errors"
+)
+
+// Render invitation QR code
+
+type IRenderInvitationQRCodeBiz interface {
+	RenderInvitationQRCode(
+		ctx context.Context,
+		token string,
+		query *usermodel.InvitationQRCodeQuery,
+	) (*usermodel.InvitationQRCode, error)
+}
+
+type renderInvitationQRCodeBiz struct {
+	store    InvitationTokenStore
+	signed   SignedTokenChecker
+	deepLink string
+}
+
+// NewRenderInvitationQRCodeBiz renders QR codes encoding the deepLink
+// template, see RenderDeepLink
+func NewRenderInvitationQRCodeBiz(
+	store InvitationTokenStore,
+	signed SignedTokenChecker,
+	deepLink string,
+) IRenderInvitationQRCodeBiz {
+	return &renderInvitationQRCodeBiz{store: store, signed: signed, deepLink: deepLink}
+}
+
+func (biz *renderInvitationQRCodeBiz) RenderInvitationQRCode(
+	ctx context.Context,
+	token string,
+	query *usermodel.InvitationQRCodeQuery,
+) (*usermodel.InvitationQRCode, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	token, err := usermodel.NormalizeInvitationToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// only tokens that can still be used are worth handing out
+	foundToken, err := lookupInvitationToken(ctx, biz.store, biz.signed, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTokenStatus(foundToken); err != nil {
+		return nil, err
+	}
+
+	// signed tokens are looked up by their id, so the link carries token
+	link, err := RenderDeepLink(biz.deepLink, token)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	if query.Format == usermodel.QRCodeFormatSVG {
+		svg, err := qrcode.SVG(link, query.Size)
+		if err != nil {
+			return nil, common.ErrInternal(err)
+		}
+		return &usermodel.InvitationQRCode{ContentType: "image/svg+xml", Data: svg}, nil
+	}
+
+	png, err := qrcode.PNG(link, query.Size)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+	return &usermodel.InvitationQRCode{ContentType: "image/png", Data: png}, nil
+}
+
+// Generate QR code sheet
+
+type IGenerateQRCodeSheetBiz interface {
+	GenerateQRCodeSheet(ctx context.Context, data *usermodel.InvitationQRCodeSheetCreate) ([]byte, error)
+}
+
+type generateQRCodeSheetBiz struct {
+	generator IGenerateTokenBiz
+	deepLink  string
+}
+
+func NewGenerateQRCodeSheetBiz(generator IGenerateTokenBiz, deepLink string) IGenerateQRCodeSheetBiz {
+	return &generateQRCodeSheetBiz{generator: generator, deepLink: deepLink}
+}
+
+// GenerateQRCodeSheet generates data.Count tokens in the batch and returns a
+// printable PDF of their QR codes, each labelled with its token so it can
+// also be typed in. Tokens generated before an error stay in the batch.
+func (biz *generateQRCodeSheetBiz) GenerateQRCodeSheet(
+	ctx context.Context,
+	data *usermodel.InvitationQRCodeSheetCreate,
+) ([]byte, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	codes := make([]qrcode.SheetCode, 0, data.Count)
+	for len(codes) < data.Count {
+		token, err := biz.generator.GenerateToken(ctx, &usermodel.InvitationTokenCreate{
+			BatchId:    data.BatchId,
+			CampaignId: data.CampaignId,
+			NotBefore:  data.NotBefore,
+			CreatedBy:  data.CreatedBy,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			return nil, common.ErrInternal(errors.New("generated invitation token already existed"))
+		}
+
+		link, err := RenderDeepLink(biz.deepLink, token.Token)
+		if err != nil {
+			return nil, common.ErrInternal(err)
+		}
+		codes = append(codes, qrcode.SheetCode{Content: link, Label: token.Token})
+	}
+
+	sheet, err := qrcode.PDFSheet("Invitations - "+data.BatchId, codes)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	return sheet, nil
+}
@@ -0,0 +1,47 @@
+package userbiz_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+)
+
+func TestGenerateQRCodeSheetBiz_GenerateQRCodeSheet(t *testing.T) {
+	tcs := []struct {
+		name        string
+		data        usermodel.InvitationQRCodeSheetCreate
+		expectedErr error
+	}{
+		{"sheet", usermodel.InvitationQRCodeSheetCreate{BatchId: "spring", Count: 13, CreatedBy: 5}, nil},
+		{"no codes", usermodel.InvitationQRCodeSheetCreate{BatchId: "spring"}, usermodel.ErrQRCodeSheetCountInvalid},
+		{"too many codes", usermodel.InvitationQRCodeSheetCreate{BatchId: "spring", Count: 121}, usermodel.ErrQRCodeSheetCountInvalid},
+		{"invalid batch", usermodel.InvitationQRCodeSheetCreate{BatchId: "spring sale", Count: 1}, usermodel.ErrBatchIdInvalid},
+	}
+
+	for _, tc := range tcs {
+		generator := mock.NewMockTokenGenerator()
+		biz := userbiz.NewGenerateQRCodeSheetBiz(generator, "myapp://invite?code={{.Token}}")
+
+		sheet, err := biz.GenerateQRCodeSheet(nil, &tc.data)
+		if tc.expectedErr != nil {
+			assert.Equal(t, tc.expectedErr, err, tc.name)
+			assert.Empty(t, generator.Tokens(), tc.name)
+			continue
+		}
+
+		require.Nil(t, err, tc.name)
+		assert.True(t, bytes.HasPrefix(sheet, []byte("%PDF-")), tc.name)
+		require.Len(t, generator.Tokens(), tc.data.Count, tc.name)
+		for _, token := range generator.Tokens() {
+			assert.Equal(t, "spring", token.BatchId, tc.name)
+			assert.Equal(t, 5, token.CreatedBy, tc.name)
+			assert.Contains(t, string(sheet), "("+token.Token+")", tc.name)
+		}
+	}
+}
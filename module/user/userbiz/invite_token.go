@@ -2,19 +2,22 @@ package userbiz
 
 import (
 	"app-invite-service/common"
+	"app-invite-service/component/invitecode"
 	"app-invite-service/component/tokenprovider"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/campaign/campaignmodel"
+	"app-invite-service/module/invitehistory/invitehistorymodel"
 	usermodel "app-invite-service/module/user/usermodel"
 	"context"
 	crand "crypto/rand"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"math/big"
 	"math/rand"
 	"strings"
 	"time"
-
-	"github.com/go-redis/redis/v8"
 )
 
 var (
@@ -28,6 +31,16 @@ var (
 		"invalid invite token",
 		"ErrInvalidInviteToken",
 	)
+	ErrInviteTokenRedeemed = common.NewCustomError(
+		errors.New("invite token redeemed"),
+		"invite token has already been redeemed",
+		"ErrInviteTokenRedeemed",
+	)
+	ErrInviteTokenRecipientMismatch = common.NewCustomError(
+		errors.New("invite token recipient mismatch"),
+		"invite token was issued for a different email",
+		"ErrInviteTokenRecipientMismatch",
+	)
 )
 
 // Adapted from https://elithrar.github.io/article/generating-secure-random-numbers-crypto-rand/
@@ -64,43 +77,268 @@ func GenerateRandomString(min, max int) (string, error) {
 	return string(ret), nil
 }
 
+// InvitationTokenStore keeps invitation tokens until they expire
+type InvitationTokenStore interface {
+	FindInvitationToken(ctx context.Context, token string) (*usermodel.InvitationToken, error)
+	CreateInvitationToken(ctx context.Context, token *usermodel.InvitationToken) (bool, error)
+	UpdateInvitationToken(ctx context.Context, token *usermodel.InvitationToken) error
+	DeleteInvitationToken(ctx context.Context, token string) error
+	ListInvitationTokens(ctx context.Context) ([]usermodel.InvitationToken, error)
+	UpdateInvitationTokens(ctx context.Context, tokens []string, change usermodel.InvitationTokenChange) error
+}
+
+// InvitationHistory records every issue, validation and login attempt of an
+// invitation token
+type InvitationHistory interface {
+	Record(ctx context.Context, event *invitehistorymodel.Event) error
+}
+
+// InviteQuota limits how many invitations each user may generate
+type InviteQuota interface {
+	ReserveInvite(ctx context.Context, userId int) (string, error)
+	ReleaseInvite(ctx context.Context, userId int, reservation string) error
+}
+
+// CampaignPolicy decides what tokens generated under a campaign inherit and
+// when they may be redeemed
+type CampaignPolicy interface {
+	CampaignForIssue(ctx context.Context, id int) (*campaignmodel.Campaign, error)
+	CheckRedeemable(ctx context.Context, id int) (*campaignmodel.Campaign, error)
+	Redeem(ctx context.Context, id int, token string) (*campaignmodel.Campaign, error)
+}
+
+// withTokenMetadata copies what analytics group by from token into event
+func withTokenMetadata(event *invitehistorymodel.Event, token *usermodel.InvitationToken) {
+	event.BatchId = token.BatchId
+	event.TokenCreatedAt = token.CreatedAt
+	event.TokenExpiresAt = token.ExpiresAt
+	if token.CreatedBy != 0 {
+		createdBy := token.CreatedBy
+		event.CreatedBy = &createdBy
+	}
+}
+
+// recordAttempt stores the outcome of an attempt and returns err. The
+// history only observes attempts, so one it can't write is logged and
+// never changes their outcome.
+func recordAttempt(ctx context.Context, history InvitationHistory, event *invitehistorymodel.Event, err error) error {
+	event.Outcome = invitehistorymodel.OutcomeSuccess
+	if err != nil {
+		event.Fail(err)
+	}
+
+	if recordErr := history.Record(ctx, event); recordErr != nil {
+		log.Printf("invitation history: cannot record %s of %s: %v", event.Action, usermodel.InvitationTokenId(event.Token), recordErr)
+	}
+
+	return err
+}
+
+// findInvitationToken loads a stored token, ErrInviteTokenNotExisted if
+// it's missing. Malformed tokens are rejected without a lookup.
+func findInvitationToken(ctx context.Context, store InvitationTokenStore, token string) (*usermodel.InvitationToken, error) {
+	token, err := usermodel.NormalizeInvitationToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	foundToken, err := store.FindInvitationToken(ctx, token)
+	if err == common.ErrRecordNotFound {
+		return nil, ErrInviteTokenNotExisted
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return foundToken, nil
+}
+
+// updateInvitationToken saves a stored token, ErrInviteTokenNotExisted if
+// it was deleted meanwhile
+func updateInvitationToken(ctx context.Context, store InvitationTokenStore, token *usermodel.InvitationToken) error {
+	err := store.UpdateInvitationToken(ctx, token)
+	if err == common.ErrRecordNotFound {
+		return ErrInviteTokenNotExisted
+	}
+	return err
+}
+
+// checkTokenStatus rejects tokens that can't be used, telling why
+func checkTokenStatus(token *usermodel.InvitationToken) error {
+	switch token.Status {
+	case usermodel.InvitationStatusActive:
+		return nil
+	case usermodel.InvitationStatusExhausted:
+		return ErrInviteTokenUsedUp
+	case usermodel.InvitationStatusExpired:
+		return ErrInviteTokenExpired
+	case usermodel.InvitationStatusRedeemed:
+		return ErrInviteTokenRedeemed
+	}
+	return ErrInvalidInviteToken
+}
+
+// checkTokenActivation rejects tokens scheduled to become active after now
+func checkTokenActivation(token *usermodel.InvitationToken, now time.Time) error {
+	if token.IsPending(now) {
+		return usermodel.ErrInviteTokenNotYetActive(*token.NotBefore)
+	}
+	return nil
+}
+
 // generate invitation token
 
 type IGenerateTokenBiz interface {
-	GenerateToken(ctx context.Context) (*usermodel.InvitationToken, error)
+	GenerateToken(ctx context.Context, data *usermodel.InvitationTokenCreate) (*usermodel.InvitationToken, error)
 }
 
 type generateTokenBiz struct {
-	redis *redis.Client
+	store      InvitationTokenStore
+	codeFormat string
+	domains    DomainChecker
+	quotas     InviteQuota
+	campaigns  CampaignPolicy
+	audit      AuditLogger
+	history    InvitationHistory
 }
 
-func NewGenerateTokenBiz(redis *redis.Client) IGenerateTokenBiz {
-	return &generateTokenBiz{redis: redis}
+// NewGenerateTokenBiz generates tokens in codeFormat, one of the
+// usermodel.CodeFormat constants
+func NewGenerateTokenBiz(
+	store InvitationTokenStore,
+	codeFormat string,
+	domains DomainChecker,
+	quotas InviteQuota,
+	campaigns CampaignPolicy,
+	audit AuditLogger,
+	history InvitationHistory,
+) IGenerateTokenBiz {
+	return &generateTokenBiz{
+		store:      store,
+		codeFormat: codeFormat,
+		domains:    domains,
+		quotas:     quotas,
+		campaigns:  campaigns,
+		audit:      audit,
+		history:    history,
+	}
 }
 
-func (biz *generateTokenBiz) GenerateToken(ctx context.Context) (*usermodel.InvitationToken, error) {
-	var minTokenLen = 6
-	var maxTokenLen = 12
-	token, err := GenerateRandomString(minTokenLen, maxTokenLen)
-	if err != nil {
+func (biz *generateTokenBiz) GenerateToken(
+	ctx context.Context,
+	data *usermodel.InvitationTokenCreate,
+) (*usermodel.InvitationToken, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	// recipient-bound invites obey the same domain rules as registration
+	if domain := data.RecipientDomain(); domain != "" {
+		if err := biz.domains.CheckEmailDomain(ctx, domain); err != nil {
+			return nil, err
+		}
+	}
+
+	// scheduled tokens live their TTL from when they become active
+	now := time.Now().UTC()
+	start := now
+	if data.NotBefore != nil {
+		start = *data.NotBefore
+	}
+
+	ttl := common.InviteTokenExpirySecond * time.Second
+	if data.CampaignId != 0 {
+		campaign, err := biz.campaigns.CampaignForIssue(ctx, data.CampaignId)
+		if err != nil {
+			return nil, err
+		}
+		ttl = campaign.TokenExpiry(start, ttl)
+	}
+	if ttl <= 0 {
+		return nil, usermodel.ErrNotBeforeAfterExpiry
+	}
+	expiresAt := start.Add(ttl)
+
+	// tokens without a creator are issued by the service itself and aren't limited
+	var reservation string
+	if data.CreatedBy != 0 {
+		var err error
+		if reservation, err = biz.quotas.ReserveInvite(ctx, data.CreatedBy); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := biz.generate(ctx, data, now, expiresAt)
+	if token == nil && reservation != "" {
+		// the invitation wasn't handed out, so it doesn't count
+		if releaseErr := biz.quotas.ReleaseInvite(ctx, data.CreatedBy, reservation); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}
+	if err != nil || token == nil {
 		return nil, err
 	}
 
-	payload := usermodel.InvitationToken{Token: token, Status: 1}
+	return token, nil
+}
 
-	p, err := payload.MarshalBinary()
+// generate stores a new token and returns it even when recording it fails, so
+// the caller knows it was stored
+func (biz *generateTokenBiz) generate(
+	ctx context.Context,
+	data *usermodel.InvitationTokenCreate,
+	now time.Time,
+	expiresAt time.Time,
+) (*usermodel.InvitationToken, error) {
+	token, err := biz.newToken()
 	if err != nil {
 		return nil, err
 	}
 
-	val, err := biz.redis.SetNX(ctx, token, string(p), common.InviteTokenExpirySecond*time.Second).Result()
-	if err != nil || !val {
+	payload := usermodel.InvitationToken{
+		Token:       token,
+		Status:      usermodel.InvitationStatusActive,
+		Email:       data.Email,
+		EmailDomain: data.EmailDomain,
+		BatchId:     data.BatchId,
+		CampaignId:  data.CampaignId,
+		CreatedBy:   data.CreatedBy,
+		CreatedAt:   &now,
+		NotBefore:   data.NotBefore,
+		ExpiresAt:   &expiresAt,
+	}
+
+	created, err := biz.store.CreateInvitationToken(ctx, &payload)
+	if err != nil || !created {
 		return nil, err
 	}
 
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionInvitationGenerate,
+		TargetType: auditmodel.TargetInvitationToken,
+		TargetId:   usermodel.InvitationTokenId(payload.Token),
+		After:      payload.Audited(),
+	})
+
+	event := invitehistorymodel.Event{Token: payload.Token, Action: invitehistorymodel.ActionIssue, Email: payload.Email}
+	withTokenMetadata(&event, &payload)
+	if err := recordAttempt(ctx, biz.history, &event, nil); err != nil {
+		return &payload, err
+	}
+
 	return &payload, nil
 }
 
+func (biz *generateTokenBiz) newToken() (string, error) {
+	if biz.codeFormat == usermodel.CodeFormatCrockford {
+		return invitecode.Generate()
+	}
+
+	var minTokenLen = 6
+	var maxTokenLen = 12
+	return GenerateRandomString(minTokenLen, maxTokenLen)
+}
+
 // Login with invitation token
 
 type ILoginWithInviteTokenBiz interface {
@@ -108,23 +346,32 @@ type ILoginWithInviteTokenBiz interface {
 }
 
 type loginWithInviteTokenBiz struct {
-	redis         *redis.Client
+	store         InvitationTokenStore
+	signed        SignedTokenChecker
 	tokenProvider tokenprovider.Provider
 	hash          Hash
 	tokenConfig   *tokenprovider.TokenConfig
+	campaigns     CampaignPolicy
+	history       InvitationHistory
 }
 
 func NewLoginWithInviteTokenBiz(
-	redis *redis.Client,
+	store InvitationTokenStore,
+	signed SignedTokenChecker,
 	tokenProvider tokenprovider.Provider,
 	hash Hash,
 	tokenConfig *tokenprovider.TokenConfig,
+	campaigns CampaignPolicy,
+	history InvitationHistory,
 ) ILoginWithInviteTokenBiz {
 	return &loginWithInviteTokenBiz{
-		redis:         redis,
+		store:         store,
+		signed:        signed,
 		tokenProvider: tokenProvider,
 		hash:          hash,
 		tokenConfig:   tokenConfig,
+		campaigns:     campaigns,
+		history:       history,
 	}
 }
 
@@ -133,19 +380,48 @@ func (biz *loginWithInviteTokenBiz) LoginWithInviteToken(ctx context.Context, da
 		return nil, err
 	}
 
-	// check redis token existed
-	tokenFromRedis := biz.redis.Get(ctx, data.InvitationToken)
-	if tokenFromRedis.Val() == "" {
-		return nil, ErrInviteTokenNotExisted
+	event := invitehistorymodel.Event{
+		Token:  data.InvitationToken,
+		Action: invitehistorymodel.ActionLogin,
+		Email:  data.Email,
+	}
+
+	account, err := biz.login(ctx, data, &event)
+	if err := recordAttempt(ctx, biz.history, &event, err); err != nil {
+		return nil, err
 	}
 
+	return account, nil
+}
+
+func (biz *loginWithInviteTokenBiz) login(
+	ctx context.Context,
+	data *usermodel.UserLoginWithInviteToken,
+	event *invitehistorymodel.Event,
+) (*usermodel.Account, error) {
+	// check token existed
+	foundToken, err := lookupInvitationToken(ctx, biz.store, biz.signed, data.InvitationToken)
+	if err != nil {
+		return nil, err
+	}
+	event.Token = foundToken.Token
+	withTokenMetadata(event, foundToken)
+
 	// check whether invitation token is disabled or not
-	var foundToken usermodel.InvitationToken
-	if err := foundToken.UnmarshalBinary([]byte(tokenFromRedis.Val())); err != nil {
-		return nil, common.ErrInternal(err)
+	if err := checkTokenStatus(foundToken); err != nil {
+		return nil, err
+	}
+
+	if err := checkTokenActivation(foundToken, time.Now()); err != nil {
+		return nil, err
 	}
-	if foundToken.Status == 0 {
-		return nil, ErrInvalidInviteToken
+
+	if !foundToken.MatchesRecipient(data.Email) {
+		return nil, ErrInviteTokenRecipientMismatch
+	}
+
+	if _, err := redeemInvitationToken(ctx, biz.store, biz.signed, biz.campaigns, foundToken); err != nil {
+		return nil, err
 	}
 
 	// create JWT token
@@ -172,80 +448,216 @@ func (biz *loginWithInviteTokenBiz) LoginWithInviteToken(ctx context.Context, da
 
 type IValidateInviteTokenBiz interface {
 	ValidateInvitationToken(ctx context.Context, token string) error
+	ValidateInvitationTokenForEmail(ctx context.Context, token, email string) (*usermodel.InvitationToken, error)
+	CheckInvitationCampaign(ctx context.Context, token *usermodel.InvitationToken) (*campaignmodel.Campaign, error)
+	RedeemInvitationToken(ctx context.Context, token *usermodel.InvitationToken) (*campaignmodel.Campaign, error)
 }
 
 type validateInviteTokenBiz struct {
-	redis *redis.Client
+	store     InvitationTokenStore
+	signed    SignedTokenChecker
+	campaigns CampaignPolicy
+	history   InvitationHistory
 }
 
-func NewValidateInviteTokenBiz(redis *redis.Client) IValidateInviteTokenBiz {
-	return &validateInviteTokenBiz{redis: redis}
+func NewValidateInviteTokenBiz(
+	store InvitationTokenStore,
+	signed SignedTokenChecker,
+	campaigns CampaignPolicy,
+	history InvitationHistory,
+) IValidateInviteTokenBiz {
+	return &validateInviteTokenBiz{store: store, signed: signed, campaigns: campaigns, history: history}
 }
 
 func (biz *validateInviteTokenBiz) ValidateInvitationToken(ctx context.Context, token string) error {
+	event := invitehistorymodel.Event{
+		Token:  strings.TrimSpace(token),
+		Action: invitehistorymodel.ActionValidate,
+	}
+
+	// anyone may validate, so only tokens which were issued get a row in
+	// the history; guesses would otherwise fill it for free
+	foundToken, err := biz.validate(ctx, token, &event)
+	if foundToken == nil {
+		return err
+	}
+
+	return recordAttempt(ctx, biz.history, &event, err)
+}
+
+func (biz *validateInviteTokenBiz) validate(
+	ctx context.Context,
+	token string,
+	event *invitehistorymodel.Event,
+) (*usermodel.InvitationToken, error) {
 	// check token existed
-	tokenFromRedis := biz.redis.Get(ctx, strings.TrimSpace(token))
-	if tokenFromRedis.Val() == "" {
-		return ErrInviteTokenNotExisted
+	foundToken, err := lookupInvitationToken(ctx, biz.store, biz.signed, token)
+	if err != nil {
+		return nil, err
 	}
+	event.Token = foundToken.Token
+	withTokenMetadata(event, foundToken)
 
 	// check whether token disabled or not
-	var foundToken usermodel.InvitationToken
-	if err := foundToken.UnmarshalBinary([]byte(tokenFromRedis.Val())); err != nil {
-		return common.ErrInternal(err)
+
+	if err := checkTokenStatus(foundToken); err != nil {
+		return foundToken, err
 	}
-	if foundToken.Status == 0 {
-		return ErrInvalidInviteToken
+
+	if err := checkTokenActivation(foundToken, time.Now()); err != nil {
+		return foundToken, err
 	}
 
-	return nil
+	if foundToken.CampaignId != 0 {
+		if _, err := biz.campaigns.CheckRedeemable(ctx, foundToken.CampaignId); err != nil {
+			return foundToken, err
+		}
+	}
+
+	return foundToken, nil
+}
+
+// ValidateInvitationTokenForEmail also rejects tokens bound to another
+// recipient. It checks tokens for other flows and is not recorded as a
+// validation attempt; those flows call RedeemInvitationToken once they use
+// the token.
+func (biz *validateInviteTokenBiz) ValidateInvitationTokenForEmail(
+	ctx context.Context,
+	token, email string,
+) (*usermodel.InvitationToken, error) {
+	foundToken, err := lookupInvitationToken(ctx, biz.store, biz.signed, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTokenStatus(foundToken); err != nil {
+		return nil, err
+	}
+
+	if err := checkTokenActivation(foundToken, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if !foundToken.MatchesRecipient(email) {
+		return nil, ErrInviteTokenRecipientMismatch
+	}
+
+	return foundToken, nil
+}
+
+// CheckInvitationCampaign returns the campaign of a token if it may still be
+// redeemed, or nil for tokens without one
+func (biz *validateInviteTokenBiz) CheckInvitationCampaign(
+	ctx context.Context,
+	token *usermodel.InvitationToken,
+) (*campaignmodel.Campaign, error) {
+	if token.CampaignId == 0 {
+		return nil, nil
+	}
+
+	return biz.campaigns.CheckRedeemable(ctx, token.CampaignId)
+}
+
+// RedeemInvitationToken counts a token against its max uses and campaign and
+// returns the campaign, or nil for tokens without one. A stored token whose
+// campaign ran out becomes exhausted, and one sent to a single recipient is
+// redeemed once they used it.
+func (biz *validateInviteTokenBiz) RedeemInvitationToken(
+	ctx context.Context,
+	token *usermodel.InvitationToken,
+) (*campaignmodel.Campaign, error) {
+	return redeemInvitationToken(ctx, biz.store, biz.signed, biz.campaigns, token)
+}
+
+// redeemInvitationToken is RedeemInvitationToken for every way a token is
+// redeemed, registering or logging in with it
+func redeemInvitationToken(
+	ctx context.Context,
+	store InvitationTokenStore,
+	signed SignedTokenChecker,
+	campaigns CampaignPolicy,
+	token *usermodel.InvitationToken,
+) (*campaignmodel.Campaign, error) {
+	if token.Signed {
+		if err := signed.UseSignedToken(ctx, token); err != nil {
+			return nil, err
+		}
+	}
+
+	var campaign *campaignmodel.Campaign
+	if token.CampaignId != 0 {
+		var err error
+		if campaign, err = campaigns.Redeem(ctx, token.CampaignId, token.Token); err != nil {
+			if err == campaignmodel.ErrCampaignExhausted {
+				moveToStatus(ctx, store, token, usermodel.InvitationStatusExhausted)
+			}
+			return nil, err
+		}
+	}
+
+	if token.Email != "" {
+		moveToStatus(ctx, store, token, usermodel.InvitationStatusRedeemed)
+	}
+
+	return campaign, nil
+}
+
+// moveToStatus stores what redeeming a token did to it. The redemption
+// stands either way, so a status which can't be saved is only logged.
+func moveToStatus(
+	ctx context.Context,
+	store InvitationTokenStore,
+	token *usermodel.InvitationToken,
+	status usermodel.InvitationStatus,
+) {
+	// signed tokens aren't stored; their uses are counted instead
+	if token.Signed || !token.Status.CanTransitionTo(status) {
+		return
+	}
+
+	token.Status = status
+	if err := updateInvitationToken(ctx, store, token); err != nil {
+		log.Printf("invitation: cannot mark %s %s: %v", usermodel.InvitationTokenId(token.Token), status, err)
+	}
 }
 
 // List all invitation token
 
 type IListInvitationTokenBiz interface {
-	ListInvitationToken(ctx context.Context, filter *usermodel.InvitationTokenFilter) ([]usermodel.InvitationToken, error)
+	ListInvitationToken(
+		ctx context.Context,
+		filter *usermodel.InvitationTokenFilter,
+	) ([]usermodel.InvitationTokenListItem, error)
 }
 
 type listInvitationTokenBiz struct {
-	redis *redis.Client
+	store InvitationTokenStore
 }
 
-func NewListInvitationTokenBiz(redis *redis.Client) IListInvitationTokenBiz {
-	return &listInvitationTokenBiz{redis: redis}
+func NewListInvitationTokenBiz(store InvitationTokenStore) IListInvitationTokenBiz {
+	return &listInvitationTokenBiz{store: store}
 }
 
+// ListInvitationToken lists the tokens matching filter, each telling whether
+// it is still pending
 func (biz *listInvitationTokenBiz) ListInvitationToken(
 	ctx context.Context, filter *usermodel.InvitationTokenFilter,
-) ([]usermodel.InvitationToken, error) {
-	var listToken []usermodel.InvitationToken
-
-	iter := biz.redis.Scan(ctx, 0, "*", 0).Iterator()
-	for iter.Next(ctx) {
-		tokenFromRedis := biz.redis.Get(ctx, iter.Val())
-		if tokenFromRedis.Val() == "" {
-			continue
-		}
-		token := usermodel.InvitationToken{}
-		if err := token.UnmarshalBinary([]byte(tokenFromRedis.Val())); err != nil {
-			continue
-		}
-		listToken = append(listToken, token)
-	}
-
-	if err := iter.Err(); err != nil {
+) ([]usermodel.InvitationTokenListItem, error) {
+	if err := filter.Validate(); err != nil {
 		return nil, err
 	}
 
-	if filter.Status == nil {
-		return listToken, nil
+	listToken, err := biz.store.ListInvitationTokens(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// filter token by status
-	var filteredListToken []usermodel.InvitationToken
-	for _, token := range listToken {
-		if *filter.Status == token.Status {
-			filteredListToken = append(filteredListToken, token)
+	// filter token by status and activation
+	now := time.Now()
+	filteredListToken := make([]usermodel.InvitationTokenListItem, 0, len(listToken))
+	for i := range listToken {
+		if filter.Matches(&listToken[i], now) {
+			filteredListToken = append(filteredListToken, usermodel.NewInvitationTokenListItem(listToken[i], now))
 		}
 	}
 
@@ -259,11 +671,23 @@ type IUpdateInvitationTokenBiz interface {
 }
 
 type updateInvitationTokenBiz struct {
-	redis *redis.Client
+	store   InvitationTokenStore
+	audit   AuditLogger
+	history InvitationHistory
+	minTTL  time.Duration
+	maxTTL  time.Duration
 }
 
-func NewUpdateInvitationTokenBiz(redis *redis.Client) IUpdateInvitationTokenBiz {
-	return &updateInvitationTokenBiz{redis: redis}
+// NewUpdateInvitationTokenBiz allows moving the expiry of a token to between
+// minTTL and maxTTL from now
+func NewUpdateInvitationTokenBiz(
+	store InvitationTokenStore,
+	audit AuditLogger,
+	history InvitationHistory,
+	minTTL time.Duration,
+	maxTTL time.Duration,
+) IUpdateInvitationTokenBiz {
+	return &updateInvitationTokenBiz{store: store, audit: audit, history: history, minTTL: minTTL, maxTTL: maxTTL}
 }
 
 func (biz *updateInvitationTokenBiz) UpdateInvitationToken(
@@ -271,27 +695,177 @@ func (biz *updateInvitationTokenBiz) UpdateInvitationToken(
 	token string,
 	data *usermodel.InvitationTokenUpdate,
 ) error {
-	// check redis token existed
-	tokenFromRedis := biz.redis.Get(ctx, strings.TrimSpace(token))
-	if tokenFromRedis.Val() == "" {
-		return ErrInviteTokenNotExisted
+	if err := data.Validate(); err != nil {
+		return err
 	}
 
-	var foundToken usermodel.InvitationToken
-	if err := foundToken.UnmarshalBinary([]byte(tokenFromRedis.Val())); err != nil {
-		return common.ErrInternal(err)
+	// check token existed
+	foundToken, err := findInvitationToken(ctx, biz.store, token)
+	if err != nil {
+		return err
 	}
 
+	before := *foundToken
+
 	// update token
-	foundToken.Status = data.Status
-	t, err := foundToken.MarshalBinary()
-	if err != nil {
-		return common.ErrInternal(err)
+	if data.Status != nil && *data.Status != foundToken.Status {
+		if !foundToken.Status.CanTransitionTo(*data.Status) {
+			return usermodel.ErrInvitationStatusTransition(foundToken.Status, *data.Status)
+		}
+		foundToken.Status = *data.Status
+	}
+
+	if data.ChangesExpiry() {
+		if err := biz.moveExpiry(foundToken, data); err != nil {
+			return err
+		}
+	}
+
+	if err := updateInvitationToken(ctx, biz.store, foundToken); err != nil {
+		return err
+	}
+
+	biz.recordUpdate(ctx, before, foundToken)
+	if !data.ChangesExpiry() {
+		return nil
+	}
+
+	event := invitehistorymodel.Event{Token: foundToken.Token, Action: invitehistorymodel.ActionChangeExpiry}
+	withTokenMetadata(&event, foundToken)
+	return recordAttempt(ctx, biz.history, &event, nil)
+}
+
+// moveExpiry sets the expiry data asks for on token, which a scheduled token
+// must still reach active
+func (biz *updateInvitationTokenBiz) moveExpiry(
+	token *usermodel.InvitationToken,
+	data *usermodel.InvitationTokenUpdate,
+) error {
+	now := time.Now().UTC()
+
+	// the store knows when every token expires, even those issued before
+	// expires_at was kept
+	current := now
+	if token.ExpiresAt != nil {
+		current = *token.ExpiresAt
 	}
 
-	if _, err := biz.redis.SetXX(ctx, token, t, redis.KeepTTL).Result(); err != nil {
-		return common.ErrInternal(err)
+	expiresAt := data.NewExpiry(current)
+	ttl := expiresAt.Sub(now)
+	if ttl < biz.minTTL || ttl > biz.maxTTL {
+		return usermodel.ErrTokenExpiryOutOfRange(biz.minTTL, biz.maxTTL)
+	}
+	if token.NotBefore != nil && !expiresAt.After(*token.NotBefore) {
+		return usermodel.ErrNotBeforeAfterExpiry
 	}
 
+	token.ExpiresAt = &expiresAt
 	return nil
 }
+
+func (biz *updateInvitationTokenBiz) recordUpdate(
+	ctx context.Context,
+	before usermodel.InvitationToken,
+	after *usermodel.InvitationToken,
+) {
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionInvitationUpdate,
+		TargetType: auditmodel.TargetInvitationToken,
+		TargetId:   usermodel.InvitationTokenId(after.Token),
+		Before:     before.Audited(),
+		After:      after.Audited(),
+	})
+}
+
+// disableTokens disables every active token matching and returns the tokens
+// it disabled
+func disableTokens(
+	ctx context.Context,
+	store InvitationTokenStore,
+	audit AuditLogger,
+	matches func(token *usermodel.InvitationToken) bool,
+) ([]string, error) {
+	tokens, err := store.ListInvitationTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var disabled []string
+	for i := range tokens {
+		token := tokens[i]
+		if !token.Status.CanTransitionTo(usermodel.InvitationStatusDisabled) || !matches(&token) {
+			continue
+		}
+
+		before := token
+		token.Status = usermodel.InvitationStatusDisabled
+		if err := updateInvitationToken(ctx, store, &token); err != nil {
+			// a token deleted meanwhile needs no disabling
+			if err == ErrInviteTokenNotExisted {
+				continue
+			}
+			return nil, err
+		}
+
+		audit.Record(ctx, &auditmodel.Entry{
+			Action:     auditmodel.ActionInvitationUpdate,
+			TargetType: auditmodel.TargetInvitationToken,
+			TargetId:   usermodel.InvitationTokenId(token.Token),
+			Before:     before.Audited(),
+			After:      token.Audited(),
+		})
+
+		disabled = append(disabled, token.Token)
+	}
+
+	return disabled, nil
+}
+
+// Disable tokens by creator
+
+type IDisableTokensByCreatorBiz interface {
+	DisableTokensByCreator(ctx context.Context, creatorIds []int) ([]string, error)
+}
+
+type disableTokensByCreatorBiz struct {
+	store InvitationTokenStore
+	audit AuditLogger
+}
+
+func NewDisableTokensByCreatorBiz(store InvitationTokenStore, audit AuditLogger) IDisableTokensByCreatorBiz {
+	return &disableTokensByCreatorBiz{store: store, audit: audit}
+}
+
+// DisableTokensByCreator disables every active token created by one of
+// creatorIds and returns the tokens it disabled
+func (biz *disableTokensByCreatorBiz) DisableTokensByCreator(ctx context.Context, creatorIds []int) ([]string, error) {
+	creators := map[int]bool{}
+	for _, id := range creatorIds {
+		creators[id] = true
+	}
+
+	return disableTokens(ctx, biz.store, biz.audit, func(token *usermodel.InvitationToken) bool {
+		return creators[token.CreatedBy]
+	})
+}
+
+// Disable tokens by campaign
+
+type IDisableTokensByCampaignBiz interface {
+	DisableTokensByCampaign(ctx context.Context, campaignId int) ([]string, error)
+}
+
+type disableTokensByCampaignBiz struct {
+	store InvitationTokenStore
+	audit AuditLogger
+}
+
+func NewDisableTokensByCampaignBiz(store InvitationTokenStore, audit AuditLogger) IDisableTokensByCampaignBiz {
+	return &disableTokensByCampaignBiz{store: store, audit: audit}
+}
+
+func (biz *disableTokensByCampaignBiz) DisableTokensByCampaign(ctx context.Context, campaignId int) ([]string, error) {
+	return disableTokens(ctx, biz.store, biz.audit, func(token *usermodel.InvitationToken) bool {
+		return token.CampaignId == campaignId
+	})
+}
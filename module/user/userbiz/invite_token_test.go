@@ -0,0 +1,273 @@
+package userbiz_test
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/mock"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/campaign/campaignbiz"
+	"app-invite-service/module/campaign/campaignmodel"
+	"app-invite-service/module/invitehistory/invitehistorybiz"
+	"app-invite-service/module/invitehistory/invitehistorymodel"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func errKey(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.(*common.AppError).Key
+}
+
+func newTestHistory() userbiz.InvitationHistory {
+	return invitehistorybiz.NewRecordEventBiz(
+		mock.NewMockInvitationEventStore(nil, nil),
+		func(context.Context) *invitehistorymodel.RequestInfo { return nil },
+	)
+}
+
+func newStoredToken(token string, status usermodel.InvitationStatus, ttl time.Duration) usermodel.InvitationToken {
+	now := time.Now().UTC().Truncate(time.Second)
+	expiresAt := now.Add(ttl)
+	return usermodel.InvitationToken{Token: token, Status: status, CreatedAt: &now, ExpiresAt: &expiresAt}
+}
+
+func TestValidateInviteTokenBiz_ValidateInvitationTokenForEmail(t *testing.T) {
+	notBefore := time.Now().Add(time.Hour)
+	pending := newStoredToken("Pending1", usermodel.InvitationStatusActive, 2*time.Hour)
+	pending.NotBefore = &notBefore
+	bound := newStoredToken("Bound123", usermodel.InvitationStatusActive, time.Hour)
+	bound.Email = "user@gmail.com"
+
+	store := mock.NewMockInvitationTokenStore(
+		newStoredToken("Active12", usermodel.InvitationStatusActive, time.Hour),
+		newStoredToken("Expired1", usermodel.InvitationStatusActive, -time.Minute),
+		newStoredToken("Disabled", usermodel.InvitationStatusDisabled, time.Hour),
+		pending,
+		bound,
+	)
+	biz := userbiz.NewValidateInviteTokenBiz(store, userbiz.NewSignedTokenBiz(nil, nil), nil, newTestHistory())
+
+	tcs := []struct {
+		token    string
+		email    string
+		expected string
+	}{
+		{"Active12", "user@gmail.com", ""},
+		{"Missing1", "user@gmail.com", "ErrInviteTokenNotExisted"},
+		{"Expired1", "user@gmail.com", "ErrInviteTokenNotExisted"},
+		{"Disabled", "user@gmail.com", "ErrInvalidInviteToken"},
+		{"Pending1", "user@gmail.com", "ErrInviteTokenNotYetActive"},
+		{"Bound123", "user@gmail.com", ""},
+		{"Bound123", "other@gmail.com", "ErrInviteTokenRecipientMismatch"},
+	}
+
+	for _, tc := range tcs {
+		token, err := biz.ValidateInvitationTokenForEmail(nil, tc.token, tc.email)
+		if tc.expected == "" {
+			require.Nil(t, err, tc.token)
+			assert.Equal(t, tc.token, token.Token)
+			continue
+		}
+		assert.Equal(t, tc.expected, errKey(err), tc.token)
+	}
+}
+
+// failingHistory counts the events it is given and fails to record them
+type failingHistory struct {
+	events []invitehistorymodel.Event
+}
+
+func (h *failingHistory) Record(_ context.Context, event *invitehistorymodel.Event) error {
+	h.events = append(h.events, *event)
+	return common.ErrDB(errors.New("history down"))
+}
+
+func TestValidateInviteTokenBiz_ValidateInvitationToken(t *testing.T) {
+	store := mock.NewMockInvitationTokenStore(
+		newStoredToken("Active12", usermodel.InvitationStatusActive, time.Hour),
+		newStoredToken("Disabled", usermodel.InvitationStatusDisabled, time.Hour),
+	)
+	history := &failingHistory{}
+	biz := userbiz.NewValidateInviteTokenBiz(store, userbiz.NewSignedTokenBiz(nil, nil), nil, history)
+
+	tcs := []struct {
+		token    string
+		expected string
+		recorded int
+	}{
+		{"Active12", "", 1},
+		{"Disabled", "ErrInvalidInviteToken", 2},
+		{"Missing1", "ErrInviteTokenNotExisted", 2},
+		{"no!", "ErrInviteTokenMalformed", 2},
+	}
+
+	for _, tc := range tcs {
+		err := biz.ValidateInvitationToken(nil, tc.token)
+		assert.Equal(t, tc.expected, errKey(err), tc.token)
+		assert.Len(t, history.events, tc.recorded, tc.token)
+	}
+}
+
+func TestValidateInviteTokenBiz_RedeemInvitationToken(t *testing.T) {
+	open := newStoredToken("Open1234", usermodel.InvitationStatusActive, time.Hour)
+	bound := newStoredToken("Bound123", usermodel.InvitationStatusActive, time.Hour)
+	bound.Email = "user@gmail.com"
+	full := newStoredToken("Full1234", usermodel.InvitationStatusActive, time.Hour)
+	full.CampaignId = 1
+
+	store := mock.NewMockInvitationTokenStore(open, bound, full)
+	campaigns := campaignbiz.NewCampaignPolicyBiz(mock.NewMockCampaignStore(
+		campaignmodel.Campaign{Id: 1, Name: "launch", Status: 1, MaxRedemptions: 1, Redemptions: 1},
+	))
+	biz := userbiz.NewValidateInviteTokenBiz(store, userbiz.NewSignedTokenBiz(nil, nil), campaigns, newTestHistory())
+
+	tcs := []struct {
+		token    usermodel.InvitationToken
+		expected string
+		status   usermodel.InvitationStatus
+	}{
+		{open, "", usermodel.InvitationStatusActive},
+		{bound, "", usermodel.InvitationStatusRedeemed},
+		{full, "ErrCampaignExhausted", usermodel.InvitationStatusExhausted},
+	}
+
+	for _, tc := range tcs {
+		token := tc.token
+		_, err := biz.RedeemInvitationToken(nil, &token)
+		assert.Equal(t, tc.expected, errKey(err), tc.token.Token)
+
+		stored, ok := store.Token(tc.token.Token)
+		require.True(t, ok, tc.token.Token)
+		assert.Equal(t, tc.status, stored.Status, tc.token.Token)
+	}
+
+	// the recipient can't use their token a second time
+	_, err := biz.ValidateInvitationTokenForEmail(nil, "Bound123", "user@gmail.com")
+	assert.Equal(t, "ErrInviteTokenRedeemed", errKey(err))
+}
+
+func TestLoginWithInviteTokenBiz_LoginWithInviteToken(t *testing.T) {
+	open := newStoredToken("Open1234", usermodel.InvitationStatusActive, time.Hour)
+	bound := newStoredToken("Bound123", usermodel.InvitationStatusActive, time.Hour)
+	bound.Email = "user@gmail.com"
+	full := newStoredToken("Full1234", usermodel.InvitationStatusActive, time.Hour)
+	full.CampaignId = 1
+
+	store := mock.NewMockInvitationTokenStore(open, bound, full)
+	campaigns := campaignbiz.NewCampaignPolicyBiz(mock.NewMockCampaignStore(
+		campaignmodel.Campaign{Id: 1, Name: "launch", Status: 1, MaxRedemptions: 1, Redemptions: 1},
+	))
+	biz := userbiz.NewLoginWithInviteTokenBiz(
+		store,
+		userbiz.NewSignedTokenBiz(nil, nil),
+		mock.NewMockProvider(),
+		mock.NewMockHash(),
+		&tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800},
+		campaigns,
+		newTestHistory(),
+	)
+
+	tcs := []struct {
+		name     string
+		token    string
+		email    string
+		expected string
+		status   usermodel.InvitationStatus
+	}{
+		{"open", "Open1234", "", "", usermodel.InvitationStatusActive},
+		{"open again", "Open1234", "", "", usermodel.InvitationStatusActive},
+		{"bound", "Bound123", "user@gmail.com", "", usermodel.InvitationStatusRedeemed},
+		{"bound again", "Bound123", "user@gmail.com", "ErrInviteTokenRedeemed", usermodel.InvitationStatusRedeemed},
+		{"campaign full", "Full1234", "", "ErrCampaignExhausted", usermodel.InvitationStatusExhausted},
+	}
+
+	for _, tc := range tcs {
+		_, err := biz.LoginWithInviteToken(nil, &usermodel.UserLoginWithInviteToken{InvitationToken: tc.token, Email: tc.email})
+		assert.Equal(t, tc.expected, errKey(err), tc.name)
+
+		stored, ok := store.Token(tc.token)
+		require.True(t, ok, tc.name)
+		assert.Equal(t, tc.status, stored.Status, tc.name)
+	}
+}
+
+func TestListInvitationTokenBiz_ListInvitationToken(t *testing.T) {
+	notBefore := time.Now().UTC().Add(time.Hour)
+	scheduled := newStoredToken("Schedul1", usermodel.InvitationStatusActive, 2*time.Hour)
+	scheduled.NotBefore = &notBefore
+	biz := userbiz.NewListInvitationTokenBiz(mock.NewMockInvitationTokenStore(
+		newStoredToken("Active12", usermodel.InvitationStatusActive, time.Hour),
+		newStoredToken("Disabled", usermodel.InvitationStatusDisabled, time.Hour),
+		scheduled,
+	))
+	pending, active := true, false
+
+	tcs := []struct {
+		name     string
+		filter   usermodel.InvitationTokenFilter
+		expected map[string]bool
+	}{
+		{"all", usermodel.InvitationTokenFilter{}, map[string]bool{"Active12": false, "Disabled": false, "Schedul1": true}},
+		{"status", usermodel.InvitationTokenFilter{Status: "active"}, map[string]bool{"Active12": false, "Schedul1": true}},
+		{"pending", usermodel.InvitationTokenFilter{Pending: &pending}, map[string]bool{"Schedul1": true}},
+		{"not pending", usermodel.InvitationTokenFilter{Pending: &active}, map[string]bool{"Active12": false, "Disabled": false}},
+	}
+
+	for _, tc := range tcs {
+		tokens, err := biz.ListInvitationToken(nil, &tc.filter)
+		require.Nil(t, err, tc.name)
+
+		listed := map[string]bool{}
+		for _, token := range tokens {
+			listed[token.Token] = token.Pending
+		}
+		assert.Equal(t, tc.expected, listed, tc.name)
+	}
+}
+
+func TestUpdateInvitationTokenBiz_UpdateInvitationToken(t *testing.T) {
+	notBefore := time.Now().UTC().Add(3 * time.Hour)
+	scheduled := newStoredToken("Schedul1", usermodel.InvitationStatusActive, 5*time.Hour)
+	scheduled.NotBefore = &notBefore
+	store := mock.NewMockInvitationTokenStore(
+		newStoredToken("Active12", usermodel.InvitationStatusActive, time.Hour),
+		newStoredToken("Redeemed", usermodel.InvitationStatusRedeemed, time.Hour),
+		scheduled,
+	)
+	audit := mock.NewMockAuditLogger()
+	biz := userbiz.NewUpdateInvitationTokenBiz(store, audit, newTestHistory(), time.Minute, 24*time.Hour)
+
+	disabled := usermodel.InvitationStatusDisabled
+	require.Nil(t, biz.UpdateInvitationToken(nil, "Active12", &usermodel.InvitationTokenUpdate{Status: &disabled, ExtendBy: 3600}))
+
+	updated, ok := store.Token("Active12")
+	require.True(t, ok)
+	assert.Equal(t, usermodel.InvitationStatusDisabled, updated.Status)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), *updated.ExpiresAt, 2*time.Second)
+	require.Len(t, audit.Entries(), 1)
+	assert.Equal(t, auditmodel.ActionInvitationUpdate, audit.Entries()[0].Action)
+
+	err := biz.UpdateInvitationToken(nil, "Active12", &usermodel.InvitationTokenUpdate{ExtendBy: 48 * 3600})
+	assert.Equal(t, "ErrTokenExpiryOutOfRange", errKey(err))
+
+	// a scheduled token may not expire before it becomes active
+	err = biz.UpdateInvitationToken(nil, "Schedul1", &usermodel.InvitationTokenUpdate{ExtendBy: -3 * 3600})
+	assert.Equal(t, "ErrNotBeforeAfterExpiry", errKey(err))
+	require.Nil(t, biz.UpdateInvitationToken(nil, "Schedul1", &usermodel.InvitationTokenUpdate{ExtendBy: -3600}))
+
+	active := usermodel.InvitationStatusActive
+	err = biz.UpdateInvitationToken(nil, "Redeemed", &usermodel.InvitationTokenUpdate{Status: &active})
+	assert.Equal(t, "ErrInvitationStatusTransition", errKey(err))
+
+	err = biz.UpdateInvitationToken(nil, "Missing1", &usermodel.InvitationTokenUpdate{Status: &active})
+	assert.Equal(t, userbiz.ErrInviteTokenNotExisted, err)
+}
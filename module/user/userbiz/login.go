@@ -11,11 +11,20 @@ type LoginStore interface {
 	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
 }
 
+type LoginLimiter interface {
+	Check(ctx context.Context, email, ip string) error
+	RecordFailure(ctx context.Context, email, ip string) error
+	Reset(ctx context.Context, email, ip string) error
+}
+
 type loginBiz struct {
-	loginStore    LoginStore
-	tokenProvider tokenprovider.Provider
-	hash          Hash
-	tokenConfig   *tokenprovider.TokenConfig
+	loginStore           LoginStore
+	tokenProvider        tokenprovider.Provider
+	hash                 Hash
+	tokenConfig          *tokenprovider.TokenConfig
+	limiter              LoginLimiter
+	requireVerifiedEmail bool
+	mfaChallengeExpiry   int
 }
 
 func NewLoginBiz(
@@ -23,36 +32,88 @@ func NewLoginBiz(
 	tokenProvider tokenprovider.Provider,
 	hash Hash,
 	tokenConfig *tokenprovider.TokenConfig,
+	limiter LoginLimiter,
+	requireVerifiedEmail bool,
+	mfaChallengeExpiry int,
 ) *loginBiz {
 	return &loginBiz{
-		loginStore:    loginStore,
-		tokenProvider: tokenProvider,
-		hash:          hash,
-		tokenConfig:   tokenConfig,
+		loginStore:           loginStore,
+		tokenProvider:        tokenProvider,
+		hash:                 hash,
+		tokenConfig:          tokenConfig,
+		limiter:              limiter,
+		requireVerifiedEmail: requireVerifiedEmail,
+		mfaChallengeExpiry:   mfaChallengeExpiry,
 	}
 }
 
 func (biz *loginBiz) Login(ctx context.Context, data *usermodel.UserLogin) (*usermodel.Account, error) {
+	if err := biz.limiter.Check(ctx, data.Email, data.ClientIP); err != nil {
+		return nil, err
+	}
+
 	user, err := biz.loginStore.FindUser(ctx, map[string]interface{}{"email": data.Email})
-	if err != nil {
-		return nil, usermodel.ErrEmailOrPasswordInvalid
+	if err != nil && err != common.ErrRecordNotFound {
+		return nil, err
 	}
 
-	hashedPassword := biz.hash.Hash(data.Password + user.Salt)
-	if user.Password != hashedPassword {
+	// unknown emails count as failures too, so lockouts can't be used to
+	// find out which emails are registered
+	if user == nil || user.Password != biz.hash.Hash(data.Password+user.Salt) {
+		if err := biz.limiter.RecordFailure(ctx, data.Email, data.ClientIP); err != nil {
+			return nil, err
+		}
 		return nil, usermodel.ErrEmailOrPasswordInvalid
 	}
 
+	if err := biz.limiter.Reset(ctx, data.Email, ""); err != nil {
+		return nil, err
+	}
+
+	// only checked after the password so the error can't be used to probe emails
+	if biz.requireVerifiedEmail && !user.IsEmailVerified() {
+		return nil, usermodel.ErrEmailNotVerified
+	}
+
+	// users with MFA get a challenge token to trade in at the MFA step
+	if user.IsMfaEnabled() {
+		return issueMfaChallenge(biz.tokenProvider, user.Id, biz.mfaChallengeExpiry)
+	}
+
+	return issueAccount(biz.tokenProvider, biz.tokenConfig, user.Id)
+}
+
+func issueMfaChallenge(
+	tokenProvider tokenprovider.Provider,
+	userId int,
+	expiry int,
+) (*usermodel.Account, error) {
+	mfaToken, err := tokenProvider.Generate(
+		tokenprovider.TokenPayload{UserId: userId, Purpose: tokenprovider.PurposeMfa},
+		expiry,
+	)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	return &usermodel.Account{MfaToken: mfaToken}, nil
+}
+
+func issueAccount(
+	tokenProvider tokenprovider.Provider,
+	tokenConfig *tokenprovider.TokenConfig,
+	userId int,
+) (*usermodel.Account, error) {
 	payload := tokenprovider.TokenPayload{
-		UserId: user.Id,
+		UserId: userId,
 	}
 
-	accessToken, err := biz.tokenProvider.Generate(payload, biz.tokenConfig.AccessTokenExpiry)
+	accessToken, err := tokenProvider.Generate(payload, tokenConfig.AccessTokenExpiry)
 	if err != nil {
 		return nil, common.ErrInternal(err)
 	}
 
-	refreshToken, err := biz.tokenProvider.Generate(payload, biz.tokenConfig.RefreshTokenExpiry)
+	refreshToken, err := tokenProvider.Generate(payload, tokenConfig.RefreshTokenExpiry)
 	if err != nil {
 		return nil, common.ErrInternal(err)
 	}
@@ -0,0 +1,207 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	loginFailureKeyPrefix = "login_failure:"
+	loginLockKeyPrefix    = "login_lock:"
+	loginDelayKeyPrefix   = "login_delay:"
+)
+
+var ErrAccountLocked = common.NewFullErrorResponse(
+	http.StatusForbidden,
+	errors.New("account locked"),
+	"account is temporarily locked because of too many failed logins",
+	"account locked",
+	"ErrAccountLocked",
+)
+
+func ErrLoginThrottled(retryAfter time.Duration) *common.AppError {
+	msg := fmt.Sprintf("too many failed logins, retry in %d seconds", int(math.Ceil(retryAfter.Seconds())))
+	return common.NewFullErrorResponse(
+		http.StatusTooManyRequests,
+		errors.New("login throttled"),
+		msg,
+		msg,
+		"ErrLoginThrottled",
+	)
+}
+
+type LoginLimitConfig struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	FailureWindow      time.Duration
+	LockoutDuration    time.Duration
+	// DelayAfter failures in a row, each further attempt has to wait
+	// BaseDelay, doubled per failure and capped at MaxDelay
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// LoginDelay returns how long the next attempt has to wait after `failures`
+// consecutive failed logins
+func LoginDelay(failures int, cfg *LoginLimitConfig) time.Duration {
+	if failures <= cfg.DelayAfter || cfg.BaseDelay <= 0 {
+		return 0
+	}
+
+	exp := failures - cfg.DelayAfter - 1
+	if exp > 30 {
+		return cfg.MaxDelay
+	}
+
+	delay := cfg.BaseDelay * time.Duration(1<<exp)
+	if cfg.MaxDelay > 0 && delay > cfg.MaxDelay {
+		return cfg.MaxDelay
+	}
+
+	return delay
+}
+
+// Counters are keyed by the submitted email whether or not a user owns it,
+// so lockouts don't reveal which emails are registered.
+func accountKey(prefix, email string) string {
+	return prefix + "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(prefix, ip string) string {
+	return prefix + "ip:" + ip
+}
+
+type ILoginLimiter interface {
+	Check(ctx context.Context, email, ip string) error
+	RecordFailure(ctx context.Context, email, ip string) error
+	Reset(ctx context.Context, email, ip string) error
+}
+
+type loginLimiter struct {
+	redis *redis.Client
+	cfg   *LoginLimitConfig
+}
+
+func NewLoginLimiter(redis *redis.Client, cfg *LoginLimitConfig) ILoginLimiter {
+	return &loginLimiter{redis: redis, cfg: cfg}
+}
+
+func (l *loginLimiter) Check(ctx context.Context, email, ip string) error {
+	pipe := l.redis.Pipeline()
+	accountLocked := pipe.Exists(ctx, accountKey(loginLockKeyPrefix, email))
+	ipLocked := pipe.PTTL(ctx, ipKey(loginLockKeyPrefix, ip))
+	delay := pipe.PTTL(ctx, accountKey(loginDelayKeyPrefix, email))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return common.ErrInternal(err)
+	}
+
+	if accountLocked.Val() > 0 {
+		return ErrAccountLocked
+	}
+
+	if ip != "" && ipLocked.Val() > 0 {
+		return ErrLoginThrottled(ipLocked.Val())
+	}
+
+	if delay.Val() > 0 {
+		return ErrLoginThrottled(delay.Val())
+	}
+
+	return nil
+}
+
+func (l *loginLimiter) RecordFailure(ctx context.Context, email, ip string) error {
+	pipe := l.redis.TxPipeline()
+	accountFailures := pipe.Incr(ctx, accountKey(loginFailureKeyPrefix, email))
+	pipe.Expire(ctx, accountKey(loginFailureKeyPrefix, email), l.cfg.FailureWindow)
+	var ipFailures *redis.IntCmd
+	if ip != "" {
+		ipFailures = pipe.Incr(ctx, ipKey(loginFailureKeyPrefix, ip))
+		pipe.Expire(ctx, ipKey(loginFailureKeyPrefix, ip), l.cfg.FailureWindow)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	pipe = l.redis.TxPipeline()
+	if failures := int(accountFailures.Val()); failures >= l.cfg.MaxAccountFailures {
+		pipe.Set(ctx, accountKey(loginLockKeyPrefix, email), 1, l.cfg.LockoutDuration)
+		pipe.Del(ctx, accountKey(loginFailureKeyPrefix, email), accountKey(loginDelayKeyPrefix, email))
+	} else if delay := LoginDelay(failures, l.cfg); delay > 0 {
+		pipe.Set(ctx, accountKey(loginDelayKeyPrefix, email), 1, delay)
+	}
+	if ipFailures != nil && int(ipFailures.Val()) >= l.cfg.MaxIPFailures {
+		pipe.Set(ctx, ipKey(loginLockKeyPrefix, ip), 1, l.cfg.LockoutDuration)
+		pipe.Del(ctx, ipKey(loginFailureKeyPrefix, ip))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// Reset clears the counters, delay and lock of an account, and of an IP when
+// one is given
+func (l *loginLimiter) Reset(ctx context.Context, email, ip string) error {
+	keys := []string{
+		accountKey(loginFailureKeyPrefix, email),
+		accountKey(loginLockKeyPrefix, email),
+		accountKey(loginDelayKeyPrefix, email),
+	}
+	if ip != "" {
+		keys = append(keys, ipKey(loginFailureKeyPrefix, ip), ipKey(loginLockKeyPrefix, ip))
+	}
+
+	if err := l.redis.Del(ctx, keys...).Err(); err != nil {
+		return common.ErrInternal(err)
+	}
+
+	return nil
+}
+
+// Unlock account
+
+type IUnlockAccountBiz interface {
+	UnlockAccount(ctx context.Context, data *usermodel.AccountUnlock) error
+}
+
+type unlockAccountBiz struct {
+	limiter ILoginLimiter
+	audit   AuditLogger
+}
+
+func NewUnlockAccountBiz(limiter ILoginLimiter, audit AuditLogger) IUnlockAccountBiz {
+	return &unlockAccountBiz{limiter: limiter, audit: audit}
+}
+
+func (biz *unlockAccountBiz) UnlockAccount(ctx context.Context, data *usermodel.AccountUnlock) error {
+	if err := data.Validate(); err != nil {
+		return err
+	}
+
+	if err := biz.limiter.Reset(ctx, data.Email, data.IP); err != nil {
+		return err
+	}
+
+	// the lockout is keyed by email whether or not the user exists
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionUserUnlock,
+		TargetType: auditmodel.TargetUser,
+		TargetId:   data.Email,
+		After:      data,
+	})
+
+	return nil
+}
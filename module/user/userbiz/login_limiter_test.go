@@ -0,0 +1,33 @@
+package userbiz_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"app-invite-service/module/user/userbiz"
+)
+
+func TestLoginLimiter_LoginDelay(t *testing.T) {
+	cfg := &userbiz.LoginLimitConfig{DelayAfter: 2, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+	var tcs = []struct {
+		failures int
+		expected time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{6, 8 * time.Second},
+		{8, 30 * time.Second},
+		{100, 30 * time.Second},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.expected, userbiz.LoginDelay(tc.failures, cfg), "failures: %d", tc.failures)
+	}
+
+	assert.Equal(t, time.Duration(0), userbiz.LoginDelay(10, &userbiz.LoginLimitConfig{DelayAfter: 2}))
+}
@@ -0,0 +1,73 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/module/user/usermodel"
+	"context"
+)
+
+type MfaVerifier interface {
+	VerifyMfaCode(ctx context.Context, user *usermodel.User, code string) error
+}
+
+type loginWithMfaBiz struct {
+	loginStore    LoginStore
+	tokenProvider tokenprovider.Provider
+	tokenConfig   *tokenprovider.TokenConfig
+	limiter       LoginLimiter
+	verifier      MfaVerifier
+}
+
+func NewLoginWithMfaBiz(
+	loginStore LoginStore,
+	tokenProvider tokenprovider.Provider,
+	tokenConfig *tokenprovider.TokenConfig,
+	limiter LoginLimiter,
+	verifier MfaVerifier,
+) *loginWithMfaBiz {
+	return &loginWithMfaBiz{
+		loginStore:    loginStore,
+		tokenProvider: tokenProvider,
+		tokenConfig:   tokenConfig,
+		limiter:       limiter,
+		verifier:      verifier,
+	}
+}
+
+// LoginWithMfa is the second step of Login for users with MFA enabled
+func (biz *loginWithMfaBiz) LoginWithMfa(ctx context.Context, data *usermodel.UserLoginMfa) (*usermodel.Account, error) {
+	payload, err := biz.tokenProvider.Validate(data.MfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Purpose != tokenprovider.PurposeMfa || payload.UserId == 0 {
+		return nil, tokenprovider.ErrInvalidToken
+	}
+
+	user, err := biz.loginStore.FindUser(ctx, map[string]interface{}{"id": payload.UserId})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, tokenprovider.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	// wrong codes count towards the same lockout as wrong passwords
+	if err := biz.limiter.Check(ctx, user.Email, data.ClientIP); err != nil {
+		return nil, err
+	}
+
+	if err := biz.verifier.VerifyMfaCode(ctx, user, data.Code); err != nil {
+		if err := biz.limiter.RecordFailure(ctx, user.Email, data.ClientIP); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+
+	if err := biz.limiter.Reset(ctx, user.Email, ""); err != nil {
+		return nil, err
+	}
+
+	return issueAccount(biz.tokenProvider, biz.tokenConfig, user.Id)
+}
@@ -0,0 +1,48 @@
+package userbiz_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"app-invite-service/component/tokenprovider"
+	"app-invite-service/mock"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+)
+
+func TestLoginWithMfaBiz_LoginWithMfa(t *testing.T) {
+	biz := userbiz.NewLoginWithMfaBiz(
+		mock.NewMockUserStore(),
+		mock.NewMockProvider(),
+		&tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800},
+		mock.NewMockLoginLimiter(2),
+		mock.NewMockMfaVerifier(),
+	)
+
+	tcs := []struct {
+		mfaToken    string
+		code        string
+		expectedErr error
+	}{
+		// an access token can't stand in for the challenge
+		{"access-token", "123456", errors.New("invalid token provided")},
+		{"mfa-token", "654321", errors.New("mfa code is invalid")},
+		{"mfa-token", "123456", nil},
+		{"mfa-token", "654321", errors.New("mfa code is invalid")},
+		{"mfa-token", "654321", errors.New("mfa code is invalid")},
+		{"mfa-token", "123456", errors.New("account locked")},
+	}
+
+	for _, tc := range tcs {
+		account, err := biz.LoginWithMfa(nil, &usermodel.UserLoginMfa{MfaToken: tc.mfaToken, Code: tc.code})
+		if tc.expectedErr != nil {
+			assert.Nil(t, account)
+			assert.Equal(t, tc.expectedErr.Error(), err.Error())
+		} else {
+			assert.Nil(t, err)
+			assert.NotNil(t, account.AccessToken)
+		}
+	}
+}
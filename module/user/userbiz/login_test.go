@@ -14,16 +14,21 @@ import (
 
 func TestLoginBiz_Login(t *testing.T) {
 	tcs := []struct {
-		atExpiry    int
-		rtExpiry    int
-		email       string
-		password    string
-		expectedErr error
+		atExpiry             int
+		rtExpiry             int
+		email                string
+		password             string
+		requireVerifiedEmail bool
+		expectedErr          error
 	}{
-		{8600, 60800, "user@gmail.com", "user@123", nil},
-		{8600, 60800, "user@gmail.com", "user@1234", errors.New("email or password invalid")},
-		{8600, 60800, "user@gmail.com", "", errors.New("email or password invalid")},
-		{8600, 60800, "user1@gmail.com", "user@123", errors.New("email or password invalid")},
+		{8600, 60800, "user@gmail.com", "user@123", false, nil},
+		{8600, 60800, "user@gmail.com", "user@1234", false, errors.New("email or password invalid")},
+		{8600, 60800, "user@gmail.com", "", false, errors.New("email or password invalid")},
+		{8600, 60800, "user1@gmail.com", "user@123", false, errors.New("email or password invalid")},
+		{8600, 60800, "user@gmail.com", "user@123", true, nil},
+		{8600, 60800, "unverified@gmail.com", "user@123", false, nil},
+		{8600, 60800, "unverified@gmail.com", "user@123", true, errors.New("email not verified")},
+		{8600, 60800, "unverified@gmail.com", "user@1234", true, errors.New("email or password invalid")},
 	}
 
 	for _, tc := range tcs {
@@ -32,6 +37,9 @@ func TestLoginBiz_Login(t *testing.T) {
 			mock.NewMockProvider(),
 			mock.NewMockHash(),
 			&tokenprovider.TokenConfig{AccessTokenExpiry: tc.atExpiry, RefreshTokenExpiry: tc.rtExpiry},
+			mock.NewMockLoginLimiter(5),
+			tc.requireVerifiedEmail,
+			300,
 		)
 		user, err := biz.Login(nil, &usermodel.UserLogin{Email: tc.email, Password: tc.password})
 		if tc.expectedErr != nil {
@@ -40,6 +48,67 @@ func TestLoginBiz_Login(t *testing.T) {
 			assert.Equal(t, tc.expectedErr.Error(), err.Error())
 		} else {
 			assert.NotNil(t, user)
+			assert.NotNil(t, user.AccessToken)
+			assert.Nil(t, user.MfaToken)
+		}
+	}
+}
+
+func TestLoginBiz_LoginRequiresMfa(t *testing.T) {
+	biz := userbiz.NewLoginBiz(
+		mock.NewMockUserStore(),
+		mock.NewMockProvider(),
+		mock.NewMockHash(),
+		&tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800},
+		mock.NewMockLoginLimiter(5),
+		false,
+		300,
+	)
+
+	account, err := biz.Login(nil, &usermodel.UserLogin{Email: "mfa@gmail.com", Password: "user@123"})
+	assert.Nil(t, err)
+	assert.Nil(t, account.AccessToken)
+	assert.Nil(t, account.RefreshToken)
+	assert.NotNil(t, account.MfaToken)
+	assert.Equal(t, 300, account.MfaToken.Expiry)
+}
+
+func TestLoginBiz_LoginLockout(t *testing.T) {
+	biz := userbiz.NewLoginBiz(
+		mock.NewMockUserStore(),
+		mock.NewMockProvider(),
+		mock.NewMockHash(),
+		&tokenprovider.TokenConfig{AccessTokenExpiry: 8600, RefreshTokenExpiry: 60800},
+		mock.NewMockLoginLimiter(2),
+		false,
+		300,
+	)
+
+	tcs := []struct {
+		email       string
+		password    string
+		expectedErr error
+	}{
+		// a successful login resets the counter
+		{"user@gmail.com", "wrong@123", errors.New("email or password invalid")},
+		{"user@gmail.com", "user@123", nil},
+		{"user@gmail.com", "wrong@123", errors.New("email or password invalid")},
+		{"user@gmail.com", "wrong@123", errors.New("email or password invalid")},
+		{"user@gmail.com", "user@123", errors.New("account locked")},
+		// unknown emails are locked the same way
+		{"nobody@gmail.com", "wrong@123", errors.New("email or password invalid")},
+		{"nobody@gmail.com", "wrong@123", errors.New("email or password invalid")},
+		{"nobody@gmail.com", "wrong@123", errors.New("account locked")},
+	}
+
+	for _, tc := range tcs {
+		account, err := biz.Login(nil, &usermodel.UserLogin{Email: tc.email, Password: tc.password})
+		if tc.expectedErr != nil {
+			assert.Nil(t, account)
+			assert.Equal(t, tc.expectedErr.Error(), err.Error())
+		} else {
+			assert.Nil(t, err)
+			assert.NotNil(t, account)
 		}
 	}
 }
@@ -2,26 +2,84 @@ package userbiz
 
 import (
 	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/campaign/campaignmodel"
 	"app-invite-service/module/user/usermodel"
 	"context"
+	"errors"
+	"log"
+)
+
+var ErrInvitationTokenRequired = common.NewCustomError(
+	errors.New("invitation token required"),
+	"an invitation token is required to register",
+	"ErrInvitationTokenRequired",
 )
 
 type RegisterStore interface {
 	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
 	CreateUser(ctx context.Context, data *usermodel.UserCreate) error
+	DeleteUser(ctx context.Context, id int) error
 }
 
 type Hash interface {
 	Hash(data string) string
 }
 
+type EmailVerificationSender interface {
+	SendEmailVerification(ctx context.Context, userId int, email string) error
+}
+
+type InvitationChecker interface {
+	ValidateInvitationTokenForEmail(ctx context.Context, token, email string) (*usermodel.InvitationToken, error)
+	CheckInvitationCampaign(ctx context.Context, token *usermodel.InvitationToken) (*campaignmodel.Campaign, error)
+	RedeemInvitationToken(ctx context.Context, token *usermodel.InvitationToken) (*campaignmodel.Campaign, error)
+}
+
+// ReferralRecorder remembers who invited a new user
+type ReferralRecorder interface {
+	RecordReferral(ctx context.Context, inviterId, inviteeId int, invitationToken string) error
+}
+
+type DomainChecker interface {
+	CheckEmailDomain(ctx context.Context, domain string) error
+}
+
+type AuditLogger interface {
+	Record(ctx context.Context, entry *auditmodel.Entry)
+}
+
 type registerBiz struct {
-	store RegisterStore
-	hash  Hash
+	store      RegisterStore
+	hash       Hash
+	verifier   EmailVerificationSender
+	invites    InvitationChecker
+	domains    DomainChecker
+	audit      AuditLogger
+	referrals  ReferralRecorder
+	inviteOnly bool
 }
 
-func NewRegisterBiz(store RegisterStore, hash Hash) *registerBiz {
-	return &registerBiz{store: store, hash: hash}
+func NewRegisterBiz(
+	store RegisterStore,
+	hash Hash,
+	verifier EmailVerificationSender,
+	invites InvitationChecker,
+	domains DomainChecker,
+	audit AuditLogger,
+	referrals ReferralRecorder,
+	inviteOnly bool,
+) *registerBiz {
+	return &registerBiz{
+		store:      store,
+		hash:       hash,
+		verifier:   verifier,
+		invites:    invites,
+		domains:    domains,
+		audit:      audit,
+		referrals:  referrals,
+		inviteOnly: inviteOnly,
+	}
 }
 
 func (biz *registerBiz) Register(ctx context.Context, data *usermodel.UserCreate) error {
@@ -29,6 +87,25 @@ func (biz *registerBiz) Register(ctx context.Context, data *usermodel.UserCreate
 		return err
 	}
 
+	if err := biz.domains.CheckEmailDomain(ctx, usermodel.EmailDomain(data.Email)); err != nil {
+		return err
+	}
+
+	if biz.inviteOnly && data.InvitationToken == "" {
+		return ErrInvitationTokenRequired
+	}
+
+	var invitation *usermodel.InvitationToken
+	if data.InvitationToken != "" {
+		token, err := biz.invites.ValidateInvitationTokenForEmail(ctx, data.InvitationToken, data.Email)
+		if err != nil {
+			return err
+		}
+		invitation = token
+		// codes may be typed in any case; keep the stored form
+		data.InvitationToken = token.Token
+	}
+
 	user, err := biz.store.FindUser(ctx, map[string]interface{}{"email": data.Email})
 
 	if user != nil {
@@ -41,8 +118,26 @@ func (biz *registerBiz) Register(ctx context.Context, data *usermodel.UserCreate
 		data.Password = biz.hash.Hash(data.Password + salt)
 		data.Salt = salt
 
-		if err := biz.store.CreateUser(ctx, data); err != nil {
-			return common.ErrCannotCreateEntity(usermodel.EntityName, err)
+		if err := createInvitedUser(ctx, biz.store, biz.invites, invitation, data); err != nil {
+			return err
+		}
+
+		biz.audit.Record(ctx, &auditmodel.Entry{
+			Action:     auditmodel.ActionUserRegister,
+			TargetType: auditmodel.TargetUser,
+			TargetId:   auditmodel.IntId(data.Id),
+			After:      map[string]interface{}{"email": data.Email, "invitation_token_id": invitationTokenId(invitation)},
+		})
+
+		if err := recordReferral(ctx, biz.referrals, invitation, data.Id); err != nil {
+			return err
+		}
+
+		// the account exists at this point; if sending fails the user can
+		// ask for another email through the resend endpoint, so registering
+		// still succeeds
+		if err := biz.verifier.SendEmailVerification(ctx, data.Id, data.Email); err != nil {
+			log.Printf("register: cannot send verification email to user %d: %v", data.Id, err)
 		}
 	} else {
 		return common.ErrDB(err)
@@ -50,3 +145,65 @@ func (biz *registerBiz) Register(ctx context.Context, data *usermodel.UserCreate
 
 	return nil
 }
+
+// createInvitedUser creates the user who signed up with invitation, with the
+// role its campaign grants, and only then uses the invitation up, so an
+// insert which fails doesn't cost it a redemption. The user is deleted again
+// when the invitation turns out to be used up in the meantime.
+func createInvitedUser(
+	ctx context.Context,
+	store RegisterStore,
+	invites InvitationChecker,
+	invitation *usermodel.InvitationToken,
+	data *usermodel.UserCreate,
+) error {
+	if invitation != nil {
+		campaign, err := invites.CheckInvitationCampaign(ctx, invitation)
+		if err != nil {
+			return err
+		}
+		if campaign != nil && campaign.DefaultRole != "" {
+			data.Role = campaign.DefaultRole
+		}
+	}
+
+	if err := store.CreateUser(ctx, data); err != nil {
+		return common.ErrCannotCreateEntity(usermodel.EntityName, err)
+	}
+
+	if invitation == nil {
+		return nil
+	}
+
+	if _, err := invites.RedeemInvitationToken(ctx, invitation); err != nil {
+		if deleteErr := store.DeleteUser(ctx, data.Id); deleteErr != nil {
+			log.Printf("register: cannot delete user %d whose invitation was used up: %v", data.Id, deleteErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// invitationTokenId names the invitation a user signed up with in the audit
+// log, which must not keep the token itself
+func invitationTokenId(invitation *usermodel.InvitationToken) string {
+	if invitation == nil {
+		return ""
+	}
+	return usermodel.InvitationTokenId(invitation.Token)
+}
+
+// recordReferral links a new user to whoever created their invitation
+func recordReferral(
+	ctx context.Context,
+	referrals ReferralRecorder,
+	invitation *usermodel.InvitationToken,
+	userId int,
+) error {
+	if invitation == nil || invitation.CreatedBy == 0 {
+		return nil
+	}
+
+	return referrals.RecordReferral(ctx, invitation.CreatedBy, userId, invitation.Token)
+}
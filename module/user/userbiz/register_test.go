@@ -8,33 +8,176 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUserBiz_Register(t *testing.T) {
 	tcs := []struct {
 		email       string
 		password    string
+		inviteToken string
+		inviteOnly  bool
 		expectedErr error
 	}{
-		{"user1@gmail.com", "user@123", nil},
-		{"user@gmail.com", "user@123", errors.New("user already exists")},
-		{"user2@gmail.com", "", errors.New("password must have at least 8 characters")},
-		{"user3@gmail.com", "user", errors.New("password must have at least 8 characters")},
-		{"user4@gmail.com", "password", errors.New("password must have at least 1 number")},
-		{"user5@gmail.com", "12345678", errors.New("password must have at least 1 letter")},
-		{"user6@gmail.com", "pass1234", errors.New("password must have at least 1 special character")},
-		{"user7@gmail.com", "!@#$%^&*", errors.New("password must have at least 1 number")},
+		{"user1@gmail.com", "user@123", "", false, nil},
+		{"user@gmail.com", "user@123", "", false, errors.New("user already exists")},
+		{"user2@gmail.com", "", "", false, errors.New("password must have at least 8 characters")},
+		{"user3@gmail.com", "user", "", false, errors.New("password must have at least 8 characters")},
+		{"user4@gmail.com", "password", "", false, errors.New("password must have at least 1 number")},
+		{"user5@gmail.com", "12345678", "", false, errors.New("password must have at least 1 letter")},
+		{"user6@gmail.com", "pass1234", "", false, errors.New("password must have at least 1 special character")},
+		{"user7@gmail.com", "!@#$%^&*", "", false, errors.New("password must have at least 1 number")},
+		{"user1@gmail.com", "user@123", "", true, errors.New("invitation token required")},
+		{"user1@gmail.com", "user@123", "invite123", true, nil},
+		{"user1@gmail.com", "user@123", "unknown", false, errors.New("invite token not existed")},
+		{"user1@gmail.com", "user@123", "bound123", true, nil},
+		{"USER1@gmail.com", "user@123", "bound123", true, nil},
+		{"user8@gmail.com", "user@123", "bound123", true, errors.New("invite token was issued for a different email")},
+		{"user@blocked.com", "user@123", "", false, errors.New("email domain not allowed")},
+		{"user@blocked.com", "user@123", "invite123", false, errors.New("email domain not allowed")},
 	}
 
 	for _, tc := range tcs {
 		biz := userbiz.NewRegisterBiz(
 			mock.NewMockUserStore(),
 			mock.NewMockHash(),
+			mock.NewMockEmailVerificationSender(),
+			mock.NewMockInvitationChecker(),
+			mock.NewMockDomainChecker(),
+			mock.NewMockAuditLogger(),
+			mock.NewMockReferralRecorder(),
+			tc.inviteOnly,
 		)
-		err := biz.Register(nil, &usermodel.UserCreate{Email: tc.email, Password: tc.password})
+		err := biz.Register(nil, &usermodel.UserCreate{Email: tc.email, Password: tc.password, InvitationToken: tc.inviteToken})
 		if tc.expectedErr != nil {
 			assert.Error(t, err)
 			assert.Equal(t, tc.expectedErr.Error(), err.Error())
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+}
+
+func TestRegisterBiz_RecordsReferral(t *testing.T) {
+	tcs := []struct {
+		email         string
+		inviteToken   string
+		expectedEdges int
+	}{
+		{"user1@gmail.com", "", 0},
+		{"user1@gmail.com", "invite123", 0},
+		{"user1@gmail.com", "referral123", 1},
+	}
+
+	for _, tc := range tcs {
+		referrals := mock.NewMockReferralRecorder()
+		biz := userbiz.NewRegisterBiz(
+			mock.NewMockUserStore(),
+			mock.NewMockHash(),
+			mock.NewMockEmailVerificationSender(),
+			mock.NewMockInvitationChecker(),
+			mock.NewMockDomainChecker(),
+			mock.NewMockAuditLogger(),
+			referrals,
+			false,
+		)
+
+		data := usermodel.UserCreate{Email: tc.email, Password: "user@123", InvitationToken: tc.inviteToken}
+		require.Nil(t, biz.Register(nil, &data))
+
+		require.Len(t, referrals.Referrals(), tc.expectedEdges, tc.inviteToken)
+		if tc.expectedEdges > 0 {
+			assert.Equal(t, 1, referrals.Referrals()[0].InviterId)
+			assert.Equal(t, data.Id, referrals.Referrals()[0].InviteeId)
+			assert.Equal(t, tc.inviteToken, referrals.Referrals()[0].InvitationToken)
+		}
+	}
+}
+
+func TestRegisterBiz_GrantsCampaignRole(t *testing.T) {
+	tcs := []struct {
+		inviteToken  string
+		expectedRole string
+	}{
+		{"invite123", ""},
+		{"campaign123", "admin"},
+	}
+
+	for _, tc := range tcs {
+		biz := userbiz.NewRegisterBiz(
+			mock.NewMockUserStore(),
+			mock.NewMockHash(),
+			mock.NewMockEmailVerificationSender(),
+			mock.NewMockInvitationChecker(),
+			mock.NewMockDomainChecker(),
+			mock.NewMockAuditLogger(),
+			mock.NewMockReferralRecorder(),
+			false,
+		)
+
+		data := usermodel.UserCreate{Email: "user1@gmail.com", Password: "user@123", InvitationToken: tc.inviteToken}
+		require.Nil(t, biz.Register(nil, &data))
+		assert.Equal(t, tc.expectedRole, data.Role, tc.inviteToken)
+	}
+}
+
+func TestRegisterBiz_AuditsInvitationById(t *testing.T) {
+	audit := mock.NewMockAuditLogger()
+	biz := userbiz.NewRegisterBiz(
+		mock.NewMockUserStore(),
+		mock.NewMockHash(),
+		mock.NewMockEmailVerificationSender(),
+		mock.NewMockInvitationChecker(),
+		mock.NewMockDomainChecker(),
+		audit,
+		mock.NewMockReferralRecorder(),
+		false,
+	)
+
+	data := usermodel.UserCreate{Email: "user1@gmail.com", Password: "user@123", InvitationToken: "invite123"}
+	require.Nil(t, biz.Register(nil, &data))
+
+	require.Len(t, audit.Entries(), 1)
+	assert.Equal(t, map[string]interface{}{
+		"email":               "user1@gmail.com",
+		"invitation_token_id": usermodel.InvitationTokenId("invite123"),
+	}, audit.Entries()[0].After)
+}
+
+func TestRegisterBiz_RedeemsOnlyOnceCreated(t *testing.T) {
+	tcs := []struct {
+		email       string
+		inviteToken string
+		expectedErr string
+		redeemed    []string
+		deleted     []int
+	}{
+		{"user1@gmail.com", "campaign123", "", []string{"campaign123"}, nil},
+		{"taken@gmail.com", "campaign123", "ErrCannotCreateUser", nil, nil},
+		{"user1@gmail.com", "exhausted123", "ErrCampaignExhausted", nil, []int{3}},
+	}
+
+	for _, tc := range tcs {
+		store := mock.NewMockUserStore()
+		invites := mock.NewMockInvitationChecker()
+		audit := mock.NewMockAuditLogger()
+		biz := userbiz.NewRegisterBiz(
+			store,
+			mock.NewMockHash(),
+			mock.NewMockEmailVerificationSender(),
+			invites,
+			mock.NewMockDomainChecker(),
+			audit,
+			mock.NewMockReferralRecorder(),
+			false,
+		)
+
+		err := biz.Register(nil, &usermodel.UserCreate{Email: tc.email, Password: "user@123", InvitationToken: tc.inviteToken})
+		assert.Equal(t, tc.expectedErr, errKey(err), tc.email)
+		assert.Equal(t, tc.redeemed, invites.Redeemed(), tc.email)
+		assert.Equal(t, tc.deleted, store.Deleted(), tc.email)
+		if tc.expectedErr != "" {
+			assert.Empty(t, audit.Entries(), tc.email)
 		}
 	}
 }
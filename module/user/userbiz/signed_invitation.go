@@ -0,0 +1,338 @@
+package userbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/component/invitesigner"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/invitehistory/invitehistorymodel"
+	usermodel "app-invite-service/module/user/usermodel"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrSignedInvitationDisabled = common.NewFullErrorResponse(
+		http.StatusNotFound,
+		errors.New("signed invitation disabled"),
+		"signed invitation tokens are not enabled",
+		"signed invitation disabled",
+		"ErrSignedInvitationDisabled",
+	)
+	ErrSignedInvitationNoPublicKey = common.NewFullErrorResponse(
+		http.StatusNotFound,
+		errors.New("signed invitation has no public key"),
+		"signed invitation tokens are checked with a shared secret, there is no public key",
+		"signed invitation has no public key",
+		"ErrSignedInvitationNoPublicKey",
+	)
+	ErrInviteTokenExpired = common.NewCustomError(
+		errors.New("invite token expired"),
+		"invite token has expired",
+		"ErrInviteTokenExpired",
+	)
+	ErrInviteTokenRevoked = common.NewCustomError(
+		errors.New("invite token revoked"),
+		"invite token has been revoked",
+		"ErrInviteTokenRevoked",
+	)
+	ErrInviteTokenUsedUp = common.NewCustomError(
+		errors.New("invite token used up"),
+		"invite token has no uses left",
+		"ErrInviteTokenUsedUp",
+	)
+)
+
+type InviteSigner interface {
+	Algorithm() string
+	Sign(claims *invitesigner.Claims) (string, error)
+	Verify(token string, now time.Time) (*invitesigner.Claims, error)
+}
+
+type SignedInvitationStore interface {
+	Revoke(ctx context.Context, id string, until time.Time) error
+	IsRevoked(ctx context.Context, id string) (bool, error)
+	CountUses(ctx context.Context, id string) (int, error)
+	AddUse(ctx context.Context, id string, maxUses int, until time.Time) (bool, error)
+}
+
+// SignedTokenChecker checks signed tokens for the validation and login bizs
+type SignedTokenChecker interface {
+	CheckSignedToken(ctx context.Context, token string) (*usermodel.InvitationToken, error)
+	UseSignedToken(ctx context.Context, token *usermodel.InvitationToken) error
+}
+
+// lookupInvitationToken checks a signed token or loads a stored one
+func lookupInvitationToken(
+	ctx context.Context,
+	store InvitationTokenStore,
+	signed SignedTokenChecker,
+	token string,
+) (*usermodel.InvitationToken, error) {
+	token, err := usermodel.NormalizeInvitationToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if invitesigner.IsSigned(token) {
+		return signed.CheckSignedToken(ctx, token)
+	}
+
+	return findInvitationToken(ctx, store, token)
+}
+
+// Check signed token
+
+type signedTokenBiz struct {
+	signer InviteSigner
+	store  SignedInvitationStore
+}
+
+// NewSignedTokenBiz checks tokens signed by signer, which is nil while signed
+// tokens are off. The store is consulted when it can be reached; tokens are
+// still accepted on their signature alone when it can't.
+func NewSignedTokenBiz(signer InviteSigner, store SignedInvitationStore) SignedTokenChecker {
+	return &signedTokenBiz{signer: signer, store: store}
+}
+
+func (biz *signedTokenBiz) CheckSignedToken(ctx context.Context, token string) (*usermodel.InvitationToken, error) {
+	if biz.signer == nil {
+		return nil, ErrInvalidInviteToken
+	}
+
+	claims, err := biz.signer.Verify(token, time.Now())
+	if err == invitesigner.ErrExpired {
+		return nil, ErrInviteTokenExpired
+	}
+	if err != nil {
+		return nil, ErrInvalidInviteToken
+	}
+
+	if revoked, err := biz.store.IsRevoked(ctx, claims.Id); err == nil && revoked {
+		return nil, ErrInviteTokenRevoked
+	}
+
+	// a token used as often as it may be is exhausted, which the status
+	// check of every caller rejects
+	status := usermodel.InvitationStatusActive
+	var uses int
+	if claims.MaxUses > 0 {
+		if n, err := biz.store.CountUses(ctx, claims.Id); err == nil {
+			if n >= claims.MaxUses {
+				status = usermodel.InvitationStatusExhausted
+			}
+			uses = n
+		}
+	}
+
+	expiresAt := claims.ExpiresAt
+	return &usermodel.InvitationToken{
+		Token:      claims.Id,
+		Status:     status,
+		CampaignId: claims.CampaignId,
+		ExpiresAt:  &expiresAt,
+		MaxUses:    claims.MaxUses,
+		Uses:       uses,
+		Signed:     true,
+	}, nil
+}
+
+// UseSignedToken counts a use of a checked token against its max uses
+func (biz *signedTokenBiz) UseSignedToken(ctx context.Context, token *usermodel.InvitationToken) error {
+	if token.MaxUses == 0 {
+		return nil
+	}
+
+	ok, err := biz.store.AddUse(ctx, token.Token, token.MaxUses, *token.ExpiresAt)
+	if err == nil && !ok {
+		return ErrInviteTokenUsedUp
+	}
+
+	return nil
+}
+
+// Generate signed token
+
+type IGenerateSignedTokenBiz interface {
+	GenerateSignedToken(ctx context.Context, data *usermodel.SignedInvitationCreate) (*usermodel.SignedInvitation, error)
+}
+
+type generateSignedTokenBiz struct {
+	signer    InviteSigner
+	quotas    InviteQuota
+	campaigns CampaignPolicy
+	audit     AuditLogger
+	history   InvitationHistory
+	maxTTL    int
+}
+
+// NewGenerateSignedTokenBiz signs tokens living at most maxTTL seconds
+func NewGenerateSignedTokenBiz(
+	signer InviteSigner,
+	quotas InviteQuota,
+	campaigns CampaignPolicy,
+	audit AuditLogger,
+	history InvitationHistory,
+	maxTTL int,
+) IGenerateSignedTokenBiz {
+	return &generateSignedTokenBiz{
+		signer:    signer,
+		quotas:    quotas,
+		campaigns: campaigns,
+		audit:     audit,
+		history:   history,
+		maxTTL:    maxTTL,
+	}
+}
+
+func (biz *generateSignedTokenBiz) GenerateSignedToken(
+	ctx context.Context,
+	data *usermodel.SignedInvitationCreate,
+) (*usermodel.SignedInvitation, error) {
+	if biz.signer == nil {
+		return nil, ErrSignedInvitationDisabled
+	}
+
+	if err := data.Validate(biz.maxTTL); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	ttl := common.InviteTokenExpirySecond * time.Second
+	if data.TTL != 0 {
+		ttl = time.Duration(data.TTL) * time.Second
+	}
+
+	if data.CampaignId != 0 {
+		campaign, err := biz.campaigns.CampaignForIssue(ctx, data.CampaignId)
+		if err != nil {
+			return nil, err
+		}
+		// a requested ttl wins over the campaign's, but neither outlives it
+		if data.TTL == 0 {
+			ttl = campaign.TokenExpiry(now, ttl)
+		} else if campaign.EndsAt != nil && now.Add(ttl).After(*campaign.EndsAt) {
+			ttl = campaign.EndsAt.Sub(now)
+		}
+	}
+
+	// revocations can't reach tokens checked offline, so none lives longer
+	// than max_ttl
+	if maxTTL := time.Duration(biz.maxTTL) * time.Second; ttl > maxTTL {
+		ttl = maxTTL
+	}
+
+	var reservation string
+	if data.CreatedBy != 0 {
+		var err error
+		if reservation, err = biz.quotas.ReserveInvite(ctx, data.CreatedBy); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := biz.sign(data, now.Add(ttl).Truncate(time.Second))
+	if err != nil {
+		if reservation != "" {
+			_ = biz.quotas.ReleaseInvite(ctx, data.CreatedBy, reservation)
+		}
+		return nil, err
+	}
+
+	// the token is a bearer credential, so only its id is recorded
+	recorded := *result
+	recorded.Token = ""
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionInvitationGenerate,
+		TargetType: auditmodel.TargetInvitationToken,
+		TargetId:   result.Id,
+		After:      recorded,
+	})
+
+	event := invitehistorymodel.Event{Token: result.Id, Action: invitehistorymodel.ActionIssue}
+	withTokenMetadata(&event, &usermodel.InvitationToken{CreatedBy: data.CreatedBy, CreatedAt: &now})
+	if err := recordAttempt(ctx, biz.history, &event, nil); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (biz *generateSignedTokenBiz) sign(
+	data *usermodel.SignedInvitationCreate,
+	expiresAt time.Time,
+) (*usermodel.SignedInvitation, error) {
+	id, err := invitesigner.NewId()
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	token, err := biz.signer.Sign(&invitesigner.Claims{
+		Id:         id,
+		ExpiresAt:  expiresAt,
+		CampaignId: data.CampaignId,
+		MaxUses:    data.MaxUses,
+	})
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	return &usermodel.SignedInvitation{
+		Token:      token,
+		Id:         id,
+		Algorithm:  biz.signer.Algorithm(),
+		CampaignId: data.CampaignId,
+		MaxUses:    data.MaxUses,
+		CreatedBy:  data.CreatedBy,
+		ExpiresAt:  &expiresAt,
+	}, nil
+}
+
+// Revoke signed token
+
+type IRevokeSignedTokenBiz interface {
+	RevokeSignedToken(ctx context.Context, token string) error
+}
+
+type revokeSignedTokenBiz struct {
+	signer InviteSigner
+	store  SignedInvitationStore
+	audit  AuditLogger
+}
+
+func NewRevokeSignedTokenBiz(signer InviteSigner, store SignedInvitationStore, audit AuditLogger) IRevokeSignedTokenBiz {
+	return &revokeSignedTokenBiz{signer: signer, store: store, audit: audit}
+}
+
+// RevokeSignedToken puts the token on the revocation list until it expires.
+// Expired tokens can't be used anyway and are left alone.
+func (biz *revokeSignedTokenBiz) RevokeSignedToken(ctx context.Context, token string) error {
+	if biz.signer == nil {
+		return ErrSignedInvitationDisabled
+	}
+
+	token, err := usermodel.NormalizeInvitationToken(token)
+	if err != nil {
+		return err
+	}
+
+	claims, err := biz.signer.Verify(token, time.Now())
+	if err == invitesigner.ErrExpired {
+		return nil
+	}
+	if err != nil {
+		return ErrInvalidInviteToken
+	}
+
+	if err := biz.store.Revoke(ctx, claims.Id, claims.ExpiresAt); err != nil {
+		return err
+	}
+
+	biz.audit.Record(ctx, &auditmodel.Entry{
+		Action:     auditmodel.ActionInvitationRevoke,
+		TargetType: auditmodel.TargetInvitationToken,
+		TargetId:   claims.Id,
+	})
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package userbiz_test
+
+import (
+	"app-invite-service/component/invitesigner"
+	"app-invite-service/mock"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/invitehistory/invitehistorybiz"
+	"app-invite-service/module/invitehistory/invitehistorymodel"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSigner() invitesigner.Signer {
+	return invitesigner.NewHMACSigner([]byte(strings.Repeat("s", 32)))
+}
+
+func newSignedToken(t *testing.T, signer invitesigner.Signer, expiresAt time.Time, maxUses int) string {
+	id, err := invitesigner.NewId()
+	require.Nil(t, err)
+	token, err := signer.Sign(&invitesigner.Claims{Id: id, ExpiresAt: expiresAt, MaxUses: maxUses})
+	require.Nil(t, err)
+	return token
+}
+
+func TestSignedTokenBiz_CheckSignedToken(t *testing.T) {
+	signer := newTestSigner()
+	store := mock.NewMockSignedInvitationStore()
+	biz := userbiz.NewSignedTokenBiz(signer, store)
+
+	valid := newSignedToken(t, signer, time.Now().Add(time.Hour), 0)
+	expired := newSignedToken(t, signer, time.Now().Add(-time.Hour), 0)
+	forged := newSignedToken(t, invitesigner.NewHMACSigner([]byte(strings.Repeat("x", 32))), time.Now().Add(time.Hour), 0)
+	revoked := newSignedToken(t, signer, time.Now().Add(time.Hour), 0)
+	require.Nil(t, userbiz.NewRevokeSignedTokenBiz(signer, store, mock.NewMockAuditLogger()).RevokeSignedToken(nil, revoked))
+
+	tcs := []struct {
+		name        string
+		token       string
+		expectedErr error
+	}{
+		{"valid", valid, nil},
+		{"expired", expired, userbiz.ErrInviteTokenExpired},
+		{"forged", forged, userbiz.ErrInvalidInviteToken},
+		{"revoked", revoked, userbiz.ErrInviteTokenRevoked},
+	}
+
+	for _, tc := range tcs {
+		token, err := biz.CheckSignedToken(nil, tc.token)
+		if tc.expectedErr != nil {
+			assert.Equal(t, tc.expectedErr, err, tc.name)
+			continue
+		}
+		require.Nil(t, err, tc.name)
+		assert.True(t, token.Signed, tc.name)
+		assert.Equal(t, usermodel.InvitationStatusActive, token.Status, tc.name)
+	}
+
+	_, err := userbiz.NewSignedTokenBiz(nil, store).CheckSignedToken(nil, valid)
+	assert.Equal(t, userbiz.ErrInvalidInviteToken, err)
+}
+
+func TestSignedTokenBiz_UseSignedToken(t *testing.T) {
+	signer := newTestSigner()
+	biz := userbiz.NewSignedTokenBiz(signer, mock.NewMockSignedInvitationStore())
+	token := newSignedToken(t, signer, time.Now().Add(time.Hour), 2)
+
+	for i := 0; i < 2; i++ {
+		checked, err := biz.CheckSignedToken(nil, token)
+		require.Nil(t, err)
+		assert.Nil(t, biz.UseSignedToken(nil, checked))
+	}
+
+	checked, err := biz.CheckSignedToken(nil, token)
+	require.Nil(t, err)
+	assert.Equal(t, usermodel.InvitationStatusExhausted, checked.Status)
+
+	_, err = userbiz.NewValidateInviteTokenBiz(nil, biz, nil, newTestHistory()).
+		ValidateInvitationTokenForEmail(nil, token, "user@gmail.com")
+	assert.Equal(t, userbiz.ErrInviteTokenUsedUp, err)
+}
+
+func TestGenerateSignedTokenBiz_GenerateSignedToken(t *testing.T) {
+	signer := newTestSigner()
+	audit := mock.NewMockAuditLogger()
+	history := invitehistorybiz.NewRecordEventBiz(
+		mock.NewMockInvitationEventStore(nil, nil),
+		func(context.Context) *invitehistorymodel.RequestInfo { return nil },
+	)
+	biz := userbiz.NewGenerateSignedTokenBiz(signer, nil, nil, audit, history, 3600)
+
+	result, err := biz.GenerateSignedToken(nil, &usermodel.SignedInvitationCreate{TTL: 600, MaxUses: 3})
+	require.Nil(t, err)
+	assert.Equal(t, invitesigner.AlgorithmHMAC, result.Algorithm)
+
+	claims, err := signer.Verify(result.Token, time.Now())
+	require.Nil(t, err)
+	assert.Equal(t, result.Id, claims.Id)
+	assert.Equal(t, 3, claims.MaxUses)
+	assert.WithinDuration(t, time.Now().Add(600*time.Second), claims.ExpiresAt, 2*time.Second)
+
+	// the signed token itself never reaches the audit log
+	require.Len(t, audit.Entries(), 1)
+	assert.Equal(t, auditmodel.ActionInvitationGenerate, audit.Entries()[0].Action)
+	assert.Equal(t, result.Id, audit.Entries()[0].TargetId)
+	assert.Equal(t, "", audit.Entries()[0].After.(usermodel.SignedInvitation).Token)
+
+	_, err = biz.GenerateSignedToken(nil, &usermodel.SignedInvitationCreate{TTL: 7200})
+	assert.Error(t, err)
+
+	_, err = userbiz.NewGenerateSignedTokenBiz(nil, nil, nil, audit, history, 3600).
+		GenerateSignedToken(nil, &usermodel.SignedInvitationCreate{})
+	assert.Equal(t, userbiz.ErrSignedInvitationDisabled, err)
+}
@@ -0,0 +1,104 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var ErrIdentityProviderNotFound = common.NewFullErrorResponse(
+	http.StatusNotFound,
+	errors.New("identity provider not found"),
+	"identity provider not found",
+	"identity provider not found",
+	"ErrIdentityProviderNotFound",
+)
+
+var ErrFederatedStateInvalid = common.NewCustomError(
+	errors.New("federated login state invalid"),
+	"sign in request is invalid or has expired, please start again",
+	"ErrFederatedStateInvalid",
+)
+
+var ErrFederatedEmailNotVerified = common.NewCustomError(
+	errors.New("federated email not verified"),
+	"identity provider did not return a verified email address",
+	"ErrFederatedEmailNotVerified",
+)
+
+var ErrFederatedInvitationRequired = common.NewCustomError(
+	errors.New("federated account requires invitation"),
+	"no account is linked to this identity, an invitation token is required to create one",
+	"ErrFederatedInvitationRequired",
+)
+
+var ErrFederatedAccountExists = common.NewFullErrorResponse(
+	http.StatusConflict,
+	errors.New("federated email has an account"),
+	"an account with this email exists, sign in and link the identity provider from it",
+	"federated email has an account",
+	"ErrFederatedAccountExists",
+)
+
+var ErrFederatedIdentityLinked = common.NewFullErrorResponse(
+	http.StatusConflict,
+	errors.New("federated identity linked to another user"),
+	"this identity is linked to another account",
+	"federated identity linked to another user",
+	"ErrFederatedIdentityLinked",
+)
+
+func ErrFederatedLoginFailed(err error) *common.AppError {
+	return common.NewUnauthorized(err, "cannot sign in with the identity provider", "ErrFederatedLoginFailed")
+}
+
+// UserIdentity links a users row to an account at an upstream OIDC provider
+type UserIdentity struct {
+	Id        int        `json:"id" gorm:"column:id;"`
+	UserId    int        `json:"user_id" gorm:"column:user_id;"`
+	Provider  string     `json:"provider" gorm:"column:provider;"`
+	Subject   string     `json:"subject" gorm:"column:subject;"`
+	Email     string     `json:"email" gorm:"column:email;"`
+	CreatedAt *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+type FederatedLoginStart struct {
+	// only needed when no account exists yet for the upstream identity
+	InvitationToken string `json:"invitation_token" form:"invitation_token"`
+	// LinkUserId is the signed in user linking the identity, set by the handler
+	LinkUserId int `json:"-" form:"-"`
+}
+
+type FederatedLoginRedirect struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+type FederatedLoginCallback struct {
+	Code  string `json:"code" form:"code" binding:"required"`
+	State string `json:"state" form:"state" binding:"required"`
+}
+
+// FederatedLoginState is kept in redis between the redirect to the provider
+// and the callback
+type FederatedLoginState struct {
+	Provider        string `json:"provider"`
+	Nonce           string `json:"nonce"`
+	CodeVerifier    string `json:"code_verifier"`
+	InvitationToken string `json:"invitation_token,omitempty"`
+	LinkUserId      int    `json:"link_user_id,omitempty"`
+}
+
+func (s *FederatedLoginState) MarshalBinary() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+func (s *FederatedLoginState) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, s)
+}
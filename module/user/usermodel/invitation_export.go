@@ -0,0 +1,250 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ExportFormatJSONL = "jsonl"
+	ExportFormatCSV   = "csv"
+
+	ImportConflictSkip      = "skip"
+	ImportConflictOverwrite = "overwrite"
+	ImportConflictFail      = "fail"
+
+	// MaxImportTokens is how many tokens one import may carry
+	MaxImportTokens = 10000
+
+	// maxConflictsShown is how many conflicting tokens ErrImportConflict names
+	maxConflictsShown = 10
+)
+
+var (
+	ErrExportFormatInvalid = common.NewCustomError(
+		errors.New("export format invalid"),
+		"format must be jsonl or csv",
+		"ErrExportFormatInvalid",
+	)
+	ErrImportConflictPolicyInvalid = common.NewCustomError(
+		errors.New("import conflict policy invalid"),
+		"conflict must be skip, overwrite or fail",
+		"ErrImportConflictPolicyInvalid",
+	)
+	ErrImportTooLarge = common.NewCustomError(
+		errors.New("import too large"),
+		fmt.Sprintf("an import may carry at most %d tokens", MaxImportTokens),
+		"ErrImportTooLarge",
+	)
+	ErrImportRecordTTLInvalid = common.NewCustomError(
+		errors.New("import record ttl invalid"),
+		"ttl must be a positive number of seconds",
+		"ErrImportRecordTTLInvalid",
+	)
+	ErrImportRecordCreatorInvalid = common.NewCustomError(
+		errors.New("import record creator invalid"),
+		"created_by must be a user's id",
+		"ErrImportRecordCreatorInvalid",
+	)
+)
+
+// ErrImportRecordTTLTooLong rejects records which would live longer than
+// admins may make any token live
+func ErrImportRecordTTLTooLong(maxTTL time.Duration) *common.AppError {
+	return common.NewCustomError(
+		errors.New("import record ttl too long"),
+		fmt.Sprintf("ttl must be at most %d seconds", int(maxTTL.Seconds())),
+		"ErrImportRecordTTLTooLong",
+	)
+}
+
+// ErrImportRecordInvalid tells which line of an import can't be read and why
+func ErrImportRecordInvalid(line int, err error) *common.AppError {
+	reason := err.Error()
+	if appErr, ok := err.(*common.AppError); ok {
+		reason = appErr.Message
+	}
+
+	return common.NewCustomError(
+		errors.New("import record invalid"),
+		fmt.Sprintf("line %d: %s", line, reason),
+		"ErrImportRecordInvalid",
+	)
+}
+
+// ErrImportConflict names the tokens of an import which exist already
+func ErrImportConflict(tokens []string) *common.AppError {
+	shown := tokens
+	if len(shown) > maxConflictsShown {
+		shown = shown[:maxConflictsShown]
+	}
+
+	msg := fmt.Sprintf("%d tokens exist already: %s", len(tokens), strings.Join(shown, ", "))
+	if len(shown) < len(tokens) {
+		msg += ", ..."
+	}
+
+	return common.NewFullErrorResponse(
+		http.StatusConflict,
+		errors.New("import conflict"),
+		msg,
+		msg,
+		"ErrImportConflict",
+	)
+}
+
+type InvitationTokenExportQuery struct {
+	Format string `json:"format,omitempty" form:"format"`
+}
+
+func (q *InvitationTokenExportQuery) Validate() error {
+	return validateExportFormat(&q.Format)
+}
+
+// InvitationTokenImport is how an import is done. Tokens which exist already
+// are skipped, overwritten or fail the whole import, as Conflict says; a
+// DryRun only tells what the import would do.
+type InvitationTokenImport struct {
+	Format   string `json:"format,omitempty" form:"format"`
+	Conflict string `json:"conflict,omitempty" form:"conflict"`
+	DryRun   bool   `json:"dry_run,omitempty" form:"dry_run"`
+}
+
+func (i *InvitationTokenImport) Validate() error {
+	if err := validateExportFormat(&i.Format); err != nil {
+		return err
+	}
+
+	i.Conflict = strings.ToLower(strings.TrimSpace(i.Conflict))
+	switch i.Conflict {
+	case "":
+		i.Conflict = ImportConflictFail
+	case ImportConflictSkip, ImportConflictOverwrite, ImportConflictFail:
+	default:
+		return ErrImportConflictPolicyInvalid
+	}
+
+	return nil
+}
+
+// validateExportFormat normalises format, which is JSON Lines unless set
+func validateExportFormat(format *string) error {
+	*format = strings.ToLower(strings.TrimSpace(*format))
+	switch *format {
+	case "":
+		*format = ExportFormatJSONL
+	case ExportFormatJSONL, ExportFormatCSV:
+	default:
+		return ErrExportFormatInvalid
+	}
+	return nil
+}
+
+// InvitationTokenImportResult counts what an import did, or would do on a
+// dry run
+type InvitationTokenImportResult struct {
+	DryRun      bool `json:"dry_run"`
+	Created     int  `json:"created"`
+	Overwritten int  `json:"overwritten"`
+	Skipped     int  `json:"skipped"`
+}
+
+// InvitationTokenRecord is a token as it is exported and imported. TTL is
+// the seconds it had left when exported, which it has again once imported.
+type InvitationTokenRecord struct {
+	Token          string           `json:"token"`
+	Status         InvitationStatus `json:"status"`
+	Email          string           `json:"email,omitempty"`
+	EmailDomain    string           `json:"email_domain,omitempty"`
+	DeliveryStatus string           `json:"delivery_status,omitempty"`
+	DeliveryError  string           `json:"delivery_error,omitempty"`
+	DeliveredAt    *time.Time       `json:"delivered_at,omitempty"`
+	BatchId        string           `json:"batch_id,omitempty"`
+	CampaignId     int              `json:"campaign_id,omitempty"`
+	CreatedBy      int              `json:"created_by,omitempty"`
+	CreatedAt      *time.Time       `json:"created_at,omitempty"`
+	NotBefore      *time.Time       `json:"not_before,omitempty"`
+	TTL            int              `json:"ttl"`
+}
+
+// NewInvitationTokenRecord exports token as it is at now. Part of a second
+// left counts as one, so a token exported just before it expires can still
+// be imported.
+func NewInvitationTokenRecord(token *InvitationToken, now time.Time) InvitationTokenRecord {
+	ttl := token.TTL(now)
+
+	return InvitationTokenRecord{
+		Token:          token.Token,
+		Status:         token.Status,
+		Email:          token.Email,
+		EmailDomain:    token.EmailDomain,
+		DeliveryStatus: token.DeliveryStatus,
+		DeliveryError:  token.DeliveryError,
+		DeliveredAt:    token.DeliveredAt,
+		BatchId:        token.BatchId,
+		CampaignId:     token.CampaignId,
+		CreatedBy:      token.CreatedBy,
+		CreatedAt:      token.CreatedAt,
+		NotBefore:      token.NotBefore,
+		TTL:            int((ttl + time.Second - 1) / time.Second),
+	}
+}
+
+func (r *InvitationTokenRecord) Validate() error {
+	token, err := NormalizeInvitationToken(r.Token)
+	if err != nil {
+		return err
+	}
+	r.Token = token
+
+	if r.Status < 0 || int(r.Status) >= len(invitationStatusNames) {
+		return ErrInvitationStatusInvalid
+	}
+
+	if r.TTL <= 0 {
+		return ErrImportRecordTTLInvalid
+	}
+
+	if err := ValidateBatchId(r.BatchId); err != nil {
+		return err
+	}
+
+	if r.CampaignId < 0 {
+		return ErrCampaignIdInvalid
+	}
+
+	if r.CreatedBy < 0 {
+		return ErrImportRecordCreatorInvalid
+	}
+
+	if r.Email != "" && r.EmailDomain != "" {
+		return ErrRecipientInvalid
+	}
+
+	return nil
+}
+
+// InvitationToken is the token the record imports at now
+func (r *InvitationTokenRecord) InvitationToken(now time.Time) InvitationToken {
+	expiresAt := now.UTC().Truncate(time.Second).Add(time.Duration(r.TTL) * time.Second)
+
+	return InvitationToken{
+		Token:          r.Token,
+		Status:         r.Status,
+		Email:          r.Email,
+		EmailDomain:    r.EmailDomain,
+		DeliveryStatus: r.DeliveryStatus,
+		DeliveryError:  r.DeliveryError,
+		DeliveredAt:    r.DeliveredAt,
+		BatchId:        r.BatchId,
+		CampaignId:     r.CampaignId,
+		CreatedBy:      r.CreatedBy,
+		CreatedAt:      r.CreatedAt,
+		NotBefore:      r.NotBefore,
+		ExpiresAt:      &expiresAt,
+	}
+}
@@ -0,0 +1,129 @@
+package usermodel
+
+import (
+	"app-invite-service/common"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// InvitationStatus is where a token is in its life. MySQL keeps it as a
+// number, while the Redis cache and the API use its name. Before statuses had
+// names a token was disabled at 0 and active at any other number, which is
+// how numbers are still read from JSON and from older clients.
+type InvitationStatus int
+
+const (
+	InvitationStatusDisabled InvitationStatus = iota
+	InvitationStatusActive
+	InvitationStatusExhausted
+	InvitationStatusExpired
+	InvitationStatusRedeemed
+)
+
+var invitationStatusNames = []string{"disabled", "active", "exhausted", "expired", "redeemed"}
+
+// invitationStatusTransitions lists where each status may move to. Expired
+// and redeemed tokens are done with.
+var invitationStatusTransitions = map[InvitationStatus][]InvitationStatus{
+	InvitationStatusActive: {
+		InvitationStatusDisabled,
+		InvitationStatusExhausted,
+		InvitationStatusExpired,
+		InvitationStatusRedeemed,
+	},
+	InvitationStatusDisabled:  {InvitationStatusActive, InvitationStatusExpired},
+	InvitationStatusExhausted: {InvitationStatusActive, InvitationStatusDisabled, InvitationStatusExpired},
+}
+
+var ErrInvitationStatusInvalid = common.NewCustomError(
+	errors.New("invitation status invalid"),
+	"status must be one of "+strings.Join(invitationStatusNames, ", "),
+	"ErrInvitationStatusInvalid",
+)
+
+func ErrInvitationStatusTransition(from, to InvitationStatus) *common.AppError {
+	return common.NewCustomError(
+		errors.New("invitation status transition invalid"),
+		fmt.Sprintf("a %s token can't become %s", from, to),
+		"ErrInvitationStatusTransition",
+	)
+}
+
+// ParseInvitationStatus reads a status by name, or by the number older
+// clients send
+func ParseInvitationStatus(s string) (InvitationStatus, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range invitationStatusNames {
+		if s == name {
+			return InvitationStatus(i), nil
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return legacyInvitationStatus(n), nil
+	}
+	return 0, ErrInvitationStatusInvalid
+}
+
+// legacyInvitationStatus reads a status the way it was before statuses had
+// names, when every number but 0 meant active
+func legacyInvitationStatus(n int) InvitationStatus {
+	if n == 0 {
+		return InvitationStatusDisabled
+	}
+	return InvitationStatusActive
+}
+
+func (s InvitationStatus) String() string {
+	if s < 0 || int(s) >= len(invitationStatusNames) {
+		return fmt.Sprintf("InvitationStatus(%d)", int(s))
+	}
+	return invitationStatusNames[s]
+}
+
+// IsActive reports whether a token with the status may be used
+func (s InvitationStatus) IsActive() bool {
+	return s == InvitationStatusActive
+}
+
+// CanTransitionTo reports whether a token may move from s to next
+func (s InvitationStatus) CanTransitionTo(next InvitationStatus) bool {
+	for _, allowed := range invitationStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+func (s InvitationStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON takes a name or, for tokens stored before statuses had
+// names, a number
+func (s *InvitationStatus) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	var status InvitationStatus
+	var err error
+	switch v := value.(type) {
+	case string:
+		status, err = ParseInvitationStatus(v)
+	case float64:
+		status = legacyInvitationStatus(int(v))
+	default:
+		err = ErrInvitationStatusInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	*s = status
+	return nil
+}
@@ -0,0 +1,87 @@
+package usermodel_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/module/user/usermodel"
+)
+
+func TestParseInvitationStatus(t *testing.T) {
+	var tsc = []struct {
+		input    string
+		expected usermodel.InvitationStatus
+		err      error
+	}{
+		{"active", usermodel.InvitationStatusActive, nil},
+		{" Disabled ", usermodel.InvitationStatusDisabled, nil},
+		{"redeemed", usermodel.InvitationStatusRedeemed, nil},
+		{"0", usermodel.InvitationStatusDisabled, nil},
+		{"1", usermodel.InvitationStatusActive, nil},
+		{"4", usermodel.InvitationStatusActive, nil},
+		{"5", usermodel.InvitationStatusActive, nil},
+		{"enabled", 0, usermodel.ErrInvitationStatusInvalid},
+		{"", 0, usermodel.ErrInvitationStatusInvalid},
+	}
+	for _, tc := range tsc {
+		status, err := usermodel.ParseInvitationStatus(tc.input)
+		assert.Equal(t, tc.err, err, tc.input)
+		assert.Equal(t, tc.expected, status, tc.input)
+	}
+}
+
+func TestInvitationStatus_JSON(t *testing.T) {
+	b, err := json.Marshal(usermodel.InvitationToken{Token: "aZ09bY", Status: usermodel.InvitationStatusExhausted})
+	require.Nil(t, err)
+	assert.Contains(t, string(b), `"status":"exhausted"`)
+
+	var token usermodel.InvitationToken
+	require.Nil(t, json.Unmarshal(b, &token))
+	assert.Equal(t, usermodel.InvitationStatusExhausted, token.Status)
+
+	// tokens stored before statuses had names
+	require.Nil(t, token.UnmarshalBinary([]byte(`{"token":"aZ09bY","status":0}`)))
+	assert.Equal(t, usermodel.InvitationStatusDisabled, token.Status)
+	for _, legacy := range []string{"1", "2", "4", "7"} {
+		require.Nil(t, token.UnmarshalBinary([]byte(`{"token":"aZ09bY","status":`+legacy+`}`)))
+		assert.Equal(t, usermodel.InvitationStatusActive, token.Status, legacy)
+	}
+
+	assert.Error(t, json.Unmarshal([]byte(`{"status":"gone"}`), &token))
+	assert.Error(t, json.Unmarshal([]byte(`{"status":true}`), &token))
+}
+
+func TestInvitationStatus_CanTransitionTo(t *testing.T) {
+	var tsc = []struct {
+		from     usermodel.InvitationStatus
+		to       usermodel.InvitationStatus
+		expected bool
+	}{
+		{usermodel.InvitationStatusActive, usermodel.InvitationStatusDisabled, true},
+		{usermodel.InvitationStatusActive, usermodel.InvitationStatusRedeemed, true},
+		{usermodel.InvitationStatusDisabled, usermodel.InvitationStatusActive, true},
+		{usermodel.InvitationStatusDisabled, usermodel.InvitationStatusRedeemed, false},
+		{usermodel.InvitationStatusExhausted, usermodel.InvitationStatusActive, true},
+		{usermodel.InvitationStatusExpired, usermodel.InvitationStatusActive, false},
+		{usermodel.InvitationStatusRedeemed, usermodel.InvitationStatusDisabled, false},
+		{usermodel.InvitationStatusActive, usermodel.InvitationStatusActive, false},
+	}
+	for _, tc := range tsc {
+		assert.Equal(t, tc.expected, tc.from.CanTransitionTo(tc.to), "%s to %s", tc.from, tc.to)
+	}
+}
+
+func TestInvitationTokenFilter_Validate(t *testing.T) {
+	filter := usermodel.InvitationTokenFilter{Status: "0"}
+	require.Nil(t, filter.Validate())
+	assert.Equal(t, "disabled", filter.Status)
+	assert.True(t, filter.Matches(&usermodel.InvitationToken{Status: usermodel.InvitationStatusDisabled}, time.Now()))
+	assert.False(t, filter.Matches(&usermodel.InvitationToken{Status: usermodel.InvitationStatusActive}, time.Now()))
+
+	filter = usermodel.InvitationTokenFilter{Status: "paused"}
+	assert.Equal(t, usermodel.ErrInvitationStatusInvalid, filter.Validate())
+}
@@ -2,12 +2,22 @@ package usermodel
 
 import (
 	"app-invite-service/common"
+	"app-invite-service/component/invitecode"
+	"app-invite-service/component/invitesigner"
 	"app-invite-service/component/tokenprovider"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/mail"
+	"regexp"
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 )
 
 const EntityName = "User"
@@ -26,6 +36,85 @@ var ErrEmailOrPasswordInvalid = common.NewCustomError(
 	"ErrEmailOrPasswordInvalid",
 )
 
+var ErrEmailInvalid = common.NewCustomError(
+	errors.New("email invalid"),
+	"email address is invalid",
+	"ErrEmailInvalid",
+)
+
+const MaxDisplayNameLength = 100
+
+var ErrDisplayNameTooLong = common.NewCustomError(
+	errors.New("display name too long"),
+	fmt.Sprintf("display name must have at most %d characters", MaxDisplayNameLength),
+	"ErrDisplayNameTooLong",
+)
+
+var ErrRecipientInvalid = common.NewCustomError(
+	errors.New("recipient invalid"),
+	"recipient must be either a valid email or an email domain",
+	"ErrRecipientInvalid",
+)
+
+var ErrBatchIdInvalid = common.NewCustomError(
+	errors.New("batch id invalid"),
+	"batch_id must be at most 64 letters, digits, '.', '_' or '-'",
+	"ErrBatchIdInvalid",
+)
+
+var ErrCampaignIdInvalid = common.NewCustomError(
+	errors.New("campaign id invalid"),
+	"campaign_id must be a campaign's id",
+	"ErrCampaignIdInvalid",
+)
+
+var (
+	ErrNotBeforeInvalid = common.NewCustomError(
+		errors.New("not before invalid"),
+		"not_before must be in the future",
+		"ErrNotBeforeInvalid",
+	)
+	ErrNotBeforeAfterExpiry = common.NewCustomError(
+		errors.New("not before after expiry"),
+		"the token would expire before not_before, when it becomes active",
+		"ErrNotBeforeAfterExpiry",
+	)
+)
+
+func ErrInviteTokenNotYetActive(notBefore time.Time) *common.AppError {
+	msg := fmt.Sprintf("this invitation becomes active at %s", notBefore.UTC().Format(time.RFC3339))
+	return common.NewCustomError(errors.New("invite token not yet active"), msg, "ErrInviteTokenNotYetActive")
+}
+
+var batchIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+var ErrInviteTokenMalformed = common.NewCustomError(
+	errors.New("invite token malformed"),
+	"invite token is malformed, check it for typos",
+	"ErrInviteTokenMalformed",
+)
+
+// randomTokenPattern matches the tokens generated in the random format
+var randomTokenPattern = regexp.MustCompile(`^[A-Za-z0-9]{6,11}$`)
+
+// Invitation token formats, see invitation.code_format in the config
+const (
+	CodeFormatRandom    = "random"
+	CodeFormatCrockford = "crockford"
+)
+
+var ErrIPInvalid = common.NewCustomError(
+	errors.New("ip invalid"),
+	"ip address is invalid",
+	"ErrIPInvalid",
+)
+
+var ErrEmailNotVerified = common.NewCustomError(
+	errors.New("email not verified"),
+	"email has not been verified",
+	"ErrEmailNotVerified",
+)
+
 func ErrPasswordInvalid(msg string) *common.AppError {
 	return common.NewCustomError(
 		errors.New(msg),
@@ -35,14 +124,18 @@ func ErrPasswordInvalid(msg string) *common.AppError {
 }
 
 type User struct {
-	Id        int        `json:"-" gorm:"column:id;"`
-	Status    int        `json:"status" gorm:"column:status;default:1;"`
-	Email     string     `json:"email" form:"email" binding:"required" gorm:"column:email;"`
-	Password  string     `json:"password" form:"password" binding:"required" gorm:"column:password;"`
-	Role      string     `json:"role" gorm:"column:role;"`
-	Salt      string     `json:"-" gorm:"column:salt;"`
-	CreatedAt *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+	Id              int        `json:"-" gorm:"column:id;"`
+	Status          int        `json:"status" gorm:"column:status;default:1;"`
+	Email           string     `json:"email" form:"email" binding:"required" gorm:"column:email;"`
+	DisplayName     string     `json:"display_name,omitempty" gorm:"column:display_name;"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" gorm:"column:email_verified_at;"`
+	Password        string     `json:"password" form:"password" binding:"required" gorm:"column:password;"`
+	Role            string     `json:"role" gorm:"column:role;"`
+	Salt            string     `json:"-" gorm:"column:salt;"`
+	MfaSecret       string     `json:"-" gorm:"column:mfa_secret;"`
+	MfaEnabledAt    *time.Time `json:"mfa_enabled_at,omitempty" gorm:"column:mfa_enabled_at;"`
+	CreatedAt       *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
 }
 
 func (User) TableName() string {
@@ -57,15 +150,26 @@ func (u *User) GetRole() string {
 	return u.Role
 }
 
+func (u *User) IsMfaEnabled() bool {
+	return u.MfaEnabledAt != nil
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
 type UserCreate struct {
-	Id        int        `json:"-" gorm:"column:id;"`
-	Status    int        `json:"status" gorm:"column:status;default:1;"`
-	Email     string     `json:"email" form:"email" binding:"required" gorm:"column:email;"`
-	Password  string     `json:"password" form:"password" binding:"required" gorm:"column:password;"`
-	Role      string     `json:"role" form:"role" gorm:"column:role;type:enum('user', 'admin');default:'user'"`
-	Salt      string     `json:"-" gorm:"column:salt;"`
-	CreatedAt *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+	Id              int        `json:"-" gorm:"column:id;"`
+	Status          int        `json:"status" gorm:"column:status;default:1;"`
+	Email           string     `json:"email" form:"email" binding:"required" gorm:"column:email;"`
+	DisplayName     string     `json:"display_name,omitempty" form:"display_name" gorm:"column:display_name;"`
+	Password        string     `json:"password" form:"password" binding:"required" gorm:"column:password;"`
+	Role            string     `json:"role" form:"role" gorm:"column:role;type:enum('user', 'admin');default:'user'"`
+	Salt            string     `json:"-" gorm:"column:salt;"`
+	EmailVerifiedAt *time.Time `json:"-" form:"-" gorm:"column:email_verified_at;"`
+	InvitationToken string     `json:"invitation_token,omitempty" form:"invitation_token" gorm:"-"`
+	CreatedAt       *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
 }
 
 func (UserCreate) TableName() string {
@@ -75,6 +179,12 @@ func (UserCreate) TableName() string {
 func (u *UserCreate) Validate() error {
 	u.Email = strings.TrimSpace(u.Email)
 	u.Password = strings.TrimSpace(u.Password)
+	u.InvitationToken = strings.TrimSpace(u.InvitationToken)
+	u.DisplayName = strings.TrimSpace(u.DisplayName)
+
+	if utf8.RuneCountInString(u.DisplayName) > MaxDisplayNameLength {
+		return ErrDisplayNameTooLong
+	}
 
 	if errMsg := VerifyPassword(u.Password); errMsg != "" {
 		return ErrPasswordInvalid(errMsg)
@@ -127,24 +237,68 @@ func VerifyPassword(s string) string {
 type UserLogin struct {
 	Email    string `json:"email" form:"email" binding:"required" gorm:"column:email;"`
 	Password string `json:"password" form:"password" binding:"required" gorm:"column:password;"`
+	// ClientIP is filled in by the transport layer
+	ClientIP string `json:"-" form:"-" gorm:"-"`
+}
+
+type UserLoginMfa struct {
+	MfaToken string `json:"mfa_token" form:"mfa_token" binding:"required"`
+	// Code is either a TOTP code or an unused recovery code
+	Code     string `json:"code" form:"code" binding:"required"`
+	ClientIP string `json:"-" form:"-"`
+}
+
+type AccountUnlock struct {
+	Email string `json:"email" form:"email" binding:"required"`
+	IP    string `json:"ip,omitempty" form:"ip"`
+}
+
+func (a *AccountUnlock) Validate() error {
+	a.Email = strings.TrimSpace(a.Email)
+	a.IP = strings.TrimSpace(a.IP)
+
+	if a.IP != "" && net.ParseIP(a.IP) == nil {
+		return ErrIPInvalid
+	}
+
+	return nil
 }
 
 func (UserLogin) TableName() string {
 	return User{}.TableName()
 }
 
+type EmailVerificationResend struct {
+	Email string `json:"email" form:"email" binding:"required"`
+}
+
+func (e *EmailVerificationResend) Validate() error {
+	e.Email = strings.TrimSpace(e.Email)
+	return nil
+}
+
 type UserLoginWithInviteToken struct {
 	InvitationToken string `json:"invitation_token" form:"invitation_token" binding:"required"`
+	// Email is only needed for tokens bound to a recipient
+	Email string `json:"email,omitempty" form:"email"`
 }
 
 func (u *UserLoginWithInviteToken) Validate() error {
-	u.InvitationToken = strings.TrimSpace(u.InvitationToken)
+	token, err := NormalizeInvitationToken(u.InvitationToken)
+	if err != nil {
+		return err
+	}
+	u.InvitationToken = token
+	u.Email = strings.TrimSpace(u.Email)
 	return nil
 }
 
 type Account struct {
-	AccessToken  *tokenprovider.Token `json:"access_token"`
-	RefreshToken *tokenprovider.Token `json:"refresh_token"`
+	AccessToken  *tokenprovider.Token `json:"access_token,omitempty"`
+	RefreshToken *tokenprovider.Token `json:"refresh_token,omitempty"`
+	// MfaToken is returned instead of the tokens above when the user still
+	// has to pass the MFA step, see UserLoginMfa
+	MfaToken *tokenprovider.Token `json:"mfa_token,omitempty"`
 }
 
 func NewAccount(at, rt *tokenprovider.Token) *Account {
@@ -154,10 +308,36 @@ func NewAccount(at, rt *tokenprovider.Token) *Account {
 	}
 }
 
+const (
+	DeliveryStatusPending = "pending"
+	DeliveryStatusSent    = "sent"
+	DeliveryStatusFailed  = "failed"
+)
+
 type InvitationToken struct {
-	Status int    `json:"status"`
-	Expiry int    `json:"expiry"`
-	Token  string `json:"token"`
+	Status         InvitationStatus `json:"status" gorm:"column:status;"`
+	Expiry         int              `json:"expiry" gorm:"-"`
+	Token          string           `json:"token" gorm:"column:token;primaryKey;"`
+	Email          string           `json:"email,omitempty" gorm:"column:email;"`
+	EmailDomain    string           `json:"email_domain,omitempty" gorm:"column:email_domain;"`
+	DeliveryStatus string           `json:"delivery_status,omitempty" gorm:"column:delivery_status;"`
+	DeliveryError  string           `json:"delivery_error,omitempty" gorm:"column:delivery_error;"`
+	DeliveredAt    *time.Time       `json:"delivered_at,omitempty" gorm:"column:delivered_at;"`
+	BatchId        string           `json:"batch_id,omitempty" gorm:"column:batch_id;"`
+	CampaignId     int              `json:"campaign_id,omitempty" gorm:"column:campaign_id;"`
+	CreatedBy      int              `json:"created_by,omitempty" gorm:"column:created_by;"`
+	CreatedAt      *time.Time       `json:"created_at,omitempty" gorm:"column:created_at;"`
+	NotBefore      *time.Time       `json:"not_before,omitempty" gorm:"column:not_before;"`
+	ExpiresAt      *time.Time       `json:"expires_at,omitempty" gorm:"column:expires_at;"`
+	// MaxUses, Uses and Signed are only set on signed tokens, which are
+	// named by their id in Token and never stored
+	MaxUses int  `json:"max_uses,omitempty" gorm:"-"`
+	Uses    int  `json:"uses,omitempty" gorm:"-"`
+	Signed  bool `json:"signed,omitempty" gorm:"-"`
+}
+
+func (InvitationToken) TableName() string {
+	return "invitation_tokens"
 }
 
 func (t *InvitationToken) MarshalBinary() ([]byte, error) {
@@ -172,10 +352,582 @@ func (t *InvitationToken) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// TTL is how long the token has left at now, or zero once it expired
+func (t *InvitationToken) TTL(now time.Time) time.Duration {
+	if t.ExpiresAt == nil || !t.ExpiresAt.After(now) {
+		return 0
+	}
+	return t.ExpiresAt.Sub(now)
+}
+
+// IsPending reports whether the token is scheduled to become active after now
+func (t *InvitationToken) IsPending(now time.Time) bool {
+	return t.NotBefore != nil && now.Before(*t.NotBefore)
+}
+
+// IsRecipientBound reports whether only a given email or domain may redeem the token
+func (t *InvitationToken) IsRecipientBound() bool {
+	return t.Email != "" || t.EmailDomain != ""
+}
+
+// MatchesRecipient reports whether email is allowed to redeem the token.
+// Tokens which are not recipient bound match every email.
+func (t *InvitationToken) MatchesRecipient(email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	if t.Email != "" {
+		return email == strings.ToLower(t.Email)
+	}
+
+	if t.EmailDomain != "" {
+		return EmailDomain(email) == t.EmailDomain
+	}
+
+	return true
+}
+
+// RecipientHint tells who the token is for without giving the address away,
+// like "j***@example.com" for an email or "@example.com" for a domain
+func (t *InvitationToken) RecipientHint() string {
+	if t.Email != "" {
+		at := strings.LastIndex(t.Email, "@")
+		first, _ := utf8.DecodeRuneInString(t.Email)
+		if at <= 0 {
+			return string(first) + "***"
+		}
+		return string(first) + "***" + t.Email[at:]
+	}
+
+	if t.EmailDomain != "" {
+		return "@" + t.EmailDomain
+	}
+
+	return ""
+}
+
+// EmailDomain returns the lower-cased part after the last "@" of email
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
+
+type InvitationTokenCreate struct {
+	Email       string `json:"email,omitempty" form:"email"`
+	EmailDomain string `json:"email_domain,omitempty" form:"email_domain"`
+	// BatchId groups tokens generated together, for analytics
+	BatchId string `json:"batch_id,omitempty" form:"batch_id"`
+	// CampaignId generates the token under a campaign, with its settings
+	CampaignId int `json:"campaign_id,omitempty" form:"campaign_id"`
+	// NotBefore schedules the token to become active later. Its TTL counts
+	// from then.
+	NotBefore *time.Time `json:"not_before,omitempty" form:"not_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	// CreatedBy is the user generating the token, set by the handler
+	CreatedBy int `json:"-" form:"-"`
+}
+
+// InvitationTokenId names a token where the token itself, a bearer
+// credential, must not be kept, like the audit log: the first half of its
+// SHA-256 in hex
+func InvitationTokenId(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:16])
+}
+
+// InvitationTokenIds is InvitationTokenId of each of tokens
+func InvitationTokenIds(tokens []string) []string {
+	ids := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		ids = append(ids, InvitationTokenId(token))
+	}
+	return ids
+}
+
+// Audited is the token as the audit log keeps it, named by its id
+func (t InvitationToken) Audited() InvitationToken {
+	t.Token = InvitationTokenId(t.Token)
+	return t
+}
+
+// NormalizeInvitationToken returns token the way it is stored. Codes are
+// case-insensitive and may be typed without dashes; random and signed tokens
+// are taken as they are. Anything else can't be a token and is rejected.
+func NormalizeInvitationToken(token string) (string, error) {
+	token = strings.TrimSpace(token)
+
+	if code, ok := invitecode.Parse(token); ok {
+		return code, nil
+	}
+
+	if randomTokenPattern.MatchString(token) || invitesigner.IsSigned(token) {
+		return token, nil
+	}
+
+	return "", ErrInviteTokenMalformed
+}
+
+func ValidateBatchId(batchId string) error {
+	if batchId != "" && !batchIdPattern.MatchString(batchId) {
+		return ErrBatchIdInvalid
+	}
+	return nil
+}
+
+func (i *InvitationTokenCreate) Validate() error {
+	i.Email = strings.TrimSpace(i.Email)
+	i.EmailDomain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(i.EmailDomain), "@"))
+	i.BatchId = strings.TrimSpace(i.BatchId)
+
+	if err := ValidateBatchId(i.BatchId); err != nil {
+		return err
+	}
+
+	if i.CampaignId < 0 {
+		return ErrCampaignIdInvalid
+	}
+
+	if i.NotBefore != nil {
+		if !i.NotBefore.After(time.Now()) {
+			return ErrNotBeforeInvalid
+		}
+		notBefore := i.NotBefore.UTC().Truncate(time.Second)
+		i.NotBefore = &notBefore
+	}
+
+	if i.Email != "" && i.EmailDomain != "" {
+		return ErrRecipientInvalid
+	}
+
+	if i.Email != "" {
+		if addr, err := mail.ParseAddress(i.Email); err != nil || addr.Address != i.Email {
+			return ErrEmailInvalid
+		}
+	}
+
+	if i.EmailDomain != "" {
+		if strings.Contains(i.EmailDomain, "@") || !strings.Contains(i.EmailDomain, ".") {
+			return ErrRecipientInvalid
+		}
+	}
+
+	return nil
+}
+
+// RecipientDomain returns the domain the token will be bound to, if any
+func (i *InvitationTokenCreate) RecipientDomain() string {
+	if i.Email != "" {
+		return EmailDomain(i.Email)
+	}
+	return i.EmailDomain
+}
+
+type InvitationEmailCreate struct {
+	Email      string `json:"email" form:"email" binding:"required"`
+	BatchId    string `json:"batch_id,omitempty" form:"batch_id"`
+	CampaignId int    `json:"campaign_id,omitempty" form:"campaign_id"`
+	CreatedBy  int    `json:"-" form:"-"`
+}
+
+func (i *InvitationEmailCreate) Validate() error {
+	i.Email = strings.TrimSpace(i.Email)
+	i.BatchId = strings.TrimSpace(i.BatchId)
+
+	if err := ValidateBatchId(i.BatchId); err != nil {
+		return err
+	}
+
+	addr, err := mail.ParseAddress(i.Email)
+	if err != nil || addr.Address != i.Email {
+		return ErrEmailInvalid
+	}
+
+	return nil
+}
+
+const (
+	QRCodeFormatPNG = "png"
+	QRCodeFormatSVG = "svg"
+
+	DefaultQRCodeSize = 256
+	MinQRCodeSize     = 64
+	MaxQRCodeSize     = 2048
+
+	// MaxQRCodeSheetCodes is how many codes one printable sheet may hold
+	MaxQRCodeSheetCodes = 120
+)
+
+var ErrQRCodeFormatInvalid = common.NewCustomError(
+	errors.New("qr code format invalid"),
+	"format must be png or svg",
+	"ErrQRCodeFormatInvalid",
+)
+
+var ErrQRCodeSizeInvalid = common.NewCustomError(
+	errors.New("qr code size invalid"),
+	fmt.Sprintf("size must be between %d and %d pixels", MinQRCodeSize, MaxQRCodeSize),
+	"ErrQRCodeSizeInvalid",
+)
+
+var ErrQRCodeSheetCountInvalid = common.NewCustomError(
+	errors.New("qr code sheet count invalid"),
+	fmt.Sprintf("count must be between 1 and %d", MaxQRCodeSheetCodes),
+	"ErrQRCodeSheetCountInvalid",
+)
+
+// InvitationQRCodeQuery asks for the QR code of a token as a PNG or SVG
+// image of Size pixels
+type InvitationQRCodeQuery struct {
+	Format string `form:"format"`
+	Size   int    `form:"size"`
+}
+
+func (q *InvitationQRCodeQuery) Validate() error {
+	q.Format = strings.ToLower(strings.TrimSpace(q.Format))
+	if q.Format == "" {
+		q.Format = QRCodeFormatPNG
+	}
+	if q.Format != QRCodeFormatPNG && q.Format != QRCodeFormatSVG {
+		return ErrQRCodeFormatInvalid
+	}
+
+	if q.Size == 0 {
+		q.Size = DefaultQRCodeSize
+	}
+	if q.Size < MinQRCodeSize || q.Size > MaxQRCodeSize {
+		return ErrQRCodeSizeInvalid
+	}
+
+	return nil
+}
+
+// InvitationQRCode is a rendered QR code image
+type InvitationQRCode struct {
+	ContentType string
+	Data        []byte
+}
+
+// InvitationQRCodeSheetCreate asks for Count new tokens in a batch, printed
+// on a PDF sheet
+type InvitationQRCodeSheetCreate struct {
+	BatchId    string `json:"batch_id" form:"batch_id" binding:"required"`
+	Count      int    `json:"count" form:"count" binding:"required"`
+	CampaignId int    `json:"campaign_id,omitempty" form:"campaign_id"`
+	// NotBefore schedules every token on the sheet, see InvitationTokenCreate
+	NotBefore *time.Time `json:"not_before,omitempty" form:"not_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBy int        `json:"-" form:"-"`
+}
+
+func (i *InvitationQRCodeSheetCreate) Validate() error {
+	i.BatchId = strings.TrimSpace(i.BatchId)
+
+	if err := ValidateBatchId(i.BatchId); err != nil {
+		return err
+	}
+
+	if i.Count < 1 || i.Count > MaxQRCodeSheetCodes {
+		return ErrQRCodeSheetCountInvalid
+	}
+
+	if i.CampaignId < 0 {
+		return ErrCampaignIdInvalid
+	}
+
+	return nil
+}
+
+func ErrLandingThrottled(retryAfter time.Duration) *common.AppError {
+	msg := fmt.Sprintf("too many invitation lookups, retry in %d seconds", int(retryAfter.Seconds()))
+	return common.NewFullErrorResponse(
+		http.StatusTooManyRequests,
+		errors.New("invitation landing throttled"),
+		msg,
+		msg,
+		"ErrLandingThrottled",
+	)
+}
+
+type InvitationLandingQuery struct {
+	InvitationToken string `form:"invitation_token" binding:"required"`
+	ClientIP        string `form:"-"`
+}
+
+// InvitationLanding is what anyone holding a token may learn about it, for
+// the page an invitation link opens. RemainingUses is nil without a limit.
+type InvitationLanding struct {
+	InviterName   string     `json:"inviter_name,omitempty"`
+	CampaignName  string     `json:"campaign_name,omitempty"`
+	NotBefore     *time.Time `json:"not_before,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	RemainingUses *int       `json:"remaining_uses,omitempty"`
+	RecipientHint string     `json:"recipient_hint,omitempty"`
+}
+
+var (
+	ErrTokenUpdateEmpty = common.NewCustomError(
+		errors.New("token update empty"),
+		"give a status, expires_at or extend_by",
+		"ErrTokenUpdateEmpty",
+	)
+	ErrTokenExpiryUpdateInvalid = common.NewCustomError(
+		errors.New("token expiry update invalid"),
+		"give either expires_at or extend_by",
+		"ErrTokenExpiryUpdateInvalid",
+	)
+)
+
+func ErrTokenExpiryOutOfRange(minTTL, maxTTL time.Duration) *common.AppError {
+	return common.NewCustomError(
+		errors.New("token expiry out of range"),
+		fmt.Sprintf(
+			"the token must expire between %d and %d seconds from now",
+			int(minTTL.Seconds()),
+			int(maxTTL.Seconds()),
+		),
+		"ErrTokenExpiryOutOfRange",
+	)
+}
+
+// InvitationTokenUpdate changes the status of a token and moves its expiry
+// to ExpiresAt or by ExtendBy seconds, negative to shorten it. Fields left
+// out are kept.
 type InvitationTokenUpdate struct {
-	Status int `json:"status" form:"status"`
+	Status    *InvitationStatus `json:"status,omitempty" form:"status"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty" form:"expires_at" time_format:"2006-01-02T15:04:05Z07:00"`
+	ExtendBy  int               `json:"extend_by,omitempty" form:"extend_by"`
+}
+
+func (data *InvitationTokenUpdate) Validate() error {
+	if data.ExpiresAt != nil && data.ExtendBy != 0 {
+		return ErrTokenExpiryUpdateInvalid
+	}
+
+	if data.Status == nil && !data.ChangesExpiry() {
+		return ErrTokenUpdateEmpty
+	}
+
+	return nil
+}
+
+func (data *InvitationTokenUpdate) ChangesExpiry() bool {
+	return data.ExpiresAt != nil || data.ExtendBy != 0
+}
+
+// NewExpiry is when a token expiring at current expires after the update
+func (data *InvitationTokenUpdate) NewExpiry(current time.Time) time.Time {
+	if data.ExpiresAt != nil {
+		return data.ExpiresAt.UTC().Truncate(time.Second)
+	}
+	return current.Add(time.Duration(data.ExtendBy) * time.Second).UTC().Truncate(time.Second)
+}
+
+// SignedInvitationCreate asks for a signed token living TTL seconds, or the
+// default invitation TTL, and usable MaxUses times. Zero MaxUses is no limit.
+type SignedInvitationCreate struct {
+	TTL        int `json:"ttl,omitempty" form:"ttl"`
+	CampaignId int `json:"campaign_id,omitempty" form:"campaign_id"`
+	MaxUses    int `json:"max_uses,omitempty" form:"max_uses"`
+	CreatedBy  int `json:"-" form:"-"`
+}
+
+func ErrSignedTokenTTLInvalid(maxTTL int) *common.AppError {
+	return common.NewCustomError(
+		errors.New("signed token ttl invalid"),
+		fmt.Sprintf("ttl must be between 0 and %d seconds", maxTTL),
+		"ErrSignedTokenTTLInvalid",
+	)
+}
+
+var ErrMaxUsesInvalid = common.NewCustomError(
+	errors.New("max uses invalid"),
+	"max_uses must not be negative",
+	"ErrMaxUsesInvalid",
+)
+
+func (i *SignedInvitationCreate) Validate(maxTTL int) error {
+	if i.TTL < 0 || i.TTL > maxTTL {
+		return ErrSignedTokenTTLInvalid(maxTTL)
+	}
+
+	if i.CampaignId < 0 {
+		return ErrCampaignIdInvalid
+	}
+
+	if i.MaxUses < 0 {
+		return ErrMaxUsesInvalid
+	}
+
+	return nil
+}
+
+// SignedInvitation is a newly signed token. Id names it in the revocation
+// list, history and audit log, so the token itself is never stored.
+type SignedInvitation struct {
+	Token      string     `json:"token,omitempty"`
+	Id         string     `json:"id"`
+	Algorithm  string     `json:"algorithm"`
+	CampaignId int        `json:"campaign_id,omitempty"`
+	MaxUses    int        `json:"max_uses,omitempty"`
+	CreatedBy  int        `json:"created_by,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+type SignedInvitationRevoke struct {
+	InvitationToken string `json:"invitation_token" form:"invitation_token" binding:"required"`
+}
+
+// InvitationTokenListItem is a token as admins list it, marked pending while
+// it waits for its not_before
+type InvitationTokenListItem struct {
+	InvitationToken
+	Pending bool `json:"pending"`
+}
+
+func NewInvitationTokenListItem(token InvitationToken, now time.Time) InvitationTokenListItem {
+	return InvitationTokenListItem{InvitationToken: token, Pending: token.IsPending(now)}
 }
 
 type InvitationTokenFilter struct {
-	Status *int `json:"status,omitempty" form:"status"`
+	Status string `json:"status,omitempty" form:"status"`
+	// Pending lists only tokens scheduled to become active later when true,
+	// and leaves them out when false
+	Pending *bool `json:"pending,omitempty" form:"pending"`
+}
+
+func (f *InvitationTokenFilter) Validate() error {
+	if f.Status == "" {
+		return nil
+	}
+
+	status, err := ParseInvitationStatus(f.Status)
+	if err != nil {
+		return err
+	}
+	f.Status = status.String()
+
+	return nil
+}
+
+func (f *InvitationTokenFilter) Matches(token *InvitationToken, now time.Time) bool {
+	if f.Pending != nil && *f.Pending != token.IsPending(now) {
+		return false
+	}
+	return f.Status == "" || f.Status == token.Status.String()
+}
+
+const (
+	BulkActionDisable = "disable"
+	BulkActionDelete  = "delete"
+
+	// MaxBulkTokens is how many tokens a bulk request may list
+	MaxBulkTokens = 1000
+
+	BulkResultDisabled  = "disabled"
+	BulkResultDeleted   = "deleted"
+	BulkResultUnchanged = "unchanged"
+	BulkResultNotFound  = "not_found"
+)
+
+var (
+	ErrBulkActionInvalid = common.NewCustomError(
+		errors.New("bulk action invalid"),
+		"action must be disable or delete",
+		"ErrBulkActionInvalid",
+	)
+	ErrBulkTargetInvalid = common.NewCustomError(
+		errors.New("bulk target invalid"),
+		fmt.Sprintf("give either between 1 and %d tokens or a filter with at least one condition", MaxBulkTokens),
+		"ErrBulkTargetInvalid",
+	)
+)
+
+// InvitationTokenBulkFilter matches tokens having all of its set conditions
+type InvitationTokenBulkFilter struct {
+	Status        *InvitationStatus `json:"status,omitempty"`
+	BatchId       string            `json:"batch_id,omitempty"`
+	CreatedBy     int               `json:"created_by,omitempty"`
+	CreatedBefore *time.Time        `json:"created_before,omitempty"`
+}
+
+func (f *InvitationTokenBulkFilter) isEmpty() bool {
+	return f.Status == nil && f.BatchId == "" && f.CreatedBy == 0 && f.CreatedBefore == nil
+}
+
+func (f *InvitationTokenBulkFilter) Matches(token *InvitationToken) bool {
+	if f.Status != nil && *f.Status != token.Status {
+		return false
+	}
+	if f.BatchId != "" && f.BatchId != token.BatchId {
+		return false
+	}
+	if f.CreatedBy != 0 && f.CreatedBy != token.CreatedBy {
+		return false
+	}
+	if f.CreatedBefore != nil && (token.CreatedAt == nil || !token.CreatedAt.Before(*f.CreatedBefore)) {
+		return false
+	}
+	return true
+}
+
+// InvitationTokenBulkUpdate disables or deletes the listed Tokens, or every
+// token matching Filter
+type InvitationTokenBulkUpdate struct {
+	Action string                     `json:"action" form:"action" binding:"required"`
+	Tokens []string                   `json:"tokens,omitempty" form:"tokens"`
+	Filter *InvitationTokenBulkFilter `json:"filter,omitempty"`
+}
+
+func (data *InvitationTokenBulkUpdate) Validate() error {
+	if data.Action != BulkActionDisable && data.Action != BulkActionDelete {
+		return ErrBulkActionInvalid
+	}
+
+	if data.Filter != nil {
+		data.Filter.BatchId = strings.TrimSpace(data.Filter.BatchId)
+		if len(data.Tokens) != 0 || data.Filter.isEmpty() || data.Filter.CreatedBy < 0 {
+			return ErrBulkTargetInvalid
+		}
+		return ValidateBatchId(data.Filter.BatchId)
+	}
+
+	if len(data.Tokens) == 0 || len(data.Tokens) > MaxBulkTokens {
+		return ErrBulkTargetInvalid
+	}
+
+	seen := make(map[string]bool, len(data.Tokens))
+	tokens := data.Tokens[:0]
+	for _, token := range data.Tokens {
+		token, err := NormalizeInvitationToken(token)
+		if err != nil {
+			return err
+		}
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	data.Tokens = tokens
+
+	return nil
+}
+
+// InvitationTokenChange decides what a bulk update does to the tokens it
+// found: the tokens to save, new ones included, and the ones to delete
+type InvitationTokenChange func(found []InvitationToken) ([]InvitationToken, []string, error)
+
+// InvitationTokenBulkResult is what a bulk update did to one token, one of
+// the BulkResult constants
+type InvitationTokenBulkResult struct {
+	Token  string `json:"token"`
+	Result string `json:"result"`
+}
+
+// InvitationTokenCacheRebuild counts what rebuilding the token cache did:
+// tokens only cached were saved to MySQL (Backfilled) or dropped from the
+// cache (Dropped), and every stored token was cached again (Cached)
+type InvitationTokenCacheRebuild struct {
+	Backfilled int `json:"backfilled"`
+	Cached     int `json:"cached"`
+	Dropped    int `json:"dropped"`
 }
@@ -1,7 +1,9 @@
 package usermodel_test
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -16,6 +18,13 @@ func TestUserCreate_Validate(t *testing.T) {
 
 	err := user.Validate()
 	require.Nil(t, err, err)
+
+	user.DisplayName = " " + strings.Repeat("é", usermodel.MaxDisplayNameLength) + " "
+	require.Nil(t, user.Validate())
+	assert.Equal(t, strings.Repeat("é", usermodel.MaxDisplayNameLength), user.DisplayName)
+
+	user.DisplayName += "e"
+	assert.Equal(t, usermodel.ErrDisplayNameTooLong, user.Validate())
 }
 
 func TestUserCreate_VerifyPassword(t *testing.T) {
@@ -35,3 +44,243 @@ func TestUserCreate_VerifyPassword(t *testing.T) {
 		assert.Equal(t, output, tc.expected, "they should be equal")
 	}
 }
+
+func TestInvitationEmailCreate_Validate(t *testing.T) {
+	var tsc = []struct {
+		arg      string
+		expected error
+	}{
+		{"user@gmail.com", nil},
+		{"  user@gmail.com ", nil},
+		{"user", usermodel.ErrEmailInvalid},
+		{"User <user@gmail.com>", usermodel.ErrEmailInvalid},
+		{"", usermodel.ErrEmailInvalid},
+	}
+	for _, tc := range tsc {
+		data := usermodel.InvitationEmailCreate{Email: tc.arg}
+		assert.Equal(t, tc.expected, data.Validate(), "they should be equal")
+	}
+}
+
+func TestInvitationToken_MatchesRecipient(t *testing.T) {
+	var tsc = []struct {
+		token    usermodel.InvitationToken
+		email    string
+		expected bool
+	}{
+		{usermodel.InvitationToken{}, "", true},
+		{usermodel.InvitationToken{}, "user@gmail.com", true},
+		{usermodel.InvitationToken{Email: "user@gmail.com"}, "user@gmail.com", true},
+		{usermodel.InvitationToken{Email: "user@gmail.com"}, " User@Gmail.com ", true},
+		{usermodel.InvitationToken{Email: "user@gmail.com"}, "other@gmail.com", false},
+		{usermodel.InvitationToken{Email: "user@gmail.com"}, "", false},
+		{usermodel.InvitationToken{EmailDomain: "customer.com"}, "anyone@customer.com", true},
+		{usermodel.InvitationToken{EmailDomain: "customer.com"}, "anyone@CUSTOMER.com", true},
+		{usermodel.InvitationToken{EmailDomain: "customer.com"}, "anyone@evil-customer.com", false},
+		{usermodel.InvitationToken{EmailDomain: "customer.com"}, "customer.com", false},
+	}
+	for _, tc := range tsc {
+		assert.Equal(t, tc.expected, tc.token.MatchesRecipient(tc.email), tc.email)
+	}
+}
+
+func TestInvitationToken_RecipientHint(t *testing.T) {
+	var tsc = []struct {
+		token    usermodel.InvitationToken
+		expected string
+	}{
+		{usermodel.InvitationToken{}, ""},
+		{usermodel.InvitationToken{Email: "jane.doe@example.com"}, "j***@example.com"},
+		{usermodel.InvitationToken{Email: "émile@example.com"}, "é***@example.com"},
+		{usermodel.InvitationToken{EmailDomain: "example.com"}, "@example.com"},
+	}
+	for _, tc := range tsc {
+		assert.Equal(t, tc.expected, tc.token.RecipientHint())
+	}
+}
+
+func TestInvitationTokenCreate_Validate(t *testing.T) {
+	var tsc = []struct {
+		data           usermodel.InvitationTokenCreate
+		expectedDomain string
+		expected       error
+	}{
+		{usermodel.InvitationTokenCreate{}, "", nil},
+		{usermodel.InvitationTokenCreate{Email: "user@gmail.com"}, "", nil},
+		{usermodel.InvitationTokenCreate{Email: "user"}, "", usermodel.ErrEmailInvalid},
+		{usermodel.InvitationTokenCreate{EmailDomain: "@Customer.com"}, "customer.com", nil},
+		{usermodel.InvitationTokenCreate{EmailDomain: "customer"}, "customer", usermodel.ErrRecipientInvalid},
+		{usermodel.InvitationTokenCreate{Email: "user@gmail.com", EmailDomain: "gmail.com"}, "gmail.com", usermodel.ErrRecipientInvalid},
+		{usermodel.InvitationTokenCreate{BatchId: " launch-2024.1 "}, "", nil},
+		{usermodel.InvitationTokenCreate{BatchId: "launch wave"}, "", usermodel.ErrBatchIdInvalid},
+	}
+	for _, tc := range tsc {
+		assert.Equal(t, tc.expected, tc.data.Validate(), "they should be equal")
+		assert.Equal(t, tc.expectedDomain, tc.data.EmailDomain, "they should be equal")
+	}
+}
+
+func TestInvitationTokenCreate_ValidateNotBefore(t *testing.T) {
+	notBefore := time.Now().Add(time.Hour).In(time.FixedZone("ICT", 7*60*60))
+	data := usermodel.InvitationTokenCreate{NotBefore: &notBefore}
+	require.Nil(t, data.Validate())
+	assert.Equal(t, time.UTC, data.NotBefore.Location())
+	assert.Equal(t, notBefore.Truncate(time.Second).Unix(), data.NotBefore.Unix())
+
+	past := time.Now().Add(-time.Minute)
+	data = usermodel.InvitationTokenCreate{NotBefore: &past}
+	assert.Equal(t, usermodel.ErrNotBeforeInvalid, data.Validate())
+}
+
+func TestInvitationTokenFilter_MatchesPending(t *testing.T) {
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	pending := usermodel.InvitationToken{Status: usermodel.InvitationStatusActive, NotBefore: &later}
+	active := usermodel.InvitationToken{Status: usermodel.InvitationStatusActive, NotBefore: &now}
+	yes, no := true, false
+
+	var tsc = []struct {
+		filter   usermodel.InvitationTokenFilter
+		token    usermodel.InvitationToken
+		expected bool
+	}{
+		{usermodel.InvitationTokenFilter{}, pending, true},
+		{usermodel.InvitationTokenFilter{Pending: &yes}, pending, true},
+		{usermodel.InvitationTokenFilter{Pending: &yes}, active, false},
+		{usermodel.InvitationTokenFilter{Pending: &no}, pending, false},
+		{usermodel.InvitationTokenFilter{Pending: &no}, active, true},
+		{usermodel.InvitationTokenFilter{Status: "disabled", Pending: &yes}, pending, false},
+	}
+	for _, tc := range tsc {
+		assert.Equal(t, tc.expected, tc.filter.Matches(&tc.token, now))
+	}
+}
+
+func TestNormalizeInvitationToken(t *testing.T) {
+	var tsc = []struct {
+		token         string
+		expectedToken string
+		expected      error
+	}{
+		{" aZ09bY ", "aZ09bY", nil},
+		{"aZ09bY18xW7", "aZ09bY18xW7", nil},
+		{"1111-1111-111F", "1111-1111-111F", nil},
+		{" llii-1111 111f", "1111-1111-111F", nil},
+		{" AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA.BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB ", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA.BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB", nil},
+		{"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA.B", "", usermodel.ErrInviteTokenMalformed},
+		{"1111-1111-111G", "", usermodel.ErrInviteTokenMalformed},
+		{"abc", "", usermodel.ErrInviteTokenMalformed},
+		{"aZ09bY18xW7v", "", usermodel.ErrInviteTokenMalformed},
+		{"aZ09-bY18", "", usermodel.ErrInviteTokenMalformed},
+		{"", "", usermodel.ErrInviteTokenMalformed},
+	}
+	for _, tc := range tsc {
+		token, err := usermodel.NormalizeInvitationToken(tc.token)
+		assert.Equal(t, tc.expected, err, tc.token)
+		assert.Equal(t, tc.expectedToken, token, tc.token)
+	}
+}
+
+func TestInvitationQRCodeQuery_Validate(t *testing.T) {
+	tcs := []struct {
+		query       usermodel.InvitationQRCodeQuery
+		expected    usermodel.InvitationQRCodeQuery
+		expectedErr error
+	}{
+		{usermodel.InvitationQRCodeQuery{}, usermodel.InvitationQRCodeQuery{Format: "png", Size: 256}, nil},
+		{usermodel.InvitationQRCodeQuery{Format: " SVG ", Size: 512}, usermodel.InvitationQRCodeQuery{Format: "svg", Size: 512}, nil},
+		{usermodel.InvitationQRCodeQuery{Format: "gif"}, usermodel.InvitationQRCodeQuery{}, usermodel.ErrQRCodeFormatInvalid},
+		{usermodel.InvitationQRCodeQuery{Size: 32}, usermodel.InvitationQRCodeQuery{}, usermodel.ErrQRCodeSizeInvalid},
+		{usermodel.InvitationQRCodeQuery{Size: 4096}, usermodel.InvitationQRCodeQuery{}, usermodel.ErrQRCodeSizeInvalid},
+	}
+
+	for _, tc := range tcs {
+		err := tc.query.Validate()
+		assert.Equal(t, tc.expectedErr, err)
+		if tc.expectedErr == nil {
+			assert.Equal(t, tc.expected, tc.query)
+		}
+	}
+}
+
+func TestInvitationTokenBulkUpdate_Validate(t *testing.T) {
+	disabled := usermodel.InvitationStatusDisabled
+	var tsc = []struct {
+		data     usermodel.InvitationTokenBulkUpdate
+		tokens   []string
+		expected error
+	}{
+		{usermodel.InvitationTokenBulkUpdate{Action: "disable", Tokens: []string{"aZ09bY", " aZ09bY ", "1111-1111-111f"}}, []string{"aZ09bY", "1111-1111-111F"}, nil},
+		{usermodel.InvitationTokenBulkUpdate{Action: "delete", Filter: &usermodel.InvitationTokenBulkFilter{Status: &disabled}}, nil, nil},
+		{usermodel.InvitationTokenBulkUpdate{Action: "enable", Tokens: []string{"aZ09bY"}}, nil, usermodel.ErrBulkActionInvalid},
+		{usermodel.InvitationTokenBulkUpdate{Action: "delete"}, nil, usermodel.ErrBulkTargetInvalid},
+		{usermodel.InvitationTokenBulkUpdate{Action: "delete", Filter: &usermodel.InvitationTokenBulkFilter{}}, nil, usermodel.ErrBulkTargetInvalid},
+		{usermodel.InvitationTokenBulkUpdate{Action: "delete", Tokens: []string{"aZ09bY"}, Filter: &usermodel.InvitationTokenBulkFilter{CreatedBy: 1}}, nil, usermodel.ErrBulkTargetInvalid},
+		{usermodel.InvitationTokenBulkUpdate{Action: "delete", Filter: &usermodel.InvitationTokenBulkFilter{BatchId: "spring sale"}}, nil, usermodel.ErrBatchIdInvalid},
+		{usermodel.InvitationTokenBulkUpdate{Action: "delete", Tokens: []string{"abc"}}, nil, usermodel.ErrInviteTokenMalformed},
+	}
+	for _, tc := range tsc {
+		err := tc.data.Validate()
+		assert.Equal(t, tc.expected, err)
+		if tc.expected == nil && tc.tokens != nil {
+			assert.Equal(t, tc.tokens, tc.data.Tokens)
+		}
+	}
+}
+
+func TestInvitationTokenBulkFilter_Matches(t *testing.T) {
+	disabled := usermodel.InvitationStatusDisabled
+	before := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	createdAt := before.Add(-time.Hour)
+	token := usermodel.InvitationToken{Token: "aZ09bY", Status: 1, BatchId: "spring", CreatedBy: 5, CreatedAt: &createdAt}
+
+	var tsc = []struct {
+		filter   usermodel.InvitationTokenBulkFilter
+		expected bool
+	}{
+		{usermodel.InvitationTokenBulkFilter{BatchId: "spring", CreatedBy: 5, CreatedBefore: &before}, true},
+		{usermodel.InvitationTokenBulkFilter{Status: &disabled}, false},
+		{usermodel.InvitationTokenBulkFilter{BatchId: "autumn"}, false},
+		{usermodel.InvitationTokenBulkFilter{CreatedBy: 2}, false},
+		{usermodel.InvitationTokenBulkFilter{CreatedBefore: &createdAt}, false},
+	}
+	for _, tc := range tsc {
+		assert.Equal(t, tc.expected, tc.filter.Matches(&token))
+	}
+}
+
+func TestInvitationTokenUpdate_Validate(t *testing.T) {
+	enabled := usermodel.InvitationStatusActive
+	expiresAt := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	var tsc = []struct {
+		data     usermodel.InvitationTokenUpdate
+		expected error
+	}{
+		{usermodel.InvitationTokenUpdate{Status: &enabled}, nil},
+		{usermodel.InvitationTokenUpdate{ExpiresAt: &expiresAt}, nil},
+		{usermodel.InvitationTokenUpdate{Status: &enabled, ExtendBy: -3600}, nil},
+		{usermodel.InvitationTokenUpdate{}, usermodel.ErrTokenUpdateEmpty},
+		{usermodel.InvitationTokenUpdate{ExpiresAt: &expiresAt, ExtendBy: 3600}, usermodel.ErrTokenExpiryUpdateInvalid},
+	}
+	for _, tc := range tsc {
+		assert.Equal(t, tc.expected, tc.data.Validate())
+	}
+}
+
+func TestInvitationTokenUpdate_NewExpiry(t *testing.T) {
+	current := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2022, 7, 1, 14, 0, 0, 500, time.FixedZone("ICT", 7*3600))
+
+	var tsc = []struct {
+		data     usermodel.InvitationTokenUpdate
+		expected time.Time
+	}{
+		{usermodel.InvitationTokenUpdate{ExpiresAt: &expiresAt}, time.Date(2022, 7, 1, 7, 0, 0, 0, time.UTC)},
+		{usermodel.InvitationTokenUpdate{ExtendBy: 86400}, current.Add(24 * time.Hour)},
+		{usermodel.InvitationTokenUpdate{ExtendBy: -3600}, current.Add(-time.Hour)},
+	}
+	for _, tc := range tsc {
+		assert.Equal(t, tc.expected, tc.data.NewExpiry(current))
+	}
+}
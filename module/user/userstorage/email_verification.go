@@ -0,0 +1,86 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	emailVerificationKeyPrefix         = "email_verification:"
+	emailVerificationThrottleKeyPrefix = "email_verification_throttle:"
+)
+
+// IEmailVerificationTokenStore keeps the single use tokens sent in
+// verification emails, and when an address was last sent one
+type IEmailVerificationTokenStore interface {
+	SaveVerificationToken(ctx context.Context, token string, userId int, ttl time.Duration) error
+	TakeVerificationToken(ctx context.Context, token string) (int, error)
+	DeleteVerificationToken(ctx context.Context, token string) error
+	ThrottleVerification(ctx context.Context, email string, interval time.Duration) (bool, error)
+}
+
+type redisEmailVerificationTokenStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisEmailVerificationTokenStore(rdb *redis.Client) IEmailVerificationTokenStore {
+	return &redisEmailVerificationTokenStore{rdb: rdb}
+}
+
+func (s *redisEmailVerificationTokenStore) SaveVerificationToken(
+	ctx context.Context,
+	token string,
+	userId int,
+	ttl time.Duration,
+) error {
+	if err := s.rdb.Set(ctx, emailVerificationKeyPrefix+token, userId, ttl).Err(); err != nil {
+		return common.ErrDB(err)
+	}
+	return nil
+}
+
+// TakeVerificationToken removes the token and returns the user it was sent
+// to, common.ErrRecordNotFound when it is unknown, expired or taken already
+func (s *redisEmailVerificationTokenStore) TakeVerificationToken(ctx context.Context, token string) (int, error) {
+	// the token is single use, so take it out of redis atomically
+	val, err := s.rdb.GetDel(ctx, emailVerificationKeyPrefix+token).Result()
+	if err == redis.Nil {
+		return 0, common.ErrRecordNotFound
+	}
+	if err != nil {
+		return 0, common.ErrDB(err)
+	}
+
+	userId, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, common.ErrRecordNotFound
+	}
+
+	return userId, nil
+}
+
+func (s *redisEmailVerificationTokenStore) DeleteVerificationToken(ctx context.Context, token string) error {
+	if err := s.rdb.Del(ctx, emailVerificationKeyPrefix+token).Err(); err != nil {
+		return common.ErrDB(err)
+	}
+	return nil
+}
+
+// ThrottleVerification reports whether a verification email may be sent to
+// email, which it then may not again for interval
+func (s *redisEmailVerificationTokenStore) ThrottleVerification(
+	ctx context.Context,
+	email string,
+	interval time.Duration,
+) (bool, error) {
+	ok, err := s.rdb.SetNX(ctx, emailVerificationThrottleKeyPrefix+strings.ToLower(email), 1, interval).Result()
+	if err != nil {
+		return false, common.ErrDB(err)
+	}
+	return ok, nil
+}
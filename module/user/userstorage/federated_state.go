@@ -0,0 +1,55 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const federatedStateKeyPrefix = "federated_state:"
+
+type IFederatedStateStore interface {
+	SaveState(ctx context.Context, state string, data *usermodel.FederatedLoginState, expiry int) error
+	TakeState(ctx context.Context, state string) (*usermodel.FederatedLoginState, error)
+}
+
+type redisFederatedStateStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisFederatedStateStore(rdb *redis.Client) IFederatedStateStore {
+	return &redisFederatedStateStore{rdb: rdb}
+}
+
+func (s *redisFederatedStateStore) SaveState(
+	ctx context.Context,
+	state string,
+	data *usermodel.FederatedLoginState,
+	expiry int,
+) error {
+	if err := s.rdb.Set(ctx, federatedStateKeyPrefix+state, data, time.Duration(expiry)*time.Second).Err(); err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// TakeState deletes the state as it reads it, so a callback can't be replayed
+func (s *redisFederatedStateStore) TakeState(
+	ctx context.Context,
+	state string,
+) (*usermodel.FederatedLoginState, error) {
+	var data usermodel.FederatedLoginState
+
+	if err := s.rdb.GetDel(ctx, federatedStateKeyPrefix+state).Scan(&data); err != nil {
+		if err == redis.Nil {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &data, nil
+}
@@ -0,0 +1,33 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+func (s *sqlStore) CreateIdentity(_ context.Context, data *usermodel.UserIdentity) error {
+	if err := s.db.Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) FindIdentity(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*usermodel.UserIdentity, error) {
+	var identity usermodel.UserIdentity
+
+	if err := s.db.Table(identity.TableName()).Where(conditions).First(&identity).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &identity, nil
+}
@@ -0,0 +1,427 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IInvitationTokenStore keeps invitation tokens in MySQL, their source of
+// truth, and caches them in Redis under their own key until they expire.
+// Lookups read through the cache and fall back to MySQL when Redis can't be
+// reached; writes go to MySQL first and then through to the cache.
+type IInvitationTokenStore interface {
+	FindInvitationToken(ctx context.Context, token string) (*usermodel.InvitationToken, error)
+	CreateInvitationToken(ctx context.Context, token *usermodel.InvitationToken) (bool, error)
+	UpdateInvitationToken(ctx context.Context, token *usermodel.InvitationToken) error
+	DeleteInvitationToken(ctx context.Context, token string) error
+	ListInvitationTokens(ctx context.Context) ([]usermodel.InvitationToken, error)
+	UpdateInvitationTokens(ctx context.Context, tokens []string, change usermodel.InvitationTokenChange) error
+	RebuildCache(ctx context.Context, backfill bool) (*usermodel.InvitationTokenCacheRebuild, error)
+}
+
+type cachedInvitationTokenStore struct {
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+func NewCachedInvitationTokenStore(db *gorm.DB, rdb *redis.Client) IInvitationTokenStore {
+	return &cachedInvitationTokenStore{db: db, rdb: rdb}
+}
+
+// FindInvitationToken returns the token unless it is missing or expired,
+// common.ErrRecordNotFound then
+func (s *cachedInvitationTokenStore) FindInvitationToken(
+	ctx context.Context,
+	token string,
+) (*usermodel.InvitationToken, error) {
+	if cached, err := s.findCached(ctx, token); err == nil {
+		return cached, nil
+	}
+
+	var found usermodel.InvitationToken
+	if err := s.db.Table(found.TableName()).
+		Where("token = ? AND expires_at > ?", token, time.Now()).
+		First(&found).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+	inUTC(&found)
+
+	// a cache that can't be written only makes the next lookup slower
+	_ = s.cache(ctx, &found)
+
+	return &found, nil
+}
+
+// CreateInvitationToken stores a new token and returns false instead when
+// the token is taken already
+func (s *cachedInvitationTokenStore) CreateInvitationToken(
+	ctx context.Context,
+	token *usermodel.InvitationToken,
+) (bool, error) {
+	var created bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// an expired token is gone and its value may be handed out again
+		if err := tx.Table(token.TableName()).
+			Where("token = ? AND expires_at <= ?", token.Token, time.Now()).
+			Delete(&usermodel.InvitationToken{}).Error; err != nil {
+			return err
+		}
+
+		insert := tx.Table(token.TableName()).Clauses(clause.Insert{Modifier: "IGNORE"}).Create(token)
+		if insert.Error != nil {
+			return insert.Error
+		}
+		created = insert.RowsAffected == 1
+		return nil
+	})
+	if err != nil {
+		return false, common.ErrDB(err)
+	}
+	if !created {
+		return false, nil
+	}
+
+	return true, s.refresh(ctx, token)
+}
+
+// UpdateInvitationToken saves every field of token, its expiry included,
+// and returns common.ErrRecordNotFound when the token isn't stored, deleted
+// meanwhile or only cached since before tokens were kept in MySQL; those
+// are saved by RebuildCache with backfill.
+func (s *cachedInvitationTokenStore) UpdateInvitationToken(ctx context.Context, token *usermodel.InvitationToken) error {
+	if err := updateToken(s.db, token); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return common.ErrRecordNotFound
+		}
+		return common.ErrDB(err)
+	}
+
+	return s.refresh(ctx, token)
+}
+
+// updateToken saves every field of a stored token, gorm.ErrRecordNotFound
+// when there is none
+func updateToken(db *gorm.DB, token *usermodel.InvitationToken) error {
+	update := db.Table(token.TableName()).
+		Where("token = ?", token.Token).
+		Select("*").
+		Updates(token)
+	if update.Error != nil {
+		return update.Error
+	}
+	if update.RowsAffected > 0 {
+		return nil
+	}
+
+	// MySQL counts only the rows it changed, so a token saved as it was
+	// affects none either
+	var stored int64
+	if err := db.Table(token.TableName()).Where("token = ?", token.Token).Count(&stored).Error; err != nil {
+		return err
+	}
+	if stored == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (s *cachedInvitationTokenStore) DeleteInvitationToken(ctx context.Context, token string) error {
+	if err := s.db.Table(usermodel.InvitationToken{}.TableName()).
+		Where("token = ?", token).
+		Delete(&usermodel.InvitationToken{}).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	if err := s.rdb.Del(ctx, token).Err(); err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// ListInvitationTokens returns every token which hasn't expired, oldest first
+func (s *cachedInvitationTokenStore) ListInvitationTokens(_ context.Context) ([]usermodel.InvitationToken, error) {
+	var tokens []usermodel.InvitationToken
+	if err := s.db.Table(usermodel.InvitationToken{}.TableName()).
+		Where("expires_at > ?", time.Now()).
+		Order("created_at").
+		Find(&tokens).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	for i := range tokens {
+		inUTC(&tokens[i])
+	}
+
+	return tokens, nil
+}
+
+// UpdateInvitationTokens locks tokens, or every token which hasn't expired
+// when tokens is nil, and makes the changes change asks for in the same
+// transaction, so either all of them are made or none is. Saved tokens which
+// weren't found are created, replacing expired ones. Errors of change are
+// returned as they are.
+func (s *cachedInvitationTokenStore) UpdateInvitationTokens(
+	ctx context.Context,
+	tokens []string,
+	change usermodel.InvitationTokenChange,
+) error {
+	var saved []usermodel.InvitationToken
+	var deleted []string
+	var changeErr error
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		db := tx.Table(usermodel.InvitationToken{}.TableName()).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("expires_at > ?", time.Now())
+		if tokens != nil {
+			db = db.Where("token IN ?", tokens)
+		}
+
+		var found []usermodel.InvitationToken
+		if err := db.Order("created_at").Find(&found).Error; err != nil {
+			return err
+		}
+		for i := range found {
+			inUTC(&found[i])
+		}
+
+		if saved, deleted, changeErr = change(found); changeErr != nil {
+			return changeErr
+		}
+
+		if len(deleted) > 0 {
+			if err := tx.Table(usermodel.InvitationToken{}.TableName()).
+				Where("token IN ?", deleted).
+				Delete(&usermodel.InvitationToken{}).Error; err != nil {
+				return err
+			}
+		}
+
+		locked := make(map[string]bool, len(found))
+		for i := range found {
+			locked[found[i].Token] = true
+		}
+
+		for i := range saved {
+			if locked[saved[i].Token] {
+				if err := updateToken(tx, &saved[i]); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := tx.Table(saved[i].TableName()).
+				Where("token = ? AND expires_at <= ?", saved[i].Token, time.Now()).
+				Delete(&usermodel.InvitationToken{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Table(saved[i].TableName()).Create(&saved[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if changeErr != nil {
+		return changeErr
+	}
+	if err != nil {
+		return common.ErrDB(err)
+	}
+
+	for i := range saved {
+		if err := s.refresh(ctx, &saved[i]); err != nil {
+			return err
+		}
+	}
+	if len(deleted) > 0 {
+		if err := s.rdb.Del(ctx, deleted...).Err(); err != nil {
+			return common.ErrDB(err)
+		}
+	}
+
+	return nil
+}
+
+// RebuildCache caches every stored token again. Tokens found only in the
+// cache are dropped from it, or saved to MySQL when backfill is set, which
+// is meant to be done once, for tokens issued before they were kept there.
+func (s *cachedInvitationTokenStore) RebuildCache(
+	ctx context.Context,
+	backfill bool,
+) (*usermodel.InvitationTokenCacheRebuild, error) {
+	tokens, err := s.ListInvitationTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]bool, len(tokens))
+	for i := range tokens {
+		stored[tokens[i].Token] = true
+	}
+
+	keys, err := s.scanKeys(ctx)
+	if err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	var result usermodel.InvitationTokenCacheRebuild
+	for _, key := range keys {
+		if stored[key] {
+			continue
+		}
+
+		if backfill {
+			saved, err := s.backfill(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			if saved != nil {
+				tokens = append(tokens, *saved)
+				result.Backfilled++
+				continue
+			}
+		}
+
+		if err := s.rdb.Del(ctx, key).Err(); err != nil {
+			return nil, common.ErrDB(err)
+		}
+		result.Dropped++
+	}
+
+	for i := range tokens {
+		if err := s.cache(ctx, &tokens[i]); err != nil {
+			return nil, common.ErrDB(err)
+		}
+		result.Cached++
+	}
+
+	return &result, nil
+}
+
+// backfill saves the token cached under key to MySQL and returns it, or nil
+// when nothing that can be saved is cached there
+func (s *cachedInvitationTokenStore) backfill(ctx context.Context, key string) (*usermodel.InvitationToken, error) {
+	cached, err := s.findCached(ctx, key)
+	if err == redis.Nil || errors.Is(err, errNotCachedToken) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	insert := s.db.Table(cached.TableName()).Clauses(clause.Insert{Modifier: "IGNORE"}).Create(cached)
+	if insert.Error != nil {
+		return nil, common.ErrDB(insert.Error)
+	}
+	if insert.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return cached, nil
+}
+
+// errNotCachedToken is a key holding anything but a token that can be used
+var errNotCachedToken = errors.New("key holds no invitation token")
+
+// findCached returns the token cached under key, redis.Nil when there is none
+func (s *cachedInvitationTokenStore) findCached(ctx context.Context, key string) (*usermodel.InvitationToken, error) {
+	value, err := s.rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var cached usermodel.InvitationToken
+	if err := cached.UnmarshalBinary(value); err != nil || cached.Token != key {
+		return nil, errNotCachedToken
+	}
+
+	// tokens cached before expires_at was kept only have their TTL
+	if cached.ExpiresAt == nil {
+		ttl, err := s.rdb.TTL(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ttl <= 0 {
+			return nil, errNotCachedToken
+		}
+		expiresAt := time.Now().UTC().Add(ttl).Truncate(time.Second)
+		cached.ExpiresAt = &expiresAt
+	}
+
+	return &cached, nil
+}
+
+// cache writes token to redis until it expires
+func (s *cachedInvitationTokenStore) cache(ctx context.Context, token *usermodel.InvitationToken) error {
+	ttl := token.TTL(time.Now())
+	if ttl == 0 {
+		return s.rdb.Del(ctx, token.Token).Err()
+	}
+
+	payload, err := token.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.Set(ctx, token.Token, payload, ttl).Err()
+}
+
+// refresh writes a saved token through to the cache. A cache that can't be
+// written must not serve the token as it was, so it is evicted instead; only
+// when that fails too is the write an error.
+func (s *cachedInvitationTokenStore) refresh(ctx context.Context, token *usermodel.InvitationToken) error {
+	if err := s.cache(ctx, token); err == nil {
+		return nil
+	}
+
+	if err := s.rdb.Del(ctx, token.Token).Err(); err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// scanKeys returns the key of every cached invitation token
+func (s *cachedInvitationTokenStore) scanKeys(ctx context.Context) ([]string, error) {
+	var keys []string
+
+	iter := s.rdb.Scan(ctx, 0, "*", 0).Iterator()
+	for iter.Next(ctx) {
+		// invitation tokens are alphanumeric, every other key is namespaced with ":"
+		if strings.Contains(iter.Val(), ":") {
+			continue
+		}
+		keys = append(keys, iter.Val())
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// inUTC turns the times MySQL returns in its own location to UTC, the way
+// tokens are issued and cached
+func inUTC(token *usermodel.InvitationToken) {
+	for _, t := range []**time.Time{&token.DeliveredAt, &token.CreatedAt, &token.NotBefore, &token.ExpiresAt} {
+		if *t != nil {
+			utc := (*t).UTC()
+			*t = &utc
+		}
+	}
+}
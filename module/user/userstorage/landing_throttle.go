@@ -0,0 +1,45 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const landingKeyPrefix = "invitation_landing:"
+
+type ILandingThrottle interface {
+	IncrementLookups(ctx context.Context, clientIP string, window time.Duration) (int64, time.Duration, error)
+}
+
+type redisLandingThrottle struct {
+	rdb *redis.Client
+}
+
+func NewRedisLandingThrottle(rdb *redis.Client) ILandingThrottle {
+	return &redisLandingThrottle{rdb: rdb}
+}
+
+// IncrementLookups counts a landing page lookup from the client in the
+// current fixed window and returns the count so far and the time left in
+// the window
+func (s *redisLandingThrottle) IncrementLookups(
+	ctx context.Context,
+	clientIP string,
+	window time.Duration,
+) (int64, time.Duration, error) {
+	key := landingKeyPrefix + clientIP
+
+	pipe := s.rdb.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, window)
+	ttl := pipe.TTL(ctx, key)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, common.ErrDB(err)
+	}
+
+	return incr.Val(), ttl.Val(), nil
+}
@@ -0,0 +1,88 @@
+package userstorage
+
+import (
+	"app-invite-service/common"
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	signedRevokedKeyPrefix = "signed_invite_revoked:"
+	signedUsesKeyPrefix    = "signed_invite_uses:"
+)
+
+// addUseScript counts a use of a signed token unless it has ARGV[1] uses
+// already, and keeps the count until the token expires at ARGV[2]
+var addUseScript = redis.NewScript(`
+local uses = tonumber(redis.call('GET', KEYS[1]) or '0')
+if uses >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('INCR', KEYS[1])
+redis.call('EXPIREAT', KEYS[1], ARGV[2])
+return 1
+`)
+
+// ISignedInvitationStore keeps what a signed invitation token can't carry:
+// whether it was revoked and how often it was used. Entries expire with the
+// token.
+type ISignedInvitationStore interface {
+	Revoke(ctx context.Context, id string, until time.Time) error
+	IsRevoked(ctx context.Context, id string) (bool, error)
+	CountUses(ctx context.Context, id string) (int, error)
+	AddUse(ctx context.Context, id string, maxUses int, until time.Time) (bool, error)
+}
+
+type redisSignedInvitationStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisSignedInvitationStore(rdb *redis.Client) ISignedInvitationStore {
+	return &redisSignedInvitationStore{rdb: rdb}
+}
+
+func (s *redisSignedInvitationStore) Revoke(ctx context.Context, id string, until time.Time) error {
+	if err := s.rdb.Set(ctx, signedRevokedKeyPrefix+id, 1, time.Until(until)).Err(); err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *redisSignedInvitationStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	n, err := s.rdb.Exists(ctx, signedRevokedKeyPrefix+id).Result()
+	if err != nil {
+		return false, common.ErrDB(err)
+	}
+
+	return n == 1, nil
+}
+
+func (s *redisSignedInvitationStore) CountUses(ctx context.Context, id string) (int, error) {
+	n, err := s.rdb.Get(ctx, signedUsesKeyPrefix+id).Int()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, common.ErrDB(err)
+	}
+
+	return n, nil
+}
+
+// AddUse counts a use and returns false instead when the token has maxUses already
+func (s *redisSignedInvitationStore) AddUse(
+	ctx context.Context,
+	id string,
+	maxUses int,
+	until time.Time,
+) (bool, error) {
+	ok, err := addUseScript.Run(ctx, s.rdb, []string{signedUsesKeyPrefix + id}, maxUses, until.Unix()).Int()
+	if err != nil {
+		return false, common.ErrDB(err)
+	}
+
+	return ok == 1, nil
+}
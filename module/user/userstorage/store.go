@@ -11,6 +11,10 @@ import (
 type ISqlStore interface {
 	CreateUser(_ context.Context, data *usermodel.UserCreate) error
 	FindUser(_ context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+	UpdateUser(_ context.Context, id int, data map[string]interface{}) error
+	DeleteUser(_ context.Context, id int) error
+	CreateIdentity(_ context.Context, data *usermodel.UserIdentity) error
+	FindIdentity(_ context.Context, conditions map[string]interface{}) (*usermodel.UserIdentity, error)
 }
 
 type sqlStore struct {
@@ -59,3 +63,19 @@ func (s *sqlStore) FindUser(
 
 	return &user, nil
 }
+
+func (s *sqlStore) UpdateUser(_ context.Context, id int, data map[string]interface{}) error {
+	if err := s.db.Table(usermodel.User{}.TableName()).Where("id = ?", id).Updates(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) DeleteUser(_ context.Context, id int) error {
+	if err := s.db.Table(usermodel.User{}.TableName()).Where("id = ?", id).Delete(nil).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package clituser
+
+import (
+	"app-invite-service/config"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/userstorage"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// RebuildTokenCache repopulates the invitation token cache in redis from
+// MySQL and prints what it did as JSON. Run it with -backfill once after
+// upgrading, to save tokens issued before they were kept in MySQL.
+func RebuildTokenCache(
+	ctx context.Context,
+	_ *config.Config,
+	db *gorm.DB,
+	rdb *redis.Client,
+	args []string,
+	out io.Writer,
+) error {
+	flags := flag.NewFlagSet("rebuild-token-cache", flag.ContinueOnError)
+	backfill := flags.Bool("backfill", false, "save tokens found only in redis to MySQL instead of dropping them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	biz := userbiz.NewRebuildInvitationTokenCacheBiz(userstorage.NewCachedInvitationTokenStore(db, rdb))
+
+	result, err := biz.RebuildInvitationTokenCache(ctx, *backfill)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(out).Encode(result)
+}
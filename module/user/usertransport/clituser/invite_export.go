@@ -0,0 +1,110 @@
+package clituser
+
+import (
+	"app-invite-service/config"
+	"app-invite-service/module/audit/auditbiz"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/audit/auditstorage"
+	"app-invite-service/module/campaign/campaignbiz"
+	"app-invite-service/module/campaign/campaignstorage"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// newAuditRecorder records what a command does, with no one as its actor
+func newAuditRecorder(db *gorm.DB) userbiz.AuditLogger {
+	return auditbiz.NewRecordEventBiz(
+		auditstorage.NewSQLStore(db),
+		func(context.Context) *auditmodel.RequestInfo { return nil },
+	)
+}
+
+// ExportTokens writes every invitation token which hasn't expired to
+// stdout, or to the file given with -out
+func ExportTokens(
+	ctx context.Context,
+	_ *config.Config,
+	db *gorm.DB,
+	rdb *redis.Client,
+	args []string,
+	out io.Writer,
+) error {
+	flags := flag.NewFlagSet("export-tokens", flag.ContinueOnError)
+	format := flags.String("format", usermodel.ExportFormatJSONL, "jsonl or csv")
+	path := flags.String("out", "", "file to write instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *path != "" {
+		file, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		out = file
+	}
+
+	biz := userbiz.NewExportInvitationTokensBiz(userstorage.NewCachedInvitationTokenStore(db, rdb), newAuditRecorder(db))
+
+	return biz.ExportInvitationTokens(ctx, &usermodel.InvitationTokenExportQuery{Format: *format}, out)
+}
+
+// ImportTokens imports the invitation tokens read from stdin, or from the
+// file given with -in, and prints what it did as JSON
+func ImportTokens(
+	ctx context.Context,
+	cfg *config.Config,
+	db *gorm.DB,
+	rdb *redis.Client,
+	args []string,
+	out io.Writer,
+) error {
+	flags := flag.NewFlagSet("import-tokens", flag.ContinueOnError)
+	var data usermodel.InvitationTokenImport
+	flags.StringVar(&data.Format, "format", usermodel.ExportFormatJSONL, "jsonl or csv")
+	flags.StringVar(&data.Conflict, "conflict", usermodel.ImportConflictFail, "skip, overwrite or fail on tokens which exist already")
+	flags.BoolVar(&data.DryRun, "dry-run", false, "only tell what the import would do")
+	path := flags.String("in", "", "file to read instead of stdin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if *path != "" {
+		file, err := os.Open(*path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		in = file
+	}
+
+	biz := userbiz.NewImportInvitationTokensBiz(
+		userstorage.NewCachedInvitationTokenStore(db, rdb),
+		campaignbiz.NewCampaignPolicyBiz(campaignstorage.NewSQLStore(db)),
+		newAuditRecorder(db),
+		time.Duration(cfg.Invitation.MaxTTL)*time.Second,
+	)
+
+	result, err := biz.ImportInvitationTokens(ctx, &data, in)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(out).Encode(result)
+}
@@ -0,0 +1,19 @@
+package ginuser
+
+import (
+	"app-invite-service/component"
+	"app-invite-service/module/domainrule/domainrulebiz"
+	"app-invite-service/module/domainrule/domainrulemodel"
+	"app-invite-service/module/domainrule/domainrulestorage"
+)
+
+// newDomainChecker combines the domain rules from config and MySQL
+func newDomainChecker(appCtx component.AppContext) domainrulebiz.ICheckEmailDomainBiz {
+	cfg := appCtx.GetConfig()
+
+	return domainrulebiz.NewCheckEmailDomainBiz(
+		domainrulestorage.NewSQLStore(appCtx.GetDBConn()),
+		domainrulemodel.SplitPatterns(cfg.Auth.AllowedEmailDomains),
+		domainrulemodel.SplitPatterns(cfg.Auth.DeniedEmailDomains),
+	)
+}
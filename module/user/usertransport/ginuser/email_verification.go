@@ -0,0 +1,58 @@
+package ginuser
+
+import (
+	"net/http"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newEmailVerificationTokens(appCtx component.AppContext) userbiz.EmailVerificationTokens {
+	return userstorage.NewRedisEmailVerificationTokenStore(appCtx.GetRedisConn())
+}
+
+func ConfirmEmail(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store := userstorage.NewSQLStore(appCtx.GetDBConn())
+		biz := userbiz.NewConfirmEmailBiz(store, newEmailVerificationTokens(appCtx), ginaudit.NewRecorder(appCtx))
+
+		if err := biz.ConfirmEmail(c.Request.Context(), c.Query("token")); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
+
+func ResendEmailVerification(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.EmailVerificationResend
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		cfg := appCtx.GetConfig()
+		tokens := newEmailVerificationTokens(appCtx)
+		store := userstorage.NewSQLStore(appCtx.GetDBConn())
+		sender := userbiz.NewSendEmailVerificationBiz(
+			tokens,
+			appCtx.GetMailer(),
+			cfg.App.PublicURL,
+			cfg.Auth.EmailVerificationExpiry,
+		)
+		biz := userbiz.NewResendEmailVerificationBiz(store, tokens, sender, cfg.Auth.EmailVerificationInterval)
+
+		if err := biz.ResendEmailVerification(c.Request.Context(), &data); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
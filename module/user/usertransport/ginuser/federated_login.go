@@ -0,0 +1,102 @@
+package ginuser
+
+import (
+	"net/http"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/hash"
+	"app-invite-service/component/oidc"
+	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/referral/referralbiz"
+	"app-invite-service/module/referral/referralstorage"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func findIdentityProvider(appCtx component.AppContext, c *gin.Context) *oidc.RelyingParty {
+	provider, err := appCtx.GetIdentityProviders().Get(c.Param("provider"))
+	if err != nil {
+		panic(usermodel.ErrIdentityProviderNotFound)
+	}
+	return provider
+}
+
+func StartFederatedLogin(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.FederatedLoginStart
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		biz := userbiz.NewStartFederatedLoginBiz(
+			findIdentityProvider(appCtx, c),
+			userstorage.NewRedisFederatedStateStore(appCtx.GetRedisConn()),
+			appCtx.GetConfig().Federation.StateExpiry,
+		)
+
+		result, err := biz.StartFederatedLogin(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// StartFederatedLink starts linking an upstream identity to the signed in
+// user, who finishes through the same callback as a sign in
+func StartFederatedLink(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data := usermodel.FederatedLoginStart{LinkUserId: c.MustGet(common.CurrentUser).(*usermodel.User).Id}
+
+		biz := userbiz.NewStartFederatedLoginBiz(
+			findIdentityProvider(appCtx, c),
+			userstorage.NewRedisFederatedStateStore(appCtx.GetRedisConn()),
+			appCtx.GetConfig().Federation.StateExpiry,
+		)
+
+		result, err := biz.StartFederatedLogin(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func FederatedLogin(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.FederatedLoginCallback
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		biz := userbiz.NewFederatedLoginBiz(
+			findIdentityProvider(appCtx, c),
+			userstorage.NewRedisFederatedStateStore(appCtx.GetRedisConn()),
+			userstorage.NewSQLStore(appCtx.GetDBConn()),
+			hash.NewMd5Hash(),
+			newInvitationValidator(appCtx),
+			newDomainChecker(appCtx),
+			ginaudit.NewRecorder(appCtx),
+			referralbiz.NewRecordReferralBiz(referralstorage.NewSQLStore(appCtx.GetDBConn())),
+			jwt.NewTokenJWTProvider(appCtx.SecretKey()),
+			appCtx.GetTokenConfig(),
+			appCtx.GetConfig().Mfa.ChallengeExpiry,
+		)
+
+		account, err := biz.FederatedLogin(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(account))
+	}
+}
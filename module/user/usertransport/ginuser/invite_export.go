@@ -0,0 +1,80 @@
+package ginuser
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/campaign/campaigntransport/gincampaign"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBody is the largest import file accepted, well above what
+// usermodel.MaxImportTokens tokens take
+const maxImportBody = 32 << 20
+
+var exportContentTypes = map[string]string{
+	usermodel.ExportFormatJSONL: "application/x-ndjson",
+	usermodel.ExportFormatCSV:   "text/csv; charset=utf-8",
+}
+
+func ExportInvitationTokens(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var query usermodel.InvitationTokenExportQuery
+		if err := c.ShouldBind(&query); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		if err := query.Validate(); err != nil {
+			panic(err)
+		}
+
+		biz := userbiz.NewExportInvitationTokensBiz(newInvitationTokenStore(appCtx), ginaudit.NewRecorder(appCtx))
+
+		filename := fmt.Sprintf("invitation-tokens-%s.%s", time.Now().UTC().Format("20060102-150405"), query.Format)
+		c.Header("Content-Type", exportContentTypes[query.Format])
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Status(http.StatusOK)
+
+		// the header is already sent once tokens are streamed, so a failure
+		// part way can only cut the file short
+		if err := biz.ExportInvitationTokens(c.Request.Context(), &query, c.Writer); err != nil {
+			if !c.Writer.Written() {
+				panic(err)
+			}
+			_ = c.Error(err)
+		}
+	}
+}
+
+// ImportInvitationTokens takes the file to import as the request body, in
+// the format the query names
+func ImportInvitationTokens(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.InvitationTokenImport
+		if err := c.ShouldBindQuery(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		biz := userbiz.NewImportInvitationTokensBiz(
+			newInvitationTokenStore(appCtx),
+			gincampaign.NewPolicy(appCtx),
+			ginaudit.NewRecorder(appCtx),
+			time.Duration(appCtx.GetConfig().Invitation.MaxTTL)*time.Second,
+		)
+
+		body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBody)
+		result, err := biz.ImportInvitationTokens(c.Request.Context(), &data, body)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
@@ -0,0 +1,43 @@
+package ginuser
+
+import (
+	"net/http"
+	"time"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/campaign/campaigntransport/gincampaign"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetInvitationLanding(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.InvitationLandingQuery
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		data.ClientIP = c.ClientIP()
+
+		cfg := appCtx.GetConfig().Invitation
+		biz := userbiz.NewGetInvitationLandingBiz(
+			newInvitationTokenStore(appCtx),
+			newSignedTokenChecker(appCtx),
+			gincampaign.NewPolicy(appCtx),
+			userstorage.NewSQLStore(appCtx.GetDBConn()),
+			userstorage.NewRedisLandingThrottle(appCtx.GetRedisConn()),
+			cfg.LandingLimit,
+			time.Duration(cfg.LandingWindow)*time.Second,
+		)
+
+		result, err := biz.GetInvitationLanding(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
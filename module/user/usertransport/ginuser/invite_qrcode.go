@@ -0,0 +1,64 @@
+package ginuser
+
+import (
+	"fmt"
+	"net/http"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+
+	"github.com/gin-gonic/gin"
+)
+
+// qrDeepLink is the deep link template QR codes encode
+func qrDeepLink(appCtx component.AppContext) string {
+	cfg := appCtx.GetConfig().Invitation
+	if cfg.QRDeepLink != "" {
+		return cfg.QRDeepLink
+	}
+	return cfg.DeepLink
+}
+
+func GetInvitationQRCode(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var query usermodel.InvitationQRCodeQuery
+		if err := c.ShouldBind(&query); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		biz := userbiz.NewRenderInvitationQRCodeBiz(
+			newInvitationTokenStore(appCtx),
+			newSignedTokenChecker(appCtx),
+			qrDeepLink(appCtx),
+		)
+
+		result, err := biz.RenderInvitationQRCode(c.Request.Context(), c.Param("id"), &query)
+		if err != nil {
+			panic(err)
+		}
+
+		c.Data(http.StatusOK, result.ContentType, result.Data)
+	}
+}
+
+func GenerateQRCodeSheet(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.InvitationQRCodeSheetCreate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		data.CreatedBy = c.MustGet(common.CurrentUser).(*usermodel.User).Id
+
+		biz := userbiz.NewGenerateQRCodeSheetBiz(NewTokenGenerator(appCtx), qrDeepLink(appCtx))
+
+		sheet, err := biz.GenerateQRCodeSheet(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="invitations-%s.pdf"`, data.BatchId))
+		c.Data(http.StatusOK, "application/pdf", sheet)
+	}
+}
@@ -0,0 +1,44 @@
+package ginuser
+
+import (
+	"net/http"
+	"time"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newLoginLimiter(appCtx component.AppContext) userbiz.ILoginLimiter {
+	cfg := appCtx.GetConfig().LoginLimit
+
+	return userbiz.NewLoginLimiter(appCtx.GetRedisConn(), &userbiz.LoginLimitConfig{
+		MaxAccountFailures: cfg.MaxAccountFailures,
+		MaxIPFailures:      cfg.MaxIPFailures,
+		FailureWindow:      time.Duration(cfg.FailureWindow) * time.Second,
+		LockoutDuration:    time.Duration(cfg.LockoutDuration) * time.Second,
+		DelayAfter:         cfg.DelayAfter,
+		BaseDelay:          time.Duration(cfg.BaseDelay) * time.Second,
+		MaxDelay:           time.Duration(cfg.MaxDelay) * time.Second,
+	})
+}
+
+func UnlockAccount(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.AccountUnlock
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		biz := userbiz.NewUnlockAccountBiz(newLoginLimiter(appCtx), ginaudit.NewRecorder(appCtx))
+		if err := biz.UnlockAccount(c.Request.Context(), &data); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
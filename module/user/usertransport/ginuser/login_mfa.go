@@ -0,0 +1,45 @@
+package ginuser
+
+import (
+	"net/http"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/component/hash"
+	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/module/mfa/mfabiz"
+	"app-invite-service/module/mfa/mfastorage"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func LoginWithMfa(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.UserLoginMfa
+
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		db := appCtx.GetDBConn()
+		verifier := mfabiz.NewVerifyMfaBiz(mfastorage.NewSQLStore(db), appCtx.GetRedisConn(), hash.NewMd5Hash())
+		biz := userbiz.NewLoginWithMfaBiz(
+			userstorage.NewSQLStore(db),
+			jwt.NewTokenJWTProvider(appCtx.SecretKey()),
+			appCtx.GetTokenConfig(),
+			newLoginLimiter(appCtx),
+			verifier,
+		)
+
+		data.ClientIP = c.ClientIP()
+		account, err := biz.LoginWithMfa(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(account))
+	}
+}
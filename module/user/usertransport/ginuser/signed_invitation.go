@@ -0,0 +1,93 @@
+package ginuser
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/campaign/campaigntransport/gincampaign"
+	"app-invite-service/module/invitehistory/invitehistorytransport/gininvitehistory"
+	"app-invite-service/module/invitequota/invitequotatransport/gininvitequota"
+	"app-invite-service/module/user/userbiz"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newSignedTokenChecker checks signed invitation tokens against the
+// revocation list and use counts in redis
+func newSignedTokenChecker(appCtx component.AppContext) userbiz.SignedTokenChecker {
+	return userbiz.NewSignedTokenBiz(
+		appCtx.GetInviteSigner(),
+		userstorage.NewRedisSignedInvitationStore(appCtx.GetRedisConn()),
+	)
+}
+
+func GenerateSignedInvitation(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.SignedInvitationCreate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		data.CreatedBy = c.MustGet(common.CurrentUser).(*usermodel.User).Id
+
+		biz := userbiz.NewGenerateSignedTokenBiz(
+			appCtx.GetInviteSigner(),
+			gininvitequota.NewReserver(appCtx),
+			gincampaign.NewPolicy(appCtx),
+			ginaudit.NewRecorder(appCtx),
+			gininvitehistory.NewRecorder(appCtx),
+			appCtx.GetConfig().SignedInvitation.MaxTTL,
+		)
+
+		result, err := biz.GenerateSignedToken(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func RevokeSignedInvitation(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.SignedInvitationRevoke
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		biz := userbiz.NewRevokeSignedTokenBiz(
+			appCtx.GetInviteSigner(),
+			userstorage.NewRedisSignedInvitationStore(appCtx.GetRedisConn()),
+			ginaudit.NewRecorder(appCtx),
+		)
+
+		if err := biz.RevokeSignedToken(c.Request.Context(), data.InvitationToken); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
+
+// GetSignedInvitationKey publishes the Ed25519 public key, so devices can
+// check signed tokens without reaching the service
+func GetSignedInvitationKey(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		signer := appCtx.GetInviteSigner()
+		if signer == nil {
+			panic(userbiz.ErrSignedInvitationDisabled)
+		}
+		if signer.PublicKey() == nil {
+			panic(userbiz.ErrSignedInvitationNoPublicKey)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]string{
+			"algorithm":  signer.Algorithm(),
+			"public_key": base64.RawURLEncoding.EncodeToString(signer.PublicKey()),
+		}))
+	}
+}
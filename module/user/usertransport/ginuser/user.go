@@ -2,11 +2,19 @@ package ginuser
 
 import (
 	"net/http"
+	"time"
 
 	"app-invite-service/common"
 	"app-invite-service/component"
 	"app-invite-service/component/hash"
+	"app-invite-service/component/mailer"
 	"app-invite-service/component/tokenprovider/jwt"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/campaign/campaigntransport/gincampaign"
+	"app-invite-service/module/invitehistory/invitehistorytransport/gininvitehistory"
+	"app-invite-service/module/invitequota/invitequotatransport/gininvitequota"
+	"app-invite-service/module/referral/referralbiz"
+	"app-invite-service/module/referral/referralstorage"
 	"app-invite-service/module/user/userbiz"
 	"app-invite-service/module/user/usermodel"
 	"app-invite-service/module/user/userstorage"
@@ -28,8 +36,17 @@ func Login(appCtx component.AppContext) gin.HandlerFunc {
 		md5 := hash.NewMd5Hash()
 		tokenConfig := appCtx.GetTokenConfig()
 
-		biz := userbiz.NewLoginBiz(store, tokenProvider, md5, tokenConfig)
-
+		biz := userbiz.NewLoginBiz(
+			store,
+			tokenProvider,
+			md5,
+			tokenConfig,
+			newLoginLimiter(appCtx),
+			appCtx.GetConfig().Auth.RequireVerifiedEmail,
+			appCtx.GetConfig().Mfa.ChallengeExpiry,
+		)
+
+		data.ClientIP = c.ClientIP()
 		account, err := biz.Login(c.Request.Context(), &data)
 		if err != nil {
 			panic(err)
@@ -50,7 +67,24 @@ func Register(appCtx component.AppContext) gin.HandlerFunc {
 		db := appCtx.GetDBConn()
 		store := userstorage.NewSQLStore(db)
 		md5 := hash.NewMd5Hash()
-		biz := userbiz.NewRegisterBiz(store, md5)
+		cfg := appCtx.GetConfig()
+		verifier := userbiz.NewSendEmailVerificationBiz(
+			newEmailVerificationTokens(appCtx),
+			appCtx.GetMailer(),
+			cfg.App.PublicURL,
+			cfg.Auth.EmailVerificationExpiry,
+		)
+		invites := newInvitationValidator(appCtx)
+		biz := userbiz.NewRegisterBiz(
+			store,
+			md5,
+			verifier,
+			invites,
+			newDomainChecker(appCtx),
+			ginaudit.NewRecorder(appCtx),
+			referralbiz.NewRecordReferralBiz(referralstorage.NewSQLStore(db)),
+			cfg.Auth.InviteOnlyRegistration,
+		)
 
 		if err := biz.Register(c.Request.Context(), &data); err != nil {
 			panic(err)
@@ -60,12 +94,47 @@ func Register(appCtx component.AppContext) gin.HandlerFunc {
 	}
 }
 
+// newInvitationTokenStore keeps invitation tokens in MySQL and caches them
+// in redis
+func newInvitationTokenStore(appCtx component.AppContext) userbiz.InvitationTokenStore {
+	return userstorage.NewCachedInvitationTokenStore(appCtx.GetDBConn(), appCtx.GetRedisConn())
+}
+
+// newInvitationValidator checks and redeems invitation tokens
+func newInvitationValidator(appCtx component.AppContext) userbiz.IValidateInviteTokenBiz {
+	return userbiz.NewValidateInviteTokenBiz(
+		newInvitationTokenStore(appCtx),
+		newSignedTokenChecker(appCtx),
+		gincampaign.NewPolicy(appCtx),
+		gininvitehistory.NewRecorder(appCtx),
+	)
+}
+
+// NewTokenGenerator is the invitation token generation biz shared by every
+// route that hands out invitations
+func NewTokenGenerator(appCtx component.AppContext) userbiz.IGenerateTokenBiz {
+	return userbiz.NewGenerateTokenBiz(
+		newInvitationTokenStore(appCtx),
+		appCtx.GetConfig().Invitation.CodeFormat,
+		newDomainChecker(appCtx),
+		gininvitequota.NewReserver(appCtx),
+		gincampaign.NewPolicy(appCtx),
+		ginaudit.NewRecorder(appCtx),
+		gininvitehistory.NewRecorder(appCtx),
+	)
+}
+
 func GenerateInviteToken(appCtx component.AppContext) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		redis := appCtx.GetRedisConn()
-		biz := userbiz.NewGenerateTokenBiz(redis)
+		var data usermodel.InvitationTokenCreate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		data.CreatedBy = c.MustGet(common.CurrentUser).(*usermodel.User).Id
+
+		biz := NewTokenGenerator(appCtx)
 
-		result, err := biz.GenerateToken(c.Request.Context())
+		result, err := biz.GenerateToken(c.Request.Context(), &data)
 		if err != nil {
 			panic(err)
 		}
@@ -82,12 +151,20 @@ func LoginWithInviteToken(appCtx component.AppContext) gin.HandlerFunc {
 			panic(common.ErrInvalidRequest(err))
 		}
 
-		redis := appCtx.GetRedisConn()
+		store := newInvitationTokenStore(appCtx)
 		tokenProvider := jwt.NewTokenJWTProvider(appCtx.SecretKey())
 		md5 := hash.NewMd5Hash()
 		tokenConfig := appCtx.GetTokenConfig()
 
-		biz := userbiz.NewLoginWithInviteTokenBiz(redis, tokenProvider, md5, tokenConfig)
+		biz := userbiz.NewLoginWithInviteTokenBiz(
+			store,
+			newSignedTokenChecker(appCtx),
+			tokenProvider,
+			md5,
+			tokenConfig,
+			gincampaign.NewPolicy(appCtx),
+			gininvitehistory.NewRecorder(appCtx),
+		)
 
 		account, err := biz.LoginWithInviteToken(c.Request.Context(), &data)
 		if err != nil {
@@ -100,8 +177,7 @@ func LoginWithInviteToken(appCtx component.AppContext) gin.HandlerFunc {
 
 func ValidateInvitationToken(appCtx component.AppContext) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		redis := appCtx.GetRedisConn()
-		biz := userbiz.NewValidateInviteTokenBiz(redis)
+		biz := newInvitationValidator(appCtx)
 		if err := biz.ValidateInvitationToken(c.Request.Context(), c.Query("invitation_token")); err != nil {
 			panic(err)
 		}
@@ -117,8 +193,7 @@ func ListInvitationToken(appCtx component.AppContext) gin.HandlerFunc {
 			panic(common.ErrInvalidRequest(err))
 		}
 
-		redis := appCtx.GetRedisConn()
-		biz := userbiz.NewListInvitationTokenBiz(redis)
+		biz := userbiz.NewListInvitationTokenBiz(newInvitationTokenStore(appCtx))
 
 		result, err := biz.ListInvitationToken(c.Request.Context(), &filter)
 		if err != nil {
@@ -136,8 +211,14 @@ func UpdateInvitationToken(appCtx component.AppContext) gin.HandlerFunc {
 			panic(common.ErrInvalidRequest(err))
 		}
 
-		redis := appCtx.GetRedisConn()
-		biz := userbiz.NewUpdateInvitationTokenBiz(redis)
+		cfg := appCtx.GetConfig().Invitation
+		biz := userbiz.NewUpdateInvitationTokenBiz(
+			newInvitationTokenStore(appCtx),
+			ginaudit.NewRecorder(appCtx),
+			gininvitehistory.NewRecorder(appCtx),
+			time.Duration(cfg.MinTTL)*time.Second,
+			time.Duration(cfg.MaxTTL)*time.Second,
+		)
 		if err := biz.UpdateInvitationToken(c.Request.Context(), c.Param("id"), &data); err != nil {
 			panic(err)
 		}
@@ -145,3 +226,79 @@ func UpdateInvitationToken(appCtx component.AppContext) gin.HandlerFunc {
 		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
 	}
 }
+
+func DeleteInvitationToken(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		biz := userbiz.NewDeleteInvitationTokenBiz(newInvitationTokenStore(appCtx), ginaudit.NewRecorder(appCtx))
+		if err := biz.DeleteInvitationToken(c.Request.Context(), c.Param("id")); err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(map[string]bool{"success": true}))
+	}
+}
+
+func BulkUpdateInvitationTokens(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.InvitationTokenBulkUpdate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		biz := userbiz.NewBulkUpdateInvitationTokensBiz(newInvitationTokenStore(appCtx), ginaudit.NewRecorder(appCtx))
+
+		result, err := biz.BulkUpdateInvitationTokens(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+// NewEmailInviter is the biz that generates an invitation and emails it,
+// shared by the routes that invite someone by email
+func NewEmailInviter(appCtx component.AppContext) (userbiz.IInviteByEmailBiz, error) {
+	cfg := appCtx.GetConfig()
+	template, err := mailer.LoadTemplate(
+		"invitation",
+		cfg.Invitation.EmailSubject,
+		cfg.Invitation.EmailTextTemplate,
+		cfg.Invitation.EmailHTMLTemplate,
+	)
+	if err != nil {
+		return nil, common.ErrInternal(err)
+	}
+
+	return userbiz.NewInviteByEmailBiz(
+		NewTokenGenerator(appCtx),
+		newInvitationTokenStore(appCtx),
+		appCtx.GetMailer(),
+		template,
+		ginaudit.NewRecorder(appCtx),
+		cfg.App.Name,
+		cfg.Invitation.DeepLink,
+	), nil
+}
+
+func InviteByEmail(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data usermodel.InvitationEmailCreate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		data.CreatedBy = c.MustGet(common.CurrentUser).(*usermodel.User).Id
+
+		biz, err := NewEmailInviter(appCtx)
+		if err != nil {
+			panic(err)
+		}
+
+		result, err := biz.InviteByEmail(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
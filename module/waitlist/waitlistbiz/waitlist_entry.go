@@ -0,0 +1,397 @@
+package waitlistbiz
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/waitlist/waitlistmodel"
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+type WaitlistStore interface {
+	CreateEntry(ctx context.Context, data *waitlistmodel.EntryCreate) error
+	FindEntry(ctx context.Context, conditions map[string]interface{}) (*waitlistmodel.Entry, error)
+	ListEntries(
+		ctx context.Context,
+		filter *waitlistmodel.EntryFilter,
+		paging *common.Paging,
+	) ([]waitlistmodel.Entry, error)
+	CountPendingUpTo(ctx context.Context, id int) (int, error)
+	UpdateEntry(ctx context.Context, id int, data map[string]interface{}) error
+	UpdatePendingEntry(ctx context.Context, id int, data map[string]interface{}) (bool, error)
+}
+
+type SignupThrottle interface {
+	IncrementSignups(ctx context.Context, clientIP string, window time.Duration) (int64, time.Duration, error)
+}
+
+type UserStore interface {
+	FindUser(ctx context.Context, conditions map[string]interface{}, moreInfo ...string) (*usermodel.User, error)
+}
+
+type Inviter interface {
+	InviteByEmail(ctx context.Context, data *usermodel.InvitationEmailCreate) (*usermodel.InvitationToken, error)
+}
+
+type AuditLogger interface {
+	Record(ctx context.Context, entry *auditmodel.Entry)
+}
+
+// Join waitlist
+
+type IJoinWaitlistBiz interface {
+	JoinWaitlist(ctx context.Context, data *waitlistmodel.EntryCreate) (*waitlistmodel.Position, error)
+}
+
+type joinWaitlistBiz struct {
+	store     WaitlistStore
+	userStore UserStore
+	throttle  SignupThrottle
+	limit     int
+	window    time.Duration
+}
+
+// NewJoinWaitlistBiz allows limit signups per client IP within window. A zero
+// limit turns throttling off.
+func NewJoinWaitlistBiz(
+	store WaitlistStore,
+	userStore UserStore,
+	throttle SignupThrottle,
+	limit int,
+	window time.Duration,
+) IJoinWaitlistBiz {
+	return &joinWaitlistBiz{store: store, userStore: userStore, throttle: throttle, limit: limit, window: window}
+}
+
+func (biz *joinWaitlistBiz) JoinWaitlist(
+	ctx context.Context,
+	data *waitlistmodel.EntryCreate,
+) (*waitlistmodel.Position, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	// count every attempt, so the endpoint can't be used to probe emails
+	if biz.limit > 0 && data.ClientIP != "" {
+		count, retryAfter, err := biz.throttle.IncrementSignups(ctx, data.ClientIP, biz.window)
+		if err != nil {
+			return nil, err
+		}
+		if count > int64(biz.limit) {
+			return nil, waitlistmodel.ErrSignupThrottled(retryAfter)
+		}
+	}
+
+	if _, err := biz.userStore.FindUser(ctx, map[string]interface{}{"email": data.Email}); err == nil {
+		return nil, waitlistmodel.ErrAlreadyRegistered
+	} else if err != common.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if _, err := biz.store.FindEntry(ctx, map[string]interface{}{"email": data.Email}); err == nil {
+		return nil, waitlistmodel.ErrAlreadyOnWaitlist
+	} else if err != common.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if err := biz.store.CreateEntry(ctx, data); err != nil {
+		return nil, err
+	}
+
+	position, err := biz.store.CountPendingUpTo(ctx, data.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &waitlistmodel.Position{
+		Email:    data.Email,
+		Status:   waitlistmodel.StatusPending,
+		Position: position,
+	}, nil
+}
+
+// Get position
+
+type IGetPositionBiz interface {
+	GetPosition(ctx context.Context, query *waitlistmodel.PositionQuery) (*waitlistmodel.Position, error)
+}
+
+type getPositionBiz struct {
+	store    WaitlistStore
+	throttle SignupThrottle
+	limit    int
+	window   time.Duration
+}
+
+// NewGetPositionBiz counts lookups against the same limit per client IP as
+// signups, so neither can be used to probe emails faster than the other
+func NewGetPositionBiz(
+	store WaitlistStore,
+	throttle SignupThrottle,
+	limit int,
+	window time.Duration,
+) IGetPositionBiz {
+	return &getPositionBiz{store: store, throttle: throttle, limit: limit, window: window}
+}
+
+// GetPosition tells where a pending entry stands. Unknown, approved and
+// rejected emails all get ErrNotOnWaitlist, so reviews aren't disclosed to
+// whoever asks.
+func (biz *getPositionBiz) GetPosition(
+	ctx context.Context,
+	query *waitlistmodel.PositionQuery,
+) (*waitlistmodel.Position, error) {
+	email := strings.ToLower(strings.TrimSpace(query.Email))
+
+	if biz.limit > 0 && query.ClientIP != "" {
+		count, retryAfter, err := biz.throttle.IncrementSignups(ctx, query.ClientIP, biz.window)
+		if err != nil {
+			return nil, err
+		}
+		if count > int64(biz.limit) {
+			return nil, waitlistmodel.ErrSignupThrottled(retryAfter)
+		}
+	}
+
+	entry, err := biz.store.FindEntry(ctx, map[string]interface{}{"email": email})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, waitlistmodel.ErrNotOnWaitlist
+		}
+		return nil, err
+	}
+	if entry.Status != waitlistmodel.StatusPending {
+		return nil, waitlistmodel.ErrNotOnWaitlist
+	}
+
+	position, err := biz.store.CountPendingUpTo(ctx, entry.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &waitlistmodel.Position{Email: entry.Email, Status: entry.Status, Position: position}, nil
+}
+
+// List entries
+
+type IListEntriesBiz interface {
+	ListEntries(
+		ctx context.Context,
+		filter *waitlistmodel.EntryFilter,
+		paging *common.Paging,
+	) ([]waitlistmodel.Entry, error)
+}
+
+type listEntriesBiz struct {
+	store WaitlistStore
+}
+
+func NewListEntriesBiz(store WaitlistStore) IListEntriesBiz {
+	return &listEntriesBiz{store: store}
+}
+
+func (biz *listEntriesBiz) ListEntries(
+	ctx context.Context,
+	filter *waitlistmodel.EntryFilter,
+	paging *common.Paging,
+) ([]waitlistmodel.Entry, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	return biz.store.ListEntries(ctx, filter, paging)
+}
+
+// findPending looks up an entry that is still waiting for review
+func findPending(ctx context.Context, store WaitlistStore, id int) (*waitlistmodel.Entry, error) {
+	entry, err := store.FindEntry(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		if err == common.ErrRecordNotFound {
+			return nil, waitlistmodel.ErrEntryNotFound
+		}
+		return nil, err
+	}
+
+	if entry.Status != waitlistmodel.StatusPending {
+		return nil, waitlistmodel.ErrEntryNotPending
+	}
+
+	return entry, nil
+}
+
+// review moves a pending entry to status, failing if another review got to it first
+func review(ctx context.Context, store WaitlistStore, id int, status string, reviewerId int) error {
+	ok, err := store.UpdatePendingEntry(ctx, id, map[string]interface{}{
+		"status":      status,
+		"reviewed_by": reviewerId,
+		"reviewed_at": time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return waitlistmodel.ErrEntryNotPending
+	}
+
+	return nil
+}
+
+// Approve entries
+
+type IApproveEntriesBiz interface {
+	ApproveEntries(ctx context.Context, reviewerId int, data *waitlistmodel.Review) ([]waitlistmodel.ReviewResult, error)
+}
+
+type approveEntriesBiz struct {
+	store   WaitlistStore
+	inviter Inviter
+	audit   AuditLogger
+}
+
+func NewApproveEntriesBiz(store WaitlistStore, inviter Inviter, audit AuditLogger) IApproveEntriesBiz {
+	return &approveEntriesBiz{store: store, inviter: inviter, audit: audit}
+}
+
+// ApproveEntries sends an invitation to each pending entry. Entries that can't
+// be invited stay pending and report why in their result; it only fails as a
+// whole when the review itself is invalid, since earlier entries may have been
+// invited already.
+func (biz *approveEntriesBiz) ApproveEntries(
+	ctx context.Context,
+	reviewerId int,
+	data *waitlistmodel.Review,
+) ([]waitlistmodel.ReviewResult, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	results := make([]waitlistmodel.ReviewResult, 0, len(data.Ids))
+	for _, id := range data.Ids {
+		result := waitlistmodel.ReviewResult{Id: id}
+
+		token, err := biz.approve(ctx, reviewerId, id, data.CampaignId, &result)
+		if err != nil {
+			result.Fail(err)
+			results = append(results, result)
+			continue
+		}
+
+		result.Status = waitlistmodel.StatusApproved
+		result.InvitationToken = token.Token
+		result.DeliveryStatus = token.DeliveryStatus
+
+		biz.audit.Record(ctx, &auditmodel.Entry{
+			Action:     auditmodel.ActionWaitlistApprove,
+			TargetType: auditmodel.TargetWaitlistEntry,
+			TargetId:   auditmodel.IntId(id),
+			Before:     map[string]string{"status": waitlistmodel.StatusPending},
+			After:      result.Audited(),
+		})
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (biz *approveEntriesBiz) approve(
+	ctx context.Context,
+	reviewerId, id, campaignId int,
+	result *waitlistmodel.ReviewResult,
+) (*usermodel.InvitationToken, error) {
+	entry, err := findPending(ctx, biz.store, id)
+	if err != nil {
+		return nil, err
+	}
+	result.Email = entry.Email
+
+	// claim the entry before inviting, so it is invited at most once
+	if err := review(ctx, biz.store, id, waitlistmodel.StatusApproved, reviewerId); err != nil {
+		return nil, err
+	}
+
+	token, err := biz.inviter.InviteByEmail(ctx, &usermodel.InvitationEmailCreate{
+		Email:      entry.Email,
+		BatchId:    waitlistmodel.InvitationBatchId,
+		CampaignId: campaignId,
+		CreatedBy:  reviewerId,
+	})
+	if err != nil {
+		// the entry goes back in line; a failed revert leaves it approved
+		// without a token, which its result shows with its status
+		if revertErr := biz.store.UpdateEntry(ctx, id, map[string]interface{}{
+			"status":      waitlistmodel.StatusPending,
+			"reviewed_by": nil,
+			"reviewed_at": nil,
+		}); revertErr != nil {
+			log.Printf("waitlist: cannot put entry %d back in line after a failed invitation: %v", id, revertErr)
+			result.Status = waitlistmodel.StatusApproved
+		}
+		return nil, err
+	}
+
+	// the invitation is out, so the entry counts as approved either way
+	if err := biz.store.UpdateEntry(ctx, id, map[string]interface{}{"invitation_token": token.Token}); err != nil {
+		log.Printf("waitlist: cannot store the invitation of entry %d: %v", id, err)
+	}
+
+	return token, nil
+}
+
+// Reject entries
+
+type IRejectEntriesBiz interface {
+	RejectEntries(ctx context.Context, reviewerId int, data *waitlistmodel.Review) ([]waitlistmodel.ReviewResult, error)
+}
+
+type rejectEntriesBiz struct {
+	store WaitlistStore
+	audit AuditLogger
+}
+
+func NewRejectEntriesBiz(store WaitlistStore, audit AuditLogger) IRejectEntriesBiz {
+	return &rejectEntriesBiz{store: store, audit: audit}
+}
+
+func (biz *rejectEntriesBiz) RejectEntries(
+	ctx context.Context,
+	reviewerId int,
+	data *waitlistmodel.Review,
+) ([]waitlistmodel.ReviewResult, error) {
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+
+	results := make([]waitlistmodel.ReviewResult, 0, len(data.Ids))
+	for _, id := range data.Ids {
+		result := waitlistmodel.ReviewResult{Id: id}
+
+		entry, err := findPending(ctx, biz.store, id)
+		if err == nil {
+			result.Email = entry.Email
+			err = review(ctx, biz.store, id, waitlistmodel.StatusRejected, reviewerId)
+		}
+		if err != nil {
+			result.Fail(err)
+			results = append(results, result)
+			continue
+		}
+
+		result.Status = waitlistmodel.StatusRejected
+
+		biz.audit.Record(ctx, &auditmodel.Entry{
+			Action:     auditmodel.ActionWaitlistReject,
+			TargetType: auditmodel.TargetWaitlistEntry,
+			TargetId:   auditmodel.IntId(id),
+			Before:     map[string]string{"status": waitlistmodel.StatusPending},
+			After:      result,
+		})
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
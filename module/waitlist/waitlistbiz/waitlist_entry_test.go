@@ -0,0 +1,181 @@
+package waitlistbiz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"app-invite-service/common"
+	"app-invite-service/mock"
+	"app-invite-service/module/audit/auditbiz"
+	"app-invite-service/module/audit/auditmodel"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/waitlist/waitlistbiz"
+	"app-invite-service/module/waitlist/waitlistmodel"
+)
+
+func errKey(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.(*common.AppError).Key
+}
+
+func TestJoinWaitlistBiz_JoinWaitlist(t *testing.T) {
+	biz := waitlistbiz.NewJoinWaitlistBiz(
+		mock.NewMockWaitlistStore(),
+		mock.NewMockUserStore(),
+		mock.NewMockSignupThrottle(),
+		3,
+		time.Hour,
+	)
+
+	tcs := []struct {
+		name             string
+		data             waitlistmodel.EntryCreate
+		expectedPosition int
+		expectedErr      string
+	}{
+		{"first", waitlistmodel.EntryCreate{Email: "a@gmail.com", ClientIP: "1.1.1.1"}, 1, ""},
+		{"second", waitlistmodel.EntryCreate{Email: " B@Gmail.com ", Reason: "early", ClientIP: "1.1.1.1"}, 2, ""},
+		{"duplicate", waitlistmodel.EntryCreate{Email: "A@gmail.com", ClientIP: "2.2.2.2"}, 0, "ErrAlreadyOnWaitlist"},
+		{"registered", waitlistmodel.EntryCreate{Email: "user@gmail.com", ClientIP: "2.2.2.2"}, 0, "ErrAlreadyRegistered"},
+		{"invalid email", waitlistmodel.EntryCreate{Email: "a", ClientIP: "2.2.2.2"}, 0, "ErrEmailInvalid"},
+		{"throttled", waitlistmodel.EntryCreate{Email: "c@gmail.com", ClientIP: "1.1.1.1"}, 0, ""},
+		{"throttled", waitlistmodel.EntryCreate{Email: "d@gmail.com", ClientIP: "1.1.1.1"}, 0, "ErrSignupThrottled"},
+	}
+
+	for _, tc := range tcs {
+		result, err := biz.JoinWaitlist(nil, &tc.data)
+		assert.Equal(t, tc.expectedErr, errKey(err), tc.name)
+		if err == nil && tc.expectedPosition > 0 {
+			assert.Equal(t, tc.expectedPosition, result.Position, tc.name)
+			assert.Equal(t, waitlistmodel.StatusPending, result.Status, tc.name)
+		}
+	}
+}
+
+func TestWaitlistBiz_ReviewEntries(t *testing.T) {
+	store := mock.NewMockWaitlistStore()
+	join := waitlistbiz.NewJoinWaitlistBiz(store, mock.NewMockUserStore(), mock.NewMockSignupThrottle(), 0, 0)
+	for _, email := range []string{"a@gmail.com", "b@fail.com", "c@gmail.com", "d@gmail.com", "e@stuck.com"} {
+		_, err := join.JoinWaitlist(nil, &waitlistmodel.EntryCreate{Email: email})
+		require.Nil(t, err)
+	}
+
+	inviter := mock.NewMockInviter()
+	audit := mock.NewMockAuditLogger()
+	approve := waitlistbiz.NewApproveEntriesBiz(store, inviter, audit)
+	reject := waitlistbiz.NewRejectEntriesBiz(store, audit)
+	position := waitlistbiz.NewGetPositionBiz(store, mock.NewMockSignupThrottle(), 0, 0)
+
+	results, err := approve.ApproveEntries(nil, 1, &waitlistmodel.Review{Ids: []int{1, 2, 1, 9, 5}, CampaignId: 3})
+	require.Nil(t, err)
+	require.Len(t, results, 4)
+
+	assert.Equal(t, waitlistmodel.StatusApproved, results[0].Status)
+	assert.Equal(t, "waitlista@gmail.com", results[0].InvitationToken)
+	assert.Equal(t, "ErrInternal", results[1].ErrorKey)
+	assert.Empty(t, results[1].Status)
+	assert.Equal(t, "ErrEntryNotFound", results[2].ErrorKey)
+	// an entry which can't be put back in line says it was left approved
+	assert.Equal(t, "ErrInternal", results[3].ErrorKey)
+	assert.Equal(t, waitlistmodel.StatusApproved, results[3].Status)
+	assert.Equal(t, waitlistmodel.StatusApproved, store.Entry(5).Status)
+
+	require.Len(t, inviter.Invited(), 1)
+	assert.Equal(t, waitlistmodel.InvitationBatchId, inviter.Invited()[0].BatchId)
+	assert.Equal(t, 3, inviter.Invited()[0].CampaignId)
+	assert.Equal(t, 1, inviter.Invited()[0].CreatedBy)
+
+	// a failed invitation puts the entry back in line
+	assert.Equal(t, waitlistmodel.StatusPending, store.Entry(2).Status)
+	assert.Nil(t, store.Entry(2).ReviewedBy)
+	assert.Equal(t, "waitlista@gmail.com", *store.Entry(1).InvitationToken)
+
+	results, err = reject.RejectEntries(nil, 1, &waitlistmodel.Review{Ids: []int{1, 3}})
+	require.Nil(t, err)
+	assert.Equal(t, "ErrEntryNotPending", results[0].ErrorKey)
+	assert.Equal(t, waitlistmodel.StatusRejected, results[1].Status)
+
+	got, err := position.GetPosition(nil, &waitlistmodel.PositionQuery{Email: "D@gmail.com"})
+	require.Nil(t, err)
+	assert.Equal(t, 2, got.Position)
+
+	// approved, rejected and unknown emails can't be told apart
+	for _, email := range []string{"a@gmail.com", "c@gmail.com", "x@gmail.com"} {
+		_, err = position.GetPosition(nil, &waitlistmodel.PositionQuery{Email: email})
+		assert.Equal(t, waitlistmodel.ErrNotOnWaitlist, err, email)
+	}
+
+	entries := audit.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, auditmodel.ActionWaitlistApprove, entries[0].Action)
+	assert.Equal(t, usermodel.InvitationTokenId("waitlista@gmail.com"), entries[0].After.(waitlistmodel.ReviewResult).InvitationToken)
+	assert.Equal(t, auditmodel.ActionWaitlistReject, entries[1].Action)
+
+	_, err = approve.ApproveEntries(nil, 1, &waitlistmodel.Review{})
+	assert.Equal(t, waitlistmodel.ErrIdsInvalid, err)
+}
+
+func TestGetPositionBiz_Throttled(t *testing.T) {
+	store := mock.NewMockWaitlistStore()
+	throttle := mock.NewMockSignupThrottle()
+	join := waitlistbiz.NewJoinWaitlistBiz(store, mock.NewMockUserStore(), throttle, 3, time.Hour)
+	position := waitlistbiz.NewGetPositionBiz(store, throttle, 3, time.Hour)
+
+	_, err := join.JoinWaitlist(nil, &waitlistmodel.EntryCreate{Email: "a@gmail.com", ClientIP: "1.1.1.1"})
+	require.Nil(t, err)
+
+	tcs := []struct {
+		email       string
+		clientIP    string
+		expectedErr string
+	}{
+		{"a@gmail.com", "1.1.1.1", ""},
+		{"b@gmail.com", "1.1.1.1", "ErrNotOnWaitlist"},
+		{"a@gmail.com", "1.1.1.1", "ErrSignupThrottled"},
+		{"a@gmail.com", "2.2.2.2", ""},
+	}
+
+	for _, tc := range tcs {
+		_, err := position.GetPosition(nil, &waitlistmodel.PositionQuery{Email: tc.email, ClientIP: tc.clientIP})
+		assert.Equal(t, tc.expectedErr, errKey(err), tc.email+" from "+tc.clientIP)
+	}
+}
+
+// failingEventStore can't write audit events
+type failingEventStore struct {
+	auditbiz.EventStore
+}
+
+func (failingEventStore) CreateEvent(context.Context, *auditmodel.Event) error {
+	return errors.New("audit log down")
+}
+
+func TestApproveEntriesBiz_AuditFailureKeepsResults(t *testing.T) {
+	store := mock.NewMockWaitlistStore()
+	join := waitlistbiz.NewJoinWaitlistBiz(store, mock.NewMockUserStore(), mock.NewMockSignupThrottle(), 0, 0)
+	for _, email := range []string{"a@gmail.com", "b@gmail.com"} {
+		_, err := join.JoinWaitlist(nil, &waitlistmodel.EntryCreate{Email: email})
+		require.Nil(t, err)
+	}
+
+	inviter := mock.NewMockInviter()
+	results, err := waitlistbiz.NewApproveEntriesBiz(store, inviter, auditbiz.NewRecordEventBiz(
+		failingEventStore{EventStore: mock.NewMockAuditEventStore()},
+		func(context.Context) *auditmodel.RequestInfo { return nil },
+	)).
+		ApproveEntries(nil, 1, &waitlistmodel.Review{Ids: []int{1, 2}})
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.Equal(t, waitlistmodel.StatusApproved, result.Status)
+		assert.Empty(t, result.ErrorKey)
+	}
+	assert.Len(t, inviter.Invited(), 2)
+}
@@ -0,0 +1,231 @@
+package waitlistmodel
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/user/usermodel"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+const EntityName = "WaitlistEntry"
+
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// MaxReviewBatch caps how many entries a single approve or reject call handles
+const MaxReviewBatch = 100
+
+// InvitationBatchId tags the invitations sent to approved entries
+const InvitationBatchId = "waitlist"
+
+var (
+	ErrEmailInvalid = common.NewCustomError(
+		errors.New("email invalid"),
+		"email is invalid",
+		"ErrEmailInvalid",
+	)
+	ErrReasonTooLong = common.NewCustomError(
+		errors.New("reason too long"),
+		"reason must be at most 500 characters",
+		"ErrReasonTooLong",
+	)
+	ErrAlreadyOnWaitlist = common.NewCustomError(
+		errors.New("already on waitlist"),
+		"this email is already on the waitlist",
+		"ErrAlreadyOnWaitlist",
+	)
+	ErrAlreadyRegistered = common.NewCustomError(
+		errors.New("email already registered"),
+		"this email already has an account",
+		"ErrAlreadyRegistered",
+	)
+	ErrNotOnWaitlist = common.NewFullErrorResponse(
+		http.StatusNotFound,
+		errors.New("not on waitlist"),
+		"this email is not waiting on the waitlist",
+		"not on waitlist",
+		"ErrNotOnWaitlist",
+	)
+	ErrEntryNotFound = common.NewFullErrorResponse(
+		http.StatusNotFound,
+		errors.New("waitlist entry not found"),
+		"waitlist entry not found",
+		"waitlist entry not found",
+		"ErrEntryNotFound",
+	)
+	ErrEntryNotPending = common.NewCustomError(
+		errors.New("waitlist entry not pending"),
+		"waitlist entry was already reviewed",
+		"ErrEntryNotPending",
+	)
+	ErrStatusInvalid = common.NewCustomError(
+		errors.New("status invalid"),
+		"status must be one of: pending, approved, rejected",
+		"ErrStatusInvalid",
+	)
+	ErrIdsInvalid = common.NewCustomError(
+		errors.New("ids invalid"),
+		fmt.Sprintf("ids must hold between 1 and %d positive ids", MaxReviewBatch),
+		"ErrIdsInvalid",
+	)
+	ErrCampaignIdInvalid = common.NewCustomError(
+		errors.New("campaign id invalid"),
+		"campaign_id must not be negative",
+		"ErrCampaignIdInvalid",
+	)
+)
+
+func ErrSignupThrottled(retryAfter time.Duration) *common.AppError {
+	msg := fmt.Sprintf("too many waitlist requests, retry in %d seconds", int(retryAfter.Seconds()))
+	return common.NewFullErrorResponse(
+		http.StatusTooManyRequests,
+		errors.New("waitlist signup throttled"),
+		msg,
+		msg,
+		"ErrSignupThrottled",
+	)
+}
+
+// Entry is someone waiting for an invitation. Approving it sends one to Email.
+type Entry struct {
+	Id              int        `json:"id" gorm:"column:id;"`
+	Email           string     `json:"email" gorm:"column:email;"`
+	Reason          string     `json:"reason" gorm:"column:reason;"`
+	Status          string     `json:"status" gorm:"column:status;"`
+	InvitationToken *string    `json:"invitation_token,omitempty" gorm:"column:invitation_token;"`
+	ReviewedBy      *int       `json:"reviewed_by,omitempty" gorm:"column:reviewed_by;"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty" gorm:"column:reviewed_at;"`
+	ClientIP        string     `json:"client_ip" gorm:"column:client_ip;"`
+	CreatedAt       *time.Time `json:"created_at,omitempty" gorm:"column:created_at;"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty" gorm:"column:updated_at;"`
+}
+
+func (Entry) TableName() string {
+	return "waitlist_entries"
+}
+
+type EntryCreate struct {
+	Id       int    `json:"-" gorm:"column:id;"`
+	Email    string `json:"email" form:"email" binding:"required" gorm:"column:email;"`
+	Reason   string `json:"reason" form:"reason" gorm:"column:reason;"`
+	ClientIP string `json:"-" form:"-" gorm:"column:client_ip;"`
+}
+
+func (EntryCreate) TableName() string {
+	return Entry{}.TableName()
+}
+
+func (data *EntryCreate) Validate() error {
+	data.Email = strings.ToLower(strings.TrimSpace(data.Email))
+	data.Reason = strings.TrimSpace(data.Reason)
+
+	if addr, err := mail.ParseAddress(data.Email); err != nil || addr.Address != data.Email {
+		return ErrEmailInvalid
+	}
+
+	if len([]rune(data.Reason)) > 500 {
+		return ErrReasonTooLong
+	}
+
+	return nil
+}
+
+// PositionQuery asks where an email stands on the waitlist
+type PositionQuery struct {
+	Email    string `form:"email"`
+	ClientIP string `form:"-"`
+}
+
+// Position is where an email stands on the waitlist. Position is only set
+// while the entry is pending; 1 is the next in line.
+type Position struct {
+	Email    string `json:"email"`
+	Status   string `json:"status"`
+	Position int    `json:"position,omitempty"`
+}
+
+type EntryFilter struct {
+	Status string `json:"status,omitempty" form:"status"`
+}
+
+func (f *EntryFilter) Validate() error {
+	f.Status = strings.ToLower(strings.TrimSpace(f.Status))
+
+	switch f.Status {
+	case "", StatusPending, StatusApproved, StatusRejected:
+		return nil
+	}
+	return ErrStatusInvalid
+}
+
+// Review approves or rejects a batch of entries. CampaignId puts the
+// invitations sent on approval under a campaign.
+type Review struct {
+	Ids        []int `json:"ids" form:"ids" binding:"required"`
+	CampaignId int   `json:"campaign_id,omitempty" form:"campaign_id"`
+}
+
+func (data *Review) Validate() error {
+	if len(data.Ids) == 0 || len(data.Ids) > MaxReviewBatch {
+		return ErrIdsInvalid
+	}
+
+	seen := make(map[int]bool, len(data.Ids))
+	ids := data.Ids[:0]
+	for _, id := range data.Ids {
+		if id <= 0 {
+			return ErrIdsInvalid
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	data.Ids = ids
+
+	if data.CampaignId < 0 {
+		return ErrCampaignIdInvalid
+	}
+
+	return nil
+}
+
+// ReviewResult is what happened to one entry of a review. ErrorKey is set
+// when the entry wasn't reviewed; Status is set too when it was left in that
+// status anyway, like an approved entry whose invitation failed and which
+// couldn't be put back in line.
+type ReviewResult struct {
+	Id              int    `json:"id"`
+	Email           string `json:"email,omitempty"`
+	Status          string `json:"status,omitempty"`
+	InvitationToken string `json:"invitation_token,omitempty"`
+	DeliveryStatus  string `json:"delivery_status,omitempty"`
+	ErrorKey        string `json:"error_key,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// Audited is the result as the audit log keeps it, with the invitation
+// token named by its id
+func (r ReviewResult) Audited() ReviewResult {
+	if r.InvitationToken != "" {
+		r.InvitationToken = usermodel.InvitationTokenId(r.InvitationToken)
+	}
+	return r
+}
+
+// Fail records err as the reason the entry wasn't reviewed
+func (r *ReviewResult) Fail(err error) {
+	r.ErrorKey = "ErrInternal"
+	r.Error = "something went wrong with the server"
+	if appErr, ok := err.(*common.AppError); ok {
+		r.ErrorKey = appErr.Key
+		r.Error = appErr.Message
+	}
+}
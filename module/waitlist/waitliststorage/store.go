@@ -0,0 +1,122 @@
+package waitliststorage
+
+import (
+	"app-invite-service/common"
+	"app-invite-service/module/waitlist/waitlistmodel"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type ISqlStore interface {
+	CreateEntry(ctx context.Context, data *waitlistmodel.EntryCreate) error
+	FindEntry(ctx context.Context, conditions map[string]interface{}) (*waitlistmodel.Entry, error)
+	ListEntries(
+		ctx context.Context,
+		filter *waitlistmodel.EntryFilter,
+		paging *common.Paging,
+	) ([]waitlistmodel.Entry, error)
+	CountPendingUpTo(ctx context.Context, id int) (int, error)
+	UpdateEntry(ctx context.Context, id int, data map[string]interface{}) error
+	UpdatePendingEntry(ctx context.Context, id int, data map[string]interface{}) (bool, error)
+}
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+func NewSQLStore(db *gorm.DB) ISqlStore {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) CreateEntry(_ context.Context, data *waitlistmodel.EntryCreate) error {
+	if err := s.db.Table(data.TableName()).Create(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+func (s *sqlStore) FindEntry(
+	_ context.Context,
+	conditions map[string]interface{},
+) (*waitlistmodel.Entry, error) {
+	var entry waitlistmodel.Entry
+
+	if err := s.db.Table(entry.TableName()).Where(conditions).First(&entry).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, common.ErrRecordNotFound
+		}
+		return nil, common.ErrDB(err)
+	}
+
+	return &entry, nil
+}
+
+func (s *sqlStore) ListEntries(
+	_ context.Context,
+	filter *waitlistmodel.EntryFilter,
+	paging *common.Paging,
+) ([]waitlistmodel.Entry, error) {
+	db := s.db.Table(waitlistmodel.Entry{}.TableName())
+
+	if filter != nil && filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+
+	if err := db.Count(&paging.Total).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	// pending entries are reviewed first come, first served
+	order := "id desc"
+	if filter != nil && filter.Status == waitlistmodel.StatusPending {
+		order = "id asc"
+	}
+
+	var entries []waitlistmodel.Entry
+	if err := db.Order(order).
+		Offset((paging.Page - 1) * paging.Limit).
+		Limit(paging.Limit).
+		Find(&entries).Error; err != nil {
+		return nil, common.ErrDB(err)
+	}
+
+	return entries, nil
+}
+
+// CountPendingUpTo counts the pending entries that signed up no later than id
+func (s *sqlStore) CountPendingUpTo(_ context.Context, id int) (int, error) {
+	var count int64
+
+	if err := s.db.Table(waitlistmodel.Entry{}.TableName()).
+		Where("status = ? AND id <= ?", waitlistmodel.StatusPending, id).
+		Count(&count).Error; err != nil {
+		return 0, common.ErrDB(err)
+	}
+
+	return int(count), nil
+}
+
+func (s *sqlStore) UpdateEntry(_ context.Context, id int, data map[string]interface{}) error {
+	if err := s.db.Table(waitlistmodel.Entry{}.TableName()).
+		Where("id = ?", id).
+		Updates(data).Error; err != nil {
+		return common.ErrDB(err)
+	}
+
+	return nil
+}
+
+// UpdatePendingEntry updates the entry only while it is pending, so two
+// admins reviewing the same entry can't both act on it
+func (s *sqlStore) UpdatePendingEntry(_ context.Context, id int, data map[string]interface{}) (bool, error) {
+	db := s.db.Table(waitlistmodel.Entry{}.TableName()).
+		Where("id = ? AND status = ?", id, waitlistmodel.StatusPending).
+		Updates(data)
+	if db.Error != nil {
+		return false, common.ErrDB(db.Error)
+	}
+
+	return db.RowsAffected == 1, nil
+}
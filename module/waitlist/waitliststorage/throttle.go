@@ -0,0 +1,44 @@
+package waitliststorage
+
+import (
+	"app-invite-service/common"
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const signupKeyPrefix = "waitlist_signup:"
+
+type ISignupThrottle interface {
+	IncrementSignups(ctx context.Context, clientIP string, window time.Duration) (int64, time.Duration, error)
+}
+
+type redisSignupThrottle struct {
+	rdb *redis.Client
+}
+
+func NewRedisSignupThrottle(rdb *redis.Client) ISignupThrottle {
+	return &redisSignupThrottle{rdb: rdb}
+}
+
+// IncrementSignups counts a signup from the client in the current fixed window
+// and returns the count so far and the time left in the window
+func (s *redisSignupThrottle) IncrementSignups(
+	ctx context.Context,
+	clientIP string,
+	window time.Duration,
+) (int64, time.Duration, error) {
+	key := signupKeyPrefix + clientIP
+
+	pipe := s.rdb.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, window)
+	ttl := pipe.TTL(ctx, key)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, common.ErrDB(err)
+	}
+
+	return incr.Val(), ttl.Val(), nil
+}
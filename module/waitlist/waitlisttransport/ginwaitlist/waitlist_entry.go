@@ -0,0 +1,144 @@
+package ginwaitlist
+
+import (
+	"net/http"
+	"time"
+
+	"app-invite-service/common"
+	"app-invite-service/component"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/user/usermodel"
+	"app-invite-service/module/user/userstorage"
+	"app-invite-service/module/user/usertransport/ginuser"
+	"app-invite-service/module/waitlist/waitlistbiz"
+	"app-invite-service/module/waitlist/waitlistmodel"
+	"app-invite-service/module/waitlist/waitliststorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func JoinWaitlist(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data waitlistmodel.EntryCreate
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		data.ClientIP = c.ClientIP()
+
+		db := appCtx.GetDBConn()
+		cfg := appCtx.GetConfig().Waitlist
+		biz := waitlistbiz.NewJoinWaitlistBiz(
+			waitliststorage.NewSQLStore(db),
+			userstorage.NewSQLStore(db),
+			waitliststorage.NewRedisSignupThrottle(appCtx.GetRedisConn()),
+			cfg.SignupLimit,
+			time.Duration(cfg.SignupWindow)*time.Second,
+		)
+
+		result, err := biz.JoinWaitlist(c.Request.Context(), &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func GetPosition(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var query waitlistmodel.PositionQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		query.ClientIP = c.ClientIP()
+
+		cfg := appCtx.GetConfig().Waitlist
+		biz := waitlistbiz.NewGetPositionBiz(
+			waitliststorage.NewSQLStore(appCtx.GetDBConn()),
+			waitliststorage.NewRedisSignupThrottle(appCtx.GetRedisConn()),
+			cfg.SignupLimit,
+			time.Duration(cfg.SignupWindow)*time.Second,
+		)
+
+		result, err := biz.GetPosition(c.Request.Context(), &query)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func ListEntries(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter waitlistmodel.EntryFilter
+		if err := c.ShouldBind(&filter); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+
+		var paging common.Paging
+		if err := c.ShouldBind(&paging); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		paging.Fulfill()
+
+		biz := waitlistbiz.NewListEntriesBiz(waitliststorage.NewSQLStore(appCtx.GetDBConn()))
+
+		result, err := biz.ListEntries(c.Request.Context(), &filter, &paging)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.NewSuccessResponse(result, paging, filter))
+	}
+}
+
+func ApproveEntries(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data waitlistmodel.Review
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		reviewerId := c.MustGet(common.CurrentUser).(*usermodel.User).Id
+
+		inviter, err := ginuser.NewEmailInviter(appCtx)
+		if err != nil {
+			panic(err)
+		}
+
+		biz := waitlistbiz.NewApproveEntriesBiz(
+			waitliststorage.NewSQLStore(appCtx.GetDBConn()),
+			inviter,
+			ginaudit.NewRecorder(appCtx),
+		)
+
+		result, err := biz.ApproveEntries(c.Request.Context(), reviewerId, &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
+
+func RejectEntries(appCtx component.AppContext) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data waitlistmodel.Review
+		if err := c.ShouldBind(&data); err != nil {
+			panic(common.ErrInvalidRequest(err))
+		}
+		reviewerId := c.MustGet(common.CurrentUser).(*usermodel.User).Id
+
+		biz := waitlistbiz.NewRejectEntriesBiz(
+			waitliststorage.NewSQLStore(appCtx.GetDBConn()),
+			ginaudit.NewRecorder(appCtx),
+		)
+
+		result, err := biz.RejectEntries(c.Request.Context(), reviewerId, &data)
+		if err != nil {
+			panic(err)
+		}
+
+		c.JSON(http.StatusOK, common.SimpleSuccessResponse(result))
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	"app-invite-service/config"
+	"app-invite-service/module/user/usertransport/clituser"
+)
+
+// Command is a maintenance task run in place of the server, as
+// `app <name> [flags]`
+type Command func(
+	ctx context.Context,
+	cfg *config.Config,
+	db *gorm.DB,
+	rdb *redis.Client,
+	args []string,
+	out io.Writer,
+) error
+
+var commands = map[string]Command{
+	"export-tokens":       clituser.ExportTokens,
+	"import-tokens":       clituser.ImportTokens,
+	"rebuild-token-cache": clituser.RebuildTokenCache,
+}
+
+// RunCommand runs the command called name with args, printing its output
+// to stdout
+func RunCommand(cfg *config.Config, name string, args []string) error {
+	command, ok := commands[name]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for n := range commands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown command %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	dbConn, err := gorm.Open(mysql.Open(cfg.MySQL.URL), &gorm.Config{})
+	if err != nil {
+		return err
+	}
+
+	rdb := NewRedisClient(cfg)
+	defer func() {
+		_ = rdb.Close()
+	}()
+
+	return command(ctx, cfg, dbConn, rdb, args, os.Stdout)
+}
@@ -9,6 +9,7 @@ import (
 	"gorm.io/driver/mysql"
 	"log"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
@@ -21,10 +22,24 @@ import (
 
 	"app-invite-service/common"
 	"app-invite-service/component"
+	"app-invite-service/component/invitesigner"
+	"app-invite-service/component/mailer"
+	"app-invite-service/component/oidc"
 	"app-invite-service/component/tokenprovider"
 	"app-invite-service/config"
 	"app-invite-service/middleware"
+	"app-invite-service/module/apikey/apikeymodel"
+	"app-invite-service/module/apikey/apikeytransport/ginapikey"
+	"app-invite-service/module/audit/audittransport/ginaudit"
+	"app-invite-service/module/campaign/campaigntransport/gincampaign"
+	"app-invite-service/module/domainrule/domainruletransport/gindomainrule"
+	"app-invite-service/module/invitehistory/invitehistorytransport/gininvitehistory"
+	"app-invite-service/module/invitequota/invitequotatransport/gininvitequota"
+	"app-invite-service/module/mfa/mfatransport/ginmfa"
+	"app-invite-service/module/oauth/oauthtransport/ginoauth"
+	"app-invite-service/module/referral/referraltransport/ginreferral"
 	"app-invite-service/module/user/usertransport/ginuser"
+	"app-invite-service/module/waitlist/waitlisttransport/ginwaitlist"
 )
 
 // Server represents server
@@ -62,6 +77,47 @@ func RunMigration(mysqlURL string) {
 	}
 }
 
+// NewMailer returns the mailer selected by the `mail.driver` config
+func NewMailer(cfg *config.Config) (mailer.Mailer, error) {
+	switch cfg.Mail.Driver {
+	case "smtp":
+		return mailer.NewSMTPMailer(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, cfg.Mail.Password, cfg.Mail.From), nil
+	case "stdout":
+		return mailer.NewStdoutMailer(cfg.Mail.From), nil
+	case "file":
+		return mailer.NewFileMailer(cfg.Mail.Dir, cfg.Mail.From)
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Mail.Driver)
+	}
+}
+
+// NewIdentityProviders returns the upstream OIDC providers from the
+// `federation.providers` config
+func NewIdentityProviders(cfg *config.Config) oidc.Registry {
+	configs := make([]oidc.ProviderConfig, 0, len(cfg.Federation.Providers))
+	for _, p := range cfg.Federation.Providers {
+		configs = append(configs, oidc.ProviderConfig{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientId:     p.ClientId,
+			ClientSecret: os.Getenv(p.ClientSecretEnv),
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		})
+	}
+
+	return oidc.NewRegistry(configs, &http.Client{Timeout: 10 * time.Second})
+}
+
+// NewRedisClient connects to the redis from the `redis` config
+func NewRedisClient(cfg *config.Config) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
+		Password: cfg.Redis.Password,
+		DB:       0,
+	})
+}
+
 // Start start http server
 func Start(serverReady chan bool, cfg *config.Config) {
 	// Create context that listens for the interrupt signal from the OS.
@@ -82,15 +138,38 @@ func Start(serverReady chan bool, cfg *config.Config) {
 		l.Fatal("app - Run - tokenprovider.NewTokenConfig: %s", err)
 	}
 
+	appMailer, err := NewMailer(cfg)
+	if err != nil {
+		l.Fatal("app - Run - NewMailer: %s", err)
+	}
+
+	signer, err := oidc.LoadSigner(cfg.OAuth.SigningKeyFile)
+	if err != nil {
+		l.Fatal("app - Run - oidc.LoadSigner: %s", err)
+	}
+	if cfg.OAuth.SigningKeyFile == "" {
+		l.Warn("oauth.signing_key_file is not set, ID tokens are signed with a temporary key")
+	}
+
+	inviteSigner, err := invitesigner.Load(
+		cfg.SignedInvitation.Algorithm,
+		cfg.SignedInvitation.Secret,
+		cfg.SignedInvitation.PrivateKeyFile,
+	)
+	if err != nil {
+		l.Fatal("app - Run - invitesigner.Load: %s", err)
+	}
+
 	appCtx := component.NewAppContext(
 		dbConn,
-		redis.NewClient(&redis.Options{
-			Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
-			Password: cfg.Redis.Password,
-			DB:       0,
-		}),
+		NewRedisClient(cfg),
 		cfg.App.SecretKey,
 		tokenConfig,
+		appMailer,
+		cfg,
+		signer,
+		NewIdentityProviders(cfg),
+		inviteSigner,
 	)
 
 	routes := InitRoutes(cfg, appCtx)
@@ -141,36 +220,211 @@ func InitRoutes(cfg *config.Config, appCtx component.AppContext) *gin.Engine {
 	}
 
 	r.Use(gin.Recovery())
+	r.Use(middleware.RequestId())
 	r.Use(middleware.Recover(appCtx))
 	r.Use(middleware.CORSMiddleware(cfg))
 	r.Use(middleware.GinContextToContextMiddleware())
 
+	r.GET("/.well-known/openid-configuration", ginoauth.Discovery(appCtx))
+	r.GET("/.well-known/jwks.json", ginoauth.Jwks(appCtx))
+
 	v1 := r.Group("/api/v1")
 
 	v1.POST("/register", ginuser.Register(appCtx))
 	v1.POST("/login", ginuser.Login(appCtx))
 	v1.POST("/login/invitation", ginuser.LoginWithInviteToken(appCtx))
+	v1.POST("/login/mfa", ginuser.LoginWithMfa(appCtx))
+	v1.GET("/login/federated/:provider", ginuser.StartFederatedLogin(appCtx))
+	v1.GET("/login/federated/:provider/link", middleware.RequiredAuth(appCtx), ginuser.StartFederatedLink(appCtx))
+	v1.GET("/login/federated/:provider/callback", ginuser.FederatedLogin(appCtx))
+	v1.POST("/login/federated/:provider/callback", ginuser.FederatedLogin(appCtx))
+
+	v1.POST("/mfa/totp", middleware.RequiredAuth(appCtx), ginmfa.EnrollTotp(appCtx))
+	v1.POST("/mfa/totp/confirm", middleware.RequiredAuth(appCtx), ginmfa.ConfirmTotp(appCtx))
+
+	v1.GET("/email/verification", ginuser.ConfirmEmail(appCtx))
+	v1.POST("/email/verification/resend", ginuser.ResendEmailVerification(appCtx))
 
 	v1.GET("/token/validation", ginuser.ValidateInvitationToken(appCtx))
+	v1.GET("/token/landing", ginuser.GetInvitationLanding(appCtx))
+	v1.GET("/token/signed/key", ginuser.GetSignedInvitationKey(appCtx))
+	v1.POST(
+		"/token/signed/revocations",
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsWrite),
+		middleware.RequiredAdmin(appCtx),
+		ginuser.RevokeSignedInvitation(appCtx),
+	)
 	v1.GET(
 		"/token/invitation",
-		middleware.RequiredAuth(appCtx),
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsRead),
 		middleware.RequiredAdmin(appCtx),
 		ginuser.ListInvitationToken(appCtx),
 	)
+	v1.GET(
+		"/token/invitation/export",
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsRead),
+		middleware.RequiredAdmin(appCtx),
+		ginuser.ExportInvitationTokens(appCtx),
+	)
+	v1.POST(
+		"/token/invitation/import",
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsWrite),
+		middleware.RequiredAdmin(appCtx),
+		ginuser.ImportInvitationTokens(appCtx),
+	)
 	v1.PATCH(
 		"/token/invitation/:id",
-		middleware.RequiredAuth(appCtx),
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsWrite),
 		middleware.RequiredAdmin(appCtx),
 		ginuser.UpdateInvitationToken(appCtx),
 	)
+	v1.DELETE(
+		"/token/invitation/:id",
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsWrite),
+		middleware.RequiredAdmin(appCtx),
+		ginuser.DeleteInvitationToken(appCtx),
+	)
+	v1.POST(
+		"/token/invitation/bulk",
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsWrite),
+		middleware.RequiredAdmin(appCtx),
+		ginuser.BulkUpdateInvitationTokens(appCtx),
+	)
+	v1.GET(
+		"/token/invitation/:id/qrcode",
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsRead),
+		middleware.RequiredAdmin(appCtx),
+		ginuser.GetInvitationQRCode(appCtx),
+	)
 
 	v1.GET(
 		"users/invitation",
-		middleware.RequiredAuth(appCtx),
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsWrite),
 		middleware.RequiredAdmin(appCtx),
 		ginuser.GenerateInviteToken(appCtx),
 	)
+	v1.POST(
+		"users/unlock",
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeUsersWrite),
+		middleware.RequiredAdmin(appCtx),
+		ginuser.UnlockAccount(appCtx),
+	)
+	v1.POST(
+		"users/invitation/signed",
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsWrite),
+		middleware.RequiredAdmin(appCtx),
+		ginuser.GenerateSignedInvitation(appCtx),
+	)
+	v1.POST(
+		"users/invitation/qrcode/sheet",
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsWrite),
+		middleware.RequiredAdmin(appCtx),
+		ginuser.GenerateQRCodeSheet(appCtx),
+	)
+	v1.POST(
+		"users/invitation/email",
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsWrite),
+		middleware.RequiredAdmin(appCtx),
+		ginuser.InviteByEmail(appCtx),
+	)
+
+	invitationEvents := v1.Group(
+		"/invitation-events",
+		middleware.RequiredAuth(appCtx, apikeymodel.ScopeInvitationsRead),
+		middleware.RequiredAdmin(appCtx),
+	)
+	{
+		invitationEvents.GET("", gininvitehistory.ListEvents(appCtx))
+		invitationEvents.GET("/analytics", gininvitehistory.GetAnalytics(appCtx))
+	}
+
+	referrals := v1.Group("/referrals", middleware.RequiredAuth(appCtx))
+	{
+		referrals.POST("/invitation", ginreferral.GeneratePersonalInvitation(appCtx))
+		referrals.GET("/downline", ginreferral.GetMyDownline(appCtx))
+
+		referralUsers := referrals.Group("/users", middleware.RequiredAdmin(appCtx))
+		referralUsers.GET("/:id/downline", ginreferral.GetUserDownline(appCtx))
+		referralUsers.POST("/:id/revoke", ginreferral.RevokeSubtree(appCtx))
+	}
+
+	campaigns := v1.Group(
+		"/campaigns",
+		middleware.RequiredAuth(appCtx),
+		middleware.RequiredAdmin(appCtx),
+	)
+	{
+		campaigns.GET("", gincampaign.ListCampaigns(appCtx))
+		campaigns.POST("", gincampaign.CreateCampaign(appCtx))
+		campaigns.GET("/:id", gincampaign.GetCampaign(appCtx))
+		campaigns.POST("/:id/disable", gincampaign.DisableCampaign(appCtx))
+	}
+
+	waitlist := v1.Group("/waitlist")
+	{
+		waitlist.POST("", ginwaitlist.JoinWaitlist(appCtx))
+		waitlist.GET("/position", ginwaitlist.GetPosition(appCtx))
+
+		entries := waitlist.Group("/entries", middleware.RequiredAuth(appCtx), middleware.RequiredAdmin(appCtx))
+		entries.GET("", ginwaitlist.ListEntries(appCtx))
+		entries.POST("/approve", ginwaitlist.ApproveEntries(appCtx))
+		entries.POST("/reject", ginwaitlist.RejectEntries(appCtx))
+	}
+
+	inviteQuota := v1.Group("/invite-quota", middleware.RequiredAuth(appCtx))
+	{
+		inviteQuota.GET("", gininvitequota.GetMyAllowance(appCtx))
+
+		quotaUsers := inviteQuota.Group("/users", middleware.RequiredAdmin(appCtx))
+		quotaUsers.GET("/:id", gininvitequota.GetUserAllowance(appCtx))
+		quotaUsers.PUT("/:id", gininvitequota.SetUserQuota(appCtx))
+		quotaUsers.POST("/:id/grants", gininvitequota.GrantQuota(appCtx))
+	}
+
+	emailDomains := v1.Group(
+		"/email-domains",
+		middleware.RequiredAuth(appCtx),
+		middleware.RequiredAdmin(appCtx),
+	)
+	{
+		emailDomains.GET("", gindomainrule.ListRules(appCtx))
+		emailDomains.POST("", gindomainrule.CreateRule(appCtx))
+		emailDomains.DELETE("/:id", gindomainrule.DeleteRule(appCtx))
+	}
+
+	apiKeys := v1.Group(
+		"/api-keys",
+		middleware.RequiredAuth(appCtx),
+		middleware.RequiredAdmin(appCtx),
+	)
+	{
+		apiKeys.GET("", ginapikey.ListApiKeys(appCtx))
+		apiKeys.POST("", ginapikey.CreateApiKey(appCtx))
+		apiKeys.DELETE("/:id", ginapikey.RevokeApiKey(appCtx))
+	}
+
+	auditEvents := v1.Group(
+		"/audit-events",
+		middleware.RequiredAuth(appCtx),
+		middleware.RequiredAdmin(appCtx),
+	)
+	{
+		auditEvents.GET("", ginaudit.ListEvents(appCtx))
+		auditEvents.GET("/export", ginaudit.ExportEvents(appCtx))
+	}
+
+	oauth := v1.Group("/oauth")
+	{
+		oauth.GET("/authorize", middleware.RequiredAuth(appCtx), ginoauth.Authorize(appCtx))
+		oauth.POST("/authorize", middleware.RequiredAuth(appCtx), ginoauth.Authorize(appCtx))
+		oauth.POST("/token", ginoauth.Token(appCtx))
+		oauth.GET("/userinfo", ginoauth.UserInfo(appCtx))
+
+		clients := oauth.Group("/clients", middleware.RequiredAuth(appCtx), middleware.RequiredAdmin(appCtx))
+		clients.GET("", ginoauth.ListClients(appCtx))
+		clients.POST("", ginoauth.CreateClient(appCtx))
+		clients.DELETE("/:id", ginoauth.DeleteClient(appCtx))
+	}
 
 	return r
 }